@@ -0,0 +1,130 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/cobra"
+	"github.com/wangdayong228/ydyl-deploy-client/internal/deploy"
+)
+
+var (
+	costPricingPath string
+	costOutputDir   string
+	costDetail      bool
+)
+
+func init() {
+	cmd := &cobra.Command{
+		Use:   "cost",
+		Short: "根据部署输出与本地单价表估算本次部署成本",
+		Long: `读取 output 目录下的 servers_create.json / servers.json / deployment.json，结合本地单价表（机型 -> 每小时价格）估算成本。
+
+计算规则：
+  - 预计成本 = 小时单价 × runDuration（到期后实例自动关机）
+  - 已花费   = 小时单价 × min(当前时间 - 实例启动时间, runDuration)
+  - 未记录机型/启动时间的旧版本输出，分别按配置中该服务的首选机型、deployment.json 的运行时间推断
+
+单价表为 YAML，示例：
+  c6a.xlarge: 0.153
+  c6a.2xlarge: 0.306`,
+		RunE: runCost,
+	}
+
+	cmd.Flags().StringVarP(&configPath, "config", "f", "./config.deploy.yaml", "部署配置文件路径（YAML），用于读取 outputDir/runDuration/services")
+	cmd.Flags().StringVar(&costPricingPath, "pricing", "./pricing.yaml", "本地单价表路径（instanceType -> USD/小时）")
+	cmd.Flags().StringVar(&costOutputDir, "output-dir", "", "部署输出目录（默认使用配置中的 outputDir）")
+	cmd.Flags().BoolVar(&costDetail, "detail", false, "输出每台实例的明细")
+
+	rootCmd.AddCommand(cmd)
+}
+
+func runCost(_ *cobra.Command, _ []string) error {
+	cfg := deploy.LoadConfigFromFile(configPath)
+
+	pricing, err := deploy.LoadPricingTable(costPricingPath)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "cost 失败：", err)
+		return err
+	}
+
+	report, err := deploy.EstimateCost(*cfg, costOutputDir, pricing, time.Now())
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "cost 失败：", err)
+		return err
+	}
+
+	if costDetail {
+		printCostDetailTable(report)
+		fmt.Println()
+	}
+	printCostSummaryTable(report)
+	return nil
+}
+
+func printCostSummaryTable(report *deploy.CostReport) {
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"SERVICE", "INSTANCE TYPE", "COUNT", "RATE/H", "TOTAL/H", "ESTIMATED", "SPENT"})
+	table.SetBorder(true)
+	table.SetAutoWrapText(false)
+	table.SetHeaderAlignment(tablewriter.ALIGN_LEFT)
+	table.SetAlignment(tablewriter.ALIGN_LEFT)
+
+	count := 0
+	for _, s := range report.Summaries {
+		count += s.Count
+		table.Append([]string{
+			s.ServiceType,
+			s.InstanceType,
+			fmt.Sprintf("%d", s.Count),
+			fmtUSD(s.HourlyRate),
+			fmtUSD(s.Hourly),
+			fmtUSD(s.Estimated),
+			fmtUSD(s.Spent),
+		})
+	}
+	table.SetFooter([]string{"TOTAL", "", fmt.Sprintf("%d", count), "", fmtUSD(report.TotalHourly), fmtUSD(report.TotalEstimated), fmtUSD(report.TotalSpent)})
+	table.Render()
+
+	deploymentID := report.DeploymentID
+	if deploymentID == "" {
+		deploymentID = "-"
+	}
+	fmt.Printf("\ndeploymentId=%s  runDuration=%s  统计时间=%s\n", deploymentID, report.RunDuration, report.GeneratedAt.Format(time.RFC3339))
+	if len(report.MissingPrices) > 0 {
+		fmt.Printf("⚠️ 单价表缺少以下机型，未计入合计: %v\n", report.MissingPrices)
+	}
+}
+
+func printCostDetailTable(report *deploy.CostReport) {
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"NAME", "TYPE", "IP", "INSTANCE", "INSTANCE TYPE", "LAUNCHED", "ELAPSED", "ESTIMATED", "SPENT"})
+	table.SetBorder(true)
+	table.SetAutoWrapText(false)
+	table.SetHeaderAlignment(tablewriter.ALIGN_LEFT)
+	table.SetAlignment(tablewriter.ALIGN_LEFT)
+
+	for _, item := range report.Items {
+		instanceType := item.InstanceType
+		if item.InstanceTypeGuessed {
+			instanceType += " (推断)"
+		}
+		launched, elapsed := "-", "-"
+		if !item.LaunchTime.IsZero() {
+			launched = item.LaunchTime.UTC().Format("01-02 15:04:05")
+			elapsed = fmtDuration(item.Elapsed)
+		}
+		estimated, spent := "-", "-"
+		if item.PriceKnown {
+			estimated, spent = fmtUSD(item.Estimated), fmtUSD(item.Spent)
+		}
+		table.Append([]string{item.Name, item.ServiceType, item.IP, item.InstanceID, instanceType, launched, elapsed, estimated, spent})
+	}
+	table.Render()
+}
+
+func fmtUSD(v float64) string {
+	return fmt.Sprintf("$%.2f", v)
+}
@@ -5,7 +5,9 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"syscall"
 
 	"github.com/spf13/cobra"
 	"github.com/wangdayong228/ydyl-deploy-client/internal/deploy"
@@ -14,6 +16,10 @@ import (
 var (
 	configPath        string
 	serversCreatePath string
+	deployAppend      bool
+	deployResume      bool
+	deployOnly        []string
+	deploySkip        []string
 )
 
 func init() {
@@ -26,18 +32,28 @@ func init() {
 
 	cmd.Flags().StringVarP(&configPath, "config", "f", "./config.deploy.yaml", "部署配置文件路径（YAML）")
 	cmd.Flags().StringVar(&serversCreatePath, "servers-create", "", "已有 servers_create.json 路径（会先复制到临时文件再部署；不传则按配置新建 EC2）")
+	cmd.Flags().BoolVar(&deployAppend, "append", false, "在已有部署上扩容：不归档 output/logs，count 视为目标总数，仅创建并配置新增节点")
+	cmd.Flags().BoolVar(&deployResume, "resume", false, "从 state.json 记录的阶段进度继续上次被中断的 deploy（跳过已完成的充值 / 创建 / SSH / 命令启动）")
+	cmd.Flags().StringSliceVar(&deployOnly, "only", nil, "只执行指定阶段（逗号分隔）：fund,launch,ssh,tag,exec,monitor,sync；不含 launch 时在已有 output 上继续")
+	cmd.Flags().StringSliceVar(&deploySkip, "skip", nil, "跳过指定阶段（逗号分隔，取值同 --only，不能与 --only 同时使用）")
 	rootCmd.AddCommand(cmd)
 }
 
 func runDeploy(cmd *cobra.Command, args []string) error {
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
+	// Ctrl+C / SIGTERM 取消 ctx：各阶段尽快返回并保存进度，之后可用 --resume 继续。
+	// 首次信号后恢复默认处理，保存进度卡住时再按一次 Ctrl+C 可强制退出。
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
 
 	cfg := deploy.LoadConfigFromFile(configPath)
 	clientLogFile := clientLogPath(cfg.CommonConfig.LogDir, "deploy")
 
 	return withClientCommandTee(clientLogFile, func() error {
-		opts := deploy.RunOptions{}
+		opts := deploy.RunOptions{Append: deployAppend, Resume: deployResume, Only: deployOnly, Skip: deploySkip}
 		if serversCreatePath != "" {
 			origAbs, err := filepath.Abs(serversCreatePath)
 			if err != nil {
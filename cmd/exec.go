@@ -0,0 +1,151 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/cobra"
+	"github.com/wangdayong228/ydyl-deploy-client/internal/deploy"
+)
+
+var (
+	execServersPath string
+	execTypes       []string
+	execNameGlobs   []string
+	execStatuses    []string
+	execTimeout     time.Duration
+	execJSONPath    string
+)
+
+// execGroupNamesLimit 为分组汇总中每组最多列出的节点名称数。
+const execGroupNamesLimit = 10
+
+func init() {
+	cmd := &cobra.Command{
+		Use:   "exec [flags] -- <command>",
+		Short: "在选中节点上并发执行临时命令并汇总输出",
+		Long: `按类型 / 名称 / 状态选中 servers.json / script_status.json 中的节点，通过 SSH 并发执行同一条命令：
+
+  exec --type xjst -- 'docker ps --format "{{.Names}}"'
+  exec --name-glob 'ydyl-op-*' -- df -h /
+  exec --status failed --json failed.json -- tail -n 50 /home/ubuntu/ydyl-deploy-logs/*.log
+
+  - 同类选择器可重复指定（或逗号分隔），之间为“或”；不同类选择器之间为“且”；不指定则选中全部节点
+  - 并发度与 SSH 用户 / 私钥沿用配置中的 sshMaxConcurrency / sshUser / sshKeyDir + keyName
+  - 输出完全相同（退出码、stdout、stderr）的节点合并展示
+  - 任一节点失败（含 SSH 失败、超时、退出码非 0）时命令以非 0 退出`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: runExec,
+	}
+
+	cmd.Flags().StringVarP(&configPath, "config", "f", "./config.deploy.yaml", "部署配置文件路径（YAML），用于读取 SSH/outputDir 配置")
+	cmd.Flags().StringVar(&execServersPath, "servers", "", "servers.json 路径（默认使用 outputDir/servers.json）")
+	cmd.Flags().StringArrayVar(&execTypes, "type", nil, "按服务类型选择，例如 xjst（可重复指定）")
+	cmd.Flags().StringArrayVar(&execNameGlobs, "name-glob", nil, "按节点名称 glob 选择，例如 'ydyl-xjst-2-*'（可重复指定）")
+	cmd.Flags().StringArrayVar(&execStatuses, "status", nil, "按 script_status.json 中的脚本状态选择，例如 failed（可重复指定）")
+	cmd.Flags().DurationVar(&execTimeout, "timeout", 5*time.Minute, "单个节点的执行超时，0 表示不限")
+	cmd.Flags().StringVar(&execJSONPath, "json", "", "将每个节点的完整结果以 JSON 写入该文件")
+
+	rootCmd.AddCommand(cmd)
+}
+
+func runExec(_ *cobra.Command, args []string) error {
+	ctx := context.Background()
+	cfg := deploy.LoadConfigFromFile(configPath)
+
+	results, err := deploy.Exec(ctx, cfg.CommonConfig, deploy.ExecOptions{
+		ServersPath: execServersPath,
+		Types:       execTypes,
+		NameGlobs:   execNameGlobs,
+		Statuses:    execStatuses,
+		Command:     strings.Join(args, " "),
+		Timeout:     execTimeout,
+	})
+	if len(results) > 0 {
+		printExecTable(results)
+		printExecGroups(deploy.GroupExecResults(results))
+	}
+	if len(results) > 0 && execJSONPath != "" {
+		if werr := writeExecJSON(execJSONPath, results); werr != nil {
+			fmt.Fprintln(os.Stderr, "exec 失败：", werr)
+			return werr
+		}
+		fmt.Printf("已写出 %d 个节点的结果到 %s\n", len(results), execJSONPath)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "exec 失败：", err)
+		return err
+	}
+	return nil
+}
+
+func printExecTable(results []deploy.ExecResult) {
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"NAME", "TYPE", "IP", "STATUS", "EXIT", "DURATION", "RESULT"})
+	table.SetBorder(true)
+	table.SetAutoWrapText(false)
+	table.SetHeaderAlignment(tablewriter.ALIGN_LEFT)
+	table.SetAlignment(tablewriter.ALIGN_LEFT)
+
+	for _, r := range results {
+		status := r.Status
+		if status == "" {
+			status = "-"
+		}
+		exitCode, result := strconv.Itoa(r.ExitCode), "✅"
+		switch {
+		case r.Error != "":
+			exitCode = "-"
+			result = "❌ " + truncateStr(r.Error, 40)
+		case r.ExitCode != 0:
+			result = "❌"
+		}
+		duration := fmtDuration(time.Duration(r.DurationMs) * time.Millisecond)
+		table.Append([]string{r.Name, r.ServiceType, r.IP, status, exitCode, duration, result})
+	}
+	table.Render()
+}
+
+func printExecGroups(groups []deploy.ExecOutputGroup) {
+	for i, g := range groups {
+		names := make([]string, 0, len(g.Results))
+		for _, r := range g.Results {
+			names = append(names, r.Name)
+		}
+		if len(names) > execGroupNamesLimit {
+			names = append(names[:execGroupNamesLimit:execGroupNamesLimit], fmt.Sprintf("...等 %d 个", len(g.Results)))
+		}
+		outcome := fmt.Sprintf("exit=%d", g.ExitCode)
+		if g.Error != "" {
+			outcome = "error"
+		}
+		fmt.Printf("\n===== [%d/%d] %d 个节点 %s: %s =====\n", i+1, len(groups), len(g.Results), outcome, strings.Join(names, ", "))
+		if g.Error != "" {
+			fmt.Println(g.Error)
+		}
+		if out := strings.TrimRight(g.Stdout, "\n"); out != "" {
+			fmt.Println(out)
+		}
+		if out := strings.TrimRight(g.Stderr, "\n"); out != "" && g.Error == "" {
+			fmt.Println("----- stderr -----")
+			fmt.Println(out)
+		}
+	}
+}
+
+func writeExecJSON(path string, results []deploy.ExecResult) error {
+	b, err := json.MarshalIndent(results, "", "  ")
+	if err != nil {
+		return fmt.Errorf("序列化结果失败: %w", err)
+	}
+	if err := os.WriteFile(path, b, 0o644); err != nil {
+		return fmt.Errorf("写出 %s 失败: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,99 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/cobra"
+	"github.com/wangdayong228/ydyl-deploy-client/internal/deploy"
+)
+
+var (
+	extendServersPath string
+	extendBy          time.Duration
+	extendUntil       string
+	extendCancel      bool
+)
+
+func init() {
+	cmd := &cobra.Command{
+		Use:   "extend",
+		Short: "顺延、提前或取消运行中节点的自动关机时间",
+		Long: `deploy 启动远端脚本时会下发 sudo -n shutdown -h +N（N 来自 runDuration）。
+extend 通过 SSH 在 servers.json 中每台机器上重新下发关机计划，并把新的关机时间写入 script_status.json：
+
+  extend --by 2h                  在当前关机时间基础上顺延 2 小时（负数表示提前）
+  extend --until "2026-01-02 18:00"  指定新的关机时间（也支持 RFC3339 或 "15:04"）
+  extend --cancel                 取消自动关机`,
+		RunE: runExtend,
+	}
+
+	cmd.Flags().StringVarP(&configPath, "config", "f", "./config.deploy.yaml", "部署配置文件路径（YAML），用于读取 SSH/outputDir 配置")
+	cmd.Flags().StringVar(&extendServersPath, "servers", "", "servers.json 路径（默认使用 outputDir/servers.json）")
+	cmd.Flags().DurationVar(&extendBy, "by", 0, "在当前关机时间基础上顺延的时长（例如 2h、-30m）")
+	cmd.Flags().StringVar(&extendUntil, "until", "", "新的关机时间")
+	cmd.Flags().BoolVar(&extendCancel, "cancel", false, "取消自动关机")
+
+	rootCmd.AddCommand(cmd)
+}
+
+func runExtend(_ *cobra.Command, _ []string) error {
+	ctx := context.Background()
+	cfg := deploy.LoadConfigFromFile(configPath)
+
+	opts := deploy.ExtendOptions{
+		ServersPath: extendServersPath,
+		By:          extendBy,
+		Cancel:      extendCancel,
+	}
+	if extendUntil != "" {
+		until, err := deploy.ParseShutdownDeadline(extendUntil, time.Now())
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "extend 失败：", err)
+			return err
+		}
+		opts.Until = until
+	}
+
+	results, err := deploy.Extend(ctx, cfg.CommonConfig, opts)
+	if len(results) > 0 {
+		printExtendTable(results)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "extend 失败：", err)
+		return err
+	}
+	return nil
+}
+
+func printExtendTable(results []deploy.ExtendResult) {
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"NAME", "TYPE", "IP", "PREVIOUS", "SHUTDOWN AT", "REMAINING", "RESULT"})
+	table.SetBorder(true)
+	table.SetAutoWrapText(false)
+	table.SetHeaderAlignment(tablewriter.ALIGN_LEFT)
+	table.SetAlignment(tablewriter.ALIGN_LEFT)
+
+	now := time.Now()
+	for _, r := range results {
+		prev := "-"
+		if !r.PrevShutdownAt.IsZero() {
+			prev = r.PrevShutdownAt.Local().Format("01-02 15:04")
+		}
+		next, remaining, result := "-", "-", "✅"
+		switch {
+		case r.Err != nil:
+			result = "❌ " + truncateStr(r.Err.Error(), 40)
+		case r.Canceled:
+			next = "canceled"
+		default:
+			next = r.ShutdownAt.Local().Format("01-02 15:04")
+			remaining = fmtDuration(r.ShutdownAt.Sub(now))
+		}
+		table.Append([]string{r.Name, r.ServiceType, r.IP, prev, next, remaining, result})
+	}
+	table.Render()
+}
@@ -0,0 +1,146 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/cobra"
+	"github.com/wangdayong228/ydyl-deploy-client/internal/deploy"
+)
+
+var (
+	inventoryServersPath     string
+	inventoryDeploymentID    string
+	inventoryTerminateOrphan bool
+	inventoryYes             bool
+)
+
+func init() {
+	cmd := &cobra.Command{
+		Use:   "inventory",
+		Short: "列出所有带部署标签的实例并找出孤儿实例",
+		Long: `按 ydyl:deployment-id 标签查询 EC2 上所有未终止的实例，并与 servers.json 对比：
+
+  - managed — 实例在 servers.json 中，属于当前部署
+  - orphan  — 实例带当前部署标签但不在 servers.json 中（SSH 未就绪被丢弃、补机多出等），仍在计费
+  - other   — 实例属于其它部署（旧部署遗留或并发运行中的部署），仅列出
+  - missing — servers.json 中的条目在 EC2 上找不到存活实例
+
+当前部署默认取 servers.json 同目录 deployment.json 中的 deploymentId，可通过 --deployment-id 指定。
+加 --terminate-orphans 可在确认后终止当前部署的孤儿实例，不会终止其它部署的实例。`,
+		RunE: runInventory,
+	}
+
+	cmd.Flags().StringVarP(&configPath, "config", "f", "./config.deploy.yaml", "部署配置文件路径（YAML），用于读取 region/outputDir")
+	cmd.Flags().StringVar(&inventoryServersPath, "servers", "", "servers.json 路径（默认使用 outputDir/servers.json）")
+	cmd.Flags().StringVar(&inventoryDeploymentID, "deployment-id", "", "只查询指定 deploymentId 的实例并以其判定孤儿（默认查询所有部署，以 deployment.json 判定孤儿）")
+	cmd.Flags().BoolVar(&inventoryTerminateOrphan, "terminate-orphans", false, "终止当前部署的孤儿实例（执行前会要求确认）")
+	cmd.Flags().BoolVarP(&inventoryYes, "yes", "y", false, "配合 --terminate-orphans 使用，跳过确认")
+
+	rootCmd.AddCommand(cmd)
+}
+
+func runInventory(_ *cobra.Command, _ []string) error {
+	ctx := context.Background()
+	cfg := deploy.LoadConfigFromFile(configPath)
+
+	report, err := deploy.Inventory(ctx, cfg.CommonConfig, deploy.InventoryOptions{
+		ServersPath:  inventoryServersPath,
+		DeploymentID: inventoryDeploymentID,
+	})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "inventory 失败：", err)
+		return err
+	}
+
+	printInventoryTable(report)
+
+	orphans := report.Orphans()
+	if !inventoryTerminateOrphan {
+		return nil
+	}
+	if report.DeploymentID == "" {
+		err := fmt.Errorf("无法确定当前部署，请通过 --deployment-id 指定要清理孤儿实例的部署")
+		fmt.Fprintln(os.Stderr, "inventory 失败：", err)
+		return err
+	}
+	if len(orphans) == 0 {
+		return nil
+	}
+	if !inventoryYes && !confirmPrompt(fmt.Sprintf("确认终止部署 %s 的 %d 台孤儿实例？[y/N]: ", report.DeploymentID, len(orphans))) {
+		fmt.Println("已取消")
+		return nil
+	}
+
+	ids, err := deploy.TerminateOrphans(ctx, cfg.CommonConfig, report)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "inventory 终止孤儿实例失败：", err)
+		return err
+	}
+	fmt.Printf("✅ 已提交终止 %d 台孤儿实例\n", len(ids))
+	return nil
+}
+
+func printInventoryTable(report *deploy.InventoryReport) {
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"INSTANCE", "NAME", "TYPE", "STATE", "IP", "INSTANCE TYPE", "DEPLOYMENT", "OPERATOR", "AGE", "STATUS"})
+	table.SetBorder(true)
+	table.SetAutoWrapText(false)
+	table.SetHeaderAlignment(tablewriter.ALIGN_LEFT)
+	table.SetAlignment(tablewriter.ALIGN_LEFT)
+
+	now := time.Now()
+	for _, e := range report.Entries {
+		status := "managed"
+		switch {
+		case e.Foreign:
+			status = "other"
+		case e.Orphan():
+			status = "⚠️ orphan"
+		}
+		table.Append([]string{
+			e.InstanceID,
+			e.Name,
+			e.ServiceType,
+			e.State,
+			e.PublicIP,
+			e.InstanceType,
+			e.DeploymentID,
+			e.Operator,
+			fmtDuration(now.Sub(e.LaunchTime)),
+			status,
+		})
+	}
+	table.Render()
+
+	orphans, foreign := len(report.Orphans()), 0
+	for _, e := range report.Entries {
+		if e.Foreign {
+			foreign++
+		}
+	}
+	scope := report.DeploymentID
+	if scope == "" {
+		scope = "未知"
+	}
+	fmt.Printf("\n当前部署 %s：共 %d 台带标签实例  managed=%d  ⚠️ orphan=%d  other=%d  ❌ missing=%d\n", scope, len(report.Entries), len(report.Entries)-orphans-foreign, orphans, foreign, len(report.Missing))
+	for _, m := range report.Missing {
+		fmt.Printf("❌ missing: %s (%s, %s)\n", m.Name, m.ServiceType, m.IP)
+	}
+}
+
+// confirmPrompt 在终端读取一行输入，仅 y/yes 视为确认。
+func confirmPrompt(prompt string) bool {
+	fmt.Print(prompt)
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && line == "" {
+		return false
+	}
+	answer := strings.ToLower(strings.TrimSpace(line))
+	return answer == "y" || answer == "yes"
+}
@@ -0,0 +1,123 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/cobra"
+	"github.com/wangdayong228/ydyl-deploy-client/internal/deploy"
+)
+
+var (
+	removeServersPath string
+	removeNames       []string
+	removeTerminate   bool
+	removeYes         bool
+)
+
+func init() {
+	cmd := &cobra.Command{
+		Use:   "remove",
+		Short: "从部署中移除指定链（停止远端脚本，可选终止实例）",
+		Long: `按名称 glob 选中 servers.json / script_status.json 中的节点并从部署中移除：
+
+  remove --name ydyl-op-3 --name 'ydyl-xjst-2-*'
+
+  - 停止远端 pipe 脚本（script_status.json 中记录的 PID 及其子进程）
+  - 加 --terminate 时通过 EC2 API 终止实例
+  - 从 servers.json / script_status.json / ssh_scripts.json 中删除条目
+  - xjst 以分组为单位：选中组内任一节点即整组移除，组内节点先做 SSH 预检，任一不可达则整组保留；
+    全部成员停止后才终止实例、删除条目，已停止/终止部分成员后失败的分组标记为部分移除（⚠️ partial）
+
+移除后重新执行 gen-cross-tx-config 即可生成不含这些链的 jobs。`,
+		RunE: runRemove,
+	}
+
+	cmd.Flags().StringVarP(&configPath, "config", "f", "./config.deploy.yaml", "部署配置文件路径（YAML），用于读取 SSH/region/outputDir 配置")
+	cmd.Flags().StringVar(&removeServersPath, "servers", "", "servers.json 路径（默认使用 outputDir/servers.json）")
+	cmd.Flags().StringArrayVar(&removeNames, "name", nil, "要移除的节点名称，支持 glob（可重复指定）")
+	cmd.Flags().BoolVar(&removeTerminate, "terminate", false, "同时通过 EC2 API 终止实例")
+	cmd.Flags().BoolVarP(&removeYes, "yes", "y", false, "跳过确认")
+	_ = cmd.MarkFlagRequired("name")
+
+	rootCmd.AddCommand(cmd)
+}
+
+func runRemove(_ *cobra.Command, _ []string) error {
+	ctx := context.Background()
+	cfg := deploy.LoadConfigFromFile(configPath)
+
+	opts := deploy.RemoveOptions{
+		ServersPath: removeServersPath,
+		Names:       removeNames,
+		Terminate:   removeTerminate,
+	}
+	targets, err := deploy.ResolveRemoveTargets(cfg.CommonConfig, opts)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "remove 失败：", err)
+		return err
+	}
+	printRemoveTable(targets)
+	if !removeYes {
+		action := "移除"
+		if removeTerminate {
+			action = "移除并终止"
+		}
+		if !confirmPrompt(fmt.Sprintf("确认%s以上 %d 个节点？[y/N] ", action, len(targets))) {
+			fmt.Println("已取消")
+			return nil
+		}
+	}
+
+	results, err := deploy.Remove(ctx, cfg.CommonConfig, opts)
+	if len(results) > 0 {
+		printRemoveTable(results)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "remove 失败：", err)
+		return err
+	}
+	return nil
+}
+
+func printRemoveTable(targets []deploy.RemoveTarget) {
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"NAME", "TYPE", "IP", "PID", "STATUS", "INSTANCE", "RESULT"})
+	table.SetBorder(true)
+	table.SetAutoWrapText(false)
+	table.SetHeaderAlignment(tablewriter.ALIGN_LEFT)
+	table.SetAlignment(tablewriter.ALIGN_LEFT)
+
+	for _, t := range targets {
+		name := t.Name
+		if t.Expanded {
+			name += " (同组)"
+		}
+		pid := "-"
+		if t.PID > 0 {
+			pid = strconv.Itoa(t.PID)
+		}
+		instance := t.InstanceID
+		if instance == "" {
+			instance = "-"
+		}
+		result := "-"
+		switch {
+		case t.Err != nil && t.Partial:
+			result = "⚠️ partial: " + truncateStr(t.Err.Error(), 40)
+		case t.Err != nil:
+			result = "❌ " + truncateStr(t.Err.Error(), 40)
+		case t.Removed && t.Partial:
+			result = "⚠️ partial: removed+terminated"
+		case t.Removed && t.Terminated:
+			result = "✅ removed+terminated"
+		case t.Removed:
+			result = "✅ removed"
+		}
+		table.Append([]string{name, t.ServiceType, t.IP, pid, t.Status, instance, result})
+	}
+	table.Render()
+}
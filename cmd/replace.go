@@ -0,0 +1,70 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/wangdayong228/ydyl-deploy-client/internal/deploy"
+)
+
+var (
+	replaceServersPath  string
+	replaceName         string
+	replaceRestartGroup bool
+	replaceTerminateOld bool
+)
+
+func init() {
+	cmd := &cobra.Command{
+		Use:   "replace",
+		Short: "为失效节点新建实例并原位接管其身份（名称 / chainId / groupId / vault 私钥不变）",
+		Long: `deploy-restore 只能在原 IP 上重跑；当实例本身失效时，replace 会：
+
+  - 按配置文件中对应 service 的参数新建一台实例，等待 SSH 就绪后把 Name 标签改为原逻辑名称
+  - 以相同索引重建远端命令（L2 chainId / xjst groupId / L1 vault 私钥保持不变）
+  - 将 servers.json / script_status.json 中的旧 IP 改写为新 IP
+  - xjst 节点同时重新渲染同组其它节点的 CHAIN_NODE_IPS（加 --restart-group 时一并重跑）
+  - 在新实例上启动部署命令并同步日志与状态`,
+		RunE: runReplace,
+	}
+
+	cmd.Flags().StringVarP(&configPath, "config", "f", "./config.deploy.yaml", "部署配置文件路径（YAML），需包含生成该节点的 service 配置")
+	cmd.Flags().StringVar(&replaceServersPath, "servers", "", "servers.json 路径（默认使用 outputDir/servers.json）")
+	cmd.Flags().StringVar(&replaceName, "name", "", "要替换的节点名称（servers.json 中的 name）")
+	cmd.Flags().BoolVar(&replaceRestartGroup, "restart-group", false, "xjst：同组其它节点也按新的 CHAIN_NODE_IPS 重新执行部署命令")
+	cmd.Flags().BoolVar(&replaceTerminateOld, "terminate-old", false, "通过 EC2 API 终止旧实例（若仍存活）")
+	_ = cmd.MarkFlagRequired("name")
+
+	rootCmd.AddCommand(cmd)
+}
+
+func runReplace(_ *cobra.Command, _ []string) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cfg := deploy.LoadConfigFromFile(configPath)
+	clientLogFile := clientLogPath(cfg.CommonConfig.LogDir, "replace")
+
+	return withClientCommandTee(clientLogFile, func() error {
+		result, err := deploy.Replace(ctx, *cfg, deploy.ReplaceOptions{
+			ServersPath:  replaceServersPath,
+			Name:         replaceName,
+			RestartGroup: replaceRestartGroup,
+			TerminateOld: replaceTerminateOld,
+		})
+		if result != nil {
+			fmt.Printf("%s [%s]: %s -> %s (%s)\n", result.Name, result.ServiceType, result.OldIP, result.NewIP, result.InstanceID)
+			if len(result.GroupIPs) > 0 {
+				fmt.Printf("同组节点命令已更新: %s\n", strings.Join(result.GroupIPs, ", "))
+			}
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "replace 失败：", err)
+			return err
+		}
+		return nil
+	})
+}
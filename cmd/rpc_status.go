@@ -4,11 +4,13 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"path/filepath"
 	"time"
 
 	"github.com/olekukonko/tablewriter"
 	"github.com/spf13/cobra"
 	"github.com/wangdayong228/ydyl-deploy-client/internal/chainhealth"
+	"github.com/wangdayong228/ydyl-deploy-client/internal/deploy"
 )
 
 var (
@@ -53,13 +55,47 @@ func runChainStatus(_ *cobra.Command, _ []string) error {
 		return err
 	}
 
-	printStatusTable(results)
+	printStatusTable(results, loadShutdownRemaining(chainStatusServersPath, time.Now()), loadRepoCommits(chainStatusServersPath))
 	return nil
 }
 
-func printStatusTable(nodes []chainhealth.NodeHealth) {
+// loadShutdownRemaining 从 servers.json 同目录的 script_status.json 读取各节点距离自动关机的剩余时间（按 IP）。
+func loadShutdownRemaining(serversPath string, now time.Time) map[string]string {
+	out := make(map[string]string)
+	mgr, err := deploy.LoadOutputManager(filepath.Dir(serversPath))
+	if err != nil {
+		return out
+	}
+	for _, st := range mgr.SnapshotStatuses() {
+		if st.ShutdownCanceled {
+			out[st.IP] = "canceled"
+			continue
+		}
+		if remaining, ok := st.ShutdownRemaining(now); ok {
+			out[st.IP] = fmtDuration(remaining)
+		}
+	}
+	return out
+}
+
+// loadRepoCommits 从 servers.json 同目录的 script_status.json 读取各节点的部署仓库 commit（按 IP）。
+func loadRepoCommits(serversPath string) map[string]string {
+	out := make(map[string]string)
+	mgr, err := deploy.LoadOutputManager(filepath.Dir(serversPath))
+	if err != nil {
+		return out
+	}
+	for _, st := range mgr.SnapshotStatuses() {
+		if st.RepoCommit != "" {
+			out[st.IP] = deploy.ShortCommit(st.RepoCommit)
+		}
+	}
+	return out
+}
+
+func printStatusTable(nodes []chainhealth.NodeHealth, shutdownIn, repoCommits map[string]string) {
 	table := tablewriter.NewWriter(os.Stdout)
-	table.SetHeader([]string{"NAME", "TYPE", "BLOCK", "TIME", "AGE", "STATUS"})
+	table.SetHeader([]string{"NAME", "TYPE", "BLOCK", "TIME", "AGE", "SHUTDOWN IN", "CODE", "STATUS"})
 	table.SetBorder(true)
 	table.SetAutoWrapText(false)
 	table.SetHeaderAlignment(tablewriter.ALIGN_LEFT)
@@ -67,12 +103,18 @@ func printStatusTable(nodes []chainhealth.NodeHealth) {
 	table.SetColMinWidth(0, 20)
 
 	for _, n := range nodes {
+		remaining, ok := shutdownIn[n.IP]
+		if !ok {
+			remaining = "-"
+		}
 		row := []string{
 			n.Name,
 			n.ServiceType,
 			endpointBlock(n.L2),
 			endpointTime(n.L2),
 			endpointAge(n.L2),
+			remaining,
+			dashIfEmpty(repoCommits[n.IP]),
 			n.Overall().Emoji(),
 		}
 		table.Append(row)
@@ -0,0 +1,254 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/cobra"
+	"github.com/wangdayong228/ydyl-deploy-client/internal/deploy"
+)
+
+var runsOutputDir string
+
+func init() {
+	cmd := &cobra.Command{
+		Use:   "runs",
+		Short: "查看历史部署记录（当前 output 与已归档的 output-<ts> 目录）",
+		Long: `每次 deploy 会在 output 目录写入 run.json，记录 deploymentId、脱敏后的配置及其哈希、services、起止时间与结果；
+output 目录被下次 deploy 归档为 output-<ts> 后仍可通过 runs 查询。节点数、最终状态统计与 jobs 配置（jobs/all.json）按目录中的最新状态计算。
+
+  runs list           列出所有运行记录（新 -> 旧）
+  runs show <id>      查看单次运行详情，<id> 为 deploymentId、目录名或其唯一前缀
+  runs diff <a> <b>   对比两次运行的配置、节点数、最终状态与 jobs 配置`,
+	}
+	cmd.PersistentFlags().StringVarP(&configPath, "config", "f", "./config.deploy.yaml", "部署配置文件路径（YAML），用于读取 outputDir / logDir")
+	cmd.PersistentFlags().StringVar(&runsOutputDir, "output-dir", "", "当前 output 目录（默认使用配置中的 outputDir），归档目录按其同级 <dir>-<ts> 查找")
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "list",
+		Short: "列出历史部署记录",
+		Args:  cobra.NoArgs,
+		RunE:  runRunsList,
+	})
+	cmd.AddCommand(&cobra.Command{
+		Use:   "show <id>",
+		Short: "查看单次部署记录详情",
+		Args:  cobra.ExactArgs(1),
+		RunE:  runRunsShow,
+	})
+	cmd.AddCommand(&cobra.Command{
+		Use:   "diff <a> <b>",
+		Short: "对比两次部署记录",
+		Args:  cobra.ExactArgs(2),
+		RunE:  runRunsDiff,
+	})
+
+	rootCmd.AddCommand(cmd)
+}
+
+func loadRuns() ([]*deploy.RunRecord, error) {
+	cfg := deploy.LoadConfigFromFile(configPath)
+	records, err := deploy.ListRuns(cfg.CommonConfig, runsOutputDir)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "runs 失败：", err)
+		return nil, err
+	}
+	return records, nil
+}
+
+func findRuns(ids ...string) ([]*deploy.RunRecord, error) {
+	records, err := loadRuns()
+	if err != nil {
+		return nil, err
+	}
+	found := make([]*deploy.RunRecord, 0, len(ids))
+	for _, id := range ids {
+		r, err := deploy.FindRun(records, id)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "runs 失败：", err)
+			return nil, err
+		}
+		found = append(found, r)
+	}
+	return found, nil
+}
+
+func runRunsList(_ *cobra.Command, _ []string) error {
+	records, err := loadRuns()
+	if err != nil {
+		return err
+	}
+	if len(records) == 0 {
+		fmt.Println("未找到任何运行记录")
+		return nil
+	}
+
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"ID", "STARTED", "DURATION", "RESULT", "SERVICES", "STATUS", "CONFIG", "JOBS", "DIR"})
+	table.SetBorder(true)
+	table.SetAutoWrapText(false)
+	table.SetHeaderAlignment(tablewriter.ALIGN_LEFT)
+	table.SetAlignment(tablewriter.ALIGN_LEFT)
+	for _, r := range records {
+		jobs := "-"
+		if r.JobConfig != nil {
+			jobs = fmt.Sprintf("%d", r.JobConfig.Jobs)
+		}
+		table.Append([]string{
+			r.ID,
+			fmtRunTime(r.StartedAt),
+			fmtRunDuration(r),
+			dashIfEmpty(r.Result),
+			fmtCounts(r.NodeCounts),
+			fmtCounts(r.StatusCounts),
+			dashIfEmpty(deploy.ShortHash(r.ConfigHash)),
+			jobs,
+			filepath.Base(r.OutputDir),
+		})
+	}
+	table.Render()
+	return nil
+}
+
+func runRunsShow(_ *cobra.Command, args []string) error {
+	found, err := findRuns(args[0])
+	if err != nil {
+		return err
+	}
+	r := found[0]
+
+	fmt.Printf("ID:          %s\n", r.ID)
+	fmt.Printf("目录:        %s\n", r.OutputDir)
+	fmt.Printf("操作人:      %s\n", dashIfEmpty(r.Operator))
+	fmt.Printf("Region:      %s\n", dashIfEmpty(r.Region))
+	fmt.Printf("开始时间:    %s\n", fmtRunTime(r.StartedAt))
+	for _, ts := range r.AppendedAt {
+		fmt.Printf("追加部署:    %s\n", fmtRunTime(ts))
+	}
+	fmt.Printf("结束时间:    %s（耗时 %s）\n", fmtRunTime(r.EndedAt), fmtRunDuration(r))
+	fmt.Printf("结果:        %s\n", dashIfEmpty(r.Result))
+	if r.Error != "" {
+		fmt.Printf("错误:        %s\n", r.Error)
+	}
+	fmt.Printf("配置哈希:    %s\n", dashIfEmpty(r.ConfigHash))
+	repoRef := ""
+	if r.Config != nil {
+		repoRef = r.Config.RepoRef
+	}
+	fmt.Printf("代码版本:    %s\n", dashIfEmpty(repoRef))
+	fmt.Printf("最终状态:    %s\n", fmtCounts(r.StatusCounts))
+	if r.JobConfig != nil {
+		fmt.Printf("jobs 配置:   %s（%d 条，hash=%s）\n", r.JobConfig.Path, r.JobConfig.Jobs, deploy.ShortHash(r.JobConfig.Hash))
+	} else {
+		fmt.Printf("jobs 配置:   -\n")
+	}
+
+	if len(r.Services) > 0 || len(r.NodeCounts) > 0 {
+		fmt.Println()
+		table := tablewriter.NewWriter(os.Stdout)
+		table.SetHeader([]string{"SERVICE", "TAG PREFIX", "COUNT", "NODES", "INSTANCE TYPE", "CODE"})
+		table.SetBorder(true)
+		table.SetAutoWrapText(false)
+		table.SetHeaderAlignment(tablewriter.ALIGN_LEFT)
+		table.SetAlignment(tablewriter.ALIGN_LEFT)
+		seen := make(map[string]struct{}, len(r.Services))
+		for _, s := range r.Services {
+			seen[s.Type] = struct{}{}
+			table.Append([]string{s.Type, dashIfEmpty(s.TagPrefix), fmt.Sprintf("%d", s.Count), fmt.Sprintf("%d", r.NodeCounts[s.Type]), strings.Join(s.InstanceType, ","), fmtRepoCommits(r.RepoCommits[s.Type])})
+		}
+		for _, serviceType := range sortedKeys(r.NodeCounts) {
+			if _, ok := seen[serviceType]; ok {
+				continue
+			}
+			table.Append([]string{serviceType, "-", "-", fmt.Sprintf("%d", r.NodeCounts[serviceType]), "-", fmtRepoCommits(r.RepoCommits[serviceType])})
+		}
+		table.Render()
+	}
+	return nil
+}
+
+func runRunsDiff(_ *cobra.Command, args []string) error {
+	found, err := findRuns(args[0], args[1])
+	if err != nil {
+		return err
+	}
+	a, b := found[0], found[1]
+
+	diffs := deploy.DiffRuns(a, b)
+	if len(diffs) == 0 {
+		fmt.Printf("%s 与 %s 无差异\n", a.ID, b.ID)
+		return nil
+	}
+
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"FIELD", a.ID, b.ID})
+	table.SetBorder(true)
+	table.SetAutoWrapText(false)
+	table.SetHeaderAlignment(tablewriter.ALIGN_LEFT)
+	table.SetAlignment(tablewriter.ALIGN_LEFT)
+	for _, d := range diffs {
+		table.Append([]string{d.Field, dashIfEmpty(truncateStr(d.A, 60)), dashIfEmpty(truncateStr(d.B, 60))})
+	}
+	table.Render()
+	fmt.Printf("\n共 %d 项差异（configHash: %s vs %s）\n", len(diffs), dashIfEmpty(deploy.ShortHash(a.ConfigHash)), dashIfEmpty(deploy.ShortHash(b.ConfigHash)))
+	return nil
+}
+
+func fmtRunTime(ts int64) string {
+	if ts <= 0 {
+		return "-"
+	}
+	return time.Unix(ts, 0).Local().Format("2006-01-02 15:04:05")
+}
+
+func fmtRunDuration(r *deploy.RunRecord) string {
+	if d := r.Duration(); d > 0 {
+		return fmtDuration(d)
+	}
+	return "-"
+}
+
+// fmtCounts 将计数按 key 排序输出为 "a=1 b=2"。
+func fmtCounts(counts map[string]int) string {
+	if len(counts) == 0 {
+		return "-"
+	}
+	parts := make([]string, 0, len(counts))
+	for _, k := range sortedKeys(counts) {
+		parts = append(parts, fmt.Sprintf("%s=%d", k, counts[k]))
+	}
+	return strings.Join(parts, " ")
+}
+
+// fmtRepoCommits 将 commit 列表截短后以逗号连接；多个 commit 表示同一服务的节点运行了不同版本。
+func fmtRepoCommits(commits []string) string {
+	if len(commits) == 0 {
+		return "-"
+	}
+	short := make([]string, 0, len(commits))
+	for _, c := range commits {
+		short = append(short, deploy.ShortCommit(c))
+	}
+	return strings.Join(short, ",")
+}
+
+func sortedKeys(m map[string]int) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func dashIfEmpty(s string) string {
+	if strings.TrimSpace(s) == "" {
+		return "-"
+	}
+	return s
+}
@@ -12,19 +12,26 @@ import (
 var (
 	shutdownServersPath string
 	shutdownConfigPath  string
+	shutdownTerminate   bool
 )
 
 func init() {
 	cmd := &cobra.Command{
 		Use:   "shutdown",
 		Short: "根据 servers.json 批量关机服务器",
-		Long:  "读取 servers.json 中的服务器列表，复用 deploy 配置文件中的 SSH 参数，在远端执行关机命令。",
-		RunE:  runShutdown,
+		Long: `读取 servers.json 中的服务器列表，复用 deploy 配置文件中的 SSH 参数，在远端执行关机命令。
+
+加 --terminate 时改为通过 EC2 API 终止实例（无需 SSH 可达）：
+  - 同时覆盖同目录 servers_create.json 中未进入 servers.json 的实例，并按 deployment.json 的 deploymentId 标签补充遗漏实例
+  - 实例 ID 优先取 servers_create.json 记录，否则按公网 IP 解析（仅匹配带本部署 ydyl:deployment-id 标签的实例，其余记为 unresolved）
+  - 分批终止并等待 terminated，结果写入同目录 teardown_report.json`,
+		RunE: runShutdown,
 	}
 
 	cmd.Flags().StringVar(&shutdownServersPath, "servers", "", "servers.json 路径（参考 ydyl-deploy-client/output/servers.json）")
 	_ = cmd.MarkFlagRequired("servers")
 	cmd.Flags().StringVar(&shutdownConfigPath, "config", "./config.deploy.yaml", "deploy 配置文件路径（用于读取 SSH 配置）")
+	cmd.Flags().BoolVar(&shutdownTerminate, "terminate", false, "通过 EC2 API 终止实例并等待 terminated，而不是 SSH 关机")
 
 	rootCmd.AddCommand(cmd)
 }
@@ -35,6 +42,18 @@ func runShutdown(cmd *cobra.Command, args []string) error {
 
 	cfg := deploy.LoadConfigFromFile(shutdownConfigPath)
 
+	if shutdownTerminate {
+		report, err := deploy.Terminate(ctx, cfg.CommonConfig, shutdownServersPath)
+		if report != nil {
+			fmt.Printf("terminated=%d  unresolved=%d  failed=%d\n", report.Terminated, report.Unresolved, report.Failed)
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "shutdown --terminate 失败：", err)
+			return err
+		}
+		return nil
+	}
+
 	if err := deploy.Shutdown(ctx, cfg.CommonConfig, shutdownServersPath); err != nil {
 		fmt.Fprintln(os.Stderr, "shutdown 失败：", err)
 		return err
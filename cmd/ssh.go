@@ -0,0 +1,79 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+
+	"github.com/spf13/cobra"
+	"github.com/wangdayong228/ydyl-deploy-client/internal/deploy"
+	"github.com/wangdayong228/ydyl-deploy-client/internal/infra/oscmdexec"
+)
+
+var (
+	sshServersPath string
+	sshTail        bool
+	sshTailLines   int
+)
+
+func init() {
+	cmd := &cobra.Command{
+		Use:   "ssh <name-or-ip>",
+		Short: "按节点名称登录远端机器或跟随其 pipe 日志",
+		Long: `在 servers.json / script_status.json 中查找节点，使用配置中的 sshUser / sshKeyDir + keyName 打开交互式 shell：
+
+  ssh ydyl-xjst-3-1          名称或 IP 精确匹配
+  ssh 'ydyl-op-*2'           名称 glob
+  ssh xjst-3-1               名称子串（不区分大小写）
+  ssh ydyl-op-2 --tail       跟随远端 pipe 日志（script_status.json 中的 logPath）
+
+匹配到多个节点时列出候选并退出。`,
+		Args:         cobra.ExactArgs(1),
+		SilenceUsage: true,
+		RunE:         runSSHNode,
+	}
+
+	cmd.Flags().StringVarP(&configPath, "config", "f", "./config.deploy.yaml", "部署配置文件路径（YAML），用于读取 SSH/outputDir 配置")
+	cmd.Flags().StringVar(&sshServersPath, "servers", "", "servers.json 路径（默认使用 outputDir/servers.json）")
+	cmd.Flags().BoolVar(&sshTail, "tail", false, "不打开 shell，改为 tail -F 远端 pipe 日志")
+	cmd.Flags().IntVarP(&sshTailLines, "lines", "n", 200, "--tail 时先输出的日志行数")
+
+	rootCmd.AddCommand(cmd)
+}
+
+func runSSHNode(_ *cobra.Command, args []string) error {
+	cfg := deploy.LoadConfigFromFile(configPath)
+
+	target, err := deploy.ResolveSSHTarget(cfg.CommonConfig, sshServersPath, args[0])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "ssh 失败：", err)
+		return err
+	}
+	tailLines := 0
+	if sshTail {
+		tailLines = max(sshTailLines, 1)
+	}
+	sshArgs, err := deploy.BuildInteractiveSSHArgs(cfg.CommonConfig, target, tailLines)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "ssh 失败：", err)
+		return err
+	}
+
+	if sshTail {
+		fmt.Fprintf(os.Stderr, "👉 %s (%s, %s) tail -F %s\n", target.Name, target.ServiceType, target.IP, target.LogPath)
+	} else {
+		fmt.Fprintf(os.Stderr, "👉 登录 %s (%s, %s)\n", target.Name, target.ServiceType, target.IP)
+	}
+	err = oscmdexec.DefaultRunner(context.Background(), oscmdexec.Spec{Name: "ssh", Args: sshArgs})
+	// 远端 shell / tail 的非 0 退出（包括 Ctrl-C 结束 tail）属于正常结束，只有 ssh 自身失败（255）才报错。
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode() != 255 {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("ssh %s@%s 失败: %w", cfg.CommonConfig.SSHUser, target.IP, err)
+	}
+	return nil
+}
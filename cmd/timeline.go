@@ -0,0 +1,146 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/wangdayong228/ydyl-deploy-client/internal/deploy"
+)
+
+var (
+	timelineOutputDir string
+	timelineNodes     []string
+	timelineTypes     []string
+	timelineFailed    bool
+)
+
+func init() {
+	cmd := &cobra.Command{
+		Use:   "timeline",
+		Short: "按节点展示部署事件时间线（output/events.jsonl）",
+		Long: `deploy / deploy-restore / sync / shutdown / remove / replace 会把每个动作追加写入 output/events.jsonl：
+实例创建、打标签、SSH 就绪/失败、命令启动（PID）、命令下发失败、状态迁移、restore 尝试、关机与终止等。
+
+timeline 按节点（IP + service 类型）聚合事件并按时间排序输出，时间偏移相对于整个事件日志的第一条事件，便于事后复盘长时间部署中失败节点的经过。`,
+		RunE: runTimeline,
+	}
+
+	cmd.Flags().StringVarP(&configPath, "config", "f", "./config.deploy.yaml", "部署配置文件路径（YAML），用于读取 outputDir")
+	cmd.Flags().StringVar(&timelineOutputDir, "output-dir", "", "部署输出目录（默认使用配置中的 outputDir，可指定归档的 output-<ts>）")
+	cmd.Flags().StringArrayVar(&timelineNodes, "node", nil, "仅展示名称或 IP 匹配的节点（支持 glob，可重复）")
+	cmd.Flags().StringArrayVar(&timelineTypes, "type", nil, "仅展示指定类型的事件（如 status_changed，可重复）")
+	cmd.Flags().BoolVar(&timelineFailed, "failed", false, "仅展示最终状态为 failed 的节点")
+
+	rootCmd.AddCommand(cmd)
+}
+
+func runTimeline(_ *cobra.Command, _ []string) error {
+	cfg := deploy.LoadConfigFromFile(configPath)
+
+	timelines, skipped, err := deploy.LoadTimelines(cfg.CommonConfig, timelineOutputDir)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "timeline 失败：", err)
+		return err
+	}
+	if len(timelines) == 0 {
+		fmt.Println("事件日志为空")
+		return nil
+	}
+
+	var origin time.Time
+	for _, tl := range timelines {
+		if len(tl.Events) > 0 && (origin.IsZero() || tl.Events[0].Time.Before(origin)) {
+			origin = tl.Events[0].Time
+		}
+	}
+
+	shown := 0
+	for _, tl := range timelines {
+		if !timelineNodeSelected(tl) {
+			continue
+		}
+		events := filterTimelineEvents(tl.Events)
+		if len(events) == 0 {
+			continue
+		}
+		shown++
+
+		fmt.Printf("== %s  [%s]  %s  最终状态=%s ==\n", dashIfEmpty(tl.Name), dashIfEmpty(tl.ServiceType), dashIfEmpty(tl.IP), dashIfEmpty(tl.LastStatus))
+		for _, ev := range events {
+			fmt.Printf("  %s  %-9s  %-18s %s\n",
+				ev.Time.Local().Format("01-02 15:04:05"),
+				"+"+fmtDuration(ev.Time.Sub(origin)),
+				ev.Type,
+				fmtEventDetail(ev),
+			)
+		}
+		fmt.Println()
+	}
+
+	fmt.Printf("共 %d 个节点，展示 %d 个\n", len(timelines), shown)
+	if skipped > 0 {
+		fmt.Printf("⚠️ 跳过 %d 行无法解析的事件（可能为进程中断时写入的半行）\n", skipped)
+	}
+	return nil
+}
+
+func timelineNodeSelected(tl *deploy.NodeTimeline) bool {
+	if timelineFailed && tl.LastStatus != "failed" {
+		return false
+	}
+	if len(timelineNodes) == 0 {
+		return true
+	}
+	for _, pattern := range timelineNodes {
+		for _, candidate := range []string{tl.Name, tl.IP} {
+			if candidate == "" {
+				continue
+			}
+			if ok, _ := path.Match(pattern, candidate); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func filterTimelineEvents(events []deploy.Event) []deploy.Event {
+	if len(timelineTypes) == 0 {
+		return events
+	}
+	allowed := make(map[string]struct{}, len(timelineTypes))
+	for _, t := range timelineTypes {
+		allowed[strings.TrimSpace(t)] = struct{}{}
+	}
+	out := make([]deploy.Event, 0, len(events))
+	for _, ev := range events {
+		if _, ok := allowed[ev.Type]; ok {
+			out = append(out, ev)
+		}
+	}
+	return out
+}
+
+func fmtEventDetail(ev deploy.Event) string {
+	var parts []string
+	if ev.InstanceID != "" {
+		parts = append(parts, "instance="+ev.InstanceID)
+	}
+	if ev.PID > 0 {
+		parts = append(parts, fmt.Sprintf("pid=%d", ev.PID))
+	}
+	if ev.From != "" || ev.To != "" {
+		parts = append(parts, fmt.Sprintf("%s -> %s", dashIfEmpty(ev.From), dashIfEmpty(ev.To)))
+	}
+	if ev.Attempts > 0 {
+		parts = append(parts, fmt.Sprintf("attempts=%d", ev.Attempts))
+	}
+	if ev.Message != "" {
+		parts = append(parts, truncateStr(ev.Message, 120))
+	}
+	return strings.Join(parts, "  ")
+}
@@ -0,0 +1,60 @@
+package chainstack
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/wangdayong228/ydyl-deploy-client/internal/constants/enums"
+)
+
+// ServiceTypeArb 为 Arbitrum Orbit / Nitro 链的服务类型（services[].type: arb）。
+var ServiceTypeArb = enums.RegisterServiceType(5, "arb")
+
+// arbChainIDBase 为 arb 链 ID 的起始值，与 op / cdk 的 10000 段错开。
+const arbChainIDBase = 20000
+
+// arbStack 为 Arbitrum Orbit / Nitro：每个节点通过 nitro-testnode 独立部署一条 L2 链。
+type arbStack struct{}
+
+func init() { Register(arbStack{}) }
+
+func (arbStack) Type() enums.ServiceType { return ServiceTypeArb }
+func (arbStack) IsL2() bool              { return true }
+func (arbStack) GroupSize() int          { return 1 }
+
+func (arbStack) L2ChainID(index int) int    { return arbChainIDBase + index }
+func (s arbStack) VaultIndex(index int) int { return s.L2ChainID(index) }
+
+func (arbStack) CommandTemplate() string {
+	return repoSyncCommand +
+		"L2_CHAIN_ID={{.L2ChainID}} L1_CHAIN_ID={{.Common.L1ChainId}} L1_RPC_URL={{.L1RpcUrl}} L1_RPC_URL_WS={{.L1RpcUrlWs}} L1_VAULT_PRIVATE_KEY={{.L1VaultPrivateKey}}" +
+		" L1_BRIDGE_HUB_CONTRACT={{.Common.L1BridgeHubContract}} L1_REGISTER_BRIDGE_PRIVATE_KEY={{.Common.L1RegisterBridgePrivateKey}}" +
+		" DRYRUN={{.Common.DryRun}} FORCE_DEPLOY_ARB={{.Common.ForceDeployL2Chain}} ENABLE_GEN_ACC={{.Common.EnableGenAccounts}} ./arb_pipe.sh"
+}
+
+func (s arbStack) InstanceName(tagPrefix string, ordinal int) string {
+	return sequentialName(tagPrefix, s.Type().String(), ordinal)
+}
+
+func (s arbStack) ParseOrdinal(name string) (int, error) {
+	return parseSequentialName(name, s.Type().String())
+}
+
+func (arbStack) RuntimeMonitorArgs(output string) string {
+	return fmt.Sprintf("--mode docker --output '%s' --container nitro-testnode-sequencer-1", output)
+}
+
+func (arbStack) LogTargets(runtimeLogPath string) []LogTarget {
+	return []LogTarget{
+		{Category: "deploy", RemotePath: remoteRepoDir + "/arb-work/scripts/deploy-gen.log", LocalNameGz: "deploy-arb.log.gz"},
+		runtimeLogTarget(runtimeLogPath),
+	}
+}
+
+func (arbStack) ProbeL2(ctx context.Context, ip string, p ProbeParams) (ProbeResult, error) {
+	return probeViaConsoleSummary(ctx, ip, p)
+}
+
+func (arbStack) CrossTx() CrossTxSpec {
+	return CrossTxSpec{WaitForReceipts: true}
+}
@@ -0,0 +1,55 @@
+package chainstack
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/wangdayong228/ydyl-deploy-client/internal/constants/enums"
+)
+
+// cdkStack 为 Polygon CDK：每个节点通过 kurtosis 独立部署一条 L2 链。
+type cdkStack struct{}
+
+func init() { Register(cdkStack{}) }
+
+func (cdkStack) Type() enums.ServiceType { return enums.ServiceTypeCDK }
+func (cdkStack) IsL2() bool              { return true }
+func (cdkStack) GroupSize() int          { return 1 }
+
+func (cdkStack) L2ChainID(index int) int    { return 10000 + index }
+func (s cdkStack) VaultIndex(index int) int { return s.L2ChainID(index) }
+
+func (cdkStack) CommandTemplate() string {
+	return repoSyncCommand +
+		"L2_CHAIN_ID={{.L2ChainID}} L1_CHAIN_ID={{.Common.L1ChainId}} L1_RPC_URL={{.L1RpcUrl}} L1_VAULT_PRIVATE_KEY={{.L1VaultPrivateKey}}" +
+		" L1_BRIDGE_HUB_CONTRACT={{.Common.L1BridgeHubContract}} L1_REGISTER_BRIDGE_PRIVATE_KEY={{.Common.L1RegisterBridgePrivateKey}}" +
+		" DRYRUN={{.Common.DryRun}} FORCE_DEPLOY_CDK={{.Common.ForceDeployL2Chain}} ENABLE_GEN_ACC={{.Common.EnableGenAccounts}}" +
+		" USE_REAL_PROVER={{.Common.CdkUseRealProver}} ./cdk_pipe.sh"
+}
+
+func (s cdkStack) InstanceName(tagPrefix string, ordinal int) string {
+	return sequentialName(tagPrefix, s.Type().String(), ordinal)
+}
+
+func (s cdkStack) ParseOrdinal(name string) (int, error) {
+	return parseSequentialName(name, s.Type().String())
+}
+
+func (cdkStack) RuntimeMonitorArgs(output string) string {
+	return fmt.Sprintf("--mode kurtosis --stack cdk --output '%s' --enclave cdk-gen", output)
+}
+
+func (cdkStack) LogTargets(runtimeLogPath string) []LogTarget {
+	return []LogTarget{
+		{Category: "deploy", RemotePath: remoteRepoDir + "/cdk-work/scripts/deploy-gen.log", LocalNameGz: "deploy-kurtosis-cdk.log.gz"},
+		runtimeLogTarget(runtimeLogPath),
+	}
+}
+
+func (cdkStack) ProbeL2(ctx context.Context, ip string, p ProbeParams) (ProbeResult, error) {
+	return probeViaConsoleSummary(ctx, ip, p)
+}
+
+func (cdkStack) CrossTx() CrossTxSpec {
+	return CrossTxSpec{WaitForReceipts: true}
+}
@@ -0,0 +1,190 @@
+// Package chainstack 汇总每种链部署栈（op / cdk / xjst / generic ...）在部署客户端中的差异：
+// 内置部署命令、链 ID / 分组分配、节点命名、运行日志监控、日志收集目标、健康探测与跨链压测 job 映射。
+// 每种栈在独立文件中实现 Stack 并在 init 中 Register，新增 L2 栈只需新增一个文件。
+package chainstack
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/wangdayong228/ydyl-deploy-client/internal/constants/enums"
+)
+
+// Stack 描述一种链部署栈。index 均为节点在服务内的 0-based 索引，ordinal 为 1-based 序号。
+type Stack interface {
+	// Type 为该栈对应的服务类型（services[].type）。
+	Type() enums.ServiceType
+	// IsL2 为 false 的栈（generic）不参与健康检查、跨链压测与账户生成监控。
+	IsL2() bool
+	// GroupSize 为组成一条链所需的节点数：1 表示每个节点独立成链（op / cdk），xjst 默认为 4。
+	// 组内第一个节点为主节点：负责充值、运行日志监控、日志收集、健康检查与跨链压测入口。
+	GroupSize() int
+
+	// L2ChainID 返回节点对应的 L2 链 ID，没有 L2 链 ID 的栈返回 0。
+	L2ChainID(index int) int
+	// VaultIndex 返回派生该节点 L1 vault 私钥使用的索引（同组节点共用同一个 vault）。
+	VaultIndex(index int) int
+
+	// CommandTemplate 返回未配置 remoteCmd 时使用的内置部署命令（text/template，变量见 deploy.RemoteCmdVars）；
+	// 为空表示必须在配置中显式设置 remoteCmd。
+	CommandTemplate() string
+
+	// InstanceName 生成节点名称，ParseOrdinal 为其逆过程。
+	InstanceName(tagPrefix string, ordinal int) string
+	ParseOrdinal(name string) (int, error)
+
+	// RuntimeMonitorArgs 返回主节点上 log_monitor_runtime.sh 的参数，output 为运行日志路径；为空表示不启动监控。
+	RuntimeMonitorArgs(output string) string
+	// LogTargets 返回 collect-logs 在部署日志之外额外收集的远端日志，runtimeLogPath 为运行日志路径。
+	LogTargets(runtimeLogPath string) []LogTarget
+	// ProbeL2 探测主节点 L2 最新区块。
+	ProbeL2(ctx context.Context, ip string, p ProbeParams) (ProbeResult, error)
+	// CrossTx 返回生成跨链压测 jobs 时该栈的映射规则。
+	CrossTx() CrossTxSpec
+}
+
+// LogTarget 描述一个需要收集的远端日志文件。
+type LogTarget struct {
+	Category    string
+	RemotePath  string
+	LocalNameGz string
+}
+
+// ProbeParams 控制健康探测使用的端口与超时。
+type ProbeParams struct {
+	// ConsolePort 是 ydyl-console-service 的监听端口。
+	ConsolePort int
+	// XJSTRPCPort 是 XJST RPC 监听端口。
+	XJSTRPCPort int
+	// RPCTimeout 是单次 RPC 调用的超时时间。
+	RPCTimeout time.Duration
+}
+
+// ProbeResult 为一次健康探测得到的最新区块；探测失败时 RPCURL 可能已解析。
+type ProbeResult struct {
+	RPCURL      string
+	BlockNumber uint64
+	BlockTime   time.Time
+}
+
+// CrossTxSpec 描述跨链压测 job 的映射规则。
+type CrossTxSpec struct {
+	// SelfTarget 为 true 时源链的目标链固定为自身，否则从其它链中随机选取。
+	SelfTarget bool
+	// WaitForReceipts 对应 job.wait_for_receipts（该链作为源链时）。
+	WaitForReceipts bool
+	// TargetL1BridgeFromSend 为 true 时该链作为目标链使用 L1BridgeSendContract 作为 target_l1_bridge，否则使用 L1BridgeReceiveContract。
+	TargetL1BridgeFromSend bool
+}
+
+var (
+	registryMu sync.RWMutex
+	registry   = make(map[enums.ServiceType]Stack)
+)
+
+// Register 登记一种链部署栈；同一服务类型重复登记会 panic。
+func Register(s Stack) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	if _, ok := registry[s.Type()]; ok {
+		panic(fmt.Sprintf("chainstack: 服务类型 %s 重复登记", s.Type()))
+	}
+	registry[s.Type()] = s
+}
+
+// Lookup 返回服务类型对应的链部署栈。
+func Lookup(t enums.ServiceType) (Stack, bool) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	s, ok := registry[t]
+	return s, ok
+}
+
+// MustLookup 与 Lookup 相同，未登记时 panic；用于配置已通过校验的场景。
+func MustLookup(t enums.ServiceType) Stack {
+	s, ok := Lookup(t)
+	if !ok {
+		panic(fmt.Sprintf("chainstack: 未登记的服务类型 %d", t))
+	}
+	return s
+}
+
+// LookupName 按服务类型名称（忽略大小写与首尾空白）查找链部署栈，用于 servers.json / script_status.json 中的字符串类型。
+func LookupName(name string) (Stack, bool) {
+	t, err := enums.ParseServiceType(strings.ToLower(strings.TrimSpace(name)))
+	if err != nil {
+		return nil, false
+	}
+	return Lookup(t)
+}
+
+// All 返回所有已登记的链部署栈（按服务类型排序）。
+func All() []Stack {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	out := make([]Stack, 0, len(registry))
+	for _, s := range registry {
+		out = append(out, s)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Type() < out[j].Type() })
+	return out
+}
+
+// L2Names 返回所有 L2 栈的类型名称，用于错误提示。
+func L2Names() []string {
+	var names []string
+	for _, s := range All() {
+		if s.IsL2() {
+			names = append(names, s.Type().String())
+		}
+	}
+	return names
+}
+
+// groupResizer 由分组组网的栈实现，返回分组大小为 n 的同类栈。
+type groupResizer interface {
+	withGroupSize(n int) Stack
+}
+
+// WithGroupSize 返回按 services[].groupSize 调整分组大小后的栈；n <= 0 或栈不支持分组时原样返回。
+func WithGroupSize(s Stack, n int) Stack {
+	if n <= 0 || n == s.GroupSize() {
+		return s
+	}
+	if r, ok := s.(groupResizer); ok {
+		return r.withGroupSize(n)
+	}
+	return s
+}
+
+// IsPrimaryIndex 判断 0-based 索引是否为所在分组的主节点。
+func IsPrimaryIndex(s Stack, index int) bool {
+	return index%s.GroupSize() == 0
+}
+
+// GroupID 返回 0-based 索引所在的分组（1-based）。
+func GroupID(s Stack, index int) int {
+	return index/s.GroupSize() + 1
+}
+
+// MemberIndex 返回 1-based 序号在分组内的位置（1-based）。
+func MemberIndex(s Stack, ordinal int) int {
+	return (ordinal-1)%s.GroupSize() + 1
+}
+
+// repoSyncCommand 为内置部署命令的公共前缀：配置 repoRef 时 checkout 解析出的 commit（{{.RepoCommit}}），
+// 否则在当前分支上 git pull；随后同步子模块。
+const repoSyncCommand = "{{if .RepoCommit}} GIT_SSH_COMMAND='ssh -o StrictHostKeyChecking=no' git fetch --force --tags origin && git checkout --force --detach {{.RepoCommit}}{{else}} git pull{{end}}" +
+	" && GIT_SSH_COMMAND='ssh -o StrictHostKeyChecking=no' git submodule update --init --recursive --force && "
+
+// remoteRepoDir 为远端部署仓库目录（与 deploy 中的 remoteRepoDirDefault 一致）。
+const remoteRepoDir = "/home/ubuntu/workspace/ydyl-deployment-suite"
+
+// runtimeLogTarget 为运行日志监控输出的收集目标。
+func runtimeLogTarget(runtimeLogPath string) LogTarget {
+	return LogTarget{Category: "runtime", RemotePath: runtimeLogPath, LocalNameGz: "runtime.log.gz"}
+}
@@ -0,0 +1,142 @@
+package chainstack
+
+import (
+	"testing"
+
+	"github.com/wangdayong228/ydyl-deploy-client/internal/constants/enums"
+)
+
+func TestRegistry_BuiltinStacks(t *testing.T) {
+	t.Parallel()
+
+	for _, name := range []string{"generic", "op", "cdk", "xjst", "arb", " OP "} {
+		if _, ok := LookupName(name); !ok {
+			t.Fatalf("stack %q not registered", name)
+		}
+	}
+	if _, ok := LookupName("unknown"); ok {
+		t.Fatalf("unexpected stack for unknown type")
+	}
+	if got := MustLookup(enums.ServiceTypeXJST).GroupSize(); got != 4 {
+		t.Fatalf("xjst group size = %d, want 4", got)
+	}
+	if MustLookup(enums.ServiceTypeGeneric).IsL2() {
+		t.Fatalf("generic should not be an L2 stack")
+	}
+	if got := MustLookup(ServiceTypeArb).L2ChainID(3); got != 20003 {
+		t.Fatalf("arb chain id = %d, want 20003", got)
+	}
+	if got := MustLookup(enums.ServiceTypeGeneric).VaultIndex(5); got != 0 {
+		t.Fatalf("generic vault index = %d, want 0 (all nodes share one vault)", got)
+	}
+	if MustLookup(enums.ServiceTypeGeneric).CommandTemplate() != "" {
+		t.Fatalf("generic should not have a builtin command")
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Fatalf("expected duplicate Register to panic")
+		}
+	}()
+	Register(opStack{})
+}
+
+func TestInstanceName_RoundTrip(t *testing.T) {
+	t.Parallel()
+
+	for _, s := range All() {
+		for ordinal := 1; ordinal <= 9; ordinal++ {
+			name := s.InstanceName("ydyl-test", ordinal)
+			got, err := s.ParseOrdinal(name)
+			if err != nil || got != ordinal {
+				t.Fatalf("%s: ParseOrdinal(%q) = %d, %v; want %d", s.Type(), name, got, err, ordinal)
+			}
+		}
+	}
+
+	xjst := MustLookup(enums.ServiceTypeXJST)
+	if got := xjst.InstanceName("p", 6); got != "p-xjst-2-2" {
+		t.Fatalf("xjst name = %q, want p-xjst-2-2", got)
+	}
+	for _, bad := range []string{"p-xjst-1", "p-xjst-1-5", "p-op-1-1"} {
+		if _, err := xjst.ParseOrdinal(bad); err == nil {
+			t.Fatalf("expected xjst ParseOrdinal(%q) to fail", bad)
+		}
+	}
+}
+
+func TestIsPrimaryName(t *testing.T) {
+	t.Parallel()
+
+	xjst := MustLookup(enums.ServiceTypeXJST)
+	cases := map[string]bool{
+		"tps-ydyl4-xjst-1-1": true,
+		"tps-ydyl4-xjst-2-1": true,
+		"tps-ydyl4-xjst-1-2": false,
+		// 兼容旧的平铺命名
+		"tps-ydyl-xjst-5": true,
+		"tps-ydyl-xjst-2": false,
+		"tps-ydyl-xjst":   false,
+	}
+	for name, want := range cases {
+		if got := IsPrimaryName(xjst, name); got != want {
+			t.Fatalf("IsPrimaryName(xjst, %q) = %v, want %v", name, got, want)
+		}
+	}
+	if !IsPrimaryName(MustLookup(enums.ServiceTypeOP), "ydyl-op-7") {
+		t.Fatalf("every op node should be primary")
+	}
+}
+
+func TestWithGroupSize(t *testing.T) {
+	t.Parallel()
+
+	xjst := MustLookup(enums.ServiceTypeXJST)
+	if WithGroupSize(xjst, 0) != xjst || WithGroupSize(xjst, 4) != xjst {
+		t.Fatalf("groupSize 0 or default should keep the registered stack")
+	}
+	if got := WithGroupSize(MustLookup(enums.ServiceTypeOP), 7).GroupSize(); got != 1 {
+		t.Fatalf("op should ignore groupSize, got %d", got)
+	}
+
+	xjst7 := WithGroupSize(xjst, 7)
+	if got := xjst7.InstanceName("p", 8); got != "p-xjst-2-1" {
+		t.Fatalf("xjst7 name = %q, want p-xjst-2-1", got)
+	}
+	if got, err := xjst7.ParseOrdinal("p-xjst-1-7"); err != nil || got != 7 {
+		t.Fatalf("xjst7 ParseOrdinal = %d, %v; want 7", got, err)
+	}
+	if got := xjst7.VaultIndex(13); got != 2 {
+		t.Fatalf("xjst7 vault index = %d, want 2", got)
+	}
+
+	xjst1 := WithGroupSize(xjst, 1)
+	if got := xjst1.InstanceName("p", 3); got != "p-xjst-3-1" {
+		t.Fatalf("xjst1 name = %q, want p-xjst-3-1", got)
+	}
+	if !IsPrimaryIndex(xjst1, 2) {
+		t.Fatalf("every node of a 1-node group is primary")
+	}
+}
+
+func TestParseGroupMember(t *testing.T) {
+	t.Parallel()
+
+	// 默认分组大小的栈也能解析更大分组的名称，主节点判断与分组大小无关
+	xjst := MustLookup(enums.ServiceTypeXJST)
+	group, member, err := ParseGroupMember(xjst, "p-xjst-3-6")
+	if err != nil || group != 3 || member != 6 {
+		t.Fatalf("ParseGroupMember = %d, %d, %v; want 3, 6", group, member, err)
+	}
+	if IsPrimaryName(xjst, "p-xjst-1-5") {
+		t.Fatalf("p-xjst-1-5 is not a primary node")
+	}
+	if !IsPrimaryName(xjst, "p-xjst-2-1") {
+		t.Fatalf("p-xjst-2-1 is a primary node")
+	}
+
+	group, member, err = ParseGroupMember(MustLookup(enums.ServiceTypeOP), "p-op-5")
+	if err != nil || group != 5 || member != 1 {
+		t.Fatalf("op ParseGroupMember = %d, %d, %v; want 5, 1", group, member, err)
+	}
+}
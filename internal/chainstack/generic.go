@@ -0,0 +1,40 @@
+package chainstack
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/wangdayong228/ydyl-deploy-client/internal/constants/enums"
+)
+
+// genericStack 为通用服务：必须配置 remoteCmd，不部署 L2 链。
+type genericStack struct{}
+
+func init() { Register(genericStack{}) }
+
+func (genericStack) Type() enums.ServiceType { return enums.ServiceTypeGeneric }
+func (genericStack) IsL2() bool              { return false }
+func (genericStack) GroupSize() int          { return 1 }
+
+func (genericStack) L2ChainID(int) int       { return 0 }
+func (genericStack) CommandTemplate() string { return "" }
+
+// VaultIndex 与 L2ChainID 一致恒为 0：generic 服务的所有节点共用同一个 L1 vault。
+func (genericStack) VaultIndex(int) int { return 0 }
+
+func (s genericStack) InstanceName(tagPrefix string, ordinal int) string {
+	return sequentialName(tagPrefix, s.Type().String(), ordinal)
+}
+
+func (s genericStack) ParseOrdinal(name string) (int, error) {
+	return parseSequentialName(name, s.Type().String())
+}
+
+func (genericStack) RuntimeMonitorArgs(string) string { return "" }
+func (genericStack) LogTargets(string) []LogTarget    { return nil }
+
+func (genericStack) ProbeL2(context.Context, string, ProbeParams) (ProbeResult, error) {
+	return ProbeResult{}, fmt.Errorf("generic 服务不支持 L2 健康检查")
+}
+
+func (genericStack) CrossTx() CrossTxSpec { return CrossTxSpec{} }
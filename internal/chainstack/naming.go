@@ -0,0 +1,94 @@
+package chainstack
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// sequentialName 生成独立成链节点的名称：tagPrefix-serviceType-ordinal。
+func sequentialName(tagPrefix, serviceType string, ordinal int) string {
+	return fmt.Sprintf("%s-%s-%d", tagPrefix, serviceType, ordinal)
+}
+
+// parseSequentialName 为 sequentialName 的逆过程，返回 1-based 序号。
+func parseSequentialName(name, serviceType string) (int, error) {
+	parts := strings.Split(strings.TrimSpace(name), "-")
+	if len(parts) < 3 {
+		return 0, fmt.Errorf("name 格式不合法，期望 tagPrefix-%s-ordinal", serviceType)
+	}
+	if strings.ToLower(parts[len(parts)-2]) != serviceType {
+		return 0, fmt.Errorf("name 与 serviceType 不匹配")
+	}
+	ordinal, err := strconv.Atoi(parts[len(parts)-1])
+	if err != nil || ordinal <= 0 {
+		return 0, fmt.Errorf("ordinal 必须是正整数")
+	}
+	return ordinal, nil
+}
+
+// groupedName 生成分组组网节点的名称：tagPrefix-serviceType-groupId-indexInGroup（均为 1-based）。
+func groupedName(tagPrefix, serviceType string, groupSize, ordinal int) string {
+	zeroIndex := ordinal - 1
+	return fmt.Sprintf("%s-%s-%d-%d", tagPrefix, serviceType, zeroIndex/groupSize+1, zeroIndex%groupSize+1)
+}
+
+// parseGroupedName 为 groupedName 的逆过程，返回 1-based 序号 (groupId-1)*groupSize+indexInGroup。
+func parseGroupedName(name, serviceType string, groupSize int) (int, error) {
+	groupID, index, err := parseGroupedMember(name, serviceType)
+	if err != nil {
+		return 0, err
+	}
+	if index > groupSize {
+		return 0, fmt.Errorf("index 必须在 1~%d", groupSize)
+	}
+	return (groupID-1)*groupSize + index, nil
+}
+
+// parseGroupedMember 解析 groupedName 中的 groupId 与 indexInGroup（均为 1-based），不校验分组大小。
+func parseGroupedMember(name, serviceType string) (int, int, error) {
+	parts := strings.Split(strings.TrimSpace(name), "-")
+	if len(parts) < 4 {
+		return 0, 0, fmt.Errorf("name 格式不合法，期望 tagPrefix-%s-groupId-index", serviceType)
+	}
+	if strings.ToLower(parts[len(parts)-3]) != serviceType {
+		return 0, 0, fmt.Errorf("name 与 serviceType 不匹配")
+	}
+	groupID, err := strconv.Atoi(parts[len(parts)-2])
+	if err != nil || groupID <= 0 {
+		return 0, 0, fmt.Errorf("groupId 必须是正整数")
+	}
+	index, err := strconv.Atoi(parts[len(parts)-1])
+	if err != nil || index < 1 {
+		return 0, 0, fmt.Errorf("index 必须是正整数")
+	}
+	return groupID, index, nil
+}
+
+// ParseGroupMember 从节点名称解析所在分组与组内位置（均为 1-based）。
+// 分组组网的栈按 tagPrefix-serviceType-groupId-index 解析且不限制组内位置上限，
+// 以便在不知道部署时 services[].groupSize 的场景（servers.json 的下游命令）下使用；独立成链的栈每个节点自成一组。
+func ParseGroupMember(s Stack, name string) (int, int, error) {
+	if _, ok := s.(groupResizer); ok {
+		return parseGroupedMember(name, s.Type().String())
+	}
+	ordinal, err := s.ParseOrdinal(name)
+	if err != nil {
+		return 0, 0, err
+	}
+	return ordinal, 1, nil
+}
+
+// IsPrimaryName 根据节点名称判断是否为所在分组的主节点（组内位置为 1），与分组大小无关。
+// 名称无法按 ParseGroupMember 解析时，按末段数字兼容旧的平铺命名（tagPrefix-serviceType-ordinal）。
+func IsPrimaryName(s Stack, name string) bool {
+	if _, member, err := ParseGroupMember(s, name); err == nil {
+		return member == 1
+	}
+	parts := strings.Split(strings.TrimSpace(name), "-")
+	n, err := strconv.Atoi(parts[len(parts)-1])
+	if err != nil || n <= 0 {
+		return false
+	}
+	return MemberIndex(s, n) == 1
+}
@@ -0,0 +1,55 @@
+package chainstack
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/wangdayong228/ydyl-deploy-client/internal/constants/enums"
+)
+
+// opStack 为 OP Stack：每个节点通过 kurtosis 独立部署一条 L2 链。
+type opStack struct{}
+
+func init() { Register(opStack{}) }
+
+func (opStack) Type() enums.ServiceType { return enums.ServiceTypeOP }
+func (opStack) IsL2() bool              { return true }
+func (opStack) GroupSize() int          { return 1 }
+
+func (opStack) L2ChainID(index int) int    { return 10000 + index }
+func (s opStack) VaultIndex(index int) int { return s.L2ChainID(index) }
+
+func (opStack) CommandTemplate() string {
+	return repoSyncCommand +
+		"L2_CHAIN_ID={{.L2ChainID}} L1_CHAIN_ID={{.Common.L1ChainId}} L1_RPC_URL={{.L1RpcUrl}} L1_VAULT_PRIVATE_KEY={{.L1VaultPrivateKey}}" +
+		" L1_BRIDGE_HUB_CONTRACT={{.Common.L1BridgeHubContract}} L1_REGISTER_BRIDGE_PRIVATE_KEY={{.Common.L1RegisterBridgePrivateKey}}" +
+		" DRYRUN={{.Common.DryRun}} FORCE_DEPLOY_OP={{.Common.ForceDeployL2Chain}} ENABLE_GEN_ACC={{.Common.EnableGenAccounts}}" +
+		"{{with .Common.FaultGameMaxClockDuration}} FAULT_GAME_MAX_CLOCK_DURATION={{.}}{{end}} ./op_pipe.sh"
+}
+
+func (s opStack) InstanceName(tagPrefix string, ordinal int) string {
+	return sequentialName(tagPrefix, s.Type().String(), ordinal)
+}
+
+func (s opStack) ParseOrdinal(name string) (int, error) {
+	return parseSequentialName(name, s.Type().String())
+}
+
+func (opStack) RuntimeMonitorArgs(output string) string {
+	return fmt.Sprintf("--mode kurtosis --stack op --output '%s' --enclave op-gen", output)
+}
+
+func (opStack) LogTargets(runtimeLogPath string) []LogTarget {
+	return []LogTarget{
+		{Category: "deploy", RemotePath: remoteRepoDir + "/op-work/scripts/deploy-gen.log", LocalNameGz: "deploy-kurtosis-op.log.gz"},
+		runtimeLogTarget(runtimeLogPath),
+	}
+}
+
+func (opStack) ProbeL2(ctx context.Context, ip string, p ProbeParams) (ProbeResult, error) {
+	return probeViaConsoleSummary(ctx, ip, p)
+}
+
+func (opStack) CrossTx() CrossTxSpec {
+	return CrossTxSpec{WaitForReceipts: true}
+}
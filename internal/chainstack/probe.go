@@ -0,0 +1,187 @@
+package chainstack
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/openweb3/web3go"
+	ydylconsolesdk "github.com/wangdayong228/ydyl-deploy-client/pkg/ydyl-console-service-sdk"
+)
+
+const summaryFetchTimeout = 10 * time.Second
+
+// probeViaConsoleSummary 先从 ydyl-console-service 的 summary.L2_RPC_URL 获取 L2 RPC，再按 EVM 接口探测最新区块。
+func probeViaConsoleSummary(ctx context.Context, ip string, p ProbeParams) (ProbeResult, error) {
+	l2URL, err := resolveL2RPCURL(ctx, ip, p.ConsolePort)
+	if err != nil {
+		return ProbeResult{}, fmt.Errorf("获取 L2 RPC 失败: %w", err)
+	}
+	res, err := probeEVM(ctx, l2URL, p.RPCTimeout)
+	res.RPCURL = l2URL
+	return res, err
+}
+
+func resolveL2RPCURL(ctx context.Context, serverIP string, consolePort int) (string, error) {
+	baseURL := fmt.Sprintf("http://%s:%d", serverIP, consolePort)
+	sdk := ydylconsolesdk.New(baseURL)
+
+	summaryCtx, cancel := context.WithTimeout(ctx, summaryFetchTimeout)
+	defer cancel()
+
+	summary, err := sdk.Result.GetDeploySummary(summaryCtx)
+	if err != nil {
+		return "", err
+	}
+	l2URL := replaceLocalhostWithIP(strings.TrimSpace(summary.L2_RPC_URL), serverIP)
+	if l2URL == "" {
+		return "", fmt.Errorf("summary.L2_RPC_URL 为空")
+	}
+	return l2URL, nil
+}
+
+// probeEVM 通过 eth_blockNumber / eth_getBlockByNumber 获取最新区块。
+func probeEVM(ctx context.Context, rpcURL string, rpcTimeout time.Duration) (ProbeResult, error) {
+	probeCtx, cancel := context.WithTimeout(ctx, rpcTimeout)
+	defer cancel()
+
+	client, err := web3go.NewClient(rpcURL)
+	if err != nil {
+		return ProbeResult{}, fmt.Errorf("创建 web3go client 失败: %w", err)
+	}
+
+	var blockNumHex string
+	if err := client.Provider().CallContext(probeCtx, &blockNumHex, "eth_blockNumber"); err != nil {
+		return ProbeResult{}, fmt.Errorf("调用 eth_blockNumber 失败: %w", err)
+	}
+	blockNum, err := parseUintString(blockNumHex)
+	if err != nil {
+		return ProbeResult{}, fmt.Errorf("解析 eth_blockNumber 返回值失败: %w", err)
+	}
+
+	var block struct {
+		Number    string `json:"number"`
+		Timestamp string `json:"timestamp"`
+	}
+	if err := client.Provider().CallContext(probeCtx, &block, "eth_getBlockByNumber", blockNumHex, false); err != nil {
+		return ProbeResult{}, fmt.Errorf("调用 eth_getBlockByNumber 失败: %w", err)
+	}
+	blockTime, err := parseTimestamp(block.Timestamp)
+	if err != nil {
+		return ProbeResult{}, fmt.Errorf("解析区块时间失败: %w", err)
+	}
+
+	if parsedNumber, parseErr := parseUintString(block.Number); parseErr == nil {
+		blockNum = parsedNumber
+	}
+	return ProbeResult{BlockNumber: blockNum, BlockTime: blockTime}, nil
+}
+
+// probeConflux 通过 cfx_epochNumber / cfx_getBlockByEpochNumber 获取最新区块。
+func probeConflux(ctx context.Context, rpcURL string, rpcTimeout time.Duration) (ProbeResult, error) {
+	probeCtx, cancel := context.WithTimeout(ctx, rpcTimeout)
+	defer cancel()
+
+	client, err := web3go.NewClient(rpcURL)
+	if err != nil {
+		return ProbeResult{}, fmt.Errorf("创建 web3go client 失败: %w", err)
+	}
+
+	var epochHex string
+	if err := client.Provider().CallContext(probeCtx, &epochHex, "cfx_epochNumber"); err != nil {
+		return ProbeResult{}, fmt.Errorf("调用 cfx_epochNumber 失败: %w", err)
+	}
+	blockNum, err := parseUintString(epochHex)
+	if err != nil {
+		return ProbeResult{}, fmt.Errorf("解析 cfx_epochNumber 返回值失败: %w", err)
+	}
+
+	var block struct {
+		Timestamp   string `json:"timestamp"`
+		EpochNumber string `json:"epochNumber"`
+	}
+	if err := client.Provider().CallContext(probeCtx, &block, "cfx_getBlockByEpochNumber", epochHex, false); err != nil {
+		return ProbeResult{}, fmt.Errorf("调用 cfx_getBlockByEpochNumber 失败: %w", err)
+	}
+	blockTime, err := parseTimestamp(block.Timestamp)
+	if err != nil {
+		return ProbeResult{}, fmt.Errorf("解析区块时间失败: %w", err)
+	}
+	if parsedEpoch, parseErr := parseUintString(block.EpochNumber); parseErr == nil {
+		blockNum = parsedEpoch
+	}
+	return ProbeResult{BlockNumber: blockNum, BlockTime: blockTime}, nil
+}
+
+func parseTimestamp(v string) (time.Time, error) {
+	sec, err := parseUintString(v)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Unix(int64(sec), 0), nil
+}
+
+func parseUintString(v string) (uint64, error) {
+	raw := strings.TrimSpace(v)
+	if raw == "" {
+		return 0, fmt.Errorf("空字符串")
+	}
+	n, err := strconv.ParseUint(raw, 0, 64)
+	if err == nil {
+		return n, nil
+	}
+	if strings.HasPrefix(raw, "0x") || strings.HasPrefix(raw, "0X") {
+		return 0, err
+	}
+	hexNum, hexErr := strconv.ParseUint(raw, 16, 64)
+	if hexErr != nil {
+		return 0, err
+	}
+	return hexNum, nil
+}
+
+// replaceLocalhostWithIP 将 RPC URL 中的 localhost / 127.0.0.1 / 私有 IP 替换为 serverIP。
+func replaceLocalhostWithIP(rawURL, serverIP string) string {
+	if rawURL == "" || serverIP == "" {
+		return rawURL
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" {
+		r := strings.NewReplacer("127.0.0.1", serverIP, "localhost", serverIP)
+		return r.Replace(rawURL)
+	}
+	if !shouldReplaceHost(u.Hostname()) {
+		return rawURL
+	}
+	port := u.Port()
+	if port == "" {
+		u.Host = serverIP
+	} else {
+		u.Host = net.JoinHostPort(serverIP, port)
+	}
+	return u.String()
+}
+
+func shouldReplaceHost(host string) bool {
+	if strings.EqualFold(host, "localhost") {
+		return true
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return false
+	}
+	if ip.IsLoopback() {
+		return true
+	}
+	v4 := ip.To4()
+	if v4 == nil {
+		return false
+	}
+	return v4[0] == 10 ||
+		(v4[0] == 172 && v4[1] >= 16 && v4[1] <= 31) ||
+		(v4[0] == 192 && v4[1] == 168)
+}
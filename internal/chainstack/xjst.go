@@ -0,0 +1,70 @@
+package chainstack
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/wangdayong228/ydyl-deploy-client/internal/constants/enums"
+)
+
+// xjstGroupSize 为一条 xjst 链的默认节点数，可通过 services[].groupSize 调整（见 WithGroupSize）。
+const xjstGroupSize = 4
+
+// xjstStack 为 xjst（Conflux 系）链：每 size 个节点组成一条链，组内第一个节点为主节点。
+type xjstStack struct {
+	size int
+}
+
+func init() { Register(xjstStack{size: xjstGroupSize}) }
+
+func (xjstStack) Type() enums.ServiceType { return enums.ServiceTypeXJST }
+func (xjstStack) IsL2() bool              { return true }
+func (s xjstStack) GroupSize() int        { return s.size }
+
+func (xjstStack) withGroupSize(n int) Stack { return xjstStack{size: n} }
+
+func (xjstStack) L2ChainID(int) int          { return 0 }
+func (s xjstStack) VaultIndex(index int) int { return GroupID(s, index) }
+
+// CommandTemplate 示例：
+//
+//	CHAIN_NODE_IPS='[44.252.111.46,44.247.52.12,54.245.12.147,44.249.51.138]' NODE_ID='node-1' GROUP_ID=1 \
+//	L1_RPC_URL_WS='ws://47.243.70.39/ws' L1_RPC_URL='https://confura.yidaiyilu0.site/espace' AUTO_DEPLOY_L1_CONTRACTS='false' \
+//	L2_CHAIN_ID=0 L1_CHAIN_ID=1025 L1_VAULT_PRIVATE_KEY='0x...' L1_BRIDGE_HUB_CONTRACT='0x...' L1_REGISTER_BRIDGE_PRIVATE_KEY='0x...' ./xjst_pipe.sh
+func (xjstStack) CommandTemplate() string {
+	return repoSyncCommand +
+		"CHAIN_NODE_IPS='{{.XjstGroupIPs}}' NODE_ID='node-{{.XjstNodeID}}' GROUP_ID={{.XjstGroupID}}" +
+		" L1_RPC_URL_WS='{{.L1RpcUrlWs}}' L1_RPC_URL='{{.L1RpcUrl}}' AUTO_DEPLOY_L1_CONTRACTS='false' L2_CHAIN_ID=0" +
+		" L1_CHAIN_ID={{.Common.L1ChainId}} L1_VAULT_PRIVATE_KEY='{{.L1VaultPrivateKey}}' L1_BRIDGE_HUB_CONTRACT='{{.Common.L1BridgeHubContract}}'" +
+		" L1_REGISTER_BRIDGE_PRIVATE_KEY='{{.Common.L1RegisterBridgePrivateKey}}' ENABLE_GEN_ACC='{{.Common.EnableGenAccounts}}'" +
+		" BRIDGE_GAS_PRICE=100000000000 ./xjst_pipe.sh"
+}
+
+func (s xjstStack) InstanceName(tagPrefix string, ordinal int) string {
+	return groupedName(tagPrefix, s.Type().String(), s.size, ordinal)
+}
+
+func (s xjstStack) ParseOrdinal(name string) (int, error) {
+	return parseGroupedName(name, s.Type().String(), s.size)
+}
+
+func (xjstStack) RuntimeMonitorArgs(output string) string {
+	return fmt.Sprintf("--mode docker --output '%s' --container testchain_node1", output)
+}
+
+func (xjstStack) LogTargets(runtimeLogPath string) []LogTarget {
+	return []LogTarget{runtimeLogTarget(runtimeLogPath)}
+}
+
+// ProbeL2 固定通过 http://<ip>:<XJSTRPCPort> 的 cfx RPC 探测。
+func (xjstStack) ProbeL2(ctx context.Context, ip string, p ProbeParams) (ProbeResult, error) {
+	l2URL := fmt.Sprintf("http://%s:%d", ip, p.XJSTRPCPort)
+	res, err := probeConflux(ctx, l2URL, p.RPCTimeout)
+	res.RPCURL = l2URL
+	return res, err
+}
+
+// CrossTx：xjst 作为源链时只向自身发送且不等待回执；作为目标链时 target_l1_bridge 使用 L1BridgeSendContract。
+func (xjstStack) CrossTx() CrossTxSpec {
+	return CrossTxSpec{SelfTarget: true, TargetL1BridgeFromSend: true}
+}
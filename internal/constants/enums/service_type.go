@@ -1,6 +1,8 @@
 package enums
 
 import (
+	"fmt"
+
 	"github.com/nft-rainbow/rainbow-goutils/utils/enumutils"
 )
 
@@ -74,3 +76,17 @@ func (b ServiceType) String() string {
 func ParseServiceType(s string) (ServiceType, error) {
 	return ServiceTypeEb.Parse(s)
 }
+
+// RegisterServiceType 为新的链部署栈登记服务类型，需在包初始化阶段调用（例如 chainstack 各实现文件的变量声明）。
+// t 的数值参与 L1 vault 派生路径，必须保持稳定且不能与已有类型重复。
+func RegisterServiceType(t ServiceType, name string) ServiceType {
+	if existing, ok := ServiceTypeEb.Val2StrMap[t]; ok {
+		panic(fmt.Sprintf("ServiceType %d 已登记为 %s", t, existing))
+	}
+	if _, ok := ServiceTypeEb.Str2ValMap[name]; ok {
+		panic(fmt.Sprintf("ServiceType 名称 %s 已被登记", name))
+	}
+	ServiceTypeEb.Val2StrMap[t] = name
+	ServiceTypeEb.Str2ValMap[name] = t
+	return t
+}
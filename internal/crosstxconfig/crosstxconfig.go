@@ -11,12 +11,12 @@ import (
 	"os"
 	"path/filepath"
 	"sort"
-	"strconv"
 	"strings"
 	"sync"
 
 	"github.com/tyler-smith/go-bip39"
 
+	"github.com/wangdayong228/ydyl-deploy-client/internal/chainstack"
 	"github.com/wangdayong228/ydyl-deploy-client/internal/deploy"
 	ydylconsolesdk "github.com/wangdayong228/ydyl-deploy-client/pkg/ydyl-console-service-sdk"
 )
@@ -297,7 +297,7 @@ func LoadServers(path string) ([]deploy.ServerInfo, error) {
 	return servers, nil
 }
 
-// PickChainEntries 从 servers 列表中挑选可参与跨链配置生成的入口节点。
+// PickChainEntries 从 servers 列表中挑选可参与跨链配置生成的入口节点（各 L2 栈的组内主节点）。
 // 返回值以 name 为唯一键，确保同类型多链（如多个 op/cdk）都可参与。
 func PickChainEntries(servers []deploy.ServerInfo) (map[string]deploy.ServerInfo, error) {
 	entries := make(map[string]deploy.ServerInfo)
@@ -306,88 +306,55 @@ func PickChainEntries(servers []deploy.ServerInfo) (map[string]deploy.ServerInfo
 		if t == "" {
 			continue
 		}
-		switch t {
-		case "op", "cdk", "xjst":
-			name := strings.TrimSpace(s.Name)
-			if name == "" {
-				return nil, fmt.Errorf("servers.json 存在空 name: serviceType=%s ip=%s", t, strings.TrimSpace(s.IP))
-			}
-			index, err := parseServerNameIndex(name, t)
-			if err != nil {
-				return nil, fmt.Errorf("解析 name 失败: serviceType=%s name=%q: %w", t, s.Name, err)
-			}
-			// 仅 xjst 需要过滤 index=1，op/cdk 全部参与。
-			if t == "xjst" && index != 1 {
-				continue
-			}
-
-			ip := strings.TrimSpace(s.IP)
-			if ip == "" {
-				return nil, fmt.Errorf("servers.json 存在空 ip: serviceType=%s", t)
-			}
-			if _, exists := entries[name]; exists {
-				return nil, fmt.Errorf("servers.json 存在重复 name=%q", name)
-			}
-			entries[name] = deploy.ServerInfo{
-				IP:          ip,
-				ServiceType: t,
-				Name:        name,
-			}
-		default:
-			return nil, fmt.Errorf("不支持的 serviceType=%q（仅支持 op/cdk/xjst）", s.ServiceType)
-		}
-	}
-	return entries, nil
-}
-
-func parseServerNameIndex(name, serviceType string) (int, error) {
-	parts := strings.Split(strings.TrimSpace(name), "-")
-	switch serviceType {
-	case "op", "cdk":
-		// tagPrefix-serviceType-ordinal
-		if len(parts) < 3 {
-			return 0, fmt.Errorf("name 格式不合法，期望 tagPrefix-%s-ordinal", serviceType)
+		stack, ok := chainstack.LookupName(t)
+		if !ok || !stack.IsL2() {
+			return nil, fmt.Errorf("不支持的 serviceType=%q（仅支持 %s）", s.ServiceType, strings.Join(chainstack.L2Names(), "/"))
 		}
-		prefix := strings.Join(parts[:len(parts)-2], "-")
-		if strings.TrimSpace(prefix) == "" {
-			return 0, fmt.Errorf("name 格式不合法，tagPrefix 不能为空")
+		name := strings.TrimSpace(s.Name)
+		if name == "" {
+			return nil, fmt.Errorf("servers.json 存在空 name: serviceType=%s ip=%s", t, strings.TrimSpace(s.IP))
 		}
-		if strings.ToLower(parts[len(parts)-2]) != serviceType {
-			return 0, fmt.Errorf("name 与 serviceType 不匹配")
+		index, err := parseServerNameIndex(name, t)
+		if err != nil {
+			return nil, fmt.Errorf("解析 name 失败: serviceType=%s name=%q: %w", t, s.Name, err)
 		}
-		ordinal, err := strconv.Atoi(parts[len(parts)-1])
-		if err != nil || ordinal <= 0 {
-			return 0, fmt.Errorf("ordinal 必须是正整数")
-		}
-		return ordinal, nil
-	case "xjst":
-		// tagPrefix-serviceType-groupId-index
-		if len(parts) < 4 {
-			return 0, fmt.Errorf("name 格式不合法，期望 tagPrefix-xjst-groupId-index")
-		}
-		prefix := strings.Join(parts[:len(parts)-3], "-")
-		if strings.TrimSpace(prefix) == "" {
-			return 0, fmt.Errorf("name 格式不合法，tagPrefix 不能为空")
+		// 分组组网的链（xjst）只取组内第 1 个节点，独立成链的节点全部参与。
+		if index != 1 {
+			continue
 		}
-		if strings.ToLower(parts[len(parts)-3]) != serviceType {
-			return 0, fmt.Errorf("name 与 serviceType 不匹配")
+
+		ip := strings.TrimSpace(s.IP)
+		if ip == "" {
+			return nil, fmt.Errorf("servers.json 存在空 ip: serviceType=%s", t)
 		}
-		groupID, err := strconv.Atoi(parts[len(parts)-2])
-		if err != nil || groupID <= 0 {
-			return 0, fmt.Errorf("groupId 必须是正整数")
+		if _, exists := entries[name]; exists {
+			return nil, fmt.Errorf("servers.json 存在重复 name=%q", name)
 		}
-		index, err := strconv.Atoi(parts[len(parts)-1])
-		if err != nil || index < 1 || index > 4 {
-			return 0, fmt.Errorf("index 必须在 1~4")
+		entries[name] = deploy.ServerInfo{
+			IP:          ip,
+			ServiceType: t,
+			Name:        name,
 		}
-		return index, nil
-	default:
+	}
+	return entries, nil
+}
+
+// parseServerNameIndex 按链部署栈的命名规则解析节点名称，返回节点在分组内的序号（1-based，独立成链的节点恒为 1）。
+// 分组大小由部署时的 services[].groupSize 决定，这里不限制组内序号上限。
+func parseServerNameIndex(name, serviceType string) (int, error) {
+	stack, ok := chainstack.LookupName(serviceType)
+	if !ok {
 		return 0, fmt.Errorf("不支持的 serviceType=%q", serviceType)
 	}
+	_, member, err := chainstack.ParseGroupMember(stack, name)
+	if err != nil {
+		return 0, err
+	}
+	return member, nil
 }
 
 // GenerateJobs 生成 jobs：源链遍历所有链，目标链默认随机选取且不为自身；
-// 当源链为 xjst 时，目标链固定为源链自身。
+// 源链 / 目标链的映射差异（如 xjst 固定发给自身）由链部署栈的 CrossTx 规则决定。
 // 助记词在内部随机生成一次（12 words），所有 jobs 复用同一个。
 // 注意：该函数仅做组合与字段映射；不做网络/文件 IO，便于测试。
 func GenerateJobs(chainKeys []string, infos map[string]*ChainInfo, txAmountPerWallet int, walletAmount int, blockRange int64, l1BridgeReceiver string, l1RPC string) ([]Job, error) {
@@ -400,8 +367,9 @@ func GenerateJobs(chainKeys []string, infos map[string]*ChainInfo, txAmountPerWa
 	jobs := make([]Job, 0, len(chainKeys))
 	for _, srcKey := range chainKeys {
 		source := infos[srcKey]
+		sourceSpec := crossTxSpecOf(source.Type)
 		dstType := srcKey
-		if source.Type != "xjst" {
+		if !sourceSpec.SelfTarget {
 			targetCandidates := make([]string, 0, len(chainKeys)-1)
 			for _, candidate := range chainKeys {
 				if candidate == srcKey {
@@ -439,12 +407,12 @@ func GenerateJobs(chainKeys []string, infos map[string]*ChainInfo, txAmountPerWa
 			TargetL2RPC:     replaceLocalhostWithIP(target.Summary.L2_RPC_URL, target.IP),
 			TargetL2Bridge:  target.Contracts.L2BridgeReceiveContract.Hex(),
 			BlockRange:      blockRange,
-			WaitForReceipts: source.Type != "xjst",
+			WaitForReceipts: sourceSpec.WaitForReceipts,
 			SourceL1Bridge:  source.Contracts.L1BridgeReceiveContract.Hex(),
 			L1RPC:           l1RPC,
 		}
 
-		if target.Type == "xjst" {
+		if crossTxSpecOf(target.Type).TargetL1BridgeFromSend {
 			job.TargetL1Bridge = target.Contracts.L1BridgeSendContract.Hex()
 		}
 
@@ -454,6 +422,14 @@ func GenerateJobs(chainKeys []string, infos map[string]*ChainInfo, txAmountPerWa
 	return jobs, nil
 }
 
+// crossTxSpecOf 返回链类型的跨链 job 映射规则；未登记的类型按独立成链的默认规则处理。
+func crossTxSpecOf(chainType string) chainstack.CrossTxSpec {
+	if stack, ok := chainstack.LookupName(chainType); ok {
+		return stack.CrossTx()
+	}
+	return chainstack.CrossTxSpec{WaitForReceipts: true}
+}
+
 func pickRandomTarget(candidates []string) (string, error) {
 	if len(candidates) == 0 {
 		return "", fmt.Errorf("候选目标链为空")
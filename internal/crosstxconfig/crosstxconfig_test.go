@@ -320,6 +320,33 @@ func TestGenerateJobs_WaitForReceipts_BySourceChainType(t *testing.T) {
 	require.Equal(t, false, got["xjst"])
 }
 
+func TestGenerateJobs_ArbSource(t *testing.T) {
+	chainTypes := []string{"arb", "op"}
+	infos := map[string]*ChainInfo{
+		"arb": newTestChainInfo("arb", "4.4.4.4", "4"),
+		"op":  newTestChainInfo("op", "1.1.1.1", "1"),
+	}
+
+	jobs, err := GenerateJobs(chainTypes, infos, 1000, 10, 100000, "0x00000000000000000000000000000000000000ff", "https://example.org/rpc")
+	require.NoError(t, err)
+	require.Len(t, jobs, 2)
+
+	for _, j := range jobs {
+		require.True(t, j.WaitForReceipts)
+		switch j.SourceL2ChainType {
+		case "arb":
+			require.Equal(t, "op", j.TargetL2ChainType)
+			require.Equal(t, infos["arb"].Contracts.L2BridgeSendContract.Hex(), j.SourceL2Bridge)
+			require.Equal(t, infos["op"].Contracts.L1BridgeReceiveContract.Hex(), j.TargetL1Bridge)
+		case "op":
+			require.Equal(t, "arb", j.TargetL2ChainType)
+			require.Equal(t, infos["arb"].Contracts.L1BridgeReceiveContract.Hex(), j.TargetL1Bridge)
+		default:
+			t.Fatalf("unexpected source chain type %q", j.SourceL2ChainType)
+		}
+	}
+}
+
 func TestReplaceLocalhostWithIP_RewriteRules(t *testing.T) {
 	tests := []struct {
 		name   string
@@ -381,6 +408,7 @@ func TestPickChainEntries_FilterRules(t *testing.T) {
 		{IP: "2.2.2.1", ServiceType: "cdk", Name: "my-tag-cdk-1"},
 		{IP: "3.3.3.2", ServiceType: "xjst", Name: "prefix-xjst-1-2"},
 		{IP: "3.3.3.5", ServiceType: "xjst", Name: "prefix-xjst-2-1"},
+		{IP: "4.4.4.1", ServiceType: "arb", Name: "ydyl-arb-1"},
 	}
 
 	got, err := PickChainEntries(servers)
@@ -391,6 +419,23 @@ func TestPickChainEntries_FilterRules(t *testing.T) {
 		"my-tag-cdk-3":    {IP: "2.2.2.3", ServiceType: "cdk", Name: "my-tag-cdk-3"},
 		"my-tag-cdk-1":    {IP: "2.2.2.1", ServiceType: "cdk", Name: "my-tag-cdk-1"},
 		"prefix-xjst-2-1": {IP: "3.3.3.5", ServiceType: "xjst", Name: "prefix-xjst-2-1"},
+		"ydyl-arb-1":      {IP: "4.4.4.1", ServiceType: "arb", Name: "ydyl-arb-1"},
+	}, got)
+}
+
+func TestPickChainEntries_XJSTGroupOf7(t *testing.T) {
+	servers := []deploy.ServerInfo{
+		{IP: "3.3.3.1", ServiceType: "xjst", Name: "prefix-xjst-1-1"},
+		{IP: "3.3.3.6", ServiceType: "xjst", Name: "prefix-xjst-1-6"},
+		{IP: "3.3.3.7", ServiceType: "xjst", Name: "prefix-xjst-1-7"},
+		{IP: "3.3.3.8", ServiceType: "xjst", Name: "prefix-xjst-2-1"},
+	}
+
+	got, err := PickChainEntries(servers)
+	require.NoError(t, err)
+	require.Equal(t, map[string]deploy.ServerInfo{
+		"prefix-xjst-1-1": {IP: "3.3.3.1", ServiceType: "xjst", Name: "prefix-xjst-1-1"},
+		"prefix-xjst-2-1": {IP: "3.3.3.8", ServiceType: "xjst", Name: "prefix-xjst-2-1"},
 	}, got)
 }
 
@@ -0,0 +1,144 @@
+package deploy
+
+import (
+	"log"
+	"strings"
+
+	"github.com/wangdayong228/ydyl-deploy-client/internal/chainstack"
+)
+
+// appendPlan 描述 deploy --append 时某个服务类型已有节点的情况。
+// offset 为新节点的起始 0-based 索引（即已有最大序号），新节点的名称序号、L2 chainId 与 xjst groupId 均从这里继续；
+// existingIPs 按 0-based 索引记录已有节点 IP（缺失位置为空串），用于拼接 buildRemoteCommandForIndex 所需的全局 IP 列表。
+type appendPlan struct {
+	offset      int
+	existingIPs []string
+}
+
+// buildAppendPlans 从已有 servers.json / script_status.json 的节点名称解析每个服务类型的最大序号。
+// 分组组网的服务（xjst）以完整分组为单位续接：offset 会向上取整到分组大小的倍数，保证新 groupId 不与已有分组重叠。
+// groupSizes 为服务类型 -> services[].groupSize（见 serviceGroupSizes），未配置的类型使用链部署栈默认值。
+func buildAppendPlans(servers []ServerInfo, statuses []*ScriptStatus, groupSizes map[string]int) map[string]*appendPlan {
+	plans := make(map[string]*appendPlan)
+	record := func(name, serviceType, ip string) {
+		serviceType = strings.TrimSpace(serviceType)
+		ordinal, ok := parseInstanceOrdinal(name, serviceType, groupSizes[serviceType])
+		if !ok {
+			return
+		}
+		plan, exists := plans[serviceType]
+		if !exists {
+			plan = &appendPlan{}
+			plans[serviceType] = plan
+		}
+		if ordinal > plan.offset {
+			plan.offset = ordinal
+		}
+		for len(plan.existingIPs) < ordinal {
+			plan.existingIPs = append(plan.existingIPs, "")
+		}
+		if plan.existingIPs[ordinal-1] == "" {
+			plan.existingIPs[ordinal-1] = strings.TrimSpace(ip)
+		}
+	}
+	for _, s := range servers {
+		record(s.Name, s.ServiceType, s.IP)
+	}
+	for _, st := range statuses {
+		if st != nil {
+			record(st.Name, st.ServiceType, st.IP)
+		}
+	}
+
+	for serviceType, plan := range plans {
+		stack, ok := chainstack.LookupName(serviceType)
+		if !ok {
+			continue
+		}
+		size := chainstack.WithGroupSize(stack, groupSizes[serviceType]).GroupSize()
+		if plan.offset%size == 0 {
+			continue
+		}
+		plan.offset += size - plan.offset%size
+		for len(plan.existingIPs) < plan.offset {
+			plan.existingIPs = append(plan.existingIPs, "")
+		}
+	}
+	return plans
+}
+
+// parseInstanceOrdinal 为 buildInstanceName 的逆过程，返回 1-based 序号，命名规则由链部署栈决定：
+//   - 独立成链（op / cdk / generic）：tagPrefix-serviceType-ordinal
+//   - 分组组网（xjst）：tagPrefix-xjst-groupId-indexInGroup，序号为 (groupId-1)*groupSize+indexInGroup
+//
+// groupSize 为 services[].groupSize，0 表示使用链部署栈默认值。
+func parseInstanceOrdinal(name, serviceType string, groupSize int) (int, bool) {
+	stack, ok := chainstack.LookupName(serviceType)
+	if !ok {
+		return 0, false
+	}
+	ordinal, err := chainstack.WithGroupSize(stack, groupSize).ParseOrdinal(name)
+	return ordinal, err == nil
+}
+
+// isInstanceName 判断名称是否符合 deploy 生成的节点命名（分组组网的服务不限制组内位置上限，见 chainstack.ParseGroupMember）。
+func isInstanceName(name, serviceType string) bool {
+	stack, ok := chainstack.LookupName(serviceType)
+	if !ok {
+		return false
+	}
+	_, _, err := chainstack.ParseGroupMember(stack, name)
+	return err == nil
+}
+
+// serviceGroupSizes 返回服务类型 -> services[].groupSize，供解析已有节点名称时使用。
+func serviceGroupSizes(services []ServiceConfig) map[string]int {
+	sizes := make(map[string]int, len(services))
+	for _, svc := range services {
+		if svc.GroupSize > 0 {
+			sizes[svc.Type.String()] = svc.GroupSize
+		}
+	}
+	return sizes
+}
+
+// serviceIndexOffset 返回该服务新节点的起始 0-based 索引；非 append 模式恒为 0。
+func (d *Deployer) serviceIndexOffset(svc ServiceConfig) int {
+	if plan, ok := d.appendPlans[svc.Type.String()]; ok {
+		return plan.offset
+	}
+	return 0
+}
+
+// serviceNewCount 返回本次需要新建的节点数：append 模式下 count 视为该服务的目标总数，只补齐差额。
+func (d *Deployer) serviceNewCount(svc ServiceConfig) int {
+	n := int(svc.Count) - d.serviceIndexOffset(svc)
+	if n < 0 {
+		return 0
+	}
+	return n
+}
+
+// serviceGlobalIPs 将已有节点 IP 与本次新节点 IP 按索引拼接，新节点位于 [offset, offset+len(newIPs))。
+func (d *Deployer) serviceGlobalIPs(svc ServiceConfig, newIPs []string) []string {
+	offset := d.serviceIndexOffset(svc)
+	globalIps := make([]string, offset, offset+len(newIPs))
+	if plan, ok := d.appendPlans[svc.Type.String()]; ok {
+		copy(globalIps, plan.existingIPs)
+	}
+	return append(globalIps, newIPs...)
+}
+
+// logAppendPlans 输出 append 模式下各服务的续接起点，便于核对 chainId / groupId。
+func (d *Deployer) logAppendPlans() {
+	for _, svc := range d.cfg.Services {
+		offset := d.serviceIndexOffset(svc)
+		newCount := d.serviceNewCount(svc)
+		switch {
+		case svc.groupSize() > 1:
+			log.Printf("ℹ️ [append][%s] 已有 %d 个节点，新增 %d 个，groupId 从 %d 开始\n", svc.Type.String(), offset, newCount, d.resolveXjstGroupId(svc, offset))
+		default:
+			log.Printf("ℹ️ [append][%s] 已有 %d 个节点，新增 %d 个，L2 chainId 从 %d 开始\n", svc.Type.String(), offset, newCount, svc.resolveL2ChainID(offset))
+		}
+	}
+}
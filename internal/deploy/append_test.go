@@ -0,0 +1,178 @@
+package deploy
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/wangdayong228/ydyl-deploy-client/internal/constants/enums"
+)
+
+func TestParseInstanceOrdinal(t *testing.T) {
+	t.Parallel()
+
+	d := &Deployer{}
+	for _, tc := range []struct {
+		serviceType string
+		ordinal     int
+	}{
+		{"op", 1}, {"op", 12}, {"cdk", 3}, {"xjst", 1}, {"xjst", 4}, {"xjst", 7},
+	} {
+		name := d.buildInstanceName("ydyl-test", tc.serviceType, tc.ordinal)
+		got, ok := parseInstanceOrdinal(name, tc.serviceType, 0)
+		if !ok || got != tc.ordinal {
+			t.Fatalf("parseInstanceOrdinal(%q, 0) = %d,%v; want %d", name, got, ok, tc.ordinal)
+		}
+	}
+	for name, serviceType := range map[string]string{
+		"ydyl-cdk-1":    "op",
+		"ydyl-op-x":     "op",
+		"ydyl-op-0":     "op",
+		"ydyl-xjst-1-5": "xjst",
+		"ydyl-xjst-1":   "xjst",
+	} {
+		if _, ok := parseInstanceOrdinal(name, serviceType, 0); ok {
+			t.Fatalf("expected %q (%s) to be rejected", name, serviceType)
+		}
+	}
+}
+
+func TestBuildAppendPlans_ContinuesAfterMax(t *testing.T) {
+	t.Parallel()
+
+	plans := buildAppendPlans(
+		[]ServerInfo{
+			{IP: "1.1.1.1", ServiceType: "op", Name: "ydyl-op-1"},
+			{IP: "1.1.1.3", ServiceType: "op", Name: "ydyl-op-3"},
+			{IP: "2.2.2.1", ServiceType: "xjst", Name: "ydyl-xjst-1-1"},
+			{IP: "2.2.2.2", ServiceType: "xjst", Name: "ydyl-xjst-1-2"},
+			{IP: "2.2.2.5", ServiceType: "xjst", Name: "ydyl-xjst-2-1"},
+		},
+		[]*ScriptStatus{
+			{IP: "1.1.1.4", ServiceType: "op", Name: "ydyl-op-4"},
+			nil,
+		},
+		nil,
+	)
+
+	d := &Deployer{appendPlans: plans}
+	op := ServiceConfig{Type: enums.ServiceTypeOP, Count: 6}
+	if got := d.serviceIndexOffset(op); got != 4 {
+		t.Fatalf("op offset = %d, want 4", got)
+	}
+	if got := d.serviceNewCount(op); got != 2 {
+		t.Fatalf("op new count = %d, want 2", got)
+	}
+	if got := op.resolveL2ChainID(d.serviceIndexOffset(op)); got != 10004 {
+		t.Fatalf("first new chain id = %d, want 10004", got)
+	}
+	globalIps := d.serviceGlobalIPs(op, []string{"9.9.9.1", "9.9.9.2"})
+	if strings.Join(globalIps, ",") != "1.1.1.1,,1.1.1.3,1.1.1.4,9.9.9.1,9.9.9.2" {
+		t.Fatalf("unexpected op global ips: %v", globalIps)
+	}
+
+	// xjst 第 2 组未满也按整组占位，新节点从第 3 组开始。
+	xjst := ServiceConfig{Type: enums.ServiceTypeXJST, Count: 12}
+	if got := d.serviceIndexOffset(xjst); got != 8 {
+		t.Fatalf("xjst offset = %d, want 8", got)
+	}
+	if got := d.resolveXjstGroupId(xjst, d.serviceIndexOffset(xjst)); got != 3 {
+		t.Fatalf("first new xjst group = %d, want 3", got)
+	}
+	if got := d.serviceNewCount(xjst); got != 4 {
+		t.Fatalf("xjst new count = %d, want 4", got)
+	}
+
+	if got := d.serviceNewCount(ServiceConfig{Type: enums.ServiceTypeOP, Count: 2}); got != 0 {
+		t.Fatalf("count below existing should create nothing, got=%d", got)
+	}
+	if got := d.serviceIndexOffset(ServiceConfig{Type: enums.ServiceTypeCDK, Count: 2}); got != 0 {
+		t.Fatalf("service without existing nodes should start at 0, got=%d", got)
+	}
+}
+
+func TestBuildRemoteCommandForIndex_XJSTAppendUsesNewGroup(t *testing.T) {
+	t.Parallel()
+
+	d := &Deployer{
+		cfg: DeployConfig{
+			CommonConfig: CommonConfig{
+				L1ChainId:       "7655",
+				L1RpcUrl:        "https://l1.example/rpc",
+				L1VaultMnemonic: "test test test test test test test test test test test junk",
+			},
+		},
+		l1VaultDeriveRand: 1,
+		appendPlans: buildAppendPlans([]ServerInfo{
+			{IP: "1.0.0.1", ServiceType: "xjst", Name: "ydyl-xjst-1-1"},
+			{IP: "1.0.0.2", ServiceType: "xjst", Name: "ydyl-xjst-1-2"},
+			{IP: "1.0.0.3", ServiceType: "xjst", Name: "ydyl-xjst-1-3"},
+			{IP: "1.0.0.4", ServiceType: "xjst", Name: "ydyl-xjst-1-4"},
+		}, nil, nil),
+	}
+	svc := ServiceConfig{Type: enums.ServiceTypeXJST, Count: 8, TagPrefix: "ydyl"}
+	offset := d.serviceIndexOffset(svc)
+	globalIps := d.serviceGlobalIPs(svc, []string{"2.0.0.1", "2.0.0.2", "2.0.0.3", "2.0.0.4"})
+
+	got, err := d.buildRemoteCommandForIndex(globalIps, offset+1, svc)
+	if err != nil {
+		t.Fatalf("buildRemoteCommandForIndex returned error: %v", err)
+	}
+	if !strings.Contains(got, "CHAIN_NODE_IPS='[2.0.0.1,2.0.0.2,2.0.0.3,2.0.0.4]' NODE_ID='node-2' GROUP_ID=2 ") {
+		t.Fatalf("appended xjst node should join the new group, got=%s", got)
+	}
+	if name := d.buildInstanceName(svc.TagPrefix, svc.Type.String(), offset+2); name != "ydyl-xjst-2-2" {
+		t.Fatalf("unexpected appended name: %s", name)
+	}
+}
+
+func TestResolveDeploymentInfo_AppendReusesExisting(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2026, 3, 4, 5, 6, 7, 0, time.UTC)
+	outputDir := t.TempDir()
+	deriveRand := uint32(4242)
+	if err := SaveDeploymentInfo(outputDir, DeploymentInfo{DeploymentID: "dep-1", L1VaultDeriveRand: &deriveRand}); err != nil {
+		t.Fatalf("SaveDeploymentInfo: %v", err)
+	}
+
+	got, err := resolveDeploymentInfo(CommonConfig{OutputDir: outputDir}, true, now)
+	if err != nil {
+		t.Fatalf("resolveDeploymentInfo: %v", err)
+	}
+	if got.DeploymentID != "dep-1" || got.L1VaultDeriveRand == nil || *got.L1VaultDeriveRand != deriveRand {
+		t.Fatalf("append should reuse existing deployment info, got=%+v", got)
+	}
+
+	fresh, err := resolveDeploymentInfo(CommonConfig{OutputDir: outputDir}, false, now)
+	if err != nil {
+		t.Fatalf("resolveDeploymentInfo: %v", err)
+	}
+	if fresh.DeploymentID == "dep-1" || fresh.L1VaultDeriveRand == nil {
+		t.Fatalf("non-append run should generate new deployment info, got=%+v", fresh)
+	}
+}
+
+func TestBuildAppendPlans_XJSTGroupSize(t *testing.T) {
+	t.Parallel()
+
+	var servers []ServerInfo
+	for i := 1; i <= 5; i++ {
+		servers = append(servers, ServerInfo{IP: fmt.Sprintf("3.0.0.%d", i), ServiceType: "xjst", Name: fmt.Sprintf("ydyl-xjst-1-%d", i)})
+	}
+	plans := buildAppendPlans(servers, nil, serviceGroupSizes([]ServiceConfig{{Type: enums.ServiceTypeXJST, GroupSize: 7}}))
+
+	d := &Deployer{appendPlans: plans}
+	xjst := ServiceConfig{Type: enums.ServiceTypeXJST, Count: 14, TagPrefix: "ydyl", GroupSize: 7}
+	offset := d.serviceIndexOffset(xjst)
+	if offset != 7 {
+		t.Fatalf("xjst offset = %d, want 7 (rounded up to groupSize)", offset)
+	}
+	if got := d.resolveXjstGroupId(xjst, offset); got != 2 {
+		t.Fatalf("xjst next groupId = %d, want 2", got)
+	}
+	if name := d.buildServiceInstanceName(xjst, offset+7); name != "ydyl-xjst-2-7" {
+		t.Fatalf("unexpected name: %s", name)
+	}
+}
@@ -0,0 +1,183 @@
+package deploy
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gofrs/flock"
+)
+
+const (
+	chainIDRegistryDirName  = ".ydyl-deploy-client"
+	chainIDRegistryFileName = "chain_ids.json"
+)
+
+// chainIDRegistry 为本机（或共享路径上）各部署已占用的 L2 链 ID 登记表，用于拒绝并发部署之间的链 ID 冲突。
+// 同一 output 目录的登记视为同一部署：新 deploy 会归档旧 output 并覆盖其登记，teardown 时释放。
+type chainIDRegistry struct {
+	Entries []chainIDClaim `json:"entries"`
+}
+
+// chainIDClaim 为一条链 ID 占用记录；链 ID 只在同一 L1 上需要唯一。
+type chainIDClaim struct {
+	L1ChainID    string `json:"l1ChainId"`
+	ChainID      int    `json:"chainId"`
+	ServiceType  string `json:"serviceType"`
+	DeploymentID string `json:"deploymentId"`
+	OutputDir    string `json:"outputDir"`
+	ClaimedAt    int64  `json:"claimedAt"`
+}
+
+// resolveChainIDRegistryPath 返回登记文件路径：优先使用 chainIdRegistry，否则为 $HOME/.ydyl-deploy-client/chain_ids.json。
+func resolveChainIDRegistryPath(common CommonConfig) (string, error) {
+	if p := strings.TrimSpace(common.ChainIDRegistry); p != "" {
+		return p, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("获取用户主目录失败: %w", err)
+	}
+	return filepath.Join(home, chainIDRegistryDirName, chainIDRegistryFileName), nil
+}
+
+// buildChainIDClaims 汇总本次部署各服务（索引 [0, count)）将使用的 L2 链 ID。
+func buildChainIDClaims(cfg DeployConfig, deploymentID, outputDir string, now time.Time) []chainIDClaim {
+	var claims []chainIDClaim
+	for _, svc := range cfg.Services {
+		for i := 0; i < int(svc.Count); i++ {
+			id := svc.resolveL2ChainID(i)
+			if id == 0 {
+				continue
+			}
+			claims = append(claims, chainIDClaim{
+				L1ChainID:    strings.TrimSpace(cfg.L1ChainId),
+				ChainID:      id,
+				ServiceType:  svc.Type.String(),
+				DeploymentID: deploymentID,
+				OutputDir:    outputDir,
+				ClaimedAt:    now.Unix(),
+			})
+		}
+	}
+	return claims
+}
+
+// claimChainIDs 在登记文件锁内检查并登记 claims：同一 L1 上已被其它 output 目录占用的链 ID 拒绝登记；
+// outputDir 自身的旧登记（上一次部署或 append / resume 前的记录）会被替换。
+func claimChainIDs(registryPath, outputDir string, claims []chainIDClaim) error {
+	return updateChainIDRegistry(registryPath, func(reg *chainIDRegistry) error {
+		kept := reg.Entries[:0]
+		held := make(map[string]chainIDClaim, len(reg.Entries))
+		for _, e := range reg.Entries {
+			if e.OutputDir == outputDir {
+				continue
+			}
+			kept = append(kept, e)
+			held[chainIDClaimKey(e.L1ChainID, e.ChainID)] = e
+		}
+
+		var conflicts []string
+		for _, c := range claims {
+			if e, ok := held[chainIDClaimKey(c.L1ChainID, c.ChainID)]; ok {
+				conflicts = append(conflicts, fmt.Sprintf("%d（%s，已被 %s 的 %s 使用，output=%s）", c.ChainID, c.ServiceType, e.DeploymentID, e.ServiceType, e.OutputDir))
+			}
+		}
+		if len(conflicts) > 0 {
+			return fmt.Errorf("L2 链 ID 与其它部署冲突，请通过 services[].chainId 调整或先 teardown 旧部署: %s", strings.Join(conflicts, "; "))
+		}
+
+		reg.Entries = append(kept, claims...)
+		return nil
+	})
+}
+
+// releaseChainIDs 删除 outputDir 登记的全部链 ID，返回删除条数；登记文件不存在时直接返回。
+func releaseChainIDs(registryPath, outputDir string) (int, error) {
+	if _, err := os.Stat(registryPath); os.IsNotExist(err) {
+		return 0, nil
+	}
+	released := 0
+	err := updateChainIDRegistry(registryPath, func(reg *chainIDRegistry) error {
+		kept := reg.Entries[:0]
+		for _, e := range reg.Entries {
+			if e.OutputDir == outputDir {
+				released++
+				continue
+			}
+			kept = append(kept, e)
+		}
+		reg.Entries = kept
+		return nil
+	})
+	return released, err
+}
+
+// updateChainIDRegistry 在文件写锁内执行“读取 -> 修改 -> 原子写回”；fn 返回错误时不写回。
+func updateChainIDRegistry(registryPath string, fn func(reg *chainIDRegistry) error) error {
+	if err := os.MkdirAll(filepath.Dir(registryPath), 0o755); err != nil {
+		return fmt.Errorf("创建链 ID 登记目录失败: %w", err)
+	}
+	lock := flock.New(registryPath + ".lock")
+	if err := lock.Lock(); err != nil {
+		return fmt.Errorf("获取链 ID 登记文件锁失败: %w", err)
+	}
+	defer func() { _ = lock.Unlock() }()
+
+	reg := &chainIDRegistry{}
+	data, err := os.ReadFile(registryPath)
+	switch {
+	case err == nil:
+		if uErr := json.Unmarshal(data, reg); uErr != nil {
+			return fmt.Errorf("解析链 ID 登记文件 %s 失败: %w", registryPath, uErr)
+		}
+	case os.IsNotExist(err):
+	default:
+		return fmt.Errorf("读取链 ID 登记文件 %s 失败: %w", registryPath, err)
+	}
+
+	if err := fn(reg); err != nil {
+		return err
+	}
+	sort.SliceStable(reg.Entries, func(i, j int) bool {
+		if reg.Entries[i].L1ChainID != reg.Entries[j].L1ChainID {
+			return reg.Entries[i].L1ChainID < reg.Entries[j].L1ChainID
+		}
+		return reg.Entries[i].ChainID < reg.Entries[j].ChainID
+	})
+	return writeJSONFileAtomic(registryPath, reg)
+}
+
+func chainIDClaimKey(l1ChainID string, chainID int) string {
+	return fmt.Sprintf("%s/%d", l1ChainID, chainID)
+}
+
+// registerDeploymentChainIDs 在 deploy 启动时登记本次部署使用的链 ID，冲突时拒绝部署。
+func registerDeploymentChainIDs(cfg DeployConfig, deploymentID string, now time.Time) error {
+	claims := buildChainIDClaims(cfg, deploymentID, absOutputDir(cfg.OutputDir), now)
+	if len(claims) == 0 {
+		return nil
+	}
+	registryPath, err := resolveChainIDRegistryPath(cfg.CommonConfig)
+	if err != nil {
+		return err
+	}
+	if err := claimChainIDs(registryPath, absOutputDir(cfg.OutputDir), claims); err != nil {
+		return err
+	}
+	log.Printf("🏷️ 已登记 %d 个 L2 链 ID: %s\n", len(claims), registryPath)
+	return nil
+}
+
+// absOutputDir 将 output 目录转为绝对路径，保证从不同工作目录执行时登记记录一致。
+func absOutputDir(outputDir string) string {
+	if abs, err := filepath.Abs(outputDir); err == nil {
+		return abs
+	}
+	return outputDir
+}
@@ -0,0 +1,64 @@
+package deploy
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/wangdayong228/ydyl-deploy-client/internal/constants/enums"
+)
+
+func TestClaimChainIDs_RefusesOtherOutputDir(t *testing.T) {
+	t.Parallel()
+
+	registry := filepath.Join(t.TempDir(), "chain_ids.json")
+	now := time.Unix(1700000000, 0)
+	cfg := DeployConfig{
+		CommonConfig: CommonConfig{L1ChainId: "7655"},
+		Services:     []ServiceConfig{{Type: enums.ServiceTypeOP, Count: 2}},
+	}
+
+	first := buildChainIDClaims(cfg, "dep-a", "/out/a", now)
+	if len(first) != 2 || first[1].ChainID != 10001 {
+		t.Fatalf("unexpected claims: %+v", first)
+	}
+	if err := claimChainIDs(registry, "/out/a", first); err != nil {
+		t.Fatalf("first claim failed: %v", err)
+	}
+
+	// 同一 output 目录重新部署（或 append）覆盖自己的旧登记
+	if err := claimChainIDs(registry, "/out/a", buildChainIDClaims(cfg, "dep-a2", "/out/a", now)); err != nil {
+		t.Fatalf("re-claim from same output dir failed: %v", err)
+	}
+
+	// 另一个部署使用相同链 ID 被拒绝
+	err := claimChainIDs(registry, "/out/b", buildChainIDClaims(cfg, "dep-b", "/out/b", now))
+	if err == nil || !strings.Contains(err.Error(), "10000") || !strings.Contains(err.Error(), "dep-a2") {
+		t.Fatalf("expected conflict with dep-a2, got=%v", err)
+	}
+
+	// 不同 L1 上的相同链 ID 不冲突
+	other := cfg
+	other.L1ChainId = "11155111"
+	if err := claimChainIDs(registry, "/out/c", buildChainIDClaims(other, "dep-c", "/out/c", now)); err != nil {
+		t.Fatalf("claim on another L1 failed: %v", err)
+	}
+
+	released, err := releaseChainIDs(registry, "/out/a")
+	if err != nil || released != 2 {
+		t.Fatalf("release = %d, %v; want 2, nil", released, err)
+	}
+	if err := claimChainIDs(registry, "/out/b", buildChainIDClaims(cfg, "dep-b", "/out/b", now)); err != nil {
+		t.Fatalf("claim after release failed: %v", err)
+	}
+}
+
+func TestReleaseChainIDs_MissingRegistry(t *testing.T) {
+	t.Parallel()
+
+	released, err := releaseChainIDs(filepath.Join(t.TempDir(), "missing", "chain_ids.json"), "/out/a")
+	if err != nil || released != 0 {
+		t.Fatalf("release = %d, %v; want 0, nil", released, err)
+	}
+}
@@ -3,12 +3,14 @@ package deploy
 import (
 	"errors"
 	"fmt"
+	"log"
 	"strconv"
 	"strings"
 	"time"
 
-	"github.com/nft-rainbow/rainbow-goutils/utils/configutils"
+	"github.com/mitchellh/mapstructure"
 	"github.com/spf13/viper"
+	"github.com/wangdayong228/ydyl-deploy-client/internal/chainstack"
 	"github.com/wangdayong228/ydyl-deploy-client/internal/constants/enums"
 )
 
@@ -24,9 +26,91 @@ type ServiceConfig struct {
 
 	Count     uint   `yaml:"count"`
 	RemoteCmd string `yaml:"remoteCmd"`
+	// RemoteCmdTemplate 为 true 时 remoteCmd 按 Go text/template 逐节点渲染（变量见 RemoteCmdVars），
+	// 否则原样执行，命令中的 {{ }}（例如 docker ps --format '{{.Names}}'）不做处理。
+	RemoteCmdTemplate bool `yaml:"remoteCmdTemplate"`
 
 	L1RpcUrl          string `yaml:"l1RpcUrl"`
 	L1VaultFundAmount int64  `yaml:"l1VaultFundAmount"` // 单位：ether
+
+	// 以下为 EC2 启动参数（均可选），未配置时沿用全局 securityGroupId / diskSizeGiB 的默认行为。
+	SubnetID string `yaml:"subnetId"`
+	// SecurityGroupIDs 追加在全局 securityGroupId 之后，重复项会被去重。
+	SecurityGroupIDs []string `yaml:"securityGroupIds"`
+	// IAMInstanceProfile 支持实例配置文件名称或完整 ARN（以 arn: 开头）。
+	IAMInstanceProfile string `yaml:"iamInstanceProfile"`
+	// UserData 为明文脚本，启动时自动做 base64 编码。
+	UserData string `yaml:"userData"`
+	// Tags 会在创建时同时打到实例与 EBS 卷上；Name 标签由部署流程统一维护，不允许在此配置。
+	Tags []TagConfig `yaml:"tags"`
+	// Volumes 为空时仅按全局 diskSizeGiB 设置根卷；deviceName 为空的条目表示根卷，设备名取自 AMI 的 RootDeviceName。
+	Volumes        []VolumeConfig `yaml:"volumes"`
+	PlacementGroup string         `yaml:"placementGroup"`
+	// AssociatePublicIP 为空时沿用子网默认行为；显式设置时通过主网卡配置是否分配公网 IP。
+	AssociatePublicIP *bool `yaml:"associatePublicIp"`
+
+	// DependsOn 为需要先完成 rollout（实例创建、SSH 就绪、远程命令启动）的服务类型；为空时与其它服务并发部署。
+	DependsOn []string `yaml:"dependsOn"`
+	// Rollout 为空时该服务的所有节点同时启动远程命令；配置后按 canary + 分批方式启动，见 RolloutConfig。
+	Rollout *RolloutConfig `yaml:"rollout"`
+
+	// ChainID 为空时按链部署栈的默认规则分配 L2 链 ID（op / cdk 为 10000+index，arb 为 20000+index），见 ChainIDConfig。
+	ChainID *ChainIDConfig `yaml:"chainId"`
+	// GroupSize 为分组组网服务（xjst）每组的节点数，0 表示使用链部署栈的默认值（xjst 为 4）。
+	GroupSize int `yaml:"groupSize"`
+	// Files 为远程命令启动前需上传到每个节点的本地文件或目录，上传后按 sha256 校验，见 FileConfig。
+	Files []FileConfig `yaml:"files"`
+}
+
+// ChainIDConfig 描述单个服务的 L2 链 ID 分配方式，base / range 与 list 二选一：
+//   - base / range：第 index 个节点的链 ID 为 base+index（base 为 0 时沿用默认起始值），range > 0 时限制该段最多 range 个 ID；
+//   - list：按 index 依次取 list 中的链 ID，长度需覆盖 count。
+type ChainIDConfig struct {
+	Base  int   `yaml:"base"`
+	Range int   `yaml:"range"`
+	List  []int `yaml:"list"`
+}
+
+// RolloutConfig 描述单个服务远程命令的灰度启动策略：
+// 先启动 canary 个节点并等待其全部 success，再按 waveSize 分批启动剩余节点，
+// 每批结束后若已结束节点的失败比例超过 maxFailureRatio 则停止后续批次。
+type RolloutConfig struct {
+	// Canary 为首批启动的节点数，0 表示不设 canary 批次。
+	Canary int `yaml:"canary"`
+	// WaveSize 为 canary 之后每批启动的节点数，0 表示剩余节点一次全部启动。
+	WaveSize int `yaml:"waveSize"`
+	// MaxFailureRatio 为允许的失败比例（0~1），0 表示任一节点失败即停止。
+	MaxFailureRatio float64 `yaml:"maxFailureRatio"`
+}
+
+// FileConfig 描述一项需上传到远端的本地文件或目录（通过 rsync over ssh 传输，保留权限位，符号链接按目标内容上传）。
+type FileConfig struct {
+	// Src 为本地文件或目录路径，相对路径相对于当前工作目录。
+	Src string `yaml:"src"`
+	// Dest 为远端绝对路径：Src 为文件时为目标文件路径，为目录时其内容同步到该目录下（不删除远端多余文件）。
+	Dest string `yaml:"dest"`
+}
+
+// TagConfig 描述一个自定义 EC2 标签。
+// 使用 key/value 列表而非 map，避免 viper 将 map key 统一转为小写。
+type TagConfig struct {
+	Key   string `yaml:"key"`
+	Value string `yaml:"value"`
+}
+
+// VolumeConfig 描述一块 EBS 卷的挂载参数。
+type VolumeConfig struct {
+	DeviceName string `yaml:"deviceName"`
+	SizeGiB    int64  `yaml:"sizeGiB"`
+	VolumeType string `yaml:"volumeType"` // 为空时默认 gp3
+	IOPS       int64  `yaml:"iops"`       // 仅 gp3 / io1 / io2 生效
+	Throughput int64  `yaml:"throughput"` // MiB/s，仅 gp3 生效
+}
+
+const defaultVolumeType = "gp3"
+
+var supportedVolumeTypes = map[string]struct{}{
+	"gp2": {}, "gp3": {}, "io1": {}, "io2": {}, "st1": {}, "sc1": {}, "standard": {},
 }
 
 func (s *ServiceConfig) CheckValid() error {
@@ -38,12 +122,213 @@ func (s *ServiceConfig) CheckValid() error {
 			return fmt.Errorf("instanceType[%d] must not be empty", i)
 		}
 	}
-	if s.Type == enums.ServiceTypeXJST && s.Count%4 != 0 {
-		return errors.New("xjst service count must be divisible by 4")
+	stack, ok := chainstack.Lookup(s.Type)
+	if !ok {
+		return fmt.Errorf("unsupported service type %s", s.Type.String())
+	}
+	if s.GroupSize < 0 {
+		return fmt.Errorf("groupSize must be >= 0, got %d", s.GroupSize)
+	}
+	if s.GroupSize > 0 && stack.GroupSize() <= 1 {
+		return fmt.Errorf("groupSize is only supported by grouped services, got %s", s.Type.String())
+	}
+	if size := s.groupSize(); s.Count%uint(size) != 0 {
+		return fmt.Errorf("%s service count must be divisible by %d", s.Type.String(), size)
+	}
+	if err := s.checkChainIDValid(stack); err != nil {
+		return err
+	}
+	for i, tag := range s.Tags {
+		key := strings.TrimSpace(tag.Key)
+		if key == "" {
+			return fmt.Errorf("tags[%d].key must not be empty", i)
+		}
+		if key == "Name" {
+			return fmt.Errorf("tags[%d]: Name tag is managed by deploy and must not be configured", i)
+		}
+		if strings.HasPrefix(key, reservedTagKeyPrefix) {
+			return fmt.Errorf("tags[%d]: key prefix %q is reserved for deployment cost tags", i, reservedTagKeyPrefix)
+		}
+	}
+	if err := checkVolumesValid(s.Volumes); err != nil {
+		return err
+	}
+	if err := checkFilesValid(s.Files); err != nil {
+		return err
+	}
+	if s.RemoteCmdTemplate {
+		if strings.TrimSpace(s.RemoteCmd) == "" {
+			return fmt.Errorf("remoteCmdTemplate requires a non-empty remoteCmd")
+		}
+		if _, err := parseRemoteCmdTemplate(s.RemoteCmd); err != nil {
+			return fmt.Errorf("remoteCmd is not a valid template: %w", err)
+		}
+	}
+	if err := s.checkRolloutValid(); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (s *ServiceConfig) checkRolloutValid() error {
+	r := s.Rollout
+	if r == nil {
+		return nil
+	}
+	if r.Canary < 0 || r.WaveSize < 0 {
+		return errors.New("rollout.canary and rollout.waveSize must be >= 0")
+	}
+	if r.Canary == 0 && r.WaveSize == 0 {
+		return errors.New("rollout requires canary > 0 or waveSize > 0")
+	}
+	if r.MaxFailureRatio < 0 || r.MaxFailureRatio > 1 {
+		return fmt.Errorf("rollout.maxFailureRatio must be within [0, 1], got %v", r.MaxFailureRatio)
+	}
+	// 分组组网的服务（xjst）同组节点需要在同一批次启动
+	if size := s.groupSize(); r.Canary%size != 0 || r.WaveSize%size != 0 {
+		return fmt.Errorf("%s rollout.canary and rollout.waveSize must be divisible by %d", s.Type.String(), size)
+	}
+	return nil
+}
+
+func (s *ServiceConfig) checkChainIDValid(stack chainstack.Stack) error {
+	c := s.ChainID
+	if c == nil {
+		return nil
+	}
+	if stack.L2ChainID(0) == 0 {
+		return fmt.Errorf("chainId is not supported by %s service", s.Type.String())
+	}
+	if c.Base < 0 || c.Range < 0 {
+		return errors.New("chainId.base and chainId.range must be >= 0")
+	}
+	if len(c.List) > 0 {
+		if c.Base > 0 || c.Range > 0 {
+			return errors.New("chainId.list must not be combined with chainId.base / chainId.range")
+		}
+		if len(c.List) < int(s.Count) {
+			return fmt.Errorf("chainId.list has %d entries, fewer than count %d", len(c.List), s.Count)
+		}
+		seen := make(map[int]struct{}, len(c.List))
+		for i, id := range c.List {
+			if id <= 0 {
+				return fmt.Errorf("chainId.list[%d] must be > 0, got %d", i, id)
+			}
+			if _, ok := seen[id]; ok {
+				return fmt.Errorf("chainId.list[%d]: duplicate chain id %d", i, id)
+			}
+			seen[id] = struct{}{}
+		}
+		return nil
+	}
+	if c.Range > 0 && int(s.Count) > c.Range {
+		return fmt.Errorf("count %d exceeds chainId.range %d", s.Count, c.Range)
+	}
+	return nil
+}
+
+// stack 返回服务对应的链部署栈，分组大小已按 services[].groupSize 调整。
+func (s *ServiceConfig) stack() (chainstack.Stack, bool) {
+	stack, ok := chainstack.Lookup(s.Type)
+	if !ok {
+		return nil, false
+	}
+	return chainstack.WithGroupSize(stack, s.GroupSize), true
+}
+
+// groupSize 返回服务实际使用的分组大小：groupSize 未配置时取链部署栈默认值。
+func (s *ServiceConfig) groupSize() int {
+	if stack, ok := s.stack(); ok {
+		return stack.GroupSize()
+	}
+	return 1
+}
+
+// resolveL2ChainID 返回服务第 index 个节点（0-based）的 L2 链 ID，没有 L2 链 ID 的服务返回 0。
+func (s *ServiceConfig) resolveL2ChainID(index int) int {
+	stack, ok := chainstack.Lookup(s.Type)
+	if !ok {
+		return 0
+	}
+	base := stack.L2ChainID(0)
+	if base == 0 || s.ChainID == nil {
+		return stack.L2ChainID(index)
+	}
+	if len(s.ChainID.List) > 0 {
+		if index < 0 || index >= len(s.ChainID.List) {
+			return 0
+		}
+		return s.ChainID.List[index]
+	}
+	if s.ChainID.Base > 0 {
+		base = s.ChainID.Base
+	}
+	return base + index
+}
+
+// checkChainIDOverlaps 校验各服务分配的 L2 链 ID 互不重叠（默认规则下 op 与 cdk 同为 10000+index，需显式配置 chainId 错开）。
+func checkChainIDOverlaps(services []ServiceConfig) error {
+	owners := make(map[int]int)
+	for i := range services {
+		for index := 0; index < int(services[i].Count); index++ {
+			id := services[i].resolveL2ChainID(index)
+			if id == 0 {
+				continue
+			}
+			if j, ok := owners[id]; ok && j != i {
+				return fmt.Errorf("services[%d] (%s) chain id %d overlaps with services[%d] (%s), configure services[].chainId to separate them",
+					i, services[i].Type.String(), id, j, services[j].Type.String())
+			}
+			owners[id] = i
+		}
+	}
+	return nil
+}
+
+func checkVolumesValid(volumes []VolumeConfig) error {
+	rootCount := 0
+	seenDevices := make(map[string]struct{}, len(volumes))
+	for i, v := range volumes {
+		device := strings.TrimSpace(v.DeviceName)
+		if device == "" {
+			rootCount++
+		} else {
+			if _, ok := seenDevices[device]; ok {
+				return fmt.Errorf("volumes[%d]: duplicate deviceName %q", i, device)
+			}
+			seenDevices[device] = struct{}{}
+		}
+		if v.SizeGiB <= 0 {
+			return fmt.Errorf("volumes[%d].sizeGiB must be > 0", i)
+		}
+		volumeType := resolveVolumeType(v.VolumeType)
+		if _, ok := supportedVolumeTypes[volumeType]; !ok {
+			return fmt.Errorf("volumes[%d]: unsupported volumeType %q", i, v.VolumeType)
+		}
+		if v.IOPS > 0 && volumeType != "gp3" && volumeType != "io1" && volumeType != "io2" {
+			return fmt.Errorf("volumes[%d]: iops is only supported by gp3/io1/io2, got %s", i, volumeType)
+		}
+		if (volumeType == "io1" || volumeType == "io2") && v.IOPS <= 0 {
+			return fmt.Errorf("volumes[%d]: iops is required for %s", i, volumeType)
+		}
+		if v.Throughput > 0 && volumeType != "gp3" {
+			return fmt.Errorf("volumes[%d]: throughput is only supported by gp3, got %s", i, volumeType)
+		}
+	}
+	if rootCount > 1 {
+		return errors.New("volumes must contain at most one root volume (empty deviceName)")
 	}
 	return nil
 }
 
+func resolveVolumeType(volumeType string) string {
+	t := strings.ToLower(strings.TrimSpace(volumeType))
+	if t == "" {
+		return defaultVolumeType
+	}
+	return t
+}
+
 type CommonConfig struct {
 	// AWS / EC2 相关（全局）
 	Region          string `yaml:"region"`
@@ -81,6 +366,18 @@ type CommonConfig struct {
 	EnableGenAccounts          bool   `yaml:"enableGenAccounts"`
 	CdkUseRealProver           bool   `yaml:"cdkUseRealProver"`
 	FaultGameMaxClockDuration  string `yaml:"faultGameMaxClockDuration" mapstructure:"faultGameMaxClockDuration,omitempty"`
+
+	// 成本归集标签（可选）：deploymentId 为空时按本次运行时间自动生成，operator 为空时取当前系统用户。
+	DeploymentID string `yaml:"deploymentId"`
+	Operator     string `yaml:"operator"`
+
+	// ChainIDRegistry 为本机已使用 L2 链 ID 的登记文件，为空时默认 $HOME/.ydyl-deploy-client/chain_ids.json；
+	// 多人共用时可指向共享路径。
+	ChainIDRegistry string `yaml:"chainIdRegistry"`
+
+	// RepoRef 为远端部署仓库需 checkout 的 commit / tag / branch；为空时沿用 AMI 当前分支执行 git pull。
+	// 配置后在首个节点上解析为 commit hash，同一部署的所有节点 checkout 同一 commit。
+	RepoRef string `yaml:"repoRef"`
 }
 
 // DeployConfig 描述一次 deploy 命令所需的全部参数
@@ -94,11 +391,67 @@ func (c *DeployConfig) CheckValid() error {
 	if err := validateFaultGameMaxClockDuration(c.FaultGameMaxClockDuration); err != nil {
 		return err
 	}
+	if err := validateRepoRef(c.RepoRef); err != nil {
+		return err
+	}
 	for _, s := range c.Services {
 		if err := s.CheckValid(); err != nil {
 			return err
 		}
 	}
+	if err := checkChainIDOverlaps(c.Services); err != nil {
+		return err
+	}
+	return checkServiceDependencies(c.Services)
+}
+
+// checkServiceDependencies 校验 services[].dependsOn：依赖的服务类型必须已配置、不能依赖自身，且不能成环。
+func checkServiceDependencies(services []ServiceConfig) error {
+	configured := make(map[string]bool, len(services))
+	for _, svc := range services {
+		configured[svc.Type.String()] = true
+	}
+	edges := make(map[string][]string)
+	for i, svc := range services {
+		for _, dep := range svc.DependsOn {
+			depType, err := enums.ParseServiceType(strings.TrimSpace(dep))
+			if err != nil {
+				return fmt.Errorf("services[%d].dependsOn: unknown service type %q", i, dep)
+			}
+			if depType == svc.Type {
+				return fmt.Errorf("services[%d].dependsOn: service %q must not depend on itself", i, dep)
+			}
+			if !configured[depType.String()] {
+				return fmt.Errorf("services[%d].dependsOn: service %q is not configured", i, dep)
+			}
+			edges[svc.Type.String()] = append(edges[svc.Type.String()], depType.String())
+		}
+	}
+
+	// 0=未访问 1=访问中 2=已完成
+	state := make(map[string]int)
+	var visit func(node string, path []string) error
+	visit = func(node string, path []string) error {
+		switch state[node] {
+		case 1:
+			return fmt.Errorf("services dependsOn cycle: %s", strings.Join(append(path, node), " -> "))
+		case 2:
+			return nil
+		}
+		state[node] = 1
+		for _, next := range edges[node] {
+			if err := visit(next, append(path, node)); err != nil {
+				return err
+			}
+		}
+		state[node] = 2
+		return nil
+	}
+	for _, svc := range services {
+		if err := visit(svc.Type.String(), nil); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
@@ -116,8 +469,123 @@ func validateFaultGameMaxClockDuration(value string) error {
 	return nil
 }
 
-// LoadConfigFromFile 从 YAML 文件加载配置并转换为内部 DeployConfig 结构
+// commonConfigDefaults 为可选的全局配置项提供默认值。
+// loadConfigFromFile 以 ErrorUnset 严格解码，YAML 中未出现的字段需在此兜底，否则加载直接 panic。
+var commonConfigDefaults = map[string]any{
+	"faultGameMaxClockDuration": "",
+	"cdkUseRealProver":          false,
+	"deploymentId":              "",
+	"operator":                  "",
+	"chainIdRegistry":           "",
+	"repoRef":                   "",
+}
+
+// serviceConfigDefaults 与 commonConfigDefaults 作用相同，但作用于 services[] 的每个元素。
+// viper.SetDefault 无法覆盖数组元素，因此在解码前预先补齐缺失字段（key 需为小写，与 viper 内部一致）。
+var serviceConfigDefaults = map[string]any{
+	"subnetid":           "",
+	"securitygroupids":   []any{},
+	"iaminstanceprofile": "",
+	"remotecmdtemplate":  false,
+	"userdata":           "",
+	"tags":               []any{},
+	"volumes":            []any{},
+	"placementgroup":     "",
+	"associatepublicip":  nil,
+	"dependson":          []any{},
+	"rollout":            nil,
+	"chainid":            nil,
+	"groupsize":          0,
+	"files":              []any{},
+}
+
+var chainIDConfigDefaults = map[string]any{
+	"base":  0,
+	"range": 0,
+	"list":  []any{},
+}
+
+var rolloutConfigDefaults = map[string]any{
+	"canary":          0,
+	"wavesize":        0,
+	"maxfailureratio": 0.0,
+}
+
+var volumeConfigDefaults = map[string]any{
+	"devicename": "",
+	"volumetype": "",
+	"iops":       0,
+	"throughput": 0,
+}
+
+// LoadConfigFromFile 从 YAML 文件加载配置并转换为内部 DeployConfig 结构，读取或解码失败时直接退出。
 func LoadConfigFromFile(path string) *DeployConfig {
-	viper.SetDefault("faultGameMaxClockDuration", "")
-	return configutils.MustLoadByFile[DeployConfig](path)
+	cfg, err := loadConfigFromFile(path)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	return cfg
+}
+
+// loadConfigFromFile 使用独立的 viper 实例读取配置：在解码前为全局项与 services[] 各元素补齐默认值，
+// 再以 ErrorUnset / ErrorUnused 严格解码，不修改全局 viper 状态。
+func loadConfigFromFile(path string) (*DeployConfig, error) {
+	v := viper.New()
+	v.SetConfigFile(path)
+	if err := v.ReadInConfig(); err != nil {
+		return nil, fmt.Errorf("fatal error config file: %w", err)
+	}
+	fmt.Printf("viper user config file: %v\n", v.ConfigFileUsed())
+
+	for key, value := range commonConfigDefaults {
+		v.SetDefault(key, value)
+	}
+	if services, ok := v.Get("services").([]any); ok {
+		applyServiceConfigDefaults(services)
+		v.Set("services", services)
+	}
+
+	var cfg DeployConfig
+	if err := v.Unmarshal(&cfg, func(dc *mapstructure.DecoderConfig) {
+		dc.ErrorUnset = true
+		dc.ErrorUnused = true
+		dc.DecodeHook = mapstructure.ComposeDecodeHookFunc(
+			mapstructure.StringToTimeDurationHookFunc(),
+			mapstructure.TextUnmarshallerHookFunc(),
+		)
+	}); err != nil {
+		return nil, fmt.Errorf("解析配置文件 %s 失败: %w", path, err)
+	}
+	return &cfg, nil
+}
+
+// applyServiceConfigDefaults 就地补齐 services[] 各元素（及其嵌套的 volumes / rollout / chainId）缺失的可选字段。
+func applyServiceConfigDefaults(services []any) {
+	for _, item := range services {
+		svc, ok := item.(map[string]any)
+		if !ok {
+			continue
+		}
+		fillMissingKeys(svc, serviceConfigDefaults)
+		volumes, _ := svc["volumes"].([]any)
+		for _, v := range volumes {
+			if volume, ok := v.(map[string]any); ok {
+				fillMissingKeys(volume, volumeConfigDefaults)
+			}
+		}
+		if rollout, ok := svc["rollout"].(map[string]any); ok {
+			fillMissingKeys(rollout, rolloutConfigDefaults)
+		}
+		if chainID, ok := svc["chainid"].(map[string]any); ok {
+			fillMissingKeys(chainID, chainIDConfigDefaults)
+		}
+	}
+}
+
+func fillMissingKeys(m map[string]any, defaults map[string]any) {
+	for key, value := range defaults {
+		if _, ok := m[key]; !ok {
+			m[key] = value
+		}
+	}
 }
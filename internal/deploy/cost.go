@@ -0,0 +1,254 @@
+package deploy
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// PricingTable 为本地维护的实例单价表：instanceType -> 每小时价格（USD）。
+// 不使用 viper 解析，因为 viper 会把 "c6a.xlarge" 之类带点的 key 拆成嵌套结构。
+type PricingTable map[string]float64
+
+// LoadPricingTable 从 YAML（或 JSON）文件读取单价表，文件格式示例：
+//
+//	c6a.xlarge: 0.153
+//	c6a.2xlarge: 0.306
+func LoadPricingTable(path string) (PricingTable, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("读取单价表失败: %w", err)
+	}
+	raw := make(map[string]float64)
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("解析单价表失败（%s）: %w", path, err)
+	}
+	table := make(PricingTable, len(raw))
+	for instanceType, rate := range raw {
+		instanceType = strings.ToLower(strings.TrimSpace(instanceType))
+		if instanceType == "" {
+			continue
+		}
+		if rate < 0 {
+			return nil, fmt.Errorf("单价表中 %s 的价格不能为负数: %v", instanceType, rate)
+		}
+		table[instanceType] = rate
+	}
+	return table, nil
+}
+
+// HourlyRate 返回某机型的小时单价，未配置时 ok=false。
+func (p PricingTable) HourlyRate(instanceType string) (float64, bool) {
+	rate, ok := p[strings.ToLower(strings.TrimSpace(instanceType))]
+	return rate, ok
+}
+
+// CostItem 描述单台实例的成本估算。
+type CostItem struct {
+	Name         string
+	ServiceType  string
+	IP           string
+	InstanceID   string
+	InstanceType string
+	// InstanceTypeGuessed 为 true 表示 servers_create.json 未记录机型，按配置中该服务的首选机型推断。
+	InstanceTypeGuessed bool
+	HourlyRate          float64
+	PriceKnown          bool
+	LaunchTime          time.Time
+	// Elapsed 为截至当前的计费时长（不超过 runDuration，到期后实例会自动关机）。
+	Elapsed   time.Duration
+	Estimated float64
+	Spent     float64
+}
+
+// CostSummary 按服务类型 + 机型汇总成本。
+type CostSummary struct {
+	ServiceType  string
+	InstanceType string
+	Count        int
+	HourlyRate   float64
+	Hourly       float64
+	Estimated    float64
+	Spent        float64
+}
+
+// CostReport 为一次部署的成本估算结果。
+type CostReport struct {
+	DeploymentID string
+	RunDuration  time.Duration
+	GeneratedAt  time.Time
+
+	Items     []CostItem
+	Summaries []CostSummary
+
+	TotalHourly    float64
+	TotalEstimated float64
+	TotalSpent     float64
+	// MissingPrices 为单价表中缺失的机型（这些实例不计入合计）。
+	MissingPrices []string
+}
+
+// EstimateCost 根据 outputDir 下的 servers_create.json / servers.json、单价表与 runDuration 估算部署成本。
+// 预计成本 = 小时单价 × runDuration；已花费 = 小时单价 × min(当前时间 - 启动时间, runDuration)。
+func EstimateCost(cfg DeployConfig, outputDir string, pricing PricingTable, now time.Time) (*CostReport, error) {
+	outputDir = resolveOutputDir(cfg.CommonConfig, outputDir)
+	outputMgr, err := LoadOutputManager(outputDir)
+	if err != nil {
+		return nil, fmt.Errorf("加载输出目录失败（%s）: %w", outputDir, err)
+	}
+	deployment, err := LoadDeploymentInfo(outputDir)
+	if err != nil {
+		return nil, err
+	}
+
+	runDuration := cfg.RunDuration
+	report := &CostReport{GeneratedAt: now}
+	var fallbackLaunch time.Time
+	if deployment != nil {
+		report.DeploymentID = deployment.DeploymentID
+		if d, parseErr := time.ParseDuration(deployment.RunDuration); parseErr == nil && d > 0 {
+			runDuration = d
+		}
+		if ts, parseErr := time.Parse(time.RFC3339, deployment.RunTimestamp); parseErr == nil {
+			fallbackLaunch = ts
+		}
+	}
+	report.RunDuration = runDuration
+
+	instances := collectCostInstances(outputMgr.SnapshotCreatedServers(), outputMgr.SnapshotServers())
+	if len(instances) == 0 {
+		return nil, fmt.Errorf("输出目录中未找到任何实例记录: %s", filepath.Join(outputDir, "servers_create.json"))
+	}
+
+	missing := make(map[string]struct{})
+	for _, inst := range instances {
+		item := CostItem{
+			Name:         inst.Name,
+			ServiceType:  inst.ServiceType,
+			IP:           inst.IP,
+			InstanceID:   inst.InstanceID,
+			InstanceType: inst.InstanceType,
+		}
+		if item.InstanceType == "" {
+			item.InstanceType = fallbackInstanceType(cfg.Services, inst.ServiceType)
+			item.InstanceTypeGuessed = item.InstanceType != ""
+		}
+		item.LaunchTime = fallbackLaunch
+		if inst.LaunchTime > 0 {
+			item.LaunchTime = time.Unix(inst.LaunchTime, 0)
+		}
+		if !item.LaunchTime.IsZero() {
+			item.Elapsed = billedDuration(item.LaunchTime, now, runDuration)
+		}
+
+		item.HourlyRate, item.PriceKnown = pricing.HourlyRate(item.InstanceType)
+		if !item.PriceKnown {
+			missing[item.InstanceType] = struct{}{}
+		} else {
+			item.Estimated = item.HourlyRate * runDuration.Hours()
+			item.Spent = item.HourlyRate * item.Elapsed.Hours()
+		}
+		report.Items = append(report.Items, item)
+	}
+
+	report.Summaries = summarizeCostItems(report.Items)
+	for _, s := range report.Summaries {
+		report.TotalHourly += s.Hourly
+		report.TotalEstimated += s.Estimated
+		report.TotalSpent += s.Spent
+	}
+	for instanceType := range missing {
+		if instanceType == "" {
+			instanceType = "<unknown>"
+		}
+		report.MissingPrices = append(report.MissingPrices, instanceType)
+	}
+	sort.Strings(report.MissingPrices)
+	return report, nil
+}
+
+// collectCostInstances 以 servers_create.json 为主（包含 SSH 未就绪但仍在计费的实例），
+// 再补充仅出现在 servers.json 中的机器（兼容旧版本输出或 --servers-create 复用场景）。
+func collectCostInstances(created []CreatedServerInfo, servers []ServerInfo) []CreatedServerInfo {
+	seen := make(map[string]struct{}, len(created)+len(servers))
+	out := make([]CreatedServerInfo, 0, len(created)+len(servers))
+	for _, c := range created {
+		key := compositeKey(c.IP, c.ServiceType)
+		if _, ok := seen[key]; ok {
+			continue
+		}
+		seen[key] = struct{}{}
+		out = append(out, c)
+	}
+	for _, s := range servers {
+		key := compositeKey(strings.TrimSpace(s.IP), strings.TrimSpace(s.ServiceType))
+		if _, ok := seen[key]; ok {
+			continue
+		}
+		seen[key] = struct{}{}
+		out = append(out, CreatedServerInfo{
+			Name:        strings.TrimSpace(s.Name),
+			ServiceType: strings.TrimSpace(s.ServiceType),
+			IP:          strings.TrimSpace(s.IP),
+		})
+	}
+	return out
+}
+
+func fallbackInstanceType(services []ServiceConfig, serviceType string) string {
+	for _, svc := range services {
+		if strings.EqualFold(svc.Type.String(), serviceType) && len(svc.InstanceType) > 0 {
+			return strings.TrimSpace(svc.InstanceType[0])
+		}
+	}
+	return ""
+}
+
+func billedDuration(launchTime, now time.Time, runDuration time.Duration) time.Duration {
+	elapsed := now.Sub(launchTime)
+	if elapsed < 0 {
+		return 0
+	}
+	if runDuration > 0 && elapsed > runDuration {
+		return runDuration
+	}
+	return elapsed
+}
+
+func summarizeCostItems(items []CostItem) []CostSummary {
+	index := make(map[string]int)
+	summaries := make([]CostSummary, 0)
+	for _, item := range items {
+		if !item.PriceKnown {
+			continue
+		}
+		key := item.ServiceType + "|" + item.InstanceType
+		i, ok := index[key]
+		if !ok {
+			i = len(summaries)
+			index[key] = i
+			summaries = append(summaries, CostSummary{
+				ServiceType:  item.ServiceType,
+				InstanceType: item.InstanceType,
+				HourlyRate:   item.HourlyRate,
+			})
+		}
+		s := &summaries[i]
+		s.Count++
+		s.Hourly += item.HourlyRate
+		s.Estimated += item.Estimated
+		s.Spent += item.Spent
+	}
+	sort.Slice(summaries, func(i, j int) bool {
+		if summaries[i].ServiceType != summaries[j].ServiceType {
+			return summaries[i].ServiceType < summaries[j].ServiceType
+		}
+		return summaries[i].InstanceType < summaries[j].InstanceType
+	})
+	return summaries
+}
@@ -0,0 +1,150 @@
+package deploy
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/wangdayong228/ydyl-deploy-client/internal/constants/enums"
+)
+
+func TestLoadPricingTable(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "pricing.yaml")
+	if err := os.WriteFile(path, []byte("c6a.xlarge: 0.153\nC6A.2XLARGE: 0.306\n"), 0o644); err != nil {
+		t.Fatalf("write pricing: %v", err)
+	}
+
+	table, err := LoadPricingTable(path)
+	if err != nil {
+		t.Fatalf("LoadPricingTable error: %v", err)
+	}
+	if rate, ok := table.HourlyRate("c6a.xlarge"); !ok || rate != 0.153 {
+		t.Fatalf("dotted instance type should not be split, got=%v ok=%v", rate, ok)
+	}
+	if rate, ok := table.HourlyRate("c6a.2xlarge"); !ok || rate != 0.306 {
+		t.Fatalf("instance type lookup should be case-insensitive, got=%v ok=%v", rate, ok)
+	}
+	if _, ok := table.HourlyRate("m5.large"); ok {
+		t.Fatalf("unknown instance type should not have a rate")
+	}
+}
+
+func TestEstimateCost(t *testing.T) {
+	t.Parallel()
+
+	outputDir := t.TempDir()
+	launch := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	mgr := NewOutputManager(outputDir)
+	if err := mgr.AddCreatedServers([]CreatedServerInfo{
+		{Name: "ydyl-op-create-1", ServiceType: "op", IP: "1.1.1.1", InstanceID: "i-1", InstanceType: "c6a.xlarge", LaunchTime: launch.Unix()},
+		{Name: "ydyl-op-create-2", ServiceType: "op", IP: "1.1.1.2", InstanceID: "i-2", InstanceType: "c6a.xlarge", LaunchTime: launch.Add(30 * time.Minute).Unix()},
+	}); err != nil {
+		t.Fatalf("AddCreatedServers: %v", err)
+	}
+	// 旧版本输出：仅出现在 servers.json 中，机型按配置推断，启动时间取 deployment.json。
+	if err := mgr.AddServers([]ServerInfo{
+		{IP: "1.1.1.1", ServiceType: "op", Name: "ydyl-op-1"},
+		{IP: "2.2.2.2", ServiceType: "cdk", Name: "ydyl-cdk-1"},
+		{IP: "3.3.3.3", ServiceType: "xjst", Name: "ydyl-xjst-1-1"},
+	}); err != nil {
+		t.Fatalf("AddServers: %v", err)
+	}
+	if err := SaveDeploymentInfo(outputDir, DeploymentInfo{DeploymentID: "dep-1", RunTimestamp: launch.Format(time.RFC3339), RunDuration: "2h0m0s"}); err != nil {
+		t.Fatalf("SaveDeploymentInfo: %v", err)
+	}
+
+	cfg := DeployConfig{
+		CommonConfig: CommonConfig{OutputDir: outputDir, RunDuration: time.Hour},
+		Services: []ServiceConfig{
+			{Type: enums.ServiceTypeCDK, InstanceType: []string{"c6a.2xlarge", "c6a.xlarge"}},
+			{Type: enums.ServiceTypeXJST, InstanceType: []string{"m5.large"}},
+		},
+	}
+	pricing := PricingTable{"c6a.xlarge": 1, "c6a.2xlarge": 2}
+
+	report, err := EstimateCost(cfg, "", pricing, launch.Add(90*time.Minute))
+	if err != nil {
+		t.Fatalf("EstimateCost error: %v", err)
+	}
+
+	if report.DeploymentID != "dep-1" || report.RunDuration != 2*time.Hour {
+		t.Fatalf("deployment.json should take precedence, got id=%s runDuration=%s", report.DeploymentID, report.RunDuration)
+	}
+	if len(report.Items) != 4 {
+		t.Fatalf("expected 4 instances (created + servers-only), got=%d", len(report.Items))
+	}
+	cdk := report.Items[2]
+	if cdk.InstanceType != "c6a.2xlarge" || !cdk.InstanceTypeGuessed || !cdk.LaunchTime.Equal(launch) {
+		t.Fatalf("unexpected fallback item: %+v", cdk)
+	}
+	if len(report.MissingPrices) != 1 || report.MissingPrices[0] != "m5.large" {
+		t.Fatalf("unexpected missing prices: %v", report.MissingPrices)
+	}
+
+	// op: 2 × $1 × 2h；cdk: $2 × 2h
+	assertFloat(t, "estimated", report.TotalEstimated, 4+4)
+	// op-1: 1.5h，op-2: 1h，cdk: 1.5h × $2
+	assertFloat(t, "spent", report.TotalSpent, 1.5+1+3)
+	assertFloat(t, "hourly", report.TotalHourly, 1+1+2)
+	if len(report.Summaries) != 2 || report.Summaries[0].ServiceType != "cdk" || report.Summaries[1].Count != 2 {
+		t.Fatalf("unexpected summaries: %+v", report.Summaries)
+	}
+}
+
+func TestBilledDuration_CappedByRunDuration(t *testing.T) {
+	t.Parallel()
+
+	launch := time.Unix(1000, 0)
+	if got := billedDuration(launch, launch.Add(5*time.Hour), 2*time.Hour); got != 2*time.Hour {
+		t.Fatalf("elapsed should be capped by runDuration, got=%s", got)
+	}
+	if got := billedDuration(launch, launch.Add(-time.Minute), time.Hour); got != 0 {
+		t.Fatalf("launch in the future should bill nothing, got=%s", got)
+	}
+}
+
+func TestDeploymentInfoCostTags(t *testing.T) {
+	t.Parallel()
+
+	if tags := (DeploymentInfo{}).CostTags("op"); tags != nil {
+		t.Fatalf("empty deployment should not produce tags, got=%v", tags)
+	}
+
+	info := newDeploymentInfo(CommonConfig{Operator: "alice", RunDuration: time.Hour}, time.Date(2026, 3, 4, 5, 6, 7, 0, time.UTC))
+	if info.DeploymentID != "ydyl-20260304-050607" || info.RunTimestamp != "2026-03-04T05:06:07Z" {
+		t.Fatalf("unexpected deployment info: %+v", info)
+	}
+
+	l := &EC2RunInstancesLauncher{commonCfg: CommonConfig{KeyName: "k"}, deployment: info}
+	input := l.buildRunInstancesInput(ServiceConfig{Type: enums.ServiceTypeOP, Tags: []TagConfig{{Key: "Project", Value: "x"}}}, "c6a.xlarge", "", 1)
+	if len(input.TagSpecifications) != 2 {
+		t.Fatalf("cost tags should apply to instance and volume, got=%d specs", len(input.TagSpecifications))
+	}
+	got := make(map[string]string)
+	for _, tag := range input.TagSpecifications[1].Tags {
+		got[*tag.Key] = *tag.Value
+	}
+	want := map[string]string{
+		TagKeyDeploymentID: "ydyl-20260304-050607",
+		TagKeyServiceType:  "op",
+		TagKeyOperator:     "alice",
+		TagKeyRunTimestamp: "2026-03-04T05:06:07Z",
+		"Project":          "x",
+	}
+	for k, v := range want {
+		if got[k] != v {
+			t.Fatalf("tag %s: got=%q want=%q", k, got[k], v)
+		}
+	}
+}
+
+func assertFloat(t *testing.T, name string, got, want float64) {
+	t.Helper()
+	if math.Abs(got-want) > 1e-9 {
+		t.Fatalf("%s: got=%v want=%v", name, got, want)
+	}
+}
@@ -19,7 +19,6 @@ import (
 	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/ec2"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
@@ -32,8 +31,8 @@ import (
 	"github.com/openweb3/web3go/types"
 	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
+	"github.com/wangdayong228/ydyl-deploy-client/internal/chainstack"
 	"github.com/wangdayong228/ydyl-deploy-client/internal/constants/enums"
-	"github.com/wangdayong228/ydyl-deploy-client/internal/utils/cryptoutil"
 	"github.com/wangdayong228/ydyl-deploy-client/internal/utils/sshutil"
 )
 
@@ -46,12 +45,38 @@ type Deployer struct {
 	ec2Client  *ec2.EC2
 	outputMgr  *OutputManager
 	sshKeyPath string
+	// deployment 为本次运行的部署元信息（deploymentId / operator 等），用于成本归集标签。
+	deployment DeploymentInfo
 	// 同一轮 deploy 固定随机段，用于 L1 vault 私钥派生路径倒数第三段。
 	l1VaultDeriveRand uint32
 
+	// skipProgress 为 true 时不写 state.json 的 progress（replace 复用 deploy 的创建流程，不得改写 deploy 的续跑进度）。
+	skipProgress bool
+
 	// reuseFromSnapshot 为 true 时从 importedSnapshot 取 IP，跳过 EC2 创建。
 	reuseFromSnapshot bool
 	importedSnapshot  []CreatedServerInfo
+
+	// appendPlans 非空表示 deploy --append：按服务类型记录已有节点，新节点的序号 / chainId / groupId 从其后续接。
+	appendPlans map[string]*appendPlan
+
+	// resume 为 true 表示在已有进度上继续（deploy --resume，或未选择 launch 阶段的分阶段续跑）：
+	// 沿用 state.json 中的阶段进度，跳过已完成的充值 / 创建 / SSH / 逐节点阶段。
+	resume bool
+	// phases 为 --only / --skip 选择的阶段集合，nil 表示执行全部阶段。
+	phases map[string]bool
+	// phase 为 deploy 整体所处的阶段（fund / 服务部署 / sync）；servicePhases 为各服务类型各自所处的阶段，
+	// 多个服务并发部署时互不覆盖。二者在中断或失败时用于记录对应阶段。
+	phaseMu       sync.Mutex
+	phase         string
+	servicePhases map[string]string
+
+	// sshSem 为各服务共享的 SSH 并发预算，见 sshBudget。
+	sshSemOnce sync.Once
+	sshSem     chan struct{}
+
+	// repoMu 保护 deployment.RepoRef / RepoCommit 的首次解析，见 ensureRepoCommit。
+	repoMu sync.Mutex
 }
 
 const (
@@ -75,20 +100,41 @@ func NewDeployer(ctx context.Context, cfg DeployConfig, opts RunOptions) (*Deplo
 		cfg.CommonConfig.OutputDir = filepath.Join(cfg.CommonConfig.LogDir, "output")
 	}
 
-	// 0) 预先归档旧 output / logs，且两者共享同一时间戳
-	archiveTS, err := resolveDeployArchiveTimestamp(cfg.CommonConfig.OutputDir, cfg.CommonConfig.LogDir)
-	if err != nil {
-		return nil, fmt.Errorf("计算归档时间戳失败: %w", err)
+	if opts.Append && strings.TrimSpace(opts.ServersCreateJSONPath) != "" {
+		return nil, fmt.Errorf("--append 不支持与 --servers-create 同时使用")
+	}
+	if opts.Resume && (opts.Append || strings.TrimSpace(opts.ServersCreateJSONPath) != "") {
+		return nil, fmt.Errorf("--resume 不支持与 --append / --servers-create 同时使用")
 	}
-	preservedClientLogsDir, err := stashClientLogsDir(cfg.CommonConfig.LogDir, archiveTS)
+	phases, err := resolveDeployPhases(opts.Only, opts.Skip)
 	if err != nil {
-		return nil, fmt.Errorf("暂存 client 日志目录失败: %w", err)
+		return nil, err
 	}
-	if _, err := rotateExistingDirWithTimestamp(cfg.CommonConfig.OutputDir, archiveTS); err != nil {
-		return nil, fmt.Errorf("归档旧的输出目录失败: %w", err)
+	// 未选择 launch 阶段时不会新建实例，视为在已有部署上分阶段续跑
+	continueProgress := opts.Resume || (phases != nil && !phases[DeployPhaseLaunch])
+	if opts.Append && continueProgress {
+		return nil, fmt.Errorf("--append 需要执行 launch 阶段")
 	}
-	if _, err := rotateExistingDirWithTimestamp(cfg.CommonConfig.LogDir, archiveTS); err != nil {
-		return nil, fmt.Errorf("归档旧的日志目录失败: %w", err)
+	// resume / 分阶段续跑与 append 一样在已有 output 上续写
+	continueExisting := opts.Append || continueProgress
+
+	// 0) 预先归档旧 output / logs，且两者共享同一时间戳；append / resume 模式在原目录上续写，不做归档
+	preservedClientLogsDir := ""
+	if !continueExisting {
+		archiveTS, err := resolveDeployArchiveTimestamp(cfg.CommonConfig.OutputDir, cfg.CommonConfig.LogDir)
+		if err != nil {
+			return nil, fmt.Errorf("计算归档时间戳失败: %w", err)
+		}
+		preservedClientLogsDir, err = stashClientLogsDir(cfg.CommonConfig.LogDir, archiveTS)
+		if err != nil {
+			return nil, fmt.Errorf("暂存 client 日志目录失败: %w", err)
+		}
+		if _, err := rotateExistingDirWithTimestamp(cfg.CommonConfig.OutputDir, archiveTS); err != nil {
+			return nil, fmt.Errorf("归档旧的输出目录失败: %w", err)
+		}
+		if _, err := rotateExistingDirWithTimestamp(cfg.CommonConfig.LogDir, archiveTS); err != nil {
+			return nil, fmt.Errorf("归档旧的日志目录失败: %w", err)
+		}
 	}
 
 	// 1) 准备日志目录
@@ -105,25 +151,47 @@ func NewDeployer(ctx context.Context, cfg DeployConfig, opts RunOptions) (*Deplo
 	}
 
 	outputMgr := NewOutputManager(cfg.CommonConfig.OutputDir)
+	var appendPlans map[string]*appendPlan
+	if continueExisting {
+		loaded, err := LoadOutputManager(cfg.CommonConfig.OutputDir)
+		if err != nil {
+			return nil, fmt.Errorf("加载已有部署输出失败: %w", err)
+		}
+		outputMgr = loaded
+		appendPlans = buildAppendPlans(outputMgr.SnapshotServers(), outputMgr.SnapshotStatuses(), serviceGroupSizes(cfg.Services))
+	}
+	if opts.Resume {
+		if _, ok := outputMgr.SnapshotProgress(); !ok {
+			return nil, fmt.Errorf("%s 中没有可恢复的 deploy 进度（output 目录为空或由旧版本生成），无法 --resume", cfg.CommonConfig.OutputDir)
+		}
+	}
 
-	// 4) 初始化 AWS session / EC2 client
-	awsCfg := aws.Config{}
-	if cfg.CommonConfig.Region != "" {
-		awsCfg.Region = aws.String(cfg.CommonConfig.Region)
+	// 3) 生成部署元信息并落盘 deployment.json，供 cost / inventory 等命令按 deploymentId 归集；
+	//    append 模式沿用已有 deploymentId 与 L1 vault 派生随机段，保证新旧节点归属同一部署
+	deployment, err := resolveDeploymentInfo(cfg.CommonConfig, continueExisting, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	// 登记本次部署使用的 L2 链 ID，与其它 output 目录的部署冲突时拒绝继续
+	if err := registerDeploymentChainIDs(cfg, deployment.DeploymentID, time.Now()); err != nil {
+		return nil, err
+	}
+	if err := SaveDeploymentInfo(cfg.CommonConfig.OutputDir, deployment); err != nil {
+		return nil, fmt.Errorf("写入 %s 失败: %w", deploymentInfoFileName, err)
+	}
+	log.Printf("🏷️ deploymentId=%s, operator=%s\n", deployment.DeploymentID, deployment.Operator)
+	if err := beginRunRecord(cfg, deployment, continueExisting, time.Now()); err != nil {
+		log.Printf("⚠️ 写入运行记录 %s 失败（不影响部署）: %v\n", runRecordFileName, err)
 	}
 
-	sess, err := session.NewSession(&awsCfg)
+	// 4) 初始化 AWS session / EC2 client
+	ec2Client, err := newEC2Client(cfg.CommonConfig.Region)
 	if err != nil {
-		return nil, fmt.Errorf("创建 AWS Session 失败: %w", err)
+		return nil, err
 	}
-	ec2Client := ec2.New(sess)
 
 	// 5) 预计算 SSH key 路径
 	keyPath := buildSSHKeyPath(cfg.CommonConfig)
-	deriveRand, err := generateL1VaultDeriveRand()
-	if err != nil {
-		return nil, fmt.Errorf("生成 L1 vault 派生随机段失败: %w", err)
-	}
 
 	reuseFromSnapshot := false
 	var importedSnapshot []CreatedServerInfo
@@ -142,12 +210,44 @@ func NewDeployer(ctx context.Context, cfg DeployConfig, opts RunOptions) (*Deplo
 		ec2Client:         ec2Client,
 		outputMgr:         outputMgr,
 		sshKeyPath:        keyPath,
-		l1VaultDeriveRand: deriveRand,
+		deployment:        deployment,
+		l1VaultDeriveRand: *deployment.L1VaultDeriveRand,
 		reuseFromSnapshot: reuseFromSnapshot,
 		importedSnapshot:  importedSnapshot,
+		appendPlans:       appendPlans,
+		resume:            continueProgress,
+		phases:            phases,
 	}, nil
 }
 
+// resolveDeploymentInfo 生成本次运行的部署元信息。
+// append 模式优先沿用 output 中已有的 deployment.json；旧版本输出缺少派生随机段时重新生成（仅影响新节点）。
+func resolveDeploymentInfo(cfg CommonConfig, appendMode bool, now time.Time) (DeploymentInfo, error) {
+	deployment := newDeploymentInfo(cfg, now)
+	if appendMode {
+		existing, err := LoadDeploymentInfo(cfg.OutputDir)
+		if err != nil {
+			return DeploymentInfo{}, err
+		}
+		if existing != nil {
+			deployment = *existing
+		} else {
+			log.Printf("⚠️ [append] 未找到 %s，将生成新的 deploymentId\n", deploymentInfoFileName)
+		}
+	}
+	if deployment.L1VaultDeriveRand == nil {
+		if appendMode {
+			log.Printf("⚠️ [append] %s 中未记录 L1 vault 派生随机段，新节点将使用新的随机段\n", deploymentInfoFileName)
+		}
+		deriveRand, err := generateL1VaultDeriveRand()
+		if err != nil {
+			return DeploymentInfo{}, fmt.Errorf("生成 L1 vault 派生随机段失败: %w", err)
+		}
+		deployment.L1VaultDeriveRand = &deriveRand
+	}
+	return deployment, nil
+}
+
 func generateL1VaultDeriveRand() (uint32, error) {
 	var b [4]byte
 	if _, err := rand.Read(b[:]); err != nil {
@@ -179,8 +279,23 @@ func RunWithRestoreRetry(ctx context.Context, cfg DeployConfig) error {
 }
 
 // RunWithRestoreRetryWithOptions 与 RunWithRestoreRetry 相同，支持传入 RunOptions。
-func RunWithRestoreRetryWithOptions(ctx context.Context, cfg DeployConfig, opts RunOptions) error {
+func RunWithRestoreRetryWithOptions(ctx context.Context, cfg DeployConfig, opts RunOptions) (err error) {
+	startedAt := time.Now()
+	defer func() {
+		if finishErr := finishRunRecord(resolveOutputDir(cfg.CommonConfig, ""), startedAt, err, time.Now()); finishErr != nil {
+			log.Printf("⚠️ 更新运行记录 %s 失败: %v\n", runRecordFileName, finishErr)
+		}
+	}()
+
 	deployErr := RunWithOptions(ctx, cfg, opts)
+	if ctx.Err() != nil {
+		// 收到中断信号：进度已落盘，不再进入失败链重试。
+		return deployErr
+	}
+	if phases, _ := resolveDeployPhases(opts.Only, opts.Skip); phases != nil && !phases[DeployPhaseSync] {
+		// 未选择 sync 阶段时脚本状态尚未收敛，失败链重试留给后续 sync / deploy-restore。
+		return deployErr
+	}
 
 	failedIPs, listErr := listFailedIPsFromOutput(cfg.CommonConfig)
 	if listErr != nil {
@@ -263,71 +378,166 @@ func selectFailedIPs(statuses []*ScriptStatus) []string {
 	return ips
 }
 
-func (d *Deployer) Run() error {
+func (d *Deployer) Run() (err error) {
 	if d == nil {
 		return nil
 	}
 
-	log.Printf("👉 准备部署，为所有 L2 链的 L1 Valut 充值。\n 配置信息: %+v\n", d.cfg)
-	if err := d.fundAllL1Vaults(); err != nil {
-		return fmt.Errorf("为所有 L1 钱包充值失败: %w", err)
+	// 收到 SIGINT / SIGTERM 时各阶段随 ctx 取消返回，这里统一记录中断现场；其它失败记录到当前阶段结果。
+	defer func() {
+		if err == nil {
+			return
+		}
+		if d.ctx.Err() != nil {
+			err = d.recordInterrupt(err)
+			return
+		}
+		for phase, phaseErr := range failedPhases(err, d.currentPhase()) {
+			d.recordPhaseResult(phase, phaseErr)
+		}
+	}()
+
+	progress, _ := d.outputMgr.SnapshotProgress()
+	if d.resume {
+		logResumePoint(progress)
+	} else {
+		progress = DeployProgress{StartIndexes: make(map[string]int)}
+		for _, svc := range d.cfg.Services {
+			progress.StartIndexes[svc.Type.String()] = d.serviceIndexOffset(svc)
+		}
+		fresh := cloneDeployProgress(&progress)
+		d.updateProgress(func(p *DeployProgress) { *p = fresh })
+	}
+	d.logSelectedPhases()
+
+	d.setPhase(DeployPhaseFund)
+	switch {
+	case !d.phaseEnabled(DeployPhaseFund):
+		log.Println("ℹ️ 跳过 fund 阶段（L1 Vault 充值）")
+		d.recordPhaseSkipped(DeployPhaseFund)
+	case d.resume && progress.phaseDone(DeployPhaseFund):
+		log.Println("ℹ️ [resume] L1 Vault 充值已完成，跳过")
+	default:
+		log.Printf("👉 准备部署，为所有 L2 链的 L1 Valut 充值。\n 配置信息: %+v\n", d.cfg)
+		if err := d.fundAllL1Vaults(progress); err != nil {
+			return fmt.Errorf("为所有 L1 钱包充值失败: %w", err)
+		}
+		d.recordPhaseResult(DeployPhaseFund, nil)
 	}
 	// return nil
 
 	log.Println("👉 开始部署")
+	if d.appendPlans != nil {
+		d.logAppendPlans()
+	}
 
-	for _, svc := range d.cfg.Services {
-		if svc.Count <= 0 {
-			continue
-		}
-		if err := d.runService(svc); err != nil {
-			return err
+	d.setPhase(DeployPhaseLaunch)
+	if err := d.runServices(); err != nil {
+		return err
+	}
+	for _, phase := range []string{DeployPhaseLaunch, DeployPhaseSSH, DeployPhaseTag, DeployPhaseExec, DeployPhaseMonitor} {
+		if d.phaseEnabled(phase) {
+			d.recordPhaseResult(phase, nil)
+		} else {
+			d.recordPhaseSkipped(phase)
 		}
 	}
 
+	if !d.phaseEnabled(DeployPhaseSync) {
+		d.recordPhaseSkipped(DeployPhaseSync)
+		d.updateProgress(func(p *DeployProgress) { p.Interrupted = nil })
+		log.Println("✅ 所选阶段执行完成（未选择 sync，可稍后执行 sync 或 deploy --only sync 同步日志与脚本状态）")
+		return nil
+	}
+
 	log.Println("👉 所有远程命令已启动，开始同步日志与脚本状态...")
 
 	// 所有服务器上的脚本都已启动后，开始同步远端日志并同步到本地，同时更新脚本运行状态。
+	d.setPhase(DeployPhaseSync)
 	s := NewSync(d.cfg.CommonConfig, d.outputMgr)
 	if err := s.Run(d.ctx); err != nil {
 		return err
 	}
+	if err := d.ctx.Err(); err != nil {
+		return err
+	}
+	d.recordPhaseResult(DeployPhaseSync, nil)
+	d.updateProgress(func(p *DeployProgress) { p.Interrupted = nil })
 
 	log.Println("✅ 所有 service 执行完成！")
 	return nil
 }
 
-func (d *Deployer) runService(svc ServiceConfig) error {
-	target := int(svc.Count)
-	log.Printf("👉 [%s] 目标可用机器数=%d，开始进行 SSH 可用性收敛...\n", svc.Type.String(), target)
+func (d *Deployer) runService(svc ServiceConfig) (err error) {
+	// 失败时带上本服务所处的阶段，Run 据此记录阶段结果，避免被并发服务的阶段覆盖
+	defer func() {
+		if err != nil {
+			err = &phaseError{phase: d.servicePhase(svc), err: err}
+		}
+	}()
 
-	readyIPs, err := d.acquireSSHReadyIPs(svc, target)
-	if err != nil {
-		return err
+	target := d.serviceNewCount(svc)
+	offset := d.serviceIndexOffset(svc)
+
+	var readyIPs []string
+	if target > 0 {
+		switch {
+		case d.phaseEnabled(DeployPhaseSSH):
+			d.setServicePhase(svc, DeployPhaseLaunch)
+			log.Printf("👉 [%s] 目标可用机器数=%d，开始进行 SSH 可用性收敛...\n", svc.Type.String(), target)
+
+			var err error
+			readyIPs, err = d.acquireSSHReadyIPs(svc, target)
+			if err != nil {
+				return err
+			}
+			log.Printf("✅ [%s] SSH 可用机器收敛完成，数量=%d，IP=%v\n", svc.Type.String(), len(readyIPs), readyIPs)
+
+			// SSH 收敛完成后才构建 name/command，避免将不可 SSH 机器纳入后续流程。
+			servers := make([]ServerInfo, 0, len(readyIPs))
+			newIndexes := make([]int, 0, len(readyIPs))
+			for idx, ip := range readyIPs {
+				servers = append(servers, ServerInfo{
+					IP:          ip,
+					ServiceType: svc.Type.String(),
+					Name:        d.buildServiceInstanceName(svc, offset+idx+1),
+				})
+				newIndexes = append(newIndexes, offset+idx)
+			}
+			if err := d.outputMgr.AddServers(servers); err != nil {
+				log.Printf("写入服务器列表失败: %v\n", err)
+			}
+			d.probeFingerprints(svc, servers)
+
+			log.Printf("👉 [%s] 预登记脚本状态（pending，可用于后续 restore）...\n", svc.Type.String())
+			if err := d.preRegisterStatuses(d.serviceGlobalIPs(svc, readyIPs), newIndexes, svc); err != nil {
+				return err
+			}
+			d.markServicePhase(svc, DeployPhaseSSH)
+		case d.phaseEnabled(DeployPhaseLaunch):
+			d.setServicePhase(svc, DeployPhaseLaunch)
+			if err := d.launchWithoutSSH(svc, target); err != nil {
+				return err
+			}
+		}
 	}
-	log.Printf("✅ [%s] SSH 可用机器收敛完成，数量=%d，IP=%v\n", svc.Type.String(), len(readyIPs), readyIPs)
 
-	// SSH 收敛完成后才构建 name/command，避免将不可 SSH 机器纳入后续流程。
-	servers := make([]ServerInfo, 0, len(readyIPs))
-	for idx, ip := range readyIPs {
-		servers = append(servers, ServerInfo{
-			IP:          ip,
-			ServiceType: svc.Type.String(),
-			Name:        d.buildInstanceName(svc.TagPrefix, svc.Type.String(), idx+1),
-		})
+	if !d.phaseEnabled(DeployPhaseTag) && !d.phaseEnabled(DeployPhaseExec) && !d.phaseEnabled(DeployPhaseMonitor) {
+		return nil
 	}
-	if err := d.outputMgr.AddServers(servers); err != nil {
-		log.Printf("写入服务器列表失败: %v\n", err)
+	globalIps := d.serviceGlobalIPs(svc, readyIPs)
+	indexes := d.pendingNodeIndexes(svc, globalIps)
+	if len(indexes) == 0 {
+		return nil
 	}
 
-	log.Printf("👉 [%s] 预登记脚本状态（pending，可用于后续 restore）...\n", svc.Type.String())
-	if err := d.preRegisterStatuses(readyIPs, svc); err != nil {
+	d.setServicePhase(svc, DeployPhaseExec)
+	log.Printf("👉 [%s] 批量执行远程命令（后台），节点数=%d...\n", svc.Type.String(), len(indexes))
+	if err := d.rolloutNodes(svc, globalIps, indexes); err != nil {
 		return err
 	}
-
-	log.Printf("👉 [%s] 批量执行远程命令（后台）...\n", svc.Type.String())
-	if err := d.runCommandsOnInstances(readyIPs, svc); err != nil {
-		return err
+	if d.phaseEnabled(DeployPhaseExec) {
+		d.markServicePhase(svc, DeployPhaseExec)
 	}
 
 	return nil
@@ -338,48 +548,95 @@ func (d *Deployer) acquireSSHReadyIPs(svc ServiceConfig, target int) ([]string,
 		return d.acquireSSHReadyIPsFromSnapshot(svc, target)
 	}
 
-	nextCreateOrdinal := 1
+	// resume / 分阶段续跑：上次已创建但 SSH 未收敛的实例优先复用；选择了 launch 阶段时不足部分再新建
+	pool := d.resumeIPPool(svc)
+	nextCreateOrdinal := d.serviceIndexOffset(svc) + len(pool) + 1
 	return d.acquireSSHReadyIPsWithProvider(svc, target, d.sshAcquireMaxRound(), func(need int, round int, svc ServiceConfig) ([]string, []string, error) {
-		log.Printf("👉 [%s] 第 %d/%d 轮补机：需补 %d 台\n", svc.Type.String(), round, d.sshAcquireMaxRound(), need)
-		batchSvc := svc
-		batchSvc.Count = uint(need)
-
-		launcher := NewEC2RunInstancesLauncher(d.ctx, d.ec2Client, d.cfg.CommonConfig, d.buildInstanceName)
-		instanceIDs, err := launcher.RunWithStartOrdinal(batchSvc, nextCreateOrdinal)
-		if err != nil {
-			return nil, nil, err
+		var successIPs, failedIPs []string
+		if len(pool) > 0 {
+			take := min(need, len(pool))
+			batch := pool[:take]
+			pool = pool[take:]
+			need -= take
+			d.setServicePhase(svc, DeployPhaseSSH)
+			log.Printf("👉 [resume][%s] 第 %d/%d 轮复用上次已创建的 %d 台实例，重新探测 SSH: %v\n", svc.Type.String(), round, d.sshAcquireMaxRound(), len(batch), batch)
+			var err error
+			successIPs, failedIPs, err = d.waitAllSSHReady(batch, svc)
+			if err != nil {
+				return nil, nil, err
+			}
+			if need <= 0 {
+				return successIPs, failedIPs, nil
+			}
 		}
-		nextCreateOrdinal += len(instanceIDs)
-		log.Printf("[%s] 第 %d 轮实例 ID: %v\n", svc.Type.String(), round, instanceIDs)
-
-		log.Printf("👉 [%s] 第 %d 轮等待实例进入 running 状态...\n", svc.Type.String(), round)
-		if err := d.waitInstancesRunning(instanceIDs); err != nil {
-			return nil, nil, err
+		if !d.phaseEnabled(DeployPhaseLaunch) {
+			return nil, nil, fmt.Errorf("[%s] 未选择 launch 阶段，且已创建未登记的实例不足: 还需 %d 台（可先执行 deploy --only launch）", svc.Type.String(), need)
 		}
 
-		log.Printf("👉 [%s] 第 %d 轮获取实例公网 IP...\n", svc.Type.String(), round)
-		ips, err := d.getInstancePublicIPs(instanceIDs)
+		ips, err := d.launchInstances(svc, need, round, nextCreateOrdinal)
 		if err != nil {
 			return nil, nil, err
 		}
-		log.Printf("[%s] 第 %d 轮实例 IP: %v\n", svc.Type.String(), round, ips)
-		createdServers := make([]CreatedServerInfo, 0, len(ips))
-		for i, ip := range ips {
-			createdServers = append(createdServers, CreatedServerInfo{
-				Name:        buildCreateStageTagName(svc.TagPrefix, svc.Type.String(), nextCreateOrdinal-len(instanceIDs)+i),
-				ServiceType: svc.Type.String(),
-				IP:          ip,
-			})
-		}
-		if err := d.outputMgr.AddCreatedServers(createdServers); err != nil {
-			return nil, nil, fmt.Errorf("写入 servers_create.json 失败: %w", err)
-		}
+		nextCreateOrdinal += need
 
+		d.setServicePhase(svc, DeployPhaseSSH)
 		log.Printf("👉 [%s] 第 %d 轮等待每台机器 SSH 就绪...\n", svc.Type.String(), round)
-		return d.waitAllSSHReady(ips, svc)
+		launchedSuccess, launchedFailed, err := d.waitAllSSHReady(ips, svc)
+		return append(successIPs, launchedSuccess...), append(failedIPs, launchedFailed...), err
 	})
 }
 
+// launchWithoutSSH 只执行 launch 阶段：补齐到 target 台已创建实例后返回，SSH 探测与登记留给后续的 ssh 阶段。
+func (d *Deployer) launchWithoutSSH(svc ServiceConfig, target int) error {
+	pool := d.resumeIPPool(svc)
+	need := target - len(pool)
+	if need <= 0 {
+		log.Printf("ℹ️ [%s] 已有 %d 台已创建未登记的实例，无需新建\n", svc.Type.String(), len(pool))
+		return nil
+	}
+	if _, err := d.launchInstances(svc, need, 1, d.serviceIndexOffset(svc)+len(pool)+1); err != nil {
+		return err
+	}
+	log.Printf("✅ [%s] 已创建 %d 台实例（未选择 ssh 阶段，稍后由 ssh 阶段探测并登记）\n", svc.Type.String(), need)
+	return nil
+}
+
+// launchInstances 创建 need 台实例、等待 running 并记录到 servers_create.json，返回拿到公网 IP 的实例 IP。
+func (d *Deployer) launchInstances(svc ServiceConfig, need, round, startOrdinal int) ([]string, error) {
+	d.setServicePhase(svc, DeployPhaseLaunch)
+	log.Printf("👉 [%s] 第 %d/%d 轮补机：需补 %d 台\n", svc.Type.String(), round, d.sshAcquireMaxRound(), need)
+	batchSvc := svc
+	batchSvc.Count = uint(need)
+
+	launcher := NewEC2RunInstancesLauncher(d.ctx, d.ec2Client, d.cfg.CommonConfig, d.deployment, d.buildInstanceName)
+	instanceIDs, err := launcher.RunWithStartOrdinal(batchSvc, startOrdinal)
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("[%s] 第 %d 轮实例 ID: %v\n", svc.Type.String(), round, instanceIDs)
+
+	log.Printf("👉 [%s] 第 %d 轮等待实例进入 running 状态...\n", svc.Type.String(), round)
+	if err := d.waitInstancesRunning(instanceIDs); err != nil {
+		return nil, err
+	}
+
+	log.Printf("👉 [%s] 第 %d 轮获取实例公网 IP...\n", svc.Type.String(), round)
+	createdServers, err := d.describeCreatedServers(instanceIDs, svc, startOrdinal)
+	if err != nil {
+		return nil, err
+	}
+	ips := make([]string, 0, len(createdServers))
+	for _, c := range createdServers {
+		ips = append(ips, c.IP)
+	}
+	log.Printf("[%s] 第 %d 轮实例 IP: %v\n", svc.Type.String(), round, ips)
+	if err := d.outputMgr.AddCreatedServers(createdServers); err != nil {
+		return nil, fmt.Errorf("写入 servers_create.json 失败: %w", err)
+	}
+	d.markServicePhase(svc, DeployPhaseLaunch)
+	return ips, nil
+}
+
 func (d *Deployer) acquireSSHReadyIPsFromSnapshot(svc ServiceConfig, target int) ([]string, error) {
 	pool := d.snapshotIPPoolForService(svc.Type.String())
 	if len(pool) < target {
@@ -469,7 +726,9 @@ func (d *Deployer) waitInstancesRunning(ids []*string) error {
 	return d.ec2Client.WaitUntilInstanceRunningWithContext(d.ctx, input)
 }
 
-func (d *Deployer) getInstancePublicIPs(ids []*string) ([]string, error) {
+// describeCreatedServers 查询刚创建实例的公网 IP / 机型 / 启动时间，按 ids 顺序生成 servers_create 条目。
+// 创建阶段的 Name 标签按 ids 顺序递增，因此这里同样按 ids 顺序对应 ordinal。
+func (d *Deployer) describeCreatedServers(ids []*string, svc ServiceConfig, startOrdinal int) ([]CreatedServerInfo, error) {
 	input := &ec2.DescribeInstancesInput{
 		InstanceIds: ids,
 	}
@@ -479,19 +738,40 @@ func (d *Deployer) getInstancePublicIPs(ids []*string) ([]string, error) {
 		return nil, fmt.Errorf("DescribeInstances 失败: %w", err)
 	}
 
-	var ips []string
+	byID := make(map[string]*ec2.Instance, len(ids))
 	for _, res := range out.Reservations {
 		for _, inst := range res.Instances {
-			if inst.PublicIpAddress != nil && *inst.PublicIpAddress != "" {
-				ips = append(ips, *inst.PublicIpAddress)
-			}
+			byID[aws.StringValue(inst.InstanceId)] = inst
 		}
 	}
 
-	if len(ips) == 0 {
+	servers := make([]CreatedServerInfo, 0, len(ids))
+	for i, id := range ids {
+		inst, ok := byID[aws.StringValue(id)]
+		if !ok || aws.StringValue(inst.PublicIpAddress) == "" {
+			continue
+		}
+		servers = append(servers, buildCreatedServerInfo(inst, buildCreateStageTagName(svc.TagPrefix, svc.Type.String(), startOrdinal+i), svc.Type.String()))
+	}
+
+	if len(servers) == 0 {
 		return nil, fmt.Errorf("未获取到任何实例公网 IP")
 	}
-	return ips, nil
+	return servers, nil
+}
+
+func buildCreatedServerInfo(inst *ec2.Instance, name, serviceType string) CreatedServerInfo {
+	info := CreatedServerInfo{
+		Name:         name,
+		ServiceType:  serviceType,
+		IP:           aws.StringValue(inst.PublicIpAddress),
+		InstanceID:   aws.StringValue(inst.InstanceId),
+		InstanceType: aws.StringValue(inst.InstanceType),
+	}
+	if inst.LaunchTime != nil {
+		info.LaunchTime = inst.LaunchTime.Unix()
+	}
+	return info
 }
 
 func (d *Deployer) waitAllSSHReady(ips []string, svc ServiceConfig) ([]string, []string, error) {
@@ -519,7 +799,12 @@ func (d *Deployer) waitAllSSHReady(ips []string, svc ServiceConfig) ([]string, [
 		ip := ips[i]
 		log.Printf("[%s] 等待 SSH 就绪...\n", ip)
 
-		attempts, err := d.waitSSHReadyWithRetry(ip)
+		var attempts uint
+		err := withSSHToken(d.ctx, d.sshBudget(), ip, "wait-ssh-ready", func() error {
+			var waitErr error
+			attempts, waitErr = d.waitSSHReadyWithRetry(ip)
+			return waitErr
+		})
 		now := time.Now().Unix()
 		if err != nil {
 			failFlags[i] = true
@@ -554,14 +839,13 @@ func (d *Deployer) waitAllSSHReady(ips []string, svc ServiceConfig) ([]string, [
 	return successIPs, failedIPs, nil
 }
 
-func (d *Deployer) runCommandsOnInstances(ips []string, svc ServiceConfig) error {
+// runCommandsOnInstances 为 ips 中 indexes 指定的节点执行 tag / exec / monitor 阶段；ips 为该服务按索引排列的全量 IP（xjst 分组需要）。
+func (d *Deployer) runCommandsOnInstances(ips []string, indexes []int, svc ServiceConfig) error {
 	var (
 		mu   sync.Mutex
 		errs []error
 	)
 
-	cfg := d.cfg.CommonConfig
-
 	// 并发收集每台机器的错误，最终统一汇总返回（不再只返回“第一个错误”）。
 	addErr := func(ip, name string, err error) {
 		if err == nil {
@@ -569,6 +853,7 @@ func (d *Deployer) runCommandsOnInstances(ips []string, svc ServiceConfig) error
 		}
 		mu.Lock()
 		defer mu.Unlock()
+		d.outputMgr.RecordEvent(Event{Type: EventCommandFailed, IP: ip, ServiceType: svc.Type.String(), Name: name, Message: err.Error()})
 		// name 可能为空（极少数早期失败场景），统一格式化方便用户排查。
 		if name != "" {
 			errs = append(errs, fmt.Errorf("[%s][%s] %w", ip, name, err))
@@ -577,114 +862,70 @@ func (d *Deployer) runCommandsOnInstances(ips []string, svc ServiceConfig) error
 		}
 	}
 
-	runWithBatchLimit("run-remote-command", len(ips), d.sshMaxConcurrency(), func(idx int) {
-		i := idx
-		ip := ips[idx]
-		name := d.buildInstanceName(svc.TagPrefix, svc.Type.String(), i+1)
-		logPrefix := fmt.Sprintf("[%s][%s]", ip, name)
-		log.Printf("%s 开始下发远端任务\n", logPrefix)
-
-		// 再次确认标签（与 shell 版一致，用 ip -> instanceId -> 打 Name 标签）
-		log.Printf("%s STEP1: 查询实例 ID...\n", logPrefix)
-		instID, err := d.findInstanceByIP(ip)
-		if err != nil {
-			addErr(ip, name, err)
-			return
-		}
-		log.Printf("%s STEP1: 查询实例 ID 完成，instanceId=%s\n", logPrefix, instID)
+	// 逐节点阶段（tag / exec / monitor）按已选择的阶段与节点已有进度执行，已完成的步骤不会重复。
+	progress, _ := d.outputMgr.SnapshotProgress()
+	statusByKey := make(map[string]*ScriptStatus)
+	for _, st := range d.outputMgr.SnapshotStatuses() {
+		statusByKey[compositeKey(st.IP, st.ServiceType)] = st
+	}
 
-		log.Printf("%s STEP2: 设置实例 Name 标签...\n", logPrefix)
-		if err := d.tagInstanceName(instID, name); err != nil {
-			addErr(ip, name, err)
-			return
-		}
-		log.Printf("%s STEP2: 设置实例 Name 标签完成\n", logPrefix)
+	runWithBatchLimit("run-remote-command", len(indexes), d.sshMaxConcurrency(), func(idx int) {
+		i := indexes[idx]
+		ip := ips[i]
+		name := d.buildServiceInstanceName(svc, i+1)
+		logPrefix := fmt.Sprintf("[%s][%s]", ip, name)
+		key := compositeKey(ip, svc.Type.String())
+		needTag, needExec, needMonitor := d.nodePhaseNeeds(statusByKey[key], progress.Nodes[key])
+		log.Printf("%s 开始下发远端任务（tag=%v, exec=%v, monitor=%v）\n", logPrefix, needTag, needExec, needMonitor)
+
+		// 与并发部署的其它服务共享 SSH 并发预算
+		if err := withSSHToken(d.ctx, d.sshBudget(), ip, "run-remote-command", func() error {
+			if needTag {
+				// 再次确认标签（与 shell 版一致，用 ip -> instanceId -> 打 Name 标签）
+				log.Printf("%s STEP1: 查询实例 ID...\n", logPrefix)
+				instID, err := d.findInstanceByIP(ip)
+				if err != nil {
+					addErr(ip, name, err)
+					return nil
+				}
+				log.Printf("%s STEP1: 查询实例 ID 完成，instanceId=%s\n", logPrefix, instID)
 
-		log.Printf("%s STEP3: 生成远端执行命令...\n", logPrefix)
-		cmdStr, err := d.buildRemoteCommandForIndex(ips, i, svc)
-		if err != nil {
-			addErr(ip, name, err)
-			return
-		}
-		log.Printf("%s STEP3: 生成远端执行命令完成\n", logPrefix)
-
-		remoteLogFile, remoteLogDir := buildRemoteLogPath("", name)
-
-		// 在远端后台运行脚本，并将 stdout/stderr 重定向到远端日志文件。
-		// 同时输出子进程 PID，便于后续状态监控。
-		log.Printf("%s STEP4: 构造远端后台运行命令...\n", logPrefix)
-		fullCmd := buildBackgroundCommand(cfg.RunDuration, cmdStr, remoteLogDir, remoteLogFile)
-		log.Printf("%s STEP4: 构造远端后台运行命令完成\n", logPrefix)
-
-		log.Printf("%s run (background): %s\n", logPrefix, fullCmd)
-
-		localLogPath := buildLocalLogPath(cfg.LogDir, ip, name)
-
-		log.Printf("%s STEP5: 通过 ssh 启动远端后台任务...\n", logPrefix)
-		sshCmd := exec.CommandContext(d.ctx, "ssh",
-			"-o", "StrictHostKeyChecking=no",
-			"-o", "IdentitiesOnly=yes",
-			"-i", d.sshKeyPath,
-			fmt.Sprintf("%s@%s", cfg.SSHUser, ip),
-			fullCmd,
-		)
-
-		var stdoutBuf bytes.Buffer
-		sshCmd.Stdout = &stdoutBuf
-		sshCmd.Stderr = &stdoutBuf
-
-		if err := sshCmd.Run(); err != nil {
-			// 为了便于排查 ssh 相关问题（如 exit status 255），这里输出更详细的日志。
-			if exitErr, ok := err.(*exec.ExitError); ok {
-				// 注意：stderr 已经重定向到 logFile，这里只打印 exitCode 和命令本身。
-				log.Printf("%s ssh 命令执行失败，exitCode=%d，cmd=%q\n", logPrefix, exitErr.ExitCode(), fullCmd)
-				addErr(ip, name, fmt.Errorf("远程命令执行失败，exitCode=%d: %w", exitErr.ExitCode(), err))
-			} else {
-				log.Printf("%s ssh 命令执行失败（非 ExitError），cmd=%q，err=%v\n", logPrefix, fullCmd, err)
-				addErr(ip, name, fmt.Errorf("远程命令执行失败: %w", err))
+				log.Printf("%s STEP2: 设置实例 Name 标签...\n", logPrefix)
+				if err := d.tagInstanceName(instID, name); err != nil {
+					addErr(ip, name, err)
+					return nil
+				}
+				d.outputMgr.RecordEvent(Event{Type: EventInstanceTagged, IP: ip, ServiceType: svc.Type.String(), Name: name, InstanceID: instID})
+				d.recordNodeProgress(key, func(np *NodeProgress) {
+					np.Tagged = true
+					np.InstanceID = instID
+				})
+				log.Printf("%s STEP2: 设置实例 Name 标签完成\n", logPrefix)
 			}
-			return
-		}
-		log.Printf("%s STEP5: ssh 启动远端后台任务完成\n", logPrefix)
 
-		// 解析远端返回的 PID，用于后续状态监控
-		log.Printf("%s STEP6: 解析远端 PID...\n", logPrefix)
-		pid, parseErr := parseRemotePID(stdoutBuf.String())
-		if parseErr != nil {
-			// output 为空/非 PID 都属于异常情况：远端未按预期返回 PID，无法进行后续监控，直接判定失败。
-			addErr(ip, name, fmt.Errorf("解析远端 PID 失败: %w，输出: %q", parseErr, stdoutBuf.String()))
-			return
-		}
-		if pid <= 0 {
-			addErr(ip, name, fmt.Errorf("任务执行失败，远端 PID 为 0，远端输出: %q", stdoutBuf.String()))
-			return
-		}
-
-		log.Printf("%s STEP6: 解析远端 PID 完成\n", logPrefix)
+			if needExec {
+				if err := d.startRemoteCommand(ips, i, svc, name, logPrefix); err != nil {
+					addErr(ip, name, err)
+					return nil
+				}
+			}
 
-		// 初始化脚本运行状态
-		log.Printf("%s STEP7: 初始化本地运行状态记录...\n", logPrefix)
-		err = d.outputMgr.InitStatus(
-			ip,
-			svc.Type.String(),
-			name,
-			cmdStr,
-			pid,
-			remoteLogFile,
-			localLogPath,
-			time.Now().Unix(),
-		)
-		if err != nil {
+			if needMonitor {
+				if monitorPID, started, monitorErr := d.startRuntimeMonitor(ip, name, svc, i); monitorErr != nil {
+					log.Printf("%s ⚠️ 运行日志监控启动失败（不阻断主部署）: %v\n", logPrefix, monitorErr)
+				} else {
+					d.recordNodeProgress(key, func(np *NodeProgress) {
+						np.MonitorDone = true
+						np.MonitorPID = monitorPID
+					})
+					if started {
+						log.Printf("%s STEP8: 运行日志监控已启动，pid=%d\n", logPrefix, monitorPID)
+					}
+				}
+			}
+			return nil
+		}); err != nil {
 			addErr(ip, name, err)
-			return
-		}
-		log.Printf("%s STEP7: 初始化本地运行状态记录完成\n", logPrefix)
-		log.Printf("%s 远端后台任务已启动，pid=%d\n", logPrefix, pid)
-
-		if monitorPID, started, monitorErr := d.startRuntimeMonitor(ip, name, svc.Type, i); monitorErr != nil {
-			log.Printf("%s ⚠️ 运行日志监控启动失败（不阻断主部署）: %v\n", logPrefix, monitorErr)
-		} else if started {
-			log.Printf("%s STEP8: 运行日志监控已启动，pid=%d\n", logPrefix, monitorPID)
 		}
 	})
 
@@ -696,8 +937,100 @@ func (d *Deployer) runCommandsOnInstances(ips []string, svc ServiceConfig) error
 	return deployMultiError{errs: errs}
 }
 
-func (d *Deployer) startRuntimeMonitor(ip, name string, serviceType enums.ServiceType, index int) (int, bool, error) {
-	cmdStr, shouldStart := buildRuntimeMonitorCommand(serviceType, index, name)
+// startRemoteCommand 为 ips[i] 上传 services[].files 后生成部署命令并在远端后台启动（STEP3-7），成功后写入 running 状态。
+func (d *Deployer) startRemoteCommand(ips []string, i int, svc ServiceConfig, name, logPrefix string) error {
+	cfg := d.cfg.CommonConfig
+	ip := ips[i]
+
+	if err := d.provisionFiles(ip, svc, logPrefix); err != nil {
+		return err
+	}
+	if err := d.ensureRepoCommit(ip); err != nil {
+		return err
+	}
+
+	log.Printf("%s STEP3: 生成远端执行命令...\n", logPrefix)
+	cmdStr, err := d.buildRemoteCommandForIndex(ips, i, svc)
+	if err != nil {
+		return err
+	}
+	log.Printf("%s STEP3: 生成远端执行命令完成\n", logPrefix)
+
+	remoteLogFile, remoteLogDir := buildRemoteLogPath("", name)
+
+	// 在远端后台运行脚本，并将 stdout/stderr 重定向到远端日志文件。
+	// 同时输出子进程 PID，便于后续状态监控。
+	log.Printf("%s STEP4: 构造远端后台运行命令...\n", logPrefix)
+	fullCmd := buildBackgroundCommand(cfg.RunDuration, cmdStr, remoteLogDir, remoteLogFile)
+	log.Printf("%s STEP4: 构造远端后台运行命令完成\n", logPrefix)
+
+	log.Printf("%s run (background): %s\n", logPrefix, fullCmd)
+
+	localLogPath := buildLocalLogPath(cfg.LogDir, ip, name)
+
+	log.Printf("%s STEP5: 通过 ssh 启动远端后台任务...\n", logPrefix)
+	launchedAt := time.Now()
+	sshCmd := exec.CommandContext(d.ctx, "ssh",
+		"-o", "StrictHostKeyChecking=no",
+		"-o", "IdentitiesOnly=yes",
+		"-i", d.sshKeyPath,
+		fmt.Sprintf("%s@%s", cfg.SSHUser, ip),
+		fullCmd,
+	)
+
+	var stdoutBuf bytes.Buffer
+	sshCmd.Stdout = &stdoutBuf
+	sshCmd.Stderr = &stdoutBuf
+
+	if err := sshCmd.Run(); err != nil {
+		// 为了便于排查 ssh 相关问题（如 exit status 255），这里输出更详细的日志。
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			// 注意：stderr 已经重定向到 logFile，这里只打印 exitCode 和命令本身。
+			log.Printf("%s ssh 命令执行失败，exitCode=%d，cmd=%q\n", logPrefix, exitErr.ExitCode(), fullCmd)
+			return fmt.Errorf("远程命令执行失败，exitCode=%d: %w", exitErr.ExitCode(), err)
+		}
+		log.Printf("%s ssh 命令执行失败（非 ExitError），cmd=%q，err=%v\n", logPrefix, fullCmd, err)
+		return fmt.Errorf("远程命令执行失败: %w", err)
+	}
+	log.Printf("%s STEP5: ssh 启动远端后台任务完成\n", logPrefix)
+
+	// 解析远端返回的 PID，用于后续状态监控
+	log.Printf("%s STEP6: 解析远端 PID...\n", logPrefix)
+	pid, parseErr := parseRemotePID(stdoutBuf.String())
+	if parseErr != nil {
+		// output 为空/非 PID 都属于异常情况：远端未按预期返回 PID，无法进行后续监控，直接判定失败。
+		return fmt.Errorf("解析远端 PID 失败: %w，输出: %q", parseErr, stdoutBuf.String())
+	}
+	if pid <= 0 {
+		return fmt.Errorf("任务执行失败，远端 PID 为 0，远端输出: %q", stdoutBuf.String())
+	}
+
+	log.Printf("%s STEP6: 解析远端 PID 完成\n", logPrefix)
+
+	// 初始化脚本运行状态
+	log.Printf("%s STEP7: 初始化本地运行状态记录...\n", logPrefix)
+	err = d.outputMgr.InitStatus(
+		ip,
+		svc.Type.String(),
+		name,
+		cmdStr,
+		pid,
+		remoteLogFile,
+		localLogPath,
+		time.Now().Unix(),
+		resolveShutdownAt(launchedAt, cfg.RunDuration),
+		d.repoCommit(),
+	)
+	if err != nil {
+		return err
+	}
+	log.Printf("%s STEP7: 初始化本地运行状态记录完成\n", logPrefix)
+	log.Printf("%s 远端后台任务已启动，pid=%d\n", logPrefix, pid)
+	return nil
+}
+
+func (d *Deployer) startRuntimeMonitor(ip, name string, svc ServiceConfig, index int) (int, bool, error) {
+	cmdStr, shouldStart := buildRuntimeMonitorCommand(svc, index, name)
 	if !shouldStart {
 		return 0, false, nil
 	}
@@ -738,7 +1071,7 @@ func (d *Deployer) waitSSHReadyWithRetry(ip string) (uint, error) {
 	return waitSSHReadyWithRetry(d.ctx, ip, d.cfg.CommonConfig.SSHUser, d.sshKeyPath, d.sshReadyRetryCount(), d.sshReadyRetryInterval())
 }
 
-func (d *Deployer) preRegisterStatuses(ips []string, svc ServiceConfig) error {
+func (d *Deployer) preRegisterStatuses(ips []string, indexes []int, svc ServiceConfig) error {
 	var (
 		mu   sync.Mutex
 		errs []error
@@ -757,11 +1090,15 @@ func (d *Deployer) preRegisterStatuses(ips []string, svc ServiceConfig) error {
 		}
 	}
 
-	runWithBatchLimit("preregister-script-status", len(ips), d.sshMaxConcurrency(), func(idx int) {
-		i := idx
-		ip := ips[idx]
-		name := d.buildInstanceName(svc.TagPrefix, svc.Type.String(), i+1)
+	runWithBatchLimit("preregister-script-status", len(indexes), d.sshMaxConcurrency(), func(idx int) {
+		i := indexes[idx]
+		ip := ips[i]
+		name := d.buildServiceInstanceName(svc, i+1)
 
+		if err := d.ensureRepoCommit(ip); err != nil {
+			addErr(ip, name, err)
+			return
+		}
 		cmdStr, err := d.buildRemoteCommandForIndex(ips, i, svc)
 		if err != nil {
 			addErr(ip, name, err)
@@ -894,15 +1231,18 @@ func waitSSHReadyWithRetry(ctx context.Context, ip, sshUser, sshKeyPath string,
 }
 
 func (d *Deployer) buildInstanceName(tagPrefix, serviceType string, ordinal int) string {
-	if serviceType != enums.ServiceTypeXJST.String() {
-		return fmt.Sprintf("%s-%s-%d", tagPrefix, serviceType, ordinal)
+	if stack, ok := chainstack.LookupName(serviceType); ok {
+		return stack.InstanceName(tagPrefix, ordinal)
 	}
+	return fmt.Sprintf("%s-%s-%d", tagPrefix, serviceType, ordinal)
+}
 
-	// ordinal 为 1-based，xjst 命名需要按每 4 个节点分组并输出组内序号 1~4。
-	zeroIndex := ordinal - 1
-	groupID := d.resolveXjstGroupId(zeroIndex)
-	indexInGroup := zeroIndex%4 + 1
-	return fmt.Sprintf("%s-%s-%d-%d", tagPrefix, serviceType, groupID, indexInGroup)
+// buildServiceInstanceName 与 buildInstanceName 相同，但按 services[].groupSize 生成分组组网节点的名称。
+func (d *Deployer) buildServiceInstanceName(svc ServiceConfig, ordinal int) string {
+	if stack, ok := svc.stack(); ok {
+		return stack.InstanceName(svc.TagPrefix, ordinal)
+	}
+	return d.buildInstanceName(svc.TagPrefix, svc.Type.String(), ordinal)
 }
 
 func buildSSHKeyPath(cfg CommonConfig) string {
@@ -953,91 +1293,33 @@ func (d *Deployer) tagInstanceName(instanceID, name string) error {
 	return nil
 }
 
-// buildRemoteCommandForIndex 根据索引与 service 策略生成远程命令。
-// 当前实现：
-//   - generic: 必须在配置中显式设置 remoteCmd，否则报错；
-//   - op: 如果未配置 remoteCmd，则为每一台机器生成不同的 PRIVATE_KEY 和 L2_CHAIN_ID，
-//     并将通用配置中的 OP 环境变量传给 op_pipe.sh。
-//
-// 后续可在此扩展 cdk / xjst 等模式。
+// buildRemoteCommandForIndex 根据索引与 service 策略生成远程命令：
+// 配置了 remoteCmd 时原样使用（remoteCmdTemplate 为 true 时按 Go text/template 渲染，变量见 RemoteCmdVars），否则渲染该链部署栈的内置命令模板；
+// 没有内置命令的栈（generic）必须显式配置 remoteCmd。
 func (d *Deployer) buildRemoteCommandForIndex(globalIps []string, i int, svc ServiceConfig) (string, error) {
-	common := d.cfg.CommonConfig
 	if svc.RemoteCmd != "" {
-		return svc.RemoteCmd, nil
+		return d.renderRemoteCmd(globalIps, i, svc)
 	}
-
-	switch svc.Type {
-	case enums.ServiceTypeGeneric:
-		return "", fmt.Errorf("service=generic 时必须显式配置 remoteCmd")
-	case enums.ServiceTypeOP:
-		l2ChainID := d.resolveL2ChainID(svc.Type, i)
-		l1VaultPrivateKey, err := d.resolveL1VaultPrivateKey(common.L1VaultMnemonic, svc.Type, l2ChainID)
-		if err != nil {
-			return "", fmt.Errorf("生成 L1_VAULT_PRIVATE_KEY 失败: %w", err)
-		}
-		l1RpcUrl := d.resolveL1RpcUrl(common.L1RpcUrl, svc.L1RpcUrl)
-		faultGameMaxClockDurationEnv := ""
-		if common.FaultGameMaxClockDuration != "" {
-			faultGameMaxClockDurationEnv = fmt.Sprintf(" FAULT_GAME_MAX_CLOCK_DURATION=%s", common.FaultGameMaxClockDuration)
-		}
-		return fmt.Sprintf(
-			" git pull && GIT_SSH_COMMAND='ssh -o StrictHostKeyChecking=no' git submodule update --init --recursive --force && L2_CHAIN_ID=%d L1_CHAIN_ID=%v L1_RPC_URL=%s L1_VAULT_PRIVATE_KEY=%s L1_BRIDGE_HUB_CONTRACT=%s L1_REGISTER_BRIDGE_PRIVATE_KEY=%s DRYRUN=%t FORCE_DEPLOY_OP=%t ENABLE_GEN_ACC=%t%s ./op_pipe.sh",
-			l2ChainID, common.L1ChainId, l1RpcUrl, cryptoutil.EcdsaPrivToWeb3Hex(l1VaultPrivateKey), common.L1BridgeHubContract, common.L1RegisterBridgePrivateKey, common.DryRun, common.ForceDeployL2Chain, common.EnableGenAccounts, faultGameMaxClockDurationEnv,
-		), nil
-	case enums.ServiceTypeCDK:
-		// L2_CHAIN_ID=2025121101 L1_CHAIN_ID=3151908 L1_RPC_URL=https://eth.yidaiyilu0.site/rpc L1_VAULT_PRIVATE_KEY=0x04b9f63ecf84210c5366c66d68fa1f5da1fa4f634fad6dfc86178e4d79ff9e59 L1_BRIDGE_HUB_CONTRACT=0x2634d61774eC4D4b721259e6ec2Ba1801733201C L1_REGISTER_BRIDGE_PRIVATE_KEY=0x9abda6411083c4e3391a7e93a9c1cfa6cf8364a04b44668854bb82c9d6d2dce0 DRYRUN=false FORCE_DEPLOY_CDK=false USE_REAL_PROVER=false START_STEP=1 ./cdk_pipe.sh
-		l2ChainID := d.resolveL2ChainID(svc.Type, i)
-		l1VaultPrivateKey, err := d.resolveL1VaultPrivateKey(common.L1VaultMnemonic, svc.Type, l2ChainID)
-		if err != nil {
-			return "", fmt.Errorf("生成 L1_VAULT_PRIVATE_KEY 失败: %w", err)
-		}
-		l1RpcUrl := d.resolveL1RpcUrl(common.L1RpcUrl, svc.L1RpcUrl)
-		return fmt.Sprintf(
-			" git pull && GIT_SSH_COMMAND='ssh -o StrictHostKeyChecking=no' git submodule update --init --recursive --force && L2_CHAIN_ID=%d L1_CHAIN_ID=%v L1_RPC_URL=%s L1_VAULT_PRIVATE_KEY=%s L1_BRIDGE_HUB_CONTRACT=%s L1_REGISTER_BRIDGE_PRIVATE_KEY=%s DRYRUN=%t FORCE_DEPLOY_CDK=%t ENABLE_GEN_ACC=%t USE_REAL_PROVER=%t ./cdk_pipe.sh",
-			l2ChainID, common.L1ChainId, l1RpcUrl, cryptoutil.EcdsaPrivToWeb3Hex(l1VaultPrivateKey), common.L1BridgeHubContract, common.L1RegisterBridgePrivateKey, common.DryRun, common.ForceDeployL2Chain, common.EnableGenAccounts, common.CdkUseRealProver,
-		), nil
-	case enums.ServiceTypeXJST:
-		groupId := d.resolveXjstGroupId(i)
-		l1VaultPrivateKey, err := d.resolveL1VaultPrivateKey(common.L1VaultMnemonic, svc.Type, groupId)
-		if err != nil {
-			return "", fmt.Errorf("生成 L1_VAULT_PRIVATE_KEY 失败: %w", err)
-		}
-		l1RpcUrl := d.resolveL1RpcUrl(common.L1RpcUrl, svc.L1RpcUrl)
-		l1RpcUrlWs := common.L1RpcUrlWs
-		// CHAIN_NODE_IPS='[44.252.111.46,44.247.52.12,54.245.12.147,44.249.51.138]' \
-		// NODE_ID='node-1' \
-		// GROUP_ID=1 \
-		// L1_RPC_URL_WS='ws://47.243.70.39/ws' \
-		// L1_RPC_URL='https://confura.yidaiyilu0.site/espace' \
-		// AUTO_DEPLOY_L1_CONTRACTS='false' \
-		// L2_CHAIN_ID=0 \
-		// L1_CHAIN_ID=1025 \
-		// L1_VAULT_PRIVATE_KEY='0xd01fd3d7fdcc808840d676f4cbff81af45b2641d414d7a00e25c7bf8cc6c7e97' \
-		// L1_BRIDGE_HUB_CONTRACT='0xC6dC4E1a24df87e78Cc4c63C43bdb5c5d9b69a22' \
-		// L1_REGISTER_BRIDGE_PRIVATE_KEY='0xa7c740e7475dc9af937574f95080df8c48ad1035a2cd53111c377b00f29a8fee' \
-		// ./xjst_pipe.sh
-		groupIpsStr, err := d.resolveXjstGroupIps(globalIps, groupId)
-		if err != nil {
-			return "", fmt.Errorf("解析 xjst 分组 IP 失败: %w", err)
-		}
-		nodeId := i%4 + 1
-
-		return fmt.Sprintf(
-			" git pull && GIT_SSH_COMMAND='ssh -o StrictHostKeyChecking=no' git submodule update --init --recursive --force && CHAIN_NODE_IPS='%s' NODE_ID='node-%d' GROUP_ID=%d L1_RPC_URL_WS='%s' L1_RPC_URL='%s' AUTO_DEPLOY_L1_CONTRACTS='false' L2_CHAIN_ID=0 L1_CHAIN_ID=%v L1_VAULT_PRIVATE_KEY='%s' L1_BRIDGE_HUB_CONTRACT='%s' L1_REGISTER_BRIDGE_PRIVATE_KEY='%s' ENABLE_GEN_ACC='%t' BRIDGE_GAS_PRICE=100000000000 ./xjst_pipe.sh",
-			groupIpsStr, nodeId, groupId, l1RpcUrlWs, l1RpcUrl, common.L1ChainId, cryptoutil.EcdsaPrivToWeb3Hex(l1VaultPrivateKey), common.L1BridgeHubContract, common.L1RegisterBridgePrivateKey, common.EnableGenAccounts,
-		), nil
-
-	default:
+	stack, ok := chainstack.Lookup(svc.Type)
+	if !ok {
 		return "", fmt.Errorf("未知的 service 类型: %s", svc.Type.String())
 	}
+	builtin := stack.CommandTemplate()
+	if builtin == "" {
+		return "", fmt.Errorf("service=%s 时必须显式配置 remoteCmd", svc.Type.String())
+	}
+	return d.renderCommandTemplate(builtin, globalIps, i, svc)
 }
 
-func (d *Deployer) resolveXjstGroupIps(globalIps []string, groupId int) (string, error) {
+func (d *Deployer) resolveXjstGroupIps(globalIps []string, groupId, groupSize int) (string, error) {
 	if groupId <= 0 {
 		return "", fmt.Errorf("groupId 必须 >= 1，当前为 %d", groupId)
 	}
-	start := (groupId - 1) * 4
-	end := start + 4
+	if groupSize <= 0 {
+		return "", fmt.Errorf("groupSize 必须 >= 1，当前为 %d", groupSize)
+	}
+	start := (groupId - 1) * groupSize
+	end := start + groupSize
 	if start < 0 || end > len(globalIps) {
 		return "", fmt.Errorf("xjst 分组 IP 越界: groupId=%d, start=%d, end=%d, total=%d", groupId, start, end, len(globalIps))
 	}
@@ -1047,8 +1329,9 @@ func (d *Deployer) resolveXjstGroupIps(globalIps []string, groupId int) (string,
 	return groupIpsStr, nil
 }
 
-// 从 源L1Vault（L1VaultMnemonic /m/44/60/0/0/0） 分发 L1 eth 到所有 service 的 L1VaultPrivateKey 地址
-func (d *Deployer) fundAllL1Vaults() error {
+// 从 源L1Vault（L1VaultMnemonic /m/44/60/0/0/0） 分发 L1 eth 到所有 service 的 L1VaultPrivateKey 地址。
+// progress 中已记录的地址（上次中断前已发出交易）会被跳过。
+func (d *Deployer) fundAllL1Vaults(progress DeployProgress) error {
 	sourceVaultPrivateKey, err := privatekeyhelper.NewFromMnemonic(d.cfg.CommonConfig.L1VaultMnemonic, 0, nil)
 	if err != nil {
 		return fmt.Errorf("生成源 L1Vault 私钥失败: %w", err)
@@ -1062,23 +1345,27 @@ func (d *Deployer) fundAllL1Vaults() error {
 			continue
 		}
 
-		for i := 0; i < int(service.Count); i++ {
-			var index int
-
-			if service.Type == enums.ServiceTypeXJST {
-				if i%4 != 0 {
-					continue
-				}
-				index = d.resolveXjstGroupId(i)
-			} else {
-				index = d.resolveL2ChainID(service.Type, i)
+		// append 模式仅为新增节点充值；分阶段续跑 / resume 从本次 deploy 开始时的起点计算，已充值的地址在下面跳过
+		start := d.serviceIndexOffset(service)
+		if base, ok := progress.StartIndexes[service.Type.String()]; ok {
+			start = base
+		}
+		for i := start; i < int(service.Count); i++ {
+			// 同组节点共用一个 vault，只为主节点充值
+			if i%service.groupSize() != 0 {
+				continue
 			}
+			index := d.resolveVaultIndex(service, i)
 
 			l1VaultPrivateKey, err := d.resolveL1VaultPrivateKey(d.cfg.CommonConfig.L1VaultMnemonic, service.Type, index)
 			if err != nil {
 				return fmt.Errorf("生成 L1_VAULT_PRIVATE_KEY 失败: %w", err)
 			}
 			s := signers.NewPrivateKeySigner(l1VaultPrivateKey)
+			if progress.isFunded(s.Address().Hex()) {
+				log.Printf("ℹ️ [resume] L1 vault %s 已充值，跳过\n", s.Address().Hex())
+				continue
+			}
 			targetAddrs = append(targetAddrs, s.Address())
 			targetAmounts = append(targetAmounts, big.NewInt(1).Mul(big.NewInt(1e18), big.NewInt(service.L1VaultFundAmount)))
 		}
@@ -1093,6 +1380,9 @@ func (d *Deployer) fundAllL1Vaults() error {
 	}
 
 	for i, targetVaultAddress := range targetAddrs {
+		if err := d.ctx.Err(); err != nil {
+			return err
+		}
 		soureValutAddress := sourceVaultSigner.Address()
 		value := hexutil.Big(*targetAmounts[i])
 
@@ -1119,6 +1409,10 @@ func (d *Deployer) fundAllL1Vaults() error {
 					if receipt != nil {
 						break
 					}
+					// 交易已发出，中断时不再等待回执，也不能返回错误触发重发
+					if d.ctx.Err() != nil {
+						break
+					}
 					time.Sleep(1000 * time.Millisecond)
 					fmt.Print(".")
 				}
@@ -1130,6 +1424,11 @@ func (d *Deployer) fundAllL1Vaults() error {
 		if err != nil {
 			return fmt.Errorf("发送交易[%d]失败: %w", i, err)
 		}
+		funded := targetVaultAddress.Hex()
+		d.updateProgress(func(p *DeployProgress) { p.FundedVaults = append(p.FundedVaults, funded) })
+	}
+	if err := d.ctx.Err(); err != nil {
+		return err
 	}
 
 	logrus.WithField("total", len(targetAddrs)).Info("发送交易完成")
@@ -1158,20 +1457,22 @@ func (d *Deployer) resolveL1VaultPrivateKey(commonL1VaultMnemonic string, servic
 	return l1VaultPrivateKey, nil
 }
 
-func (d *Deployer) resolveL2ChainID(serviceType enums.ServiceType, index int) int {
-	switch serviceType {
-	case enums.ServiceTypeOP, enums.ServiceTypeCDK:
-		return 10000 + index
-	case enums.ServiceTypeXJST:
-		return 0
-	default:
-		return 0
-	}
+func (d *Deployer) resolveXjstGroupId(svc ServiceConfig, index int) int {
+	// index 为 0-based，groupId 统一改为 1-based；分组大小以 services[].groupSize 为准。
+	return index/svc.groupSize() + 1
 }
 
-func (d *Deployer) resolveXjstGroupId(index int) int {
-	// index 为 0-based，groupId 统一改为 1-based。
-	return index/4 + 1
+// resolveVaultIndex 返回派生第 index 个节点 L1 vault 私钥的索引：分组组网的服务同组共用 groupId，
+// 其余沿用链部署栈的 VaultIndex（op / cdk 为默认链 ID 10000+index，不随 chainId 配置变化，保证 append / resume 时私钥稳定）。
+func (d *Deployer) resolveVaultIndex(svc ServiceConfig, index int) int {
+	stack, ok := chainstack.Lookup(svc.Type)
+	if !ok {
+		return index
+	}
+	if stack.GroupSize() > 1 {
+		return d.resolveXjstGroupId(svc, index)
+	}
+	return stack.VaultIndex(index)
 }
 
 func (d *Deployer) resolveL1RpcUrl(commonL1RpcUrl, svcL1RpcUrl string) string {
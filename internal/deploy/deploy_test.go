@@ -14,6 +14,8 @@ import (
 	"time"
 
 	"github.com/openweb3/go-sdk-common/privatekeyhelper"
+	"github.com/spf13/viper"
+	"github.com/wangdayong228/ydyl-deploy-client/internal/chainstack"
 	"github.com/wangdayong228/ydyl-deploy-client/internal/constants/enums"
 	"github.com/wangdayong228/ydyl-deploy-client/internal/utils/cryptoutil"
 )
@@ -187,6 +189,43 @@ func TestBuildRemoteCommandForIndex_CDKIncludesUseRealProverTrue(t *testing.T) {
 	}
 }
 
+func TestBuildRemoteCommandForIndex_ArbUsesOwnChainIDRange(t *testing.T) {
+	t.Parallel()
+
+	d := &Deployer{
+		cfg: DeployConfig{
+			CommonConfig: CommonConfig{
+				L1ChainId:                  "7655",
+				L1RpcUrl:                   "https://l1.example/rpc",
+				L1RpcUrlWs:                 "wss://l1.example/ws",
+				L1VaultMnemonic:            "test test test test test test test test test test test junk",
+				L1BridgeHubContract:        "0x1111111111111111111111111111111111111111",
+				L1RegisterBridgePrivateKey: "0x2222222222222222222222222222222222222222222222222222222222222222",
+			},
+		},
+		l1VaultDeriveRand: 1,
+	}
+
+	svc := ServiceConfig{Type: chainstack.ServiceTypeArb}
+	got, err := d.buildRemoteCommandForIndex(nil, 2, svc)
+	if err != nil {
+		t.Fatalf("buildRemoteCommandForIndex returned error: %v", err)
+	}
+	for _, want := range []string{"L2_CHAIN_ID=20002 ", "L1_RPC_URL_WS=wss://l1.example/ws ", "FORCE_DEPLOY_ARB=false ", " ./arb_pipe.sh"} {
+		if !strings.Contains(got, want) {
+			t.Fatalf("arb remote command should contain %q, got=%s", want, got)
+		}
+	}
+
+	opKey, err := d.resolveL1VaultPrivateKey(d.cfg.L1VaultMnemonic, enums.ServiceTypeOP, 20002)
+	if err != nil {
+		t.Fatalf("resolveL1VaultPrivateKey returned error: %v", err)
+	}
+	if strings.Contains(got, cryptoutil.EcdsaPrivToWeb3Hex(opKey)) {
+		t.Fatalf("arb vault key should be derived under its own service type path")
+	}
+}
+
 func TestDeployConfigCheckValid_FaultGameMaxClockDuration(t *testing.T) {
 	t.Parallel()
 
@@ -239,7 +278,7 @@ func TestResolveXjstGroupIps(t *testing.T) {
 	d := &Deployer{}
 	globalIps := []string{"192.168.1.1", "192.168.1.2", "192.168.1.3", "192.168.1.4"}
 	groupId := 1
-	got, err := d.resolveXjstGroupIps(globalIps, groupId)
+	got, err := d.resolveXjstGroupIps(globalIps, groupId, 4)
 	if err != nil {
 		t.Fatalf("resolveXjstGroupIps returned error: %v", err)
 	}
@@ -255,7 +294,7 @@ func TestResolveXjstGroupIps_OutOfRange(t *testing.T) {
 	d := &Deployer{}
 	globalIps := []string{"192.168.1.1", "192.168.1.2", "192.168.1.3", "192.168.1.4"}
 
-	_, err := d.resolveXjstGroupIps(globalIps, 2)
+	_, err := d.resolveXjstGroupIps(globalIps, 2, 4)
 	if err == nil {
 		t.Fatalf("resolveXjstGroupIps expected error for out-of-range groupId")
 	}
@@ -640,6 +679,275 @@ func TestServiceConfigCheckValid_XJSTCountStillMustDivisibleBy4(t *testing.T) {
 	}
 }
 
+func TestServiceConfigCheckValid_Volumes(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		volumes []VolumeConfig
+		wantErr string
+	}{
+		{name: "root and data volume", volumes: []VolumeConfig{{SizeGiB: 100}, {DeviceName: "/dev/sdf", SizeGiB: 500, Throughput: 250}}},
+		{name: "io2 with iops", volumes: []VolumeConfig{{SizeGiB: 100, VolumeType: "io2", IOPS: 4000}}},
+		{name: "zero size", volumes: []VolumeConfig{{SizeGiB: 0}}, wantErr: "sizeGiB"},
+		{name: "two roots", volumes: []VolumeConfig{{SizeGiB: 10}, {SizeGiB: 20}}, wantErr: "at most one root"},
+		{name: "duplicate device", volumes: []VolumeConfig{{DeviceName: "/dev/sdf", SizeGiB: 10}, {DeviceName: "/dev/sdf", SizeGiB: 20}}, wantErr: "duplicate"},
+		{name: "unknown type", volumes: []VolumeConfig{{SizeGiB: 10, VolumeType: "ssd"}}, wantErr: "unsupported volumeType"},
+		{name: "throughput on io1", volumes: []VolumeConfig{{SizeGiB: 10, VolumeType: "io1", IOPS: 100, Throughput: 100}}, wantErr: "throughput"},
+		{name: "io1 without iops", volumes: []VolumeConfig{{SizeGiB: 10, VolumeType: "io1"}}, wantErr: "iops is required"},
+		{name: "iops on gp2", volumes: []VolumeConfig{{SizeGiB: 10, VolumeType: "gp2", IOPS: 100}}, wantErr: "iops is only supported"},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			svc := ServiceConfig{Type: enums.ServiceTypeOP, InstanceType: []string{"c6a.xlarge"}, Volumes: tt.volumes}
+			err := svc.CheckValid()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("expected error containing %q, got=%v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestServiceConfigCheckValid_ChainIDAndGroupSize(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		svc     ServiceConfig
+		wantErr string
+	}{
+		{name: "base and range", svc: ServiceConfig{Type: enums.ServiceTypeOP, Count: 3, ChainID: &ChainIDConfig{Base: 30000, Range: 3}}},
+		{name: "explicit list", svc: ServiceConfig{Type: enums.ServiceTypeCDK, Count: 2, ChainID: &ChainIDConfig{List: []int{901, 902, 903}}}},
+		{name: "range too small", svc: ServiceConfig{Type: enums.ServiceTypeOP, Count: 3, ChainID: &ChainIDConfig{Range: 2}}, wantErr: "exceeds chainId.range"},
+		{name: "list too short", svc: ServiceConfig{Type: enums.ServiceTypeOP, Count: 2, ChainID: &ChainIDConfig{List: []int{901}}}, wantErr: "fewer than count"},
+		{name: "list duplicate", svc: ServiceConfig{Type: enums.ServiceTypeOP, Count: 2, ChainID: &ChainIDConfig{List: []int{901, 901}}}, wantErr: "duplicate chain id"},
+		{name: "list with base", svc: ServiceConfig{Type: enums.ServiceTypeOP, Count: 1, ChainID: &ChainIDConfig{Base: 1, List: []int{901}}}, wantErr: "must not be combined"},
+		{name: "chainId on xjst", svc: ServiceConfig{Type: enums.ServiceTypeXJST, Count: 4, ChainID: &ChainIDConfig{Base: 1}}, wantErr: "not supported"},
+		{name: "xjst group of 6", svc: ServiceConfig{Type: enums.ServiceTypeXJST, Count: 12, GroupSize: 6}},
+		{name: "xjst count not divisible by groupSize", svc: ServiceConfig{Type: enums.ServiceTypeXJST, Count: 8, GroupSize: 6}, wantErr: "divisible by 6"},
+		{name: "groupSize on op", svc: ServiceConfig{Type: enums.ServiceTypeOP, Count: 2, GroupSize: 2}, wantErr: "only supported by grouped services"},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			tt.svc.InstanceType = []string{"c6a.xlarge"}
+			err := tt.svc.CheckValid()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("expected error containing %q, got=%v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestServiceConfigResolveL2ChainID(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		svc   ServiceConfig
+		index int
+		want  int
+	}{
+		{ServiceConfig{Type: enums.ServiceTypeOP}, 2, 10002},
+		{ServiceConfig{Type: enums.ServiceTypeCDK, ChainID: &ChainIDConfig{Base: 11000}}, 2, 11002},
+		{ServiceConfig{Type: enums.ServiceTypeCDK, ChainID: &ChainIDConfig{Range: 8}}, 2, 10002},
+		{ServiceConfig{Type: enums.ServiceTypeOP, ChainID: &ChainIDConfig{List: []int{77, 88}}}, 1, 88},
+		{ServiceConfig{Type: enums.ServiceTypeOP, ChainID: &ChainIDConfig{List: []int{77, 88}}}, 2, 0},
+		{ServiceConfig{Type: enums.ServiceTypeXJST, GroupSize: 6}, 7, 0},
+	}
+	for _, c := range cases {
+		if got := c.svc.resolveL2ChainID(c.index); got != c.want {
+			t.Fatalf("%s resolveL2ChainID(%d) = %d, want %d", c.svc.Type, c.index, got, c.want)
+		}
+	}
+}
+
+func TestDeployConfigCheckValid_ChainIDOverlap(t *testing.T) {
+	t.Parallel()
+
+	cfg := DeployConfig{Services: []ServiceConfig{
+		{Type: enums.ServiceTypeOP, InstanceType: []string{"c6a.xlarge"}, Count: 2},
+		{Type: enums.ServiceTypeCDK, InstanceType: []string{"c6a.xlarge"}, Count: 2},
+	}}
+	err := cfg.CheckValid()
+	if err == nil || !strings.Contains(err.Error(), "chain id 10000 overlaps") {
+		t.Fatalf("expected op/cdk default chain id overlap, got=%v", err)
+	}
+
+	cfg.Services[1].ChainID = &ChainIDConfig{List: []int{10001, 10005}}
+	if err := cfg.CheckValid(); err == nil || !strings.Contains(err.Error(), "chain id 10001 overlaps") {
+		t.Fatalf("expected explicit list overlap, got=%v", err)
+	}
+
+	cfg.Services[1].ChainID = &ChainIDConfig{Base: 11000}
+	if err := cfg.CheckValid(); err != nil {
+		t.Fatalf("separated chain ids should pass, err=%v", err)
+	}
+}
+
+func TestBuildRemoteCmdVars_XJSTGroupSize(t *testing.T) {
+	t.Parallel()
+
+	d := &Deployer{}
+	svc := ServiceConfig{Type: enums.ServiceTypeXJST, GroupSize: 6}
+	globalIps := []string{"10.0.0.1", "10.0.0.2", "10.0.0.3", "10.0.0.4", "10.0.0.5", "10.0.0.6", "10.0.0.7"}
+
+	vars := d.buildRemoteCmdVars(globalIps, 6, svc)
+	if vars.XjstGroupID != 2 || vars.XjstNodeID != 1 {
+		t.Fatalf("unexpected group/node id: group=%d node=%d", vars.XjstGroupID, vars.XjstNodeID)
+	}
+	if got := d.resolveVaultIndex(svc, 5); got != 1 {
+		t.Fatalf("nodes in the same group should share vault index 1, got=%d", got)
+	}
+	groupIps, err := d.resolveXjstGroupIps(globalIps, 1, svc.groupSize())
+	if err != nil {
+		t.Fatalf("resolveXjstGroupIps returned error: %v", err)
+	}
+	if groupIps != "[10.0.0.1,10.0.0.2,10.0.0.3,10.0.0.4,10.0.0.5,10.0.0.6]" {
+		t.Fatalf("unexpected group ips: %s", groupIps)
+	}
+}
+
+func TestServiceConfigCheckValid_RejectsManagedTags(t *testing.T) {
+	t.Parallel()
+
+	svc := ServiceConfig{Type: enums.ServiceTypeOP, InstanceType: []string{"c6a.xlarge"}, Tags: []TagConfig{{Key: "Name", Value: "x"}}}
+	if err := svc.CheckValid(); err == nil {
+		t.Fatalf("Name tag should be rejected")
+	}
+
+	svc.Tags = []TagConfig{{Key: TagKeyDeploymentID, Value: "x"}}
+	if err := svc.CheckValid(); err == nil {
+		t.Fatalf("reserved ydyl: tag prefix should be rejected")
+	}
+}
+
+func TestLoadConfigFromFile_OptionalServiceFieldsDefault(t *testing.T) {
+	dir := t.TempDir()
+	cfgPath := filepath.Join(dir, "config.deploy.yaml")
+	cfgYAML := `region: us-west-2
+securityGroupId: sg-test
+diskSizeGiB: 100
+runDuration: 1h
+sshUser: ubuntu
+sshKeyDir: ""
+sshMaxConcurrency: 4
+sshReadyRetryCount: 3
+sshReadyRetryInterval: 3s
+keyName: test-key
+logDir: logs
+outputDir: output
+benchClientIP: ""
+l1ChainId: "11155111"
+l1RpcUrl: https://example.org/rpc
+l1RpcUrlWs: wss://example.org/ws
+l1VaultMnemonic: "test test test test test test test test test test test junk"
+l1BridgeHubContract: "0x00000000000000000000000000000000000000ff"
+l1RegisterBridgePrivateKey: "0x1111111111111111111111111111111111111111111111111111111111111111"
+dryRun: true
+forceDeployL2Chain: false
+enableGenAccounts: false
+services:
+  - type: op
+    count: 1
+    ami: ami-1
+    instanceType: [c6a.xlarge]
+    tagPrefix: ydyl
+    remoteCmd: ""
+    l1RpcUrl: ""
+    l1VaultFundAmount: 1
+  - type: cdk
+    count: 1
+    ami: ami-2
+    instanceType: [c6a.xlarge]
+    tagPrefix: ydyl
+    remoteCmd: ""
+    l1RpcUrl: ""
+    l1VaultFundAmount: 1
+    subnetId: subnet-1
+    associatePublicIp: false
+    tags:
+      - key: Project
+        value: Bench
+    volumes:
+      - sizeGiB: 300
+      - deviceName: /dev/sdf
+        sizeGiB: 1000
+        volumeType: gp3
+        throughput: 500
+    rollout:
+      canary: 1
+    chainId:
+      base: 11000
+    files:
+      - src: ` + cfgPath + `
+        dest: /home/ubuntu/config.deploy.yaml
+`
+	if err := os.WriteFile(cfgPath, []byte(cfgYAML), 0o644); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+
+	cfg := LoadConfigFromFile(cfgPath)
+	if len(cfg.Services) != 2 {
+		t.Fatalf("expected 2 services, got=%d", len(cfg.Services))
+	}
+	op := cfg.Services[0]
+	if op.SubnetID != "" || op.AssociatePublicIP != nil || len(op.Volumes) != 0 || len(op.Tags) != 0 {
+		t.Fatalf("optional fields should default to empty, got=%+v", op)
+	}
+	cdk := cfg.Services[1]
+	if cdk.SubnetID != "subnet-1" || cdk.AssociatePublicIP == nil || *cdk.AssociatePublicIP {
+		t.Fatalf("unexpected network options: %+v", cdk)
+	}
+	if len(cdk.Tags) != 1 || cdk.Tags[0].Key != "Project" || cdk.Tags[0].Value != "Bench" {
+		t.Fatalf("tag key/value case should be preserved, got=%+v", cdk.Tags)
+	}
+	if len(cdk.Volumes) != 2 || cdk.Volumes[0].DeviceName != "" || cdk.Volumes[0].SizeGiB != 300 || cdk.Volumes[1].Throughput != 500 {
+		t.Fatalf("unexpected volumes: %+v", cdk.Volumes)
+	}
+	if op.Rollout != nil {
+		t.Fatalf("rollout should default to nil, got=%+v", op.Rollout)
+	}
+	if cdk.Rollout == nil || cdk.Rollout.Canary != 1 || cdk.Rollout.WaveSize != 0 || cdk.Rollout.MaxFailureRatio != 0 {
+		t.Fatalf("unexpected rollout: %+v", cdk.Rollout)
+	}
+	if op.ChainID != nil || op.GroupSize != 0 {
+		t.Fatalf("chainId / groupSize should default to empty, got=%+v / %d", op.ChainID, op.GroupSize)
+	}
+	if cdk.ChainID == nil || cdk.ChainID.Base != 11000 || cdk.ChainID.Range != 0 || len(cdk.ChainID.List) != 0 {
+		t.Fatalf("unexpected chainId: %+v", cdk.ChainID)
+	}
+	if len(op.Files) != 0 || len(cdk.Files) != 1 || cdk.Files[0].Src != cfgPath || cdk.Files[0].Dest != "/home/ubuntu/config.deploy.yaml" {
+		t.Fatalf("unexpected files: op=%+v cdk=%+v", op.Files, cdk.Files)
+	}
+	if err := cfg.CheckValid(); err != nil {
+		t.Fatalf("loaded config should be valid, err=%v", err)
+	}
+	if cfg.CdkUseRealProver {
+		t.Fatalf("cdkUseRealProver should default to false")
+	}
+	if viper.IsSet("services") || viper.IsSet("repoRef") {
+		t.Fatalf("loading config must not mutate global viper state")
+	}
+}
+
 func TestSelectFailedIPs_MixedStatuses(t *testing.T) {
 	t.Parallel()
 
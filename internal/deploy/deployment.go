@@ -0,0 +1,119 @@
+package deploy
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// 成本归集标签：deploy 创建的每台实例及其 EBS 卷都会带上这些标签，
+// 便于在 AWS Cost Explorer / inventory 中按部署批次、服务类型与操作人汇总。
+const (
+	TagKeyDeploymentID = "ydyl:deployment-id"
+	TagKeyServiceType  = "ydyl:service-type"
+	TagKeyOperator     = "ydyl:operator"
+	TagKeyRunTimestamp = "ydyl:run-timestamp"
+
+	// reservedTagKeyPrefix 下的标签由部署流程统一维护，services[].tags 不允许使用。
+	reservedTagKeyPrefix = "ydyl:"
+
+	deploymentInfoFileName = "deployment.json"
+)
+
+// DeploymentInfo 描述一次 deploy 运行的元信息，落盘到 output/deployment.json。
+type DeploymentInfo struct {
+	DeploymentID string `json:"deploymentId"`
+	Operator     string `json:"operator"`
+	// RunTimestamp 为本次 deploy 启动时间（UTC，RFC3339）。
+	RunTimestamp string `json:"runTimestamp"`
+	Region       string `json:"region,omitempty"`
+	// RunDuration 为远端脚本计划运行时长，到期后实例自动关机（同时被 cost 用于估算）。
+	RunDuration string `json:"runDuration,omitempty"`
+	// L1VaultDeriveRand 为 L1 vault 私钥派生路径中的随机段；deploy --append 复用它以保持同一部署内派生规则一致。
+	L1VaultDeriveRand *uint32 `json:"l1VaultDeriveRand,omitempty"`
+	// RepoRef / RepoCommit 为配置的部署仓库版本及其解析出的 commit；append / resume / replace 沿用同一 commit。
+	RepoRef    string `json:"repoRef,omitempty"`
+	RepoCommit string `json:"repoCommit,omitempty"`
+}
+
+func newDeploymentInfo(cfg CommonConfig, now time.Time) DeploymentInfo {
+	deploymentID := strings.TrimSpace(cfg.DeploymentID)
+	if deploymentID == "" {
+		deploymentID = generateDeploymentID(now)
+	}
+	return DeploymentInfo{
+		DeploymentID: deploymentID,
+		Operator:     resolveOperator(cfg.Operator),
+		RunTimestamp: now.UTC().Format(time.RFC3339),
+		Region:       cfg.Region,
+		RunDuration:  cfg.RunDuration.String(),
+	}
+}
+
+func generateDeploymentID(now time.Time) string {
+	return "ydyl-" + now.UTC().Format("20060102-150405")
+}
+
+// resolveOperator 优先使用配置中的 operator，其次为当前系统用户名。
+func resolveOperator(configured string) string {
+	if op := strings.TrimSpace(configured); op != "" {
+		return op
+	}
+	if u, err := user.Current(); err == nil && strings.TrimSpace(u.Username) != "" {
+		return strings.TrimSpace(u.Username)
+	}
+	if op := strings.TrimSpace(os.Getenv("USER")); op != "" {
+		return op
+	}
+	return "unknown"
+}
+
+// CostTags 返回某个服务类型实例应携带的成本归集标签；DeploymentID 为空时返回 nil。
+func (i DeploymentInfo) CostTags(serviceType string) []TagConfig {
+	if i.DeploymentID == "" {
+		return nil
+	}
+	return []TagConfig{
+		{Key: TagKeyDeploymentID, Value: i.DeploymentID},
+		{Key: TagKeyServiceType, Value: serviceType},
+		{Key: TagKeyOperator, Value: i.Operator},
+		{Key: TagKeyRunTimestamp, Value: i.RunTimestamp},
+	}
+}
+
+// SaveDeploymentInfo 将部署元信息写入 outputDir/deployment.json。
+func SaveDeploymentInfo(outputDir string, info DeploymentInfo) error {
+	return writeJSONFileAtomic(filepath.Join(outputDir, deploymentInfoFileName), info)
+}
+
+// LoadDeploymentInfo 读取 outputDir/deployment.json；文件不存在时返回 nil（兼容旧版本输出）。
+func LoadDeploymentInfo(outputDir string) (*DeploymentInfo, error) {
+	data, err := os.ReadFile(filepath.Join(outputDir, deploymentInfoFileName))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("读取 %s 失败: %w", deploymentInfoFileName, err)
+	}
+	var info DeploymentInfo
+	if err := json.Unmarshal(data, &info); err != nil {
+		return nil, fmt.Errorf("解析 %s 失败: %w", deploymentInfoFileName, err)
+	}
+	return &info, nil
+}
+
+// resolveDeploymentID 返回 outputDir 所属部署的 deploymentId：优先取 deployment.json，其次为配置中的 deploymentId。
+func resolveDeploymentID(commonCfg CommonConfig, outputDir string) (string, error) {
+	info, err := LoadDeploymentInfo(outputDir)
+	if err != nil {
+		return "", err
+	}
+	if info != nil && strings.TrimSpace(info.DeploymentID) != "" {
+		return strings.TrimSpace(info.DeploymentID), nil
+	}
+	return strings.TrimSpace(commonCfg.DeploymentID), nil
+}
@@ -0,0 +1,209 @@
+package deploy
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/ec2/ec2iface"
+)
+
+const (
+	maxTerminateInstancesBatchSize = 100
+	// maxDescribeFilterValues 为 DescribeInstances 单个 filter 允许的最大取值数量。
+	maxDescribeFilterValues = 200
+)
+
+// newEC2Client 按全局 region 创建 EC2 client，region 为空时沿用 AWS SDK 默认解析规则。
+func newEC2Client(region string) (*ec2.EC2, error) {
+	awsCfg := aws.Config{}
+	if region != "" {
+		awsCfg.Region = aws.String(region)
+	}
+
+	sess, err := session.NewSession(&awsCfg)
+	if err != nil {
+		return nil, fmt.Errorf("创建 AWS Session 失败: %w", err)
+	}
+	return ec2.New(sess), nil
+}
+
+// newEC2APIFunc 供 inventory / shutdown --terminate 等命令创建 EC2 client，测试中可替换为 fake。
+var newEC2APIFunc = func(region string) (ec2iface.EC2API, error) {
+	return newEC2Client(region)
+}
+
+// TaggedInstance 描述一台带有部署标签的 EC2 实例。
+type TaggedInstance struct {
+	InstanceID   string    `json:"instanceId"`
+	Name         string    `json:"name,omitempty"`
+	State        string    `json:"state"`
+	InstanceType string    `json:"instanceType,omitempty"`
+	PublicIP     string    `json:"publicIp,omitempty"`
+	LaunchTime   time.Time `json:"launchTime"`
+	DeploymentID string    `json:"deploymentId,omitempty"`
+	ServiceType  string    `json:"serviceType,omitempty"`
+	Operator     string    `json:"operator,omitempty"`
+}
+
+// aliveInstanceStates 为仍会产生费用（或即将产生费用）的实例状态。
+var aliveInstanceStates = []string{
+	ec2.InstanceStateNamePending,
+	ec2.InstanceStateNameRunning,
+	ec2.InstanceStateNameStopping,
+	ec2.InstanceStateNameStopped,
+}
+
+// describeTaggedInstances 查询所有带 ydyl:deployment-id 标签且未终止的实例；deploymentID 非空时只返回该部署的实例。
+func describeTaggedInstances(ctx context.Context, client ec2iface.EC2API, deploymentID string) ([]TaggedInstance, error) {
+	filters := []*ec2.Filter{
+		{Name: aws.String("instance-state-name"), Values: aws.StringSlice(aliveInstanceStates)},
+	}
+	if deploymentID = strings.TrimSpace(deploymentID); deploymentID != "" {
+		filters = append(filters, &ec2.Filter{Name: aws.String("tag:" + TagKeyDeploymentID), Values: []*string{aws.String(deploymentID)}})
+	} else {
+		filters = append(filters, &ec2.Filter{Name: aws.String("tag-key"), Values: []*string{aws.String(TagKeyDeploymentID)}})
+	}
+
+	instances, err := describeInstancesPages(ctx, client, &ec2.DescribeInstancesInput{Filters: filters})
+	if err != nil {
+		return nil, fmt.Errorf("按部署标签查询实例失败: %w", err)
+	}
+	return instances, nil
+}
+
+// describeInstancesByIPs 按公网 IP 查询 deploymentID 部署下未终止的实例，返回 IP -> 实例。
+// 公网 IP 会被 AWS 回收复用，只按 IP 匹配可能命中账号内无关的实例，因此同时要求部署标签一致；
+// deploymentID 为空时无法确认归属，直接返回空结果。
+func describeInstancesByIPs(ctx context.Context, client ec2iface.EC2API, ips []string, deploymentID string) (map[string]TaggedInstance, error) {
+	states := append([]string{ec2.InstanceStateNameShuttingDown}, aliveInstanceStates...)
+	byIP := make(map[string]TaggedInstance, len(ips))
+	if deploymentID = strings.TrimSpace(deploymentID); deploymentID == "" {
+		return byIP, nil
+	}
+	for start := 0; start < len(ips); start += maxDescribeFilterValues {
+		end := start + maxDescribeFilterValues
+		if end > len(ips) {
+			end = len(ips)
+		}
+		instances, err := describeInstancesPages(ctx, client, &ec2.DescribeInstancesInput{
+			Filters: []*ec2.Filter{
+				{Name: aws.String("ip-address"), Values: aws.StringSlice(ips[start:end])},
+				{Name: aws.String("instance-state-name"), Values: aws.StringSlice(states)},
+				{Name: aws.String("tag:" + TagKeyDeploymentID), Values: []*string{aws.String(deploymentID)}},
+			},
+		})
+		if err != nil {
+			return nil, fmt.Errorf("根据 IP 查询实例失败: %w", err)
+		}
+		for _, inst := range instances {
+			if inst.PublicIP != "" {
+				byIP[inst.PublicIP] = inst
+			}
+		}
+	}
+	return byIP, nil
+}
+
+// describeInstanceStates 查询指定实例的当前状态，返回 instanceId -> state。
+func describeInstanceStates(ctx context.Context, client ec2iface.EC2API, ids []string) (map[string]string, error) {
+	states := make(map[string]string, len(ids))
+	for start := 0; start < len(ids); start += maxTerminateInstancesBatchSize {
+		end := start + maxTerminateInstancesBatchSize
+		if end > len(ids) {
+			end = len(ids)
+		}
+		instances, err := describeInstancesPages(ctx, client, &ec2.DescribeInstancesInput{InstanceIds: aws.StringSlice(ids[start:end])})
+		if err != nil {
+			return nil, fmt.Errorf("查询实例状态失败: %w", err)
+		}
+		for _, inst := range instances {
+			states[inst.InstanceID] = inst.State
+		}
+	}
+	return states, nil
+}
+
+func describeInstancesPages(ctx context.Context, client ec2iface.EC2API, input *ec2.DescribeInstancesInput) ([]TaggedInstance, error) {
+	var instances []TaggedInstance
+	err := client.DescribeInstancesPagesWithContext(ctx, input, func(out *ec2.DescribeInstancesOutput, _ bool) bool {
+		for _, res := range out.Reservations {
+			for _, inst := range res.Instances {
+				instances = append(instances, buildTaggedInstance(inst))
+			}
+		}
+		return true
+	})
+	return instances, err
+}
+
+func buildTaggedInstance(inst *ec2.Instance) TaggedInstance {
+	out := TaggedInstance{
+		InstanceID:   aws.StringValue(inst.InstanceId),
+		InstanceType: aws.StringValue(inst.InstanceType),
+		PublicIP:     aws.StringValue(inst.PublicIpAddress),
+		LaunchTime:   aws.TimeValue(inst.LaunchTime),
+	}
+	if inst.State != nil {
+		out.State = aws.StringValue(inst.State.Name)
+	}
+	for _, tag := range inst.Tags {
+		switch aws.StringValue(tag.Key) {
+		case "Name":
+			out.Name = aws.StringValue(tag.Value)
+		case TagKeyDeploymentID:
+			out.DeploymentID = aws.StringValue(tag.Value)
+		case TagKeyServiceType:
+			out.ServiceType = aws.StringValue(tag.Value)
+		case TagKeyOperator:
+			out.Operator = aws.StringValue(tag.Value)
+		}
+	}
+	return out
+}
+
+// terminateInstances 按批调用 TerminateInstances；单批失败不影响后续批次，返回提交失败的 instanceId -> 错误。
+func terminateInstances(ctx context.Context, client ec2iface.EC2API, ids []string) map[string]error {
+	failed := make(map[string]error)
+	for start := 0; start < len(ids); start += maxTerminateInstancesBatchSize {
+		end := start + maxTerminateInstancesBatchSize
+		if end > len(ids) {
+			end = len(ids)
+		}
+		batch := ids[start:end]
+		if _, err := client.TerminateInstancesWithContext(ctx, &ec2.TerminateInstancesInput{
+			InstanceIds: aws.StringSlice(batch),
+		}); err != nil {
+			for _, id := range batch {
+				failed[id] = err
+			}
+			log.Printf("⚠️ 终止实例失败（%s）: %v\n", strings.Join(batch, ","), err)
+			continue
+		}
+		log.Printf("🗑️ 已提交终止 %d 台实例: %s\n", len(batch), strings.Join(batch, ","))
+	}
+	return failed
+}
+
+// waitInstancesTerminated 按批等待实例进入 terminated 状态，返回等待失败的批次错误。
+func waitInstancesTerminated(ctx context.Context, client ec2iface.EC2API, ids []string) []error {
+	var errs []error
+	for start := 0; start < len(ids); start += maxTerminateInstancesBatchSize {
+		end := start + maxTerminateInstancesBatchSize
+		if end > len(ids) {
+			end = len(ids)
+		}
+		batch := ids[start:end]
+		if err := client.WaitUntilInstanceTerminatedWithContext(ctx, &ec2.DescribeInstancesInput{
+			InstanceIds: aws.StringSlice(batch),
+		}); err != nil {
+			errs = append(errs, fmt.Errorf("等待实例终止失败（%s）: %w", strings.Join(batch, ","), err))
+		}
+	}
+	return errs
+}
@@ -2,14 +2,15 @@ package deploy
 
 import (
 	"context"
+	"encoding/base64"
 	"fmt"
 	"log"
 	"strings"
 
-	"github.com/nft-rainbow/rainbow-goutils/utils/commonutils"
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/nft-rainbow/rainbow-goutils/utils/commonutils"
 )
 
 const maxRunInstancesBatchSize = 50
@@ -19,14 +20,21 @@ type EC2RunInstancesLauncher struct {
 	ec2Client         *ec2.EC2
 	commonCfg         CommonConfig
 	buildInstanceName func(tagPrefix, serviceType string, ordinal int) string
+	// deployment 提供成本归集标签，创建时随 TagSpecifications 打到实例与卷上。
+	deployment DeploymentInfo
+
+	// rootDeviceNames 缓存 AMI -> RootDeviceName，避免每个批次重复 DescribeImages。
+	rootDeviceNames map[string]string
 }
 
-func NewEC2RunInstancesLauncher(ctx context.Context, ec2Client *ec2.EC2, commonCfg CommonConfig, buildInstanceName func(tagPrefix, serviceType string, ordinal int) string) *EC2RunInstancesLauncher {
+func NewEC2RunInstancesLauncher(ctx context.Context, ec2Client *ec2.EC2, commonCfg CommonConfig, deployment DeploymentInfo, buildInstanceName func(tagPrefix, serviceType string, ordinal int) string) *EC2RunInstancesLauncher {
 	return &EC2RunInstancesLauncher{
 		ctx:               ctx,
 		ec2Client:         ec2Client,
 		commonCfg:         commonCfg,
 		buildInstanceName: buildInstanceName,
+		deployment:        deployment,
+		rootDeviceNames:   make(map[string]string),
 	}
 }
 
@@ -43,6 +51,15 @@ func (l *EC2RunInstancesLauncher) RunWithStartOrdinal(svc ServiceConfig, startOr
 		startOrdinal = 1
 	}
 
+	rootDeviceName := ""
+	if needsRootDeviceMapping(svc, l.commonCfg.DiskSizeGiB) {
+		name, err := l.resolveRootDeviceName(svc.AMI)
+		if err != nil {
+			return nil, fmt.Errorf("[%s] 获取 AMI 根设备名失败: %w", svc.Type.String(), err)
+		}
+		rootDeviceName = name
+	}
+
 	totalBatches := (totalCount + maxRunInstancesBatchSize - 1) / maxRunInstancesBatchSize
 	remaining := totalCount
 	allIDs := make([]*string, 0, totalCount)
@@ -53,7 +70,7 @@ func (l *EC2RunInstancesLauncher) RunWithStartOrdinal(svc ServiceConfig, startOr
 		}
 		log.Printf("🧩 [%s] 创建实例批次 %d/%d，计划创建 %d 台，候选机型=%v\n", svc.Type.String(), batchNo, totalBatches, batchCount, svc.InstanceType)
 
-		ids, usedInstanceType, err := l.runInstancesBatchWithFallback(svc, batchCount, batchNo, totalBatches)
+		ids, usedInstanceType, err := l.runInstancesBatchWithFallback(svc, rootDeviceName, batchCount, batchNo, totalBatches)
 		if err != nil {
 			return nil, err
 		}
@@ -75,11 +92,11 @@ func (l *EC2RunInstancesLauncher) RunWithStartOrdinal(svc ServiceConfig, startOr
 	return allIDs, nil
 }
 
-func (l *EC2RunInstancesLauncher) runInstancesBatchWithFallback(svc ServiceConfig, batchCount int, batchNo int, totalBatches int) ([]*string, string, error) {
+func (l *EC2RunInstancesLauncher) runInstancesBatchWithFallback(svc ServiceConfig, rootDeviceName string, batchCount int, batchNo int, totalBatches int) ([]*string, string, error) {
 	for idx, instanceType := range svc.InstanceType {
 		log.Printf("🚀 [%s] 批次 %d/%d 尝试机型(%d/%d): %s\n", svc.Type.String(), batchNo, totalBatches, idx+1, len(svc.InstanceType), instanceType)
 
-		input := l.buildRunInstancesInput(svc, instanceType, batchCount)
+		input := l.buildRunInstancesInput(svc, instanceType, rootDeviceName, batchCount)
 		out, err := l.ec2Client.RunInstancesWithContext(l.ctx, input)
 		if err == nil {
 			ids := make([]*string, 0, len(out.Instances))
@@ -99,34 +116,164 @@ func (l *EC2RunInstancesLauncher) runInstancesBatchWithFallback(svc ServiceConfi
 	return nil, "", fmt.Errorf("[%s] 批次 %d/%d 所有机型均容量不足: %s", svc.Type.String(), batchNo, totalBatches, strings.Join(svc.InstanceType, ","))
 }
 
-func (l *EC2RunInstancesLauncher) buildRunInstancesInput(svc ServiceConfig, instanceType string, count int) *ec2.RunInstancesInput {
+func (l *EC2RunInstancesLauncher) buildRunInstancesInput(svc ServiceConfig, instanceType, rootDeviceName string, count int) *ec2.RunInstancesInput {
 	input := &ec2.RunInstancesInput{
-		ImageId:      aws.String(svc.AMI),
-		InstanceType: aws.String(instanceType),
-		MinCount:     aws.Int64(int64(count)),
-		MaxCount:     aws.Int64(int64(count)),
-		KeyName:      aws.String(l.commonCfg.KeyName),
-		SecurityGroupIds: []*string{
-			aws.String(l.commonCfg.SecurityGroupID),
-		},
+		ImageId:                           aws.String(svc.AMI),
+		InstanceType:                      aws.String(instanceType),
+		MinCount:                          aws.Int64(int64(count)),
+		MaxCount:                          aws.Int64(int64(count)),
+		KeyName:                           aws.String(l.commonCfg.KeyName),
 		InstanceInitiatedShutdownBehavior: aws.String("terminate"),
-		TagSpecifications:                 []*ec2.TagSpecification{},
-	}
-	if l.commonCfg.DiskSizeGiB > 0 {
-		input.BlockDeviceMappings = []*ec2.BlockDeviceMapping{
-			{
-				DeviceName: aws.String("/dev/sda1"),
-				Ebs: &ec2.EbsBlockDevice{
-					VolumeSize:          aws.Int64(l.commonCfg.DiskSizeGiB),
-					VolumeType:          aws.String("gp3"),
-					DeleteOnTermination: aws.Bool(true),
-				},
-			},
+		TagSpecifications:                 buildTagSpecifications(append(l.deployment.CostTags(svc.Type.String()), svc.Tags...)),
+		BlockDeviceMappings:               buildBlockDeviceMappings(svc.Volumes, rootDeviceName, l.commonCfg.DiskSizeGiB),
+	}
+
+	securityGroupIDs := resolveSecurityGroupIDs(l.commonCfg.SecurityGroupID, svc.SecurityGroupIDs)
+	subnetID := strings.TrimSpace(svc.SubnetID)
+	if svc.AssociatePublicIP != nil {
+		// 显式控制公网 IP 时必须通过网卡描述子网与安全组，EC2 不允许与顶层参数混用。
+		nic := &ec2.InstanceNetworkInterfaceSpecification{
+			DeviceIndex:              aws.Int64(0),
+			AssociatePublicIpAddress: aws.Bool(*svc.AssociatePublicIP),
+			DeleteOnTermination:      aws.Bool(true),
+			Groups:                   aws.StringSlice(securityGroupIDs),
+		}
+		if subnetID != "" {
+			nic.SubnetId = aws.String(subnetID)
+		}
+		input.NetworkInterfaces = []*ec2.InstanceNetworkInterfaceSpecification{nic}
+	} else {
+		input.SecurityGroupIds = aws.StringSlice(securityGroupIDs)
+		if subnetID != "" {
+			input.SubnetId = aws.String(subnetID)
+		}
+	}
+
+	if profile := strings.TrimSpace(svc.IAMInstanceProfile); profile != "" {
+		if strings.HasPrefix(profile, "arn:") {
+			input.IamInstanceProfile = &ec2.IamInstanceProfileSpecification{Arn: aws.String(profile)}
+		} else {
+			input.IamInstanceProfile = &ec2.IamInstanceProfileSpecification{Name: aws.String(profile)}
 		}
 	}
+	if svc.UserData != "" {
+		input.UserData = aws.String(base64.StdEncoding.EncodeToString([]byte(svc.UserData)))
+	}
+	if group := strings.TrimSpace(svc.PlacementGroup); group != "" {
+		input.Placement = &ec2.Placement{GroupName: aws.String(group)}
+	}
 	return input
 }
 
+// needsRootDeviceMapping 判断是否需要为根卷生成 BlockDeviceMapping（此时才需要查询 AMI 的根设备名）。
+func needsRootDeviceMapping(svc ServiceConfig, defaultDiskSizeGiB int64) bool {
+	for _, v := range svc.Volumes {
+		if strings.TrimSpace(v.DeviceName) == "" {
+			return true
+		}
+	}
+	return defaultDiskSizeGiB > 0
+}
+
+// buildBlockDeviceMappings 生成实例的 EBS 挂载列表：
+// deviceName 为空（或等于 AMI 根设备名）的条目作为根卷；未配置根卷时按全局 diskSizeGiB 生成 gp3 根卷。
+func buildBlockDeviceMappings(volumes []VolumeConfig, rootDeviceName string, defaultDiskSizeGiB int64) []*ec2.BlockDeviceMapping {
+	mappings := make([]*ec2.BlockDeviceMapping, 0, len(volumes)+1)
+	hasRoot := false
+	for _, v := range volumes {
+		device := strings.TrimSpace(v.DeviceName)
+		if device == "" || device == rootDeviceName {
+			if rootDeviceName == "" {
+				continue
+			}
+			device = rootDeviceName
+			hasRoot = true
+		}
+		mappings = append(mappings, &ec2.BlockDeviceMapping{
+			DeviceName: aws.String(device),
+			Ebs:        buildEbsBlockDevice(v),
+		})
+	}
+	if !hasRoot && rootDeviceName != "" && defaultDiskSizeGiB > 0 {
+		root := &ec2.BlockDeviceMapping{
+			DeviceName: aws.String(rootDeviceName),
+			Ebs:        buildEbsBlockDevice(VolumeConfig{SizeGiB: defaultDiskSizeGiB}),
+		}
+		mappings = append([]*ec2.BlockDeviceMapping{root}, mappings...)
+	}
+	if len(mappings) == 0 {
+		return nil
+	}
+	return mappings
+}
+
+func buildEbsBlockDevice(v VolumeConfig) *ec2.EbsBlockDevice {
+	ebs := &ec2.EbsBlockDevice{
+		VolumeSize:          aws.Int64(v.SizeGiB),
+		VolumeType:          aws.String(resolveVolumeType(v.VolumeType)),
+		DeleteOnTermination: aws.Bool(true),
+	}
+	if v.IOPS > 0 {
+		ebs.Iops = aws.Int64(v.IOPS)
+	}
+	if v.Throughput > 0 {
+		ebs.Throughput = aws.Int64(v.Throughput)
+	}
+	return ebs
+}
+
+func buildTagSpecifications(tags []TagConfig) []*ec2.TagSpecification {
+	if len(tags) == 0 {
+		return nil
+	}
+	ec2Tags := make([]*ec2.Tag, 0, len(tags))
+	for _, t := range tags {
+		ec2Tags = append(ec2Tags, &ec2.Tag{
+			Key:   aws.String(strings.TrimSpace(t.Key)),
+			Value: aws.String(t.Value),
+		})
+	}
+	return []*ec2.TagSpecification{
+		{ResourceType: aws.String(ec2.ResourceTypeInstance), Tags: ec2Tags},
+		{ResourceType: aws.String(ec2.ResourceTypeVolume), Tags: ec2Tags},
+	}
+}
+
+func resolveSecurityGroupIDs(commonSecurityGroupID string, extra []string) []string {
+	seen := make(map[string]struct{}, len(extra)+1)
+	ids := make([]string, 0, len(extra)+1)
+	for _, id := range append([]string{commonSecurityGroupID}, extra...) {
+		id = strings.TrimSpace(id)
+		if id == "" {
+			continue
+		}
+		if _, ok := seen[id]; ok {
+			continue
+		}
+		seen[id] = struct{}{}
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+func (l *EC2RunInstancesLauncher) resolveRootDeviceName(ami string) (string, error) {
+	if name, ok := l.rootDeviceNames[ami]; ok {
+		return name, nil
+	}
+	out, err := l.ec2Client.DescribeImagesWithContext(l.ctx, &ec2.DescribeImagesInput{
+		ImageIds: []*string{aws.String(ami)},
+	})
+	if err != nil {
+		return "", fmt.Errorf("DescribeImages 失败（ami=%s）: %w", ami, err)
+	}
+	if len(out.Images) == 0 || aws.StringValue(out.Images[0].RootDeviceName) == "" {
+		return "", fmt.Errorf("未找到 AMI %s 的根设备名", ami)
+	}
+	name := aws.StringValue(out.Images[0].RootDeviceName)
+	l.rootDeviceNames[ami] = name
+	return name, nil
+}
+
 func (l *EC2RunInstancesLauncher) tagInstancesSequentially(svc ServiceConfig, ids []*string, startOrdinal int) error {
 	for i, id := range ids {
 		name := buildCreateStageTagName(svc.TagPrefix, svc.Type.String(), startOrdinal+i)
@@ -1,6 +1,11 @@
 package deploy
 
-import "testing"
+import (
+	"encoding/base64"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+)
 
 func TestBuildCreateStageTagName_Format(t *testing.T) {
 	t.Parallel()
@@ -30,3 +35,104 @@ func TestBuildCreateStageTagName_RelaunchOrdinalKeepsIncreasing(t *testing.T) {
 		t.Fatalf("unexpected relaunch second name: %s", relaunchSecond)
 	}
 }
+
+func TestBuildRunInstancesInput_DefaultRootVolumeUsesAMIRootDevice(t *testing.T) {
+	t.Parallel()
+
+	l := &EC2RunInstancesLauncher{commonCfg: CommonConfig{KeyName: "k", SecurityGroupID: "sg-common", DiskSizeGiB: 200}}
+	input := l.buildRunInstancesInput(ServiceConfig{AMI: "ami-1"}, "c6a.xlarge", "/dev/xvda", 2)
+
+	if len(input.BlockDeviceMappings) != 1 {
+		t.Fatalf("expected 1 block device mapping, got=%d", len(input.BlockDeviceMappings))
+	}
+	root := input.BlockDeviceMappings[0]
+	if aws.StringValue(root.DeviceName) != "/dev/xvda" {
+		t.Fatalf("root device should come from AMI, got=%s", aws.StringValue(root.DeviceName))
+	}
+	if aws.Int64Value(root.Ebs.VolumeSize) != 200 || aws.StringValue(root.Ebs.VolumeType) != "gp3" {
+		t.Fatalf("unexpected root volume: %+v", root.Ebs)
+	}
+	if got := aws.StringValueSlice(input.SecurityGroupIds); len(got) != 1 || got[0] != "sg-common" {
+		t.Fatalf("unexpected security groups: %v", got)
+	}
+	if input.NetworkInterfaces != nil || input.SubnetId != nil || input.TagSpecifications != nil {
+		t.Fatalf("optional launch fields should stay empty by default")
+	}
+}
+
+func TestBuildRunInstancesInput_FullServiceOptions(t *testing.T) {
+	t.Parallel()
+
+	associate := true
+	svc := ServiceConfig{
+		AMI:                "ami-1",
+		SubnetID:           "subnet-1",
+		SecurityGroupIDs:   []string{"sg-extra", "sg-common", ""},
+		IAMInstanceProfile: "arn:aws:iam::123:instance-profile/bench",
+		UserData:           "#!/bin/bash\necho hi",
+		Tags:               []TagConfig{{Key: "Project", Value: "ydyl"}},
+		Volumes: []VolumeConfig{
+			{SizeGiB: 500, VolumeType: "io2", IOPS: 8000},
+			{DeviceName: "/dev/sdf", SizeGiB: 1000, Throughput: 500, IOPS: 6000},
+		},
+		PlacementGroup:    "pg-bench",
+		AssociatePublicIP: &associate,
+	}
+	l := &EC2RunInstancesLauncher{commonCfg: CommonConfig{KeyName: "k", SecurityGroupID: "sg-common", DiskSizeGiB: 200}}
+	input := l.buildRunInstancesInput(svc, "c6a.xlarge", "/dev/sda1", 1)
+
+	if input.SecurityGroupIds != nil || input.SubnetId != nil {
+		t.Fatalf("network settings must move into the network interface when associatePublicIp is set")
+	}
+	if len(input.NetworkInterfaces) != 1 {
+		t.Fatalf("expected one network interface, got=%d", len(input.NetworkInterfaces))
+	}
+	nic := input.NetworkInterfaces[0]
+	if !aws.BoolValue(nic.AssociatePublicIpAddress) || aws.StringValue(nic.SubnetId) != "subnet-1" {
+		t.Fatalf("unexpected network interface: %+v", nic)
+	}
+	if got := aws.StringValueSlice(nic.Groups); len(got) != 2 || got[0] != "sg-common" || got[1] != "sg-extra" {
+		t.Fatalf("security groups should be merged and deduplicated, got=%v", got)
+	}
+	if aws.StringValue(input.IamInstanceProfile.Arn) == "" || input.IamInstanceProfile.Name != nil {
+		t.Fatalf("ARN profile should be passed as Arn, got=%+v", input.IamInstanceProfile)
+	}
+	decoded, err := base64.StdEncoding.DecodeString(aws.StringValue(input.UserData))
+	if err != nil || string(decoded) != svc.UserData {
+		t.Fatalf("user data should be base64 encoded, got=%q err=%v", decoded, err)
+	}
+	if aws.StringValue(input.Placement.GroupName) != "pg-bench" {
+		t.Fatalf("unexpected placement: %+v", input.Placement)
+	}
+	if len(input.TagSpecifications) != 2 {
+		t.Fatalf("tags should apply to instance and volume, got=%d specs", len(input.TagSpecifications))
+	}
+
+	if len(input.BlockDeviceMappings) != 2 {
+		t.Fatalf("expected 2 block device mappings, got=%d", len(input.BlockDeviceMappings))
+	}
+	root, data := input.BlockDeviceMappings[0], input.BlockDeviceMappings[1]
+	if aws.StringValue(root.DeviceName) != "/dev/sda1" || aws.Int64Value(root.Ebs.VolumeSize) != 500 || aws.StringValue(root.Ebs.VolumeType) != "io2" || aws.Int64Value(root.Ebs.Iops) != 8000 {
+		t.Fatalf("configured root volume should replace the default one, got=%s %+v", aws.StringValue(root.DeviceName), root.Ebs)
+	}
+	if aws.StringValue(data.DeviceName) != "/dev/sdf" || aws.Int64Value(data.Ebs.Throughput) != 500 || aws.StringValue(data.Ebs.VolumeType) != "gp3" {
+		t.Fatalf("unexpected data volume: %s %+v", aws.StringValue(data.DeviceName), data.Ebs)
+	}
+}
+
+func TestNeedsRootDeviceMapping(t *testing.T) {
+	t.Parallel()
+
+	if needsRootDeviceMapping(ServiceConfig{}, 0) {
+		t.Fatalf("no root volume and no diskSizeGiB should not need AMI lookup")
+	}
+	if !needsRootDeviceMapping(ServiceConfig{}, 100) {
+		t.Fatalf("global diskSizeGiB should need AMI lookup")
+	}
+	if needsRootDeviceMapping(ServiceConfig{Volumes: []VolumeConfig{{DeviceName: "/dev/sdf", SizeGiB: 10}}}, 0) {
+		t.Fatalf("data volumes only should not need AMI lookup")
+	}
+	if !needsRootDeviceMapping(ServiceConfig{Volumes: []VolumeConfig{{SizeGiB: 10}}}, 0) {
+		t.Fatalf("configured root volume should need AMI lookup")
+	}
+}
@@ -0,0 +1,241 @@
+package deploy
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// sshExitCodeConnectFailed 为 ssh 客户端自身失败（连接、认证等）时的退出码，无法与远端命令主动 exit 255 区分。
+const sshExitCodeConnectFailed = 255
+
+var runExecSSHCommandFunc = runSSHCapture
+
+// ExecOptions 描述一次批量远端命令：按类型 / 名称 / 状态选中节点，并发执行同一条命令。
+// 同类选择器之间为“或”，不同类选择器之间为“且”；未指定选择器时选中全部节点。
+type ExecOptions struct {
+	ServersPath string
+	// Types 为服务类型，例如 xjst、op。
+	Types []string
+	// NameGlobs 为节点名称的 glob 模式（path.Match 语法），例如 ydyl-xjst-2-*。
+	NameGlobs []string
+	// Statuses 为 script_status.json 中的脚本状态，例如 failed、running。
+	Statuses []string
+	Command  string
+	// Timeout 为单台节点的执行超时，0 表示不限。
+	Timeout time.Duration
+}
+
+// ExecResult 为单个节点的执行结果；ExitCode 为 -1 表示命令未能执行（Error 说明原因）。
+type ExecResult struct {
+	Name        string `json:"name"`
+	ServiceType string `json:"serviceType"`
+	IP          string `json:"ip"`
+	Status      string `json:"status,omitempty"`
+	ExitCode    int    `json:"exitCode"`
+	Stdout      string `json:"stdout"`
+	Stderr      string `json:"stderr"`
+	Error       string `json:"error,omitempty"`
+	DurationMs  int64  `json:"durationMs"`
+}
+
+// OK 表示命令已执行且退出码为 0。
+func (r ExecResult) OK() bool {
+	return r.Error == "" && r.ExitCode == 0
+}
+
+// ExecOutputGroup 为输出完全相同（退出码、stdout、stderr、错误）的一组节点。
+type ExecOutputGroup struct {
+	Results  []ExecResult
+	ExitCode int
+	Stdout   string
+	Stderr   string
+	Error    string
+}
+
+func (o ExecOptions) resolveServersPath(commonCfg CommonConfig) string {
+	if p := strings.TrimSpace(o.ServersPath); p != "" {
+		return p
+	}
+	return filepath.Join(resolveOutputDir(commonCfg, ""), "servers.json")
+}
+
+// ResolveExecTargets 在 servers.json / script_status.json 中按选择器选出待执行节点，按类型、名称排序。
+func ResolveExecTargets(commonCfg CommonConfig, opts ExecOptions) ([]ExecResult, error) {
+	outputMgr, err := LoadOutputManager(filepath.Dir(opts.resolveServersPath(commonCfg)))
+	if err != nil {
+		return nil, err
+	}
+	return selectExecTargets(collectRemoveCandidates(outputMgr.SnapshotServers(), outputMgr.SnapshotStatuses()), opts)
+}
+
+func selectExecTargets(candidates []RemoveTarget, opts ExecOptions) ([]ExecResult, error) {
+	types := cleanSelectorValues(opts.Types)
+	statuses := cleanSelectorValues(opts.Statuses)
+	globs := cleanSelectorValues(opts.NameGlobs)
+	for _, p := range globs {
+		if _, err := path.Match(p, ""); err != nil {
+			return nil, fmt.Errorf("--name-glob 模式不合法 %q: %w", p, err)
+		}
+	}
+
+	var targets []ExecResult
+	for _, c := range candidates {
+		if len(types) > 0 && !slices.Contains(types, c.ServiceType) {
+			continue
+		}
+		if len(statuses) > 0 && !slices.Contains(statuses, c.Status) {
+			continue
+		}
+		if len(globs) > 0 && !matchAnyGlob(globs, c.Name) {
+			continue
+		}
+		targets = append(targets, ExecResult{Name: c.Name, ServiceType: c.ServiceType, IP: c.IP, Status: c.Status})
+	}
+	if len(targets) == 0 {
+		return nil, errors.New("选择器未匹配任何节点")
+	}
+	sort.Slice(targets, func(i, j int) bool {
+		if targets[i].ServiceType != targets[j].ServiceType {
+			return targets[i].ServiceType < targets[j].ServiceType
+		}
+		return targets[i].Name < targets[j].Name
+	})
+	return targets, nil
+}
+
+func cleanSelectorValues(values []string) []string {
+	out := make([]string, 0, len(values))
+	for _, v := range values {
+		for _, part := range strings.Split(v, ",") {
+			if part = strings.TrimSpace(part); part != "" {
+				out = append(out, part)
+			}
+		}
+	}
+	return out
+}
+
+func matchAnyGlob(globs []string, name string) bool {
+	for _, p := range globs {
+		if ok, _ := path.Match(p, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Exec 通过 SSH 在选中节点上并发执行 opts.Command，收集每个节点的 stdout / stderr / 退出码。
+// 任一节点未能执行或退出码非 0 时，返回全部结果及汇总错误。
+func Exec(ctx context.Context, commonCfg CommonConfig, opts ExecOptions) ([]ExecResult, error) {
+	command := strings.TrimSpace(opts.Command)
+	if command == "" {
+		return nil, errors.New("远端命令不能为空")
+	}
+	sshUser := strings.TrimSpace(commonCfg.SSHUser)
+	if sshUser == "" {
+		return nil, fmt.Errorf("sshUser 不能为空")
+	}
+	results, err := ResolveExecTargets(commonCfg, opts)
+	if err != nil {
+		return nil, err
+	}
+	sshKeyPath := buildSSHKeyPath(commonCfg)
+
+	log.Printf("👉 [exec] 在 %d 个节点上执行: %s\n", len(results), command)
+	var (
+		mu     sync.Mutex
+		failed int
+	)
+	runWithBatchLimit("exec-remote", len(results), resolveSSHMaxConcurrency(commonCfg), func(i int) {
+		r := &results[i]
+		runCtx := ctx
+		if opts.Timeout > 0 {
+			var cancel context.CancelFunc
+			runCtx, cancel = context.WithTimeout(ctx, opts.Timeout)
+			defer cancel()
+		}
+
+		start := time.Now()
+		stdout, stderr, exitCode, runErr := runExecSSHCommandFunc(runCtx, sshUser, sshKeyPath, r.IP, command)
+		r.DurationMs = time.Since(start).Milliseconds()
+		r.Stdout, r.Stderr, r.ExitCode = stdout, stderr, exitCode
+		switch {
+		case runErr != nil:
+			r.ExitCode = -1
+			r.Error = runErr.Error()
+			if errors.Is(runCtx.Err(), context.DeadlineExceeded) {
+				r.Error = fmt.Sprintf("执行超时（%s）", opts.Timeout)
+			}
+		case exitCode == sshExitCodeConnectFailed:
+			r.Error = "ssh 失败（exit 255）: " + strings.TrimSpace(stderr)
+		}
+		if !r.OK() {
+			mu.Lock()
+			failed++
+			mu.Unlock()
+		}
+	})
+
+	if failed > 0 {
+		log.Printf("⚠️ [exec] 执行完成，成功=%d，失败=%d\n", len(results)-failed, failed)
+		return results, fmt.Errorf("%d/%d 个节点执行失败", failed, len(results))
+	}
+	log.Printf("✅ [exec] 执行完成，共 %d 个节点全部成功\n", len(results))
+	return results, nil
+}
+
+// runSSHCapture 执行远端命令并分别返回 stdout、stderr 与退出码；err 仅在 ssh 进程未能正常退出（如未安装、被取消）时非空。
+func runSSHCapture(ctx context.Context, user, keyPath, ip, remoteCmd string) (string, string, int, error) {
+	args := append(collectLogsSSHArgs(keyPath), fmt.Sprintf("%s@%s", user, ip), remoteCmd)
+	cmd := exec.CommandContext(ctx, "ssh", args...)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	err := cmd.Run()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && ctx.Err() == nil {
+		return stdout.String(), stderr.String(), exitErr.ExitCode(), nil
+	}
+	if err != nil {
+		return stdout.String(), stderr.String(), -1, fmt.Errorf("ssh 失败: %w", err)
+	}
+	return stdout.String(), stderr.String(), 0, nil
+}
+
+// GroupExecResults 将输出完全相同的节点合并为一组，按节点数降序排列（同数量时失败组在前）。
+func GroupExecResults(results []ExecResult) []ExecOutputGroup {
+	type groupKey struct {
+		exitCode               int
+		stdout, stderr, errMsg string
+	}
+	index := make(map[groupKey]int)
+	var groups []ExecOutputGroup
+	for _, r := range results {
+		key := groupKey{exitCode: r.ExitCode, stdout: r.Stdout, stderr: r.Stderr, errMsg: r.Error}
+		i, ok := index[key]
+		if !ok {
+			i = len(groups)
+			index[key] = i
+			groups = append(groups, ExecOutputGroup{ExitCode: r.ExitCode, Stdout: r.Stdout, Stderr: r.Stderr, Error: r.Error})
+		}
+		groups[i].Results = append(groups[i].Results, r)
+	}
+	sort.SliceStable(groups, func(i, j int) bool {
+		if len(groups[i].Results) != len(groups[j].Results) {
+			return len(groups[i].Results) > len(groups[j].Results)
+		}
+		return !groups[i].Results[0].OK() && groups[j].Results[0].OK()
+	})
+	return groups
+}
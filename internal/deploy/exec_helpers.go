@@ -5,7 +5,7 @@ import (
 	"path/filepath"
 	"time"
 
-	"github.com/wangdayong228/ydyl-deploy-client/internal/constants/enums"
+	"github.com/wangdayong228/ydyl-deploy-client/internal/chainstack"
 )
 
 const (
@@ -40,6 +40,11 @@ func buildBackgroundCommand(runDuration time.Duration, cmd, remoteLogDir, remote
 	)
 }
 
+// resolveShutdownAt 返回 buildBackgroundCommand 中 shutdown -h +N 对应的关机时间（Unix 秒）。
+func resolveShutdownAt(now time.Time, runDuration time.Duration) int64 {
+	return now.Add(time.Duration(int(runDuration.Minutes())) * time.Minute).Unix()
+}
+
 // buildLocalLogPath 构造本地日志文件路径。
 func buildLocalLogPath(logDir, ip, name string) string {
 	return filepath.Join(logDir, fmt.Sprintf("%s-%s.log", name, ip))
@@ -49,7 +54,7 @@ func buildRuntimeLogPath(name string) string {
 	return fmt.Sprintf("%s/%s-runtime.log", remoteLogDirDefault, name)
 }
 
-func buildRuntimeMonitorCommand(serviceType enums.ServiceType, index int, name string) (string, bool) {
+func buildRuntimeMonitorCommand(svc ServiceConfig, index int, name string) (string, bool) {
 	output := buildRuntimeLogPath(name)
 	scriptPath := fmt.Sprintf("%s/ydyl-scripts-lib/log_monitor_runtime.sh", remoteRepoDirDefault)
 	buildStartCmd := func(args string) string {
@@ -66,17 +71,14 @@ func buildRuntimeMonitorCommand(serviceType enums.ServiceType, index int, name s
 		)
 	}
 
-	switch serviceType {
-	case enums.ServiceTypeCDK:
-		return buildStartCmd(fmt.Sprintf("--mode kurtosis --stack cdk --output '%s' --enclave cdk-gen", output)), true
-	case enums.ServiceTypeOP:
-		return buildStartCmd(fmt.Sprintf("--mode kurtosis --stack op --output '%s' --enclave op-gen", output)), true
-	case enums.ServiceTypeXJST:
-		if index%4 != 0 {
-			return "", false
-		}
-		return buildStartCmd(fmt.Sprintf("--mode docker --output '%s' --container testchain_node1", output)), true
-	default:
+	// 分组组网的服务仅在组内主节点上启动监控（分组大小以 services[].groupSize 为准）
+	stack, ok := svc.stack()
+	if !ok || !chainstack.IsPrimaryIndex(stack, index) {
+		return "", false
+	}
+	args := stack.RuntimeMonitorArgs(output)
+	if args == "" {
 		return "", false
 	}
+	return buildStartCmd(args), true
 }
@@ -0,0 +1,126 @@
+package deploy
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSelectExecTargets(t *testing.T) {
+	t.Parallel()
+
+	candidates := []RemoveTarget{
+		{Name: "ydyl-xjst-1-1", ServiceType: "xjst", IP: "1.1.1.1", Status: "running"},
+		{Name: "ydyl-xjst-1-2", ServiceType: "xjst", IP: "1.1.1.2", Status: "failed"},
+		{Name: "ydyl-op-1", ServiceType: "op", IP: "2.2.2.1", Status: "failed"},
+		{Name: "ydyl-op-2", ServiceType: "op", IP: "2.2.2.2"},
+	}
+	names := func(targets []ExecResult) string {
+		out := make([]string, 0, len(targets))
+		for _, t := range targets {
+			out = append(out, t.Name)
+		}
+		return strings.Join(out, ",")
+	}
+
+	tests := []struct {
+		name string
+		opts ExecOptions
+		want string
+	}{
+		{name: "no selector selects all sorted", opts: ExecOptions{}, want: "ydyl-op-1,ydyl-op-2,ydyl-xjst-1-1,ydyl-xjst-1-2"},
+		{name: "type", opts: ExecOptions{Types: []string{"xjst"}}, want: "ydyl-xjst-1-1,ydyl-xjst-1-2"},
+		{name: "status across types", opts: ExecOptions{Statuses: []string{"failed"}}, want: "ydyl-op-1,ydyl-xjst-1-2"},
+		{name: "selectors are and-ed", opts: ExecOptions{Types: []string{"xjst"}, Statuses: []string{"failed"}}, want: "ydyl-xjst-1-2"},
+		{name: "same selector is or-ed and comma separated", opts: ExecOptions{NameGlobs: []string{"ydyl-op-2, ydyl-xjst-*-1"}}, want: "ydyl-op-2,ydyl-xjst-1-1"},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := selectExecTargets(candidates, tt.opts)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if names(got) != tt.want {
+				t.Fatalf("got=%s, want=%s", names(got), tt.want)
+			}
+		})
+	}
+
+	if _, err := selectExecTargets(candidates, ExecOptions{Types: []string{"cdk"}}); err == nil {
+		t.Fatalf("expected error when nothing matches")
+	}
+	if _, err := selectExecTargets(candidates, ExecOptions{NameGlobs: []string{"["}}); err == nil {
+		t.Fatalf("expected error for malformed glob")
+	}
+}
+
+func TestGroupExecResults(t *testing.T) {
+	t.Parallel()
+
+	groups := GroupExecResults([]ExecResult{
+		{Name: "a", ExitCode: 0, Stdout: "ok\n"},
+		{Name: "b", ExitCode: 1, Stderr: "boom\n"},
+		{Name: "c", ExitCode: 0, Stdout: "ok\n"},
+		{Name: "d", ExitCode: -1, Error: "ssh 失败"},
+		{Name: "e", ExitCode: 0, Stdout: "ok\n"},
+	})
+	if len(groups) != 3 {
+		t.Fatalf("expected 3 groups, got=%d", len(groups))
+	}
+	if len(groups[0].Results) != 3 || groups[0].Stdout != "ok\n" {
+		t.Fatalf("largest group should come first: %+v", groups[0])
+	}
+	if groups[1].Results[0].Name != "b" || groups[2].Results[0].Name != "d" {
+		t.Fatalf("equal-sized groups should keep first-seen order: %+v", groups[1:])
+	}
+}
+
+func TestExec_CollectsPerHostResults(t *testing.T) {
+	outputDir := t.TempDir()
+	mgr := NewOutputManager(outputDir)
+	if err := mgr.AddServers([]ServerInfo{
+		{IP: "1.1.1.1", ServiceType: "op", Name: "ydyl-op-1"},
+		{IP: "2.2.2.2", ServiceType: "op", Name: "ydyl-op-2"},
+		{IP: "3.3.3.3", ServiceType: "cdk", Name: "ydyl-cdk-1"},
+	}); err != nil {
+		t.Fatalf("AddServers: %v", err)
+	}
+
+	orig := runExecSSHCommandFunc
+	t.Cleanup(func() { runExecSSHCommandFunc = orig })
+	runExecSSHCommandFunc = func(_ context.Context, _, _, ip, remoteCmd string) (string, string, int, error) {
+		if remoteCmd != "uptime" {
+			t.Errorf("unexpected command %q", remoteCmd)
+		}
+		switch ip {
+		case "1.1.1.1":
+			return "up\n", "", 0, nil
+		case "2.2.2.2":
+			return "", "Permission denied\n", sshExitCodeConnectFailed, nil
+		}
+		return "", "", -1, errors.New("unexpected host")
+	}
+
+	results, err := Exec(context.Background(), CommonConfig{SSHUser: "ubuntu", KeyName: "k"}, ExecOptions{
+		ServersPath: filepath.Join(outputDir, "servers.json"),
+		Types:       []string{"op"},
+		Command:     "uptime",
+	})
+	if err == nil || !strings.Contains(err.Error(), "1/2") {
+		t.Fatalf("expected 1/2 failure, got=%v", err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("expected 2 results, got=%+v", results)
+	}
+	if !results[0].OK() || results[0].Stdout != "up\n" {
+		t.Fatalf("unexpected result for ydyl-op-1: %+v", results[0])
+	}
+	if results[1].OK() || !strings.Contains(results[1].Error, "Permission denied") {
+		t.Fatalf("exit 255 should be reported as ssh failure: %+v", results[1])
+	}
+}
@@ -0,0 +1,199 @@
+package deploy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+var runExtendSSHCommandFunc = runSSH
+
+// ExtendOptions 描述一次关机计划调整：By / Until / Cancel 三选一。
+type ExtendOptions struct {
+	ServersPath string
+	// By 在当前关机时间基础上顺延（可为负数表示提前）；未记录关机时间时以当前时间为基准。
+	By time.Duration
+	// Until 直接指定新的关机时间。
+	Until time.Time
+	// Cancel 取消远端已计划的关机。
+	Cancel bool
+}
+
+// ExtendResult 为单台服务器的调整结果。
+type ExtendResult struct {
+	Name           string
+	IP             string
+	ServiceType    string
+	PrevShutdownAt time.Time
+	ShutdownAt     time.Time
+	Canceled       bool
+	Err            error
+}
+
+func (o ExtendOptions) validate() error {
+	set := 0
+	if o.By != 0 {
+		set++
+	}
+	if !o.Until.IsZero() {
+		set++
+	}
+	if o.Cancel {
+		set++
+	}
+	if set != 1 {
+		return errors.New("--by / --until / --cancel 必须且只能指定一个")
+	}
+	return nil
+}
+
+// Extend 通过 SSH 在 servers.json 中每台机器上重新下发（或取消）shutdown 计划，
+// 并将新的关机时间记录到同目录 script_status.json 的 shutdownAt 字段。
+func Extend(ctx context.Context, commonCfg CommonConfig, opts ExtendOptions) ([]ExtendResult, error) {
+	if err := opts.validate(); err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	if !opts.Until.IsZero() && !opts.Until.After(now) {
+		return nil, fmt.Errorf("--until 必须晚于当前时间: %s", opts.Until.Format(time.RFC3339))
+	}
+
+	serversPath := strings.TrimSpace(opts.ServersPath)
+	if serversPath == "" {
+		serversPath = filepath.Join(resolveOutputDir(commonCfg, ""), "servers.json")
+	}
+	servers, err := loadServersFromFile(serversPath)
+	if err != nil {
+		return nil, err
+	}
+	outputMgr, err := LoadOutputManager(filepath.Dir(serversPath))
+	if err != nil {
+		return nil, err
+	}
+
+	sshUser := strings.TrimSpace(commonCfg.SSHUser)
+	if sshUser == "" {
+		return nil, fmt.Errorf("sshUser 不能为空")
+	}
+	sshKeyPath := buildSSHKeyPath(commonCfg)
+
+	// 关机按主机生效，同一 IP 只处理一次。
+	prevByIP := make(map[string]int64)
+	for _, st := range outputMgr.SnapshotStatuses() {
+		if st.ShutdownAt > 0 && !st.ShutdownCanceled && st.ShutdownAt > prevByIP[st.IP] {
+			prevByIP[st.IP] = st.ShutdownAt
+		}
+	}
+	results := make([]ExtendResult, 0, len(servers))
+	seen := make(map[string]struct{}, len(servers))
+	for _, s := range servers {
+		ip := strings.TrimSpace(s.IP)
+		if ip == "" {
+			continue
+		}
+		if _, ok := seen[ip]; ok {
+			continue
+		}
+		seen[ip] = struct{}{}
+		r := ExtendResult{Name: s.Name, IP: ip, ServiceType: s.ServiceType}
+		if prev := prevByIP[ip]; prev > 0 {
+			r.PrevShutdownAt = time.Unix(prev, 0)
+		}
+		results = append(results, r)
+	}
+	if len(results) == 0 {
+		return nil, fmt.Errorf("servers.json 中未找到有效 IP")
+	}
+
+	log.Printf("👉 [extend] 开始调整关机计划，共 %d 台服务器\n", len(results))
+	var (
+		mu   sync.Mutex
+		errs []error
+	)
+	runWithBatchLimit("extend-shutdown", len(results), resolveSSHMaxConcurrency(commonCfg), func(i int) {
+		r := &results[i]
+		remoteCmd, deadline, planErr := planShutdownChange(opts, r.PrevShutdownAt, now)
+		if planErr == nil {
+			_, planErr = runExtendSSHCommandFunc(ctx, sshUser, sshKeyPath, r.IP, remoteCmd)
+		}
+		if planErr != nil {
+			r.Err = planErr
+			mu.Lock()
+			errs = append(errs, fmt.Errorf("[%s] %w", r.IP, planErr))
+			mu.Unlock()
+			return
+		}
+
+		r.Canceled = opts.Cancel
+		r.ShutdownAt = deadline
+		if _, err := outputMgr.UpdateExistingStatuses(r.IP, func(st *ScriptStatus) {
+			st.ShutdownCanceled = opts.Cancel
+			st.ShutdownAt = 0
+			if !opts.Cancel {
+				st.ShutdownAt = deadline.Unix()
+			}
+		}); err != nil {
+			mu.Lock()
+			errs = append(errs, fmt.Errorf("[%s] 写入 script_status.json 失败: %w", r.IP, err))
+			mu.Unlock()
+		}
+		if opts.Cancel {
+			log.Printf("[extend][%s] 已取消自动关机\n", r.IP)
+		} else {
+			log.Printf("[extend][%s] 关机时间调整为 %s\n", r.IP, deadline.Format(time.RFC3339))
+		}
+	})
+
+	if len(errs) > 0 {
+		return results, deployMultiError{errs: errs}
+	}
+	log.Printf("✅ [extend] 关机计划已全部更新，共 %d 台\n", len(results))
+	return results, nil
+}
+
+// planShutdownChange 计算远端命令与新的关机时间。
+// shutdown -h +N 以分钟为粒度，这里向上取整，保证不早于期望时间。
+func planShutdownChange(opts ExtendOptions, prev, now time.Time) (string, time.Time, error) {
+	if opts.Cancel {
+		return "sudo -n shutdown -c", time.Time{}, nil
+	}
+
+	target := opts.Until
+	if target.IsZero() {
+		base := prev
+		if base.IsZero() || base.Before(now) {
+			base = now
+		}
+		target = base.Add(opts.By)
+	}
+	minutes := int(math.Ceil(target.Sub(now).Minutes()))
+	if minutes < 1 {
+		return "", time.Time{}, fmt.Errorf("新的关机时间 %s 不晚于当前时间", target.Format(time.RFC3339))
+	}
+	return fmt.Sprintf("sudo -n shutdown -h +%d", minutes), now.Add(time.Duration(minutes) * time.Minute), nil
+}
+
+// ParseShutdownDeadline 解析 --until 参数，支持 RFC3339、"2006-01-02 15:04" 与 "15:04"（本地时间，已过则取次日）。
+func ParseShutdownDeadline(value string, now time.Time) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02 15:04", value, now.Location()); err == nil {
+		return t, nil
+	}
+	if t, err := time.ParseInLocation("15:04", value, now.Location()); err == nil {
+		deadline := time.Date(now.Year(), now.Month(), now.Day(), t.Hour(), t.Minute(), 0, 0, now.Location())
+		if !deadline.After(now) {
+			deadline = deadline.AddDate(0, 0, 1)
+		}
+		return deadline, nil
+	}
+	return time.Time{}, fmt.Errorf("无法解析时间 %q，支持 RFC3339 / \"2006-01-02 15:04\" / \"15:04\"", value)
+}
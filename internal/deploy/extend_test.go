@@ -0,0 +1,131 @@
+package deploy
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestPlanShutdownChange(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2026, 1, 1, 10, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name     string
+		opts     ExtendOptions
+		prev     time.Time
+		wantCmd  string
+		wantAt   time.Time
+		wantFail bool
+	}{
+		{name: "by extends recorded deadline", opts: ExtendOptions{By: 2 * time.Hour}, prev: now.Add(30 * time.Minute), wantCmd: "sudo -n shutdown -h +150", wantAt: now.Add(150 * time.Minute)},
+		{name: "by without record starts from now", opts: ExtendOptions{By: time.Hour}, wantCmd: "sudo -n shutdown -h +60", wantAt: now.Add(time.Hour)},
+		{name: "negative by shortens", opts: ExtendOptions{By: -20 * time.Minute}, prev: now.Add(time.Hour), wantCmd: "sudo -n shutdown -h +40", wantAt: now.Add(40 * time.Minute)},
+		{name: "negative by into the past fails", opts: ExtendOptions{By: -2 * time.Hour}, prev: now.Add(time.Hour), wantFail: true},
+		{name: "until rounds up to minute", opts: ExtendOptions{Until: now.Add(90*time.Minute + 10*time.Second)}, wantCmd: "sudo -n shutdown -h +91", wantAt: now.Add(91 * time.Minute)},
+		{name: "cancel", opts: ExtendOptions{Cancel: true}, wantCmd: "sudo -n shutdown -c"},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			cmd, at, err := planShutdownChange(tt.opts, tt.prev, now)
+			if tt.wantFail {
+				if err == nil {
+					t.Fatalf("expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if cmd != tt.wantCmd || !at.Equal(tt.wantAt) {
+				t.Fatalf("got cmd=%q at=%s, want cmd=%q at=%s", cmd, at, tt.wantCmd, tt.wantAt)
+			}
+		})
+	}
+}
+
+func TestParseShutdownDeadline(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2026, 1, 1, 20, 0, 0, 0, time.UTC)
+	cases := map[string]time.Time{
+		"2026-01-02T03:04:05Z": time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC),
+		"2026-01-01 22:30":     time.Date(2026, 1, 1, 22, 30, 0, 0, time.UTC),
+		"21:00":                time.Date(2026, 1, 1, 21, 0, 0, 0, time.UTC),
+		"08:00":                time.Date(2026, 1, 2, 8, 0, 0, 0, time.UTC),
+	}
+	for in, want := range cases {
+		got, err := ParseShutdownDeadline(in, now)
+		if err != nil || !got.Equal(want) {
+			t.Fatalf("ParseShutdownDeadline(%q) = %s, %v; want %s", in, got, err, want)
+		}
+	}
+	if _, err := ParseShutdownDeadline("tomorrow", now); err == nil {
+		t.Fatalf("expected parse error")
+	}
+}
+
+func TestExtend_RecordsDeadlineAndAggregatesErrors(t *testing.T) {
+	outputDir := t.TempDir()
+	mgr := NewOutputManager(outputDir)
+	if err := mgr.AddServers([]ServerInfo{
+		{IP: "1.1.1.1", ServiceType: "op", Name: "ydyl-op-1"},
+		{IP: "2.2.2.2", ServiceType: "cdk", Name: "ydyl-cdk-1"},
+	}); err != nil {
+		t.Fatalf("AddServers: %v", err)
+	}
+	prev := time.Now().Add(time.Hour).Unix()
+	if err := mgr.InitStatus("1.1.1.1", "op", "ydyl-op-1", "cmd", 1, "", "", 0, prev, ""); err != nil {
+		t.Fatalf("InitStatus: %v", err)
+	}
+
+	orig := runExtendSSHCommandFunc
+	t.Cleanup(func() { runExtendSSHCommandFunc = orig })
+	var (
+		mu   sync.Mutex
+		cmds = make(map[string]string)
+	)
+	runExtendSSHCommandFunc = func(_ context.Context, _, _, ip, remoteCmd string) (string, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		cmds[ip] = remoteCmd
+		if ip == "2.2.2.2" {
+			return "", errors.New("ssh timeout")
+		}
+		return "", nil
+	}
+
+	results, err := Extend(context.Background(), CommonConfig{SSHUser: "ubuntu", KeyName: "k"}, ExtendOptions{
+		ServersPath: filepath.Join(outputDir, "servers.json"),
+		By:          2 * time.Hour,
+	})
+	if err == nil || !strings.Contains(err.Error(), "2.2.2.2") {
+		t.Fatalf("expected aggregated error for 2.2.2.2, got=%v", err)
+	}
+	if len(results) != 2 || results[0].Err != nil || results[1].Err == nil {
+		t.Fatalf("unexpected results: %+v", results)
+	}
+	if !strings.HasPrefix(cmds["1.1.1.1"], "sudo -n shutdown -h +18") {
+		t.Fatalf("deadline should extend the recorded one (~180m), got=%q", cmds["1.1.1.1"])
+	}
+
+	reloaded, err := LoadOutputManager(outputDir)
+	if err != nil {
+		t.Fatalf("LoadOutputManager: %v", err)
+	}
+	statuses := reloaded.SnapshotStatuses()
+	if len(statuses) != 1 {
+		t.Fatalf("extend must not create statuses for unregistered hosts, got=%d", len(statuses))
+	}
+	if statuses[0].ShutdownAt != results[0].ShutdownAt.Unix() || statuses[0].ShutdownAt <= prev {
+		t.Fatalf("new deadline not recorded: status=%d result=%d prev=%d", statuses[0].ShutdownAt, results[0].ShutdownAt.Unix(), prev)
+	}
+}
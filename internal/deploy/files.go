@@ -0,0 +1,221 @@
+package deploy
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+func checkFilesValid(files []FileConfig) error {
+	seenDest := make(map[string]struct{}, len(files))
+	for i, f := range files {
+		src := strings.TrimSpace(f.Src)
+		if src == "" {
+			return fmt.Errorf("files[%d].src must not be empty", i)
+		}
+		if _, err := os.Stat(src); err != nil {
+			return fmt.Errorf("files[%d].src %q is not accessible: %w", i, src, err)
+		}
+		dest := strings.TrimSpace(f.Dest)
+		if !path.IsAbs(dest) {
+			return fmt.Errorf("files[%d].dest must be an absolute remote path, got %q", i, f.Dest)
+		}
+		dest = path.Clean(dest)
+		if dest == "/" {
+			return fmt.Errorf("files[%d].dest must not be /", i)
+		}
+		if _, ok := seenDest[dest]; ok {
+			return fmt.Errorf("files[%d]: duplicate dest %q", i, dest)
+		}
+		seenDest[dest] = struct{}{}
+	}
+	return nil
+}
+
+// fileDigest 为一个待上传文件的远端路径与本地 sha256。
+type fileDigest struct {
+	RemotePath string
+	SHA256     string
+}
+
+// buildFileDigests 计算一项 files 配置下全部普通文件的 sha256：
+// Src 为文件时对应 Dest 本身，为目录时对应 Dest 下的同名相对路径（符号链接按目标内容计算，与上传行为一致）。
+func buildFileDigests(f FileConfig) (isDir bool, digests []fileDigest, err error) {
+	src := strings.TrimSpace(f.Src)
+	dest := path.Clean(strings.TrimSpace(f.Dest))
+	info, err := os.Stat(src)
+	if err != nil {
+		return false, nil, err
+	}
+	if !info.IsDir() {
+		sum, err := sha256File(src)
+		if err != nil {
+			return false, nil, err
+		}
+		return false, []fileDigest{{RemotePath: dest, SHA256: sum}}, nil
+	}
+
+	err = filepath.WalkDir(src, func(p string, entry fs.DirEntry, walkErr error) error {
+		if walkErr != nil {
+			return walkErr
+		}
+		if entry.IsDir() {
+			return nil
+		}
+		st, err := os.Stat(p)
+		if err != nil {
+			return err
+		}
+		if !st.Mode().IsRegular() {
+			return nil
+		}
+		rel, err := filepath.Rel(src, p)
+		if err != nil {
+			return err
+		}
+		sum, err := sha256File(p)
+		if err != nil {
+			return err
+		}
+		digests = append(digests, fileDigest{RemotePath: path.Join(dest, filepath.ToSlash(rel)), SHA256: sum})
+		return nil
+	})
+	if err != nil {
+		return true, nil, err
+	}
+	sort.Slice(digests, func(i, j int) bool { return digests[i].RemotePath < digests[j].RemotePath })
+	return true, digests, nil
+}
+
+func sha256File(p string) (string, error) {
+	file, err := os.Open(p)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, file); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// buildRemoteSHA256Command 生成远端校验命令：逐个输出 "<sha256>  <path>"，缺失文件不中断整体输出。
+func buildRemoteSHA256Command(digests []fileDigest) string {
+	quoted := make([]string, 0, len(digests))
+	for _, d := range digests {
+		quoted = append(quoted, shellQuote(d.RemotePath))
+	}
+	return fmt.Sprintf("sha256sum -- %s 2>/dev/null; true", strings.Join(quoted, " "))
+}
+
+// parseSHA256SumOutput 解析 sha256sum 输出，返回 path -> sha256。
+func parseSHA256SumOutput(out string) map[string]string {
+	sums := make(map[string]string)
+	scanner := bufio.NewScanner(strings.NewReader(out))
+	for scanner.Scan() {
+		line := scanner.Text()
+		sum, p, ok := strings.Cut(line, "  ")
+		if !ok {
+			continue
+		}
+		sums[p] = strings.TrimSpace(sum)
+	}
+	return sums
+}
+
+// compareFileDigests 对比本地与远端 sha256，返回不一致（含远端缺失）的文件说明。
+func compareFileDigests(digests []fileDigest, remote map[string]string) []string {
+	var mismatched []string
+	for _, d := range digests {
+		got, ok := remote[d.RemotePath]
+		switch {
+		case !ok:
+			mismatched = append(mismatched, fmt.Sprintf("%s（远端缺失）", d.RemotePath))
+		case got != d.SHA256:
+			mismatched = append(mismatched, fmt.Sprintf("%s（期望 %s，实际 %s）", d.RemotePath, d.SHA256, got))
+		}
+	}
+	return mismatched
+}
+
+// uploadServiceFiles 依次上传 files 到 ip 并校验 sha256，任一项失败即返回错误。
+func uploadServiceFiles(ctx context.Context, user, keyPath, ip string, files []FileConfig) error {
+	for i, f := range files {
+		if err := uploadFile(ctx, user, keyPath, ip, f); err != nil {
+			return fmt.Errorf("files[%d] %s -> %s: %w", i, f.Src, f.Dest, err)
+		}
+	}
+	return nil
+}
+
+func uploadFile(ctx context.Context, user, keyPath, ip string, f FileConfig) error {
+	isDir, digests, err := buildFileDigests(f)
+	if err != nil {
+		return fmt.Errorf("计算本地 sha256 失败: %w", err)
+	}
+	src := strings.TrimSpace(f.Src)
+	dest := path.Clean(strings.TrimSpace(f.Dest))
+
+	// rsync 不会自动创建多级父目录，先在远端 mkdir -p
+	mkdirTarget := path.Dir(dest)
+	if isDir {
+		mkdirTarget = dest
+		src = strings.TrimRight(src, string(filepath.Separator)) + string(filepath.Separator)
+		dest += "/"
+	}
+	if _, err := runSSH(ctx, user, keyPath, ip, fmt.Sprintf("mkdir -p %s", shellQuote(mkdirTarget))); err != nil {
+		return fmt.Errorf("创建远端目录失败: %w", err)
+	}
+	if err := rsyncUpload(ctx, user, keyPath, ip, src, dest); err != nil {
+		return err
+	}
+	if len(digests) == 0 {
+		return nil
+	}
+
+	out, err := runSSH(ctx, user, keyPath, ip, buildRemoteSHA256Command(digests))
+	if err != nil {
+		return fmt.Errorf("远端 sha256 校验失败: %w", err)
+	}
+	if mismatched := compareFileDigests(digests, parseSHA256SumOutput(out)); len(mismatched) > 0 {
+		return errors.New("sha256 校验不一致: " + strings.Join(mismatched, "; "))
+	}
+	return nil
+}
+
+// rsyncUpload 将本地 src 上传到远端 dest；-L 使符号链接按目标内容上传，与本地 sha256 计算一致。
+func rsyncUpload(ctx context.Context, user, keyPath, ip, src, dest string) error {
+	dst := fmt.Sprintf("%s@%s:%s", user, ip, dest)
+	cmd := exec.CommandContext(ctx, "rsync", "-azL", "-e", buildRsyncSSHSpec(keyPath), src, dst)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("rsync 上传失败: %w, output=%s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// provisionFiles 在远程命令启动前上传服务配置的 files。
+func (d *Deployer) provisionFiles(ip string, svc ServiceConfig, logPrefix string) error {
+	if len(svc.Files) == 0 {
+		return nil
+	}
+	log.Printf("%s 上传 %d 项本地文件...\n", logPrefix, len(svc.Files))
+	if err := uploadServiceFiles(d.ctx, d.cfg.CommonConfig.SSHUser, d.sshKeyPath, ip, svc.Files); err != nil {
+		return fmt.Errorf("上传文件失败: %w", err)
+	}
+	log.Printf("%s 上传本地文件完成（sha256 校验通过）\n", logPrefix)
+	return nil
+}
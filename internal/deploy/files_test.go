@@ -0,0 +1,101 @@
+package deploy
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCheckFilesValid(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	src := filepath.Join(dir, "patch.sh")
+	if err := os.WriteFile(src, []byte("echo hi\n"), 0o755); err != nil {
+		t.Fatalf("write file: %v", err)
+	}
+
+	cases := []struct {
+		name    string
+		files   []FileConfig
+		wantErr string
+	}{
+		{name: "ok", files: []FileConfig{{Src: src, Dest: "/opt/patch.sh"}, {Src: dir, Dest: "/opt/dir"}}},
+		{name: "empty src", files: []FileConfig{{Dest: "/opt/a"}}, wantErr: "files[0].src must not be empty"},
+		{name: "missing src", files: []FileConfig{{Src: filepath.Join(dir, "nope"), Dest: "/opt/a"}}, wantErr: "is not accessible"},
+		{name: "relative dest", files: []FileConfig{{Src: src, Dest: "opt/a"}}, wantErr: "absolute remote path"},
+		{name: "root dest", files: []FileConfig{{Src: dir, Dest: "/"}}, wantErr: "must not be /"},
+		{name: "duplicate dest", files: []FileConfig{{Src: src, Dest: "/opt/a"}, {Src: src, Dest: "/opt/a/"}}, wantErr: "files[1]: duplicate dest"},
+	}
+	for _, tc := range cases {
+		err := checkFilesValid(tc.files)
+		if tc.wantErr == "" {
+			if err != nil {
+				t.Fatalf("%s: unexpected err: %v", tc.name, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+			t.Fatalf("%s: expected err containing %q, got %v", tc.name, tc.wantErr, err)
+		}
+	}
+}
+
+func TestBuildFileDigests_Directory(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "sub"), 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "a.env"), []byte("A=1\n"), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "sub", "b.sh"), []byte(""), 0o755); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	isDir, digests, err := buildFileDigests(FileConfig{Src: dir, Dest: "/opt/files/"})
+	if err != nil {
+		t.Fatalf("buildFileDigests: %v", err)
+	}
+	if !isDir || len(digests) != 2 {
+		t.Fatalf("unexpected digests: isDir=%v %+v", isDir, digests)
+	}
+	if digests[0].RemotePath != "/opt/files/a.env" || digests[1].RemotePath != "/opt/files/sub/b.sh" {
+		t.Fatalf("unexpected remote paths: %+v", digests)
+	}
+	// sha256("")
+	if digests[1].SHA256 != "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855" {
+		t.Fatalf("unexpected sha256: %s", digests[1].SHA256)
+	}
+
+	isDir, digests, err = buildFileDigests(FileConfig{Src: filepath.Join(dir, "a.env"), Dest: "/etc/app.env"})
+	if err != nil || isDir || len(digests) != 1 || digests[0].RemotePath != "/etc/app.env" {
+		t.Fatalf("unexpected single file digests: isDir=%v %+v err=%v", isDir, digests, err)
+	}
+}
+
+func TestCompareFileDigests_FromSHA256SumOutput(t *testing.T) {
+	t.Parallel()
+
+	digests := []fileDigest{
+		{RemotePath: "/opt/a", SHA256: "aaa"},
+		{RemotePath: "/opt/b c", SHA256: "bbb"},
+		{RemotePath: "/opt/missing", SHA256: "ccc"},
+	}
+	cmd := buildRemoteSHA256Command(digests)
+	if cmd != "sha256sum -- '/opt/a' '/opt/b c' '/opt/missing' 2>/dev/null; true" {
+		t.Fatalf("unexpected command: %s", cmd)
+	}
+
+	out := "aaa  /opt/a\nxxx  /opt/b c\n"
+	mismatched := compareFileDigests(digests, parseSHA256SumOutput(out))
+	if len(mismatched) != 2 {
+		t.Fatalf("expected 2 mismatches, got %v", mismatched)
+	}
+	if !strings.Contains(mismatched[0], "/opt/b c（期望 bbb，实际 xxx）") || !strings.Contains(mismatched[1], "/opt/missing（远端缺失）") {
+		t.Fatalf("unexpected mismatches: %v", mismatched)
+	}
+}
@@ -0,0 +1,220 @@
+package deploy
+
+import (
+	"bufio"
+	"fmt"
+	"log"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// HostFingerprint 为 SSH 就绪后采集的远端环境指纹，用于排查同一服务节点间的 AMI / 软件版本漂移。
+type HostFingerprint struct {
+	IP          string `json:"ip"`
+	ServiceType string `json:"serviceType"`
+	Name        string `json:"name,omitempty"`
+
+	OS          string `json:"os,omitempty"`
+	Kernel      string `json:"kernel,omitempty"`
+	Arch        string `json:"arch,omitempty"`
+	CPUs        int    `json:"cpus,omitempty"`
+	MemTotalMiB int64  `json:"memTotalMiB,omitempty"`
+	// DiskFreeMiB 为 $HOME 所在文件系统的剩余空间。
+	DiskFreeMiB int64  `json:"diskFreeMiB,omitempty"`
+	Docker      string `json:"docker,omitempty"`
+	Kurtosis    string `json:"kurtosis,omitempty"`
+	// RepoCommit 为采集时远端部署仓库的 HEAD（即 AMI 自带的版本，尚未执行 git pull / checkout）。
+	RepoCommit string `json:"repoCommit,omitempty"`
+
+	// Error 非空表示采集失败，其余字段无效。
+	Error       string `json:"error,omitempty"`
+	CollectedAt int64  `json:"collectedAt"`
+}
+
+// buildFingerprintCommand 生成远端采集命令：每行输出一个 key=value，单项失败时值为空。
+func buildFingerprintCommand() string {
+	return strings.Join([]string{
+		`echo "os=$(. /etc/os-release 2>/dev/null; echo "$PRETTY_NAME")"`,
+		`echo "kernel=$(uname -r 2>/dev/null)"`,
+		`echo "arch=$(uname -m 2>/dev/null)"`,
+		`echo "cpus=$(nproc 2>/dev/null)"`,
+		`echo "memKiB=$(awk '/^MemTotal:/{print $2}' /proc/meminfo 2>/dev/null)"`,
+		`echo "diskFreeKiB=$(df -Pk "$HOME" 2>/dev/null | awk 'NR==2{print $4}')"`,
+		`echo "docker=$(docker --version 2>/dev/null)"`,
+		`echo "kurtosis=$(timeout 10 kurtosis version 2>/dev/null | head -n 1)"`,
+		fmt.Sprintf(`echo "repoCommit=$(git -C %s rev-parse HEAD 2>/dev/null)"`, remoteRepoDirDefault),
+	}, "; ")
+}
+
+var fingerprintVersionPattern = regexp.MustCompile(`\d+\.\d+(\.\d+)?[0-9A-Za-z.+-]*`)
+
+// parseFingerprintOutput 解析 buildFingerprintCommand 的输出，未知 key 与无法解析的数值忽略。
+func parseFingerprintOutput(out string) HostFingerprint {
+	var fp HostFingerprint
+	scanner := bufio.NewScanner(strings.NewReader(out))
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(scanner.Text(), "=")
+		if !ok {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		switch key {
+		case "os":
+			fp.OS = value
+		case "kernel":
+			fp.Kernel = value
+		case "arch":
+			fp.Arch = value
+		case "cpus":
+			fp.CPUs, _ = strconv.Atoi(value)
+		case "memKiB":
+			if kib, err := strconv.ParseInt(value, 10, 64); err == nil {
+				fp.MemTotalMiB = kib / 1024
+			}
+		case "diskFreeKiB":
+			if kib, err := strconv.ParseInt(value, 10, 64); err == nil {
+				fp.DiskFreeMiB = kib / 1024
+			}
+		case "docker":
+			fp.Docker = fingerprintVersionPattern.FindString(value)
+		case "kurtosis":
+			fp.Kurtosis = fingerprintVersionPattern.FindString(value)
+		case "repoCommit":
+			fp.RepoCommit = value
+		}
+	}
+	return fp
+}
+
+// fingerprintDriftFields 为参与一致性比对的字段；剩余磁盘空间天然因节点而异，不参与比对。
+// 内存按 GiB 取整比较，避免同规格实例因内核保留内存不同产生的细微差异。
+var fingerprintDriftFields = []struct {
+	name  string
+	value func(fp HostFingerprint) string
+}{
+	{"os", func(fp HostFingerprint) string { return fp.OS }},
+	{"kernel", func(fp HostFingerprint) string { return fp.Kernel }},
+	{"arch", func(fp HostFingerprint) string { return fp.Arch }},
+	{"cpus", func(fp HostFingerprint) string { return strconv.Itoa(fp.CPUs) }},
+	{"memGiB", func(fp HostFingerprint) string { return strconv.FormatInt((fp.MemTotalMiB+512)/1024, 10) }},
+	{"docker", func(fp HostFingerprint) string { return fp.Docker }},
+	{"kurtosis", func(fp HostFingerprint) string { return fp.Kurtosis }},
+	{"repoCommit", func(fp HostFingerprint) string { return ShortCommit(fp.RepoCommit) }},
+}
+
+// detectFingerprintDrift 对同一服务的指纹逐字段比对，返回存在多个取值的字段说明（按字段顺序），采集失败的节点不参与比对。
+// 每个取值列出节点数与节点名称（最多 3 个），例如 "kernel: 6.8.0-1015-aws×3 [a,b,c]；6.5.0-1022-aws×1 [d]"。
+func detectFingerprintDrift(fps []HostFingerprint) []string {
+	var drifts []string
+	for _, field := range fingerprintDriftFields {
+		groups := make(map[string][]string)
+		for _, fp := range fps {
+			if fp.Error != "" {
+				continue
+			}
+			v := field.value(fp)
+			if v == "" {
+				v = "<空>"
+			}
+			label := fp.Name
+			if label == "" {
+				label = fp.IP
+			}
+			groups[v] = append(groups[v], label)
+		}
+		if len(groups) <= 1 {
+			continue
+		}
+
+		values := make([]string, 0, len(groups))
+		for v := range groups {
+			values = append(values, v)
+		}
+		sort.Slice(values, func(i, j int) bool {
+			if len(groups[values[i]]) != len(groups[values[j]]) {
+				return len(groups[values[i]]) > len(groups[values[j]])
+			}
+			return values[i] < values[j]
+		})
+		parts := make([]string, 0, len(values))
+		for _, v := range values {
+			nodes := groups[v]
+			sort.Strings(nodes)
+			shown := nodes
+			if len(shown) > 3 {
+				shown = append(shown[:3:3], "...")
+			}
+			parts = append(parts, fmt.Sprintf("%s×%d [%s]", v, len(nodes), strings.Join(shown, ",")))
+		}
+		drifts = append(drifts, fmt.Sprintf("%s: %s", field.name, strings.Join(parts, "；")))
+	}
+	return drifts
+}
+
+var collectFingerprintFunc = func(d *Deployer, ip string) (HostFingerprint, error) {
+	out, err := runSSH(d.ctx, d.cfg.CommonConfig.SSHUser, d.sshKeyPath, ip, buildFingerprintCommand())
+	if err != nil {
+		return HostFingerprint{}, err
+	}
+	return parseFingerprintOutput(out), nil
+}
+
+// probeFingerprints 在 SSH 就绪后采集 servers 的环境指纹并写入 state.json，随后与该服务已记录的全部节点比对，
+// 存在差异时告警。采集失败只记录错误，不阻断部署。
+func (d *Deployer) probeFingerprints(svc ServiceConfig, servers []ServerInfo) {
+	if len(servers) == 0 {
+		return
+	}
+	serviceType := svc.Type.String()
+	log.Printf("👉 [%s] 采集节点环境指纹，节点数=%d...\n", serviceType, len(servers))
+
+	var (
+		mu     sync.Mutex
+		fps    = make([]HostFingerprint, 0, len(servers))
+		failed int
+	)
+	runWithBatchLimit("probe-fingerprint", len(servers), d.sshMaxConcurrency(), func(idx int) {
+		s := servers[idx]
+		var fp HostFingerprint
+		err := withSSHToken(d.ctx, d.sshBudget(), s.IP, "probe-fingerprint", func() error {
+			var collectErr error
+			fp, collectErr = collectFingerprintFunc(d, s.IP)
+			return collectErr
+		})
+		fp.IP, fp.ServiceType, fp.Name = s.IP, serviceType, s.Name
+		fp.CollectedAt = time.Now().Unix()
+		if err != nil {
+			fp.Error = err.Error()
+			log.Printf("⚠️ [%s][%s] 采集环境指纹失败: %v\n", s.IP, s.Name, err)
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		fps = append(fps, fp)
+		if err != nil {
+			failed++
+		}
+	})
+	if err := d.outputMgr.UpsertFingerprints(fps); err != nil {
+		log.Printf("⚠️ [%s] 写入环境指纹失败: %v\n", serviceType, err)
+	}
+
+	var all []HostFingerprint
+	for _, fp := range d.outputMgr.SnapshotFingerprints() {
+		if fp.ServiceType == serviceType {
+			all = append(all, fp)
+		}
+	}
+	drifts := detectFingerprintDrift(all)
+	for _, drift := range drifts {
+		log.Printf("⚠️ [%s] 节点环境不一致 %s\n", serviceType, drift)
+	}
+	if len(drifts) == 0 {
+		log.Printf("✅ [%s] 环境指纹采集完成，成功=%d，失败=%d，节点环境一致\n", serviceType, len(fps)-failed, failed)
+	} else {
+		log.Printf("⚠️ [%s] 环境指纹采集完成，成功=%d，失败=%d，%d 项不一致（详见 state.json 的 fingerprints）\n", serviceType, len(fps)-failed, failed, len(drifts))
+	}
+}
@@ -0,0 +1,170 @@
+package deploy
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/wangdayong228/ydyl-deploy-client/internal/constants/enums"
+)
+
+func TestParseFingerprintOutput(t *testing.T) {
+	t.Parallel()
+
+	out := strings.Join([]string{
+		"os=Ubuntu 22.04.4 LTS",
+		"kernel=6.5.0-1022-aws",
+		"arch=x86_64",
+		"cpus=16",
+		"memKiB=32386012",
+		"diskFreeKiB=209715200",
+		"docker=Docker version 27.3.1, build ce12230",
+		"kurtosis=CLI Version:   1.4.3",
+		"repoCommit=0123456789abcdef0123456789abcdef01234567",
+		"ignored line",
+	}, "\n")
+	fp := parseFingerprintOutput(out)
+	if fp.OS != "Ubuntu 22.04.4 LTS" || fp.Kernel != "6.5.0-1022-aws" || fp.Arch != "x86_64" || fp.CPUs != 16 {
+		t.Fatalf("unexpected host fields: %+v", fp)
+	}
+	if fp.MemTotalMiB != 31626 || fp.DiskFreeMiB != 204800 {
+		t.Fatalf("unexpected mem/disk: %+v", fp)
+	}
+	if fp.Docker != "27.3.1" || fp.Kurtosis != "1.4.3" || fp.RepoCommit != "0123456789abcdef0123456789abcdef01234567" {
+		t.Fatalf("unexpected versions: %+v", fp)
+	}
+
+	// 未安装 kurtosis / 仓库不存在时对应字段为空
+	fp = parseFingerprintOutput("kernel=6.5.0\nkurtosis=\nrepoCommit=\ncpus=abc\n")
+	if fp.Kernel != "6.5.0" || fp.Kurtosis != "" || fp.RepoCommit != "" || fp.CPUs != 0 {
+		t.Fatalf("unexpected partial fingerprint: %+v", fp)
+	}
+}
+
+func TestDetectFingerprintDrift(t *testing.T) {
+	t.Parallel()
+
+	base := HostFingerprint{OS: "Ubuntu 22.04", Kernel: "6.5.0", Arch: "x86_64", CPUs: 16, MemTotalMiB: 31626, DiskFreeMiB: 1000, Docker: "27.3.1", RepoCommit: "aaaaaaaaaaaa"}
+	a, b, c, d := base, base, base, base
+	a.Name, b.Name, c.Name, d.Name = "ydyl-op-1", "ydyl-op-2", "ydyl-op-3", "ydyl-op-4"
+	b.DiskFreeMiB = 2000 // 剩余磁盘不参与比对
+	b.MemTotalMiB = 31700
+	if drifts := detectFingerprintDrift([]HostFingerprint{a, b}); len(drifts) != 0 {
+		t.Fatalf("expected no drift, got %v", drifts)
+	}
+
+	c.Kernel = "6.8.0"
+	c.RepoCommit = "bbbbbbbbbbbb"
+	d.Error = "ssh 失败" // 采集失败的节点不参与比对
+	d.Kernel = "9.9.9"
+	drifts := detectFingerprintDrift([]HostFingerprint{a, b, c, d})
+	if len(drifts) != 2 {
+		t.Fatalf("expected kernel and repoCommit drift, got %v", drifts)
+	}
+	if drifts[0] != "kernel: 6.5.0×2 [ydyl-op-1,ydyl-op-2]；6.8.0×1 [ydyl-op-3]" {
+		t.Fatalf("unexpected kernel drift: %s", drifts[0])
+	}
+	if !strings.HasPrefix(drifts[1], "repoCommit: aaaaaaaa×2") {
+		t.Fatalf("unexpected repoCommit drift: %s", drifts[1])
+	}
+}
+
+func TestOutputManager_FingerprintsPersistAndFollowNodes(t *testing.T) {
+	t.Parallel()
+
+	outputDir := t.TempDir()
+	mgr := NewOutputManager(outputDir)
+	if err := mgr.UpsertFingerprints([]HostFingerprint{
+		{IP: "1.1.1.1", ServiceType: "op", Name: "ydyl-op-1", Kernel: "6.5.0"},
+		{IP: "1.1.1.2", ServiceType: "op", Name: "ydyl-op-2", Kernel: "6.5.0"},
+	}); err != nil {
+		t.Fatalf("UpsertFingerprints: %v", err)
+	}
+	if err := mgr.UpsertFingerprints([]HostFingerprint{{IP: "1.1.1.1", ServiceType: "op", Name: "ydyl-op-1", Kernel: "6.8.0"}}); err != nil {
+		t.Fatalf("UpsertFingerprints: %v", err)
+	}
+
+	reloaded, err := LoadOutputManager(outputDir)
+	if err != nil {
+		t.Fatalf("LoadOutputManager: %v", err)
+	}
+	got := reloaded.SnapshotFingerprints()
+	if len(got) != 2 || got[0].IP != "1.1.1.1" || got[0].Kernel != "6.8.0" {
+		t.Fatalf("unexpected fingerprints: %+v", got)
+	}
+
+	if err := reloaded.ReplaceIP("op", "1.1.1.2", "1.1.1.3", nil); err != nil {
+		t.Fatalf("ReplaceIP: %v", err)
+	}
+	if err := reloaded.RemoveEntries([]string{compositeKey("1.1.1.1", "op")}); err != nil {
+		t.Fatalf("RemoveEntries: %v", err)
+	}
+	if got := reloaded.SnapshotFingerprints(); len(got) != 0 {
+		t.Fatalf("fingerprints of removed / replaced nodes should be dropped, got %+v", got)
+	}
+}
+
+func TestLoadOutputManager_MigratesSchemaV2(t *testing.T) {
+	t.Parallel()
+
+	outputDir := t.TempDir()
+	content := `{"schemaVersion":2,"revision":5,"servers":[{"ip":"1.1.1.1","serviceType":"op"}],"createdServers":[],"statuses":[],"sshScripts":[]}`
+	if err := os.WriteFile(filepath.Join(outputDir, stateFileName), []byte(content), 0o644); err != nil {
+		t.Fatalf("write state: %v", err)
+	}
+	mgr, err := LoadOutputManager(outputDir)
+	if err != nil {
+		t.Fatalf("LoadOutputManager: %v", err)
+	}
+	if len(mgr.SnapshotServers()) != 1 || len(mgr.SnapshotFingerprints()) != 0 {
+		t.Fatalf("unexpected migrated state")
+	}
+	if err := mgr.UpsertFingerprints([]HostFingerprint{{IP: "1.1.1.1", ServiceType: "op"}}); err != nil {
+		t.Fatalf("UpsertFingerprints: %v", err)
+	}
+	if doc := readStateFile(t, outputDir); doc.SchemaVersion != stateSchemaVersion || len(doc.Fingerprints) != 1 {
+		t.Fatalf("unexpected state after write: version=%d fingerprints=%d", doc.SchemaVersion, len(doc.Fingerprints))
+	}
+}
+
+func TestProbeFingerprints_SharesSSHBudget(t *testing.T) {
+	d := &Deployer{
+		ctx:       context.Background(),
+		cfg:       DeployConfig{CommonConfig: CommonConfig{SSHMaxConcurrency: 4}},
+		outputMgr: NewOutputManager(t.TempDir()),
+	}
+	// 其它服务已占用 3 个令牌，指纹采集最多只能同时占用剩余 1 个
+	budget := d.sshBudget()
+	for i := 0; i < 3; i++ {
+		budget <- struct{}{}
+	}
+
+	orig := collectFingerprintFunc
+	t.Cleanup(func() { collectFingerprintFunc = orig })
+	var inflight, peak atomic.Int32
+	collectFingerprintFunc = func(_ *Deployer, _ string) (HostFingerprint, error) {
+		n := inflight.Add(1)
+		defer inflight.Add(-1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+		return HostFingerprint{OS: "Ubuntu"}, nil
+	}
+
+	servers := []ServerInfo{{IP: "1.1.1.1", Name: "a"}, {IP: "1.1.1.2", Name: "b"}, {IP: "1.1.1.3", Name: "c"}, {IP: "1.1.1.4", Name: "d"}}
+	d.probeFingerprints(ServiceConfig{Type: enums.ServiceTypeOP}, servers)
+	if got := peak.Load(); got != 1 {
+		t.Fatalf("fingerprint probes must respect the shared SSH budget, peak=%d", got)
+	}
+	if got := len(d.outputMgr.SnapshotFingerprints()); got != 4 {
+		t.Fatalf("unexpected fingerprints count: %d", got)
+	}
+}
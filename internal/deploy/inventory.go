@@ -0,0 +1,191 @@
+package deploy
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+var describeTaggedInstancesFunc = func(ctx context.Context, commonCfg CommonConfig, deploymentID string) ([]TaggedInstance, error) {
+	client, err := newEC2APIFunc(commonCfg.Region)
+	if err != nil {
+		return nil, err
+	}
+	return describeTaggedInstances(ctx, client, deploymentID)
+}
+
+// InventoryOptions 控制 inventory 的查询范围。
+type InventoryOptions struct {
+	// ServersPath 为参与对比的 servers.json，为空时使用 outputDir/servers.json。
+	ServersPath string
+	// DeploymentID 非空时只查询该部署的实例，否则查询所有带部署标签的实例。
+	// 孤儿判定只针对单个部署：为空时取 servers.json 同目录 deployment.json 中的 deploymentId，
+	// 其它部署（含并发运行中的部署）的实例只列出，不视为孤儿。
+	DeploymentID string
+}
+
+// InventoryEntry 为一台带部署标签的实例及其与 servers.json 的对比结果。
+type InventoryEntry struct {
+	TaggedInstance
+	// ServerName 为匹配到的 servers.json 条目名称；为空且属于本部署时表示孤儿实例。
+	ServerName string `json:"serverName,omitempty"`
+	// Foreign 表示实例属于其它部署，servers.json 无法判断其归属，不参与孤儿判定。
+	Foreign bool `json:"foreign,omitempty"`
+}
+
+// Orphan 表示实例带有本部署标签、仍在计费，但不在 servers.json 中（SSH 未就绪被丢弃、补机多出等）。
+func (e InventoryEntry) Orphan() bool {
+	return e.ServerName == "" && !e.Foreign
+}
+
+// InventoryReport 为 inventory 的结果。
+type InventoryReport struct {
+	// DeploymentID 为孤儿判定所针对的部署；为空表示无法确定，此时不判定孤儿。
+	DeploymentID string           `json:"deploymentId,omitempty"`
+	Entries      []InventoryEntry `json:"entries"`
+	// Missing 为 servers.json 中存在、但 EC2 上找不到对应存活实例的条目。
+	Missing []ServerInfo `json:"missing,omitempty"`
+}
+
+// Orphans 返回所有孤儿实例。
+func (r *InventoryReport) Orphans() []InventoryEntry {
+	var out []InventoryEntry
+	for _, e := range r.Entries {
+		if e.Orphan() {
+			out = append(out, e)
+		}
+	}
+	return out
+}
+
+// Inventory 列出所有带部署标签的存活实例，并与 servers.json 对比找出孤儿实例。
+func Inventory(ctx context.Context, commonCfg CommonConfig, opts InventoryOptions) (*InventoryReport, error) {
+	serversPath := strings.TrimSpace(opts.ServersPath)
+	if serversPath == "" {
+		serversPath = filepath.Join(resolveOutputDir(commonCfg, ""), "servers.json")
+	}
+
+	var servers []ServerInfo
+	if _, statErr := os.Stat(serversPath); statErr == nil {
+		loaded, err := loadServersFromFile(serversPath)
+		if err != nil {
+			return nil, err
+		}
+		servers = loaded
+	} else if os.IsNotExist(statErr) {
+		log.Printf("⚠️ [inventory] 未找到 %s，本部署所有带标签实例都将视为孤儿\n", serversPath)
+	} else {
+		return nil, fmt.Errorf("读取 servers.json 失败: %w", statErr)
+	}
+
+	scopeID := strings.TrimSpace(opts.DeploymentID)
+	if scopeID == "" {
+		info, err := LoadDeploymentInfo(filepath.Dir(serversPath))
+		if err != nil {
+			return nil, err
+		}
+		if info != nil {
+			scopeID = info.DeploymentID
+		}
+		if scopeID == "" {
+			log.Printf("⚠️ [inventory] 未找到 deployment.json 中的 deploymentId，不判定孤儿实例；请通过 --deployment-id 指定\n")
+		}
+	}
+
+	instances, err := describeTaggedInstancesFunc(ctx, commonCfg, opts.DeploymentID)
+	if err != nil {
+		return nil, err
+	}
+	return diffInventory(instances, servers, scopeID), nil
+}
+
+// diffInventory 以公网 IP（及 service-type 标签）将实例与 servers.json 对齐；
+// 只有 deploymentID 的实例参与孤儿判定，其它部署的实例标记为 Foreign。
+func diffInventory(instances []TaggedInstance, servers []ServerInfo, deploymentID string) *InventoryReport {
+	byIP := make(map[string][]int, len(servers))
+	for i, s := range servers {
+		ip := strings.TrimSpace(s.IP)
+		if ip == "" {
+			continue
+		}
+		byIP[ip] = append(byIP[ip], i)
+	}
+
+	matched := make([]bool, len(servers))
+	report := &InventoryReport{DeploymentID: deploymentID, Entries: make([]InventoryEntry, 0, len(instances))}
+	for _, inst := range instances {
+		entry := InventoryEntry{TaggedInstance: inst}
+		if inst.DeploymentID != deploymentID || deploymentID == "" {
+			entry.Foreign = true
+			report.Entries = append(report.Entries, entry)
+			continue
+		}
+		for _, idx := range byIP[inst.PublicIP] {
+			s := servers[idx]
+			if inst.ServiceType != "" && !strings.EqualFold(inst.ServiceType, strings.TrimSpace(s.ServiceType)) {
+				continue
+			}
+			matched[idx] = true
+			entry.ServerName = s.Name
+			if entry.ServerName == "" {
+				entry.ServerName = s.IP
+			}
+			break
+		}
+		report.Entries = append(report.Entries, entry)
+	}
+
+	for i, s := range servers {
+		if !matched[i] && strings.TrimSpace(s.IP) != "" {
+			report.Missing = append(report.Missing, s)
+		}
+	}
+
+	sort.SliceStable(report.Entries, func(i, j int) bool {
+		a, b := report.Entries[i], report.Entries[j]
+		if a.Orphan() != b.Orphan() {
+			return a.Orphan()
+		}
+		if a.DeploymentID != b.DeploymentID {
+			return a.DeploymentID < b.DeploymentID
+		}
+		return a.LaunchTime.Before(b.LaunchTime)
+	})
+	return report
+}
+
+// TerminateOrphans 终止 report 中的所有孤儿实例（仅限 report.DeploymentID 对应的部署），返回提交终止的实例 ID。
+func TerminateOrphans(ctx context.Context, commonCfg CommonConfig, report *InventoryReport) ([]string, error) {
+	if report.DeploymentID == "" {
+		return nil, fmt.Errorf("无法确定孤儿实例所属部署，请通过 --deployment-id 指定")
+	}
+	orphans := report.Orphans()
+	if len(orphans) == 0 {
+		return nil, nil
+	}
+	ids := make([]string, 0, len(orphans))
+	for _, o := range orphans {
+		ids = append(ids, o.InstanceID)
+	}
+
+	client, err := newEC2APIFunc(commonCfg.Region)
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("👉 [inventory] 开始终止 %d 台孤儿实例\n", len(ids))
+	failed := terminateInstances(ctx, client, ids)
+	if len(failed) > 0 {
+		errs := make([]error, 0, len(failed))
+		for _, id := range ids {
+			if err, ok := failed[id]; ok {
+				errs = append(errs, fmt.Errorf("[%s] %w", id, err))
+			}
+		}
+		return nil, deployMultiError{errs: errs}
+	}
+	return ids, nil
+}
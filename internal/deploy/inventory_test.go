@@ -0,0 +1,105 @@
+package deploy
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestDiffInventory(t *testing.T) {
+	t.Parallel()
+
+	base := time.Unix(1000, 0)
+	instances := []TaggedInstance{
+		{InstanceID: "i-managed", PublicIP: "1.1.1.1", ServiceType: "op", DeploymentID: "dep-2", LaunchTime: base},
+		{InstanceID: "i-ssh-failed", PublicIP: "9.9.9.9", ServiceType: "op", DeploymentID: "dep-2", LaunchTime: base.Add(time.Minute)},
+		{InstanceID: "i-type-mismatch", PublicIP: "2.2.2.2", ServiceType: "cdk", DeploymentID: "dep-2", LaunchTime: base},
+		{InstanceID: "i-stopped", State: "stopped", DeploymentID: "dep-1", LaunchTime: base},
+	}
+	servers := []ServerInfo{
+		{IP: "1.1.1.1", ServiceType: "op", Name: "ydyl-op-1"},
+		{IP: "2.2.2.2", ServiceType: "op", Name: "ydyl-op-2"},
+		{IP: "3.3.3.3", ServiceType: "xjst", Name: "ydyl-xjst-1-1"},
+	}
+
+	report := diffInventory(instances, servers, "dep-2")
+
+	orphans := report.Orphans()
+	if len(orphans) != 2 {
+		t.Fatalf("expected 2 orphans, got=%+v", orphans)
+	}
+	// 孤儿优先，按 deploymentId、启动时间排序。
+	wantOrder := []string{"i-type-mismatch", "i-ssh-failed", "i-stopped", "i-managed"}
+	for i, id := range wantOrder {
+		if report.Entries[i].InstanceID != id {
+			t.Fatalf("unexpected order at %d: got=%s want=%s", i, report.Entries[i].InstanceID, id)
+		}
+	}
+	if report.Entries[3].ServerName != "ydyl-op-1" {
+		t.Fatalf("managed instance should carry server name, got=%q", report.Entries[3].ServerName)
+	}
+	if !report.Entries[2].Foreign || report.Entries[2].Orphan() {
+		t.Fatalf("instance from another deployment must not be an orphan: %+v", report.Entries[2])
+	}
+	if len(report.Missing) != 2 || report.Missing[0].Name != "ydyl-op-2" || report.Missing[1].Name != "ydyl-xjst-1-1" {
+		t.Fatalf("unexpected missing servers: %+v", report.Missing)
+	}
+}
+
+func TestInventory_OrphansScopedToOneDeployment(t *testing.T) {
+	orig := describeTaggedInstancesFunc
+	t.Cleanup(func() { describeTaggedInstancesFunc = orig })
+
+	var gotDeploymentID string
+	describeTaggedInstancesFunc = func(_ context.Context, _ CommonConfig, deploymentID string) ([]TaggedInstance, error) {
+		gotDeploymentID = deploymentID
+		return []TaggedInstance{
+			{InstanceID: "i-1", PublicIP: "1.1.1.1", DeploymentID: "dep-1"},
+			{InstanceID: "i-concurrent", PublicIP: "5.5.5.5", DeploymentID: "dep-9"},
+		}, nil
+	}
+
+	outputDir := t.TempDir()
+	report, err := Inventory(context.Background(), CommonConfig{OutputDir: outputDir}, InventoryOptions{DeploymentID: "dep-1"})
+	if err != nil {
+		t.Fatalf("Inventory error: %v", err)
+	}
+	if gotDeploymentID != "dep-1" {
+		t.Fatalf("deployment filter not passed through, got=%q", gotDeploymentID)
+	}
+	if len(report.Orphans()) != 1 || report.Orphans()[0].InstanceID != "i-1" {
+		t.Fatalf("expected only dep-1 instance to be orphan without servers.json, got=%+v", report.Entries)
+	}
+
+	// 未指定 --deployment-id 且没有 deployment.json 时无法确定部署，不判定孤儿，也拒绝终止。
+	report, err = Inventory(context.Background(), CommonConfig{OutputDir: outputDir}, InventoryOptions{})
+	if err != nil {
+		t.Fatalf("Inventory error: %v", err)
+	}
+	if len(report.Orphans()) != 0 {
+		t.Fatalf("orphans must not be reported without a deployment scope, got=%+v", report.Orphans())
+	}
+	if _, err := TerminateOrphans(context.Background(), CommonConfig{}, report); err == nil {
+		t.Fatalf("TerminateOrphans should refuse without a deployment scope")
+	}
+
+	if err := SaveDeploymentInfo(outputDir, DeploymentInfo{DeploymentID: "dep-1"}); err != nil {
+		t.Fatalf("SaveDeploymentInfo: %v", err)
+	}
+
+	if err := os.WriteFile(filepath.Join(outputDir, "servers.json"), []byte(`[{"ip":"1.1.1.1","serviceType":"op","name":"ydyl-op-1"}]`), 0o644); err != nil {
+		t.Fatalf("write servers.json: %v", err)
+	}
+	report, err = Inventory(context.Background(), CommonConfig{OutputDir: outputDir}, InventoryOptions{})
+	if err != nil {
+		t.Fatalf("Inventory error: %v", err)
+	}
+	if report.DeploymentID != "dep-1" {
+		t.Fatalf("deployment scope should default to deployment.json, got=%q", report.DeploymentID)
+	}
+	if len(report.Orphans()) != 0 {
+		t.Fatalf("instance listed in servers.json should be managed and the concurrent deployment ignored, got=%+v", report.Entries)
+	}
+}
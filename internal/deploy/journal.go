@@ -0,0 +1,210 @@
+package deploy
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// eventJournalFileName 为 output 目录下只追加的事件日志（每行一个 JSON），用于事后复盘每个节点的完整时间线。
+const eventJournalFileName = "events.jsonl"
+
+// 事件类型
+const (
+	EventInstanceLaunched = "instance_launched"
+	EventInstanceTagged   = "instance_tagged"
+	EventSSHReady         = "ssh_ready"
+	EventSSHFailed        = "ssh_failed"
+	EventCommandStarted   = "command_started"
+	EventCommandFailed    = "command_failed"
+	EventStatusChanged    = "status_changed"
+	EventRestoreAttempt   = "restore_attempt"
+	EventShutdown         = "shutdown"
+	EventTerminated       = "terminated"
+	EventRemoved          = "removed"
+	EventReplaced         = "replaced"
+	// EventInterrupted 由 deploy 收到中断信号时写入，Message 为该节点最后完成的阶段。
+	EventInterrupted = "interrupted"
+)
+
+// Event 为事件日志中的一条记录，节点身份由 IP + ServiceType（及 Name / InstanceID）标识。
+type Event struct {
+	Time        time.Time `json:"time"`
+	Type        string    `json:"type"`
+	IP          string    `json:"ip,omitempty"`
+	ServiceType string    `json:"serviceType,omitempty"`
+	Name        string    `json:"name,omitempty"`
+	InstanceID  string    `json:"instanceId,omitempty"`
+	PID         int       `json:"pid,omitempty"`
+	// From / To 为状态迁移前后的 ScriptStatus.Status。
+	From     string `json:"from,omitempty"`
+	To       string `json:"to,omitempty"`
+	Attempts uint   `json:"attempts,omitempty"`
+	Message  string `json:"message,omitempty"`
+}
+
+// journalMu 串行化本进程内的追加写；跨进程依赖 O_APPEND 单次 write 的原子性。
+var journalMu sync.Mutex
+
+// appendEvents 将事件追加写入 outputDir/events.jsonl；Time 为空时取当前时间。
+func appendEvents(outputDir string, events ...Event) error {
+	if outputDir == "" || len(events) == 0 {
+		return nil
+	}
+
+	var buf bytes.Buffer
+	now := time.Now()
+	for _, ev := range events {
+		if ev.Time.IsZero() {
+			ev.Time = now
+		}
+		line, err := json.Marshal(ev)
+		if err != nil {
+			return err
+		}
+		buf.Write(line)
+		buf.WriteByte('\n')
+	}
+
+	journalMu.Lock()
+	defer journalMu.Unlock()
+
+	if err := os.MkdirAll(outputDir, 0o755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(filepath.Join(outputDir, eventJournalFileName), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(buf.Bytes()); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// recordEvents 追加事件，失败只打印告警：事件日志用于复盘，不应阻断部署流程。
+func recordEvents(outputDir string, events ...Event) {
+	if err := appendEvents(outputDir, events...); err != nil {
+		log.Printf("⚠️ 写入事件日志 %s 失败: %v\n", eventJournalFileName, err)
+	}
+}
+
+// RecordEvent 向 output 目录的事件日志追加一条事件。
+func (m *OutputManager) RecordEvent(ev Event) {
+	if m == nil {
+		return
+	}
+	recordEvents(m.outputDir, ev)
+}
+
+// statusEvents 根据一条脚本状态修改前后的差异生成事件：PID 变化视为命令（重新）启动，Status 变化视为状态迁移。
+func statusEvents(before ScriptStatus, after *ScriptStatus) []Event {
+	if after == nil {
+		return nil
+	}
+	base := Event{IP: after.IP, ServiceType: after.ServiceType, Name: after.Name}
+
+	var events []Event
+	if after.PID > 0 && after.PID != before.PID {
+		ev := base
+		ev.Type = EventCommandStarted
+		ev.PID = after.PID
+		ev.From = before.Status
+		ev.To = after.Status
+		events = append(events, ev)
+	} else if after.Status != before.Status {
+		ev := base
+		ev.Type = EventStatusChanged
+		ev.PID = after.PID
+		ev.From = before.Status
+		ev.To = after.Status
+		ev.Message = after.Reason
+		events = append(events, ev)
+	}
+	return events
+}
+
+// LoadEvents 读取 outputDir/events.jsonl；无法解析的行（例如进程中断导致的半行）会被跳过并计入 skipped。
+func LoadEvents(outputDir string) (events []Event, skipped int, err error) {
+	f, err := os.Open(filepath.Join(outputDir, eventJournalFileName))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, 0, nil
+		}
+		return nil, 0, fmt.Errorf("读取 %s 失败: %w", eventJournalFileName, err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		var ev Event
+		if json.Unmarshal(line, &ev) != nil {
+			skipped++
+			continue
+		}
+		events = append(events, ev)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, 0, fmt.Errorf("读取 %s 失败: %w", eventJournalFileName, err)
+	}
+	return events, skipped, nil
+}
+
+// NodeTimeline 为单个节点（IP + ServiceType）按时间排序的事件序列。
+type NodeTimeline struct {
+	IP          string
+	ServiceType string
+	// Name 取该节点最近一次出现的逻辑名称（创建阶段的 create-N 名称会被正式名称覆盖）。
+	Name string
+	// LastStatus 为最近一次状态迁移后的脚本状态（不含实例终止等事件）。
+	LastStatus string
+	Events     []Event
+}
+
+// BuildTimelines 按节点聚合事件，节点按首个事件时间排序。
+func BuildTimelines(events []Event) []*NodeTimeline {
+	byKey := make(map[string]*NodeTimeline)
+	var order []*NodeTimeline
+	sorted := append([]Event(nil), events...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Time.Before(sorted[j].Time) })
+
+	for _, ev := range sorted {
+		key := compositeKey(ev.IP, ev.ServiceType)
+		tl, ok := byKey[key]
+		if !ok {
+			tl = &NodeTimeline{IP: ev.IP, ServiceType: ev.ServiceType}
+			byKey[key] = tl
+			order = append(order, tl)
+		}
+		if ev.Name != "" && (tl.Name == "" || ev.Type != EventInstanceLaunched) {
+			tl.Name = ev.Name
+		}
+		if ev.To != "" && (ev.Type == EventStatusChanged || ev.Type == EventCommandStarted || ev.Type == EventReplaced) {
+			tl.LastStatus = ev.To
+		}
+		tl.Events = append(tl.Events, ev)
+	}
+	return order
+}
+
+// LoadTimelines 读取 output 目录（outputDirOverride 优先）的事件日志并按节点聚合，同时返回被跳过的损坏行数。
+func LoadTimelines(commonCfg CommonConfig, outputDirOverride string) ([]*NodeTimeline, int, error) {
+	events, skipped, err := LoadEvents(resolveOutputDir(commonCfg, outputDirOverride))
+	if err != nil {
+		return nil, 0, err
+	}
+	return BuildTimelines(events), skipped, nil
+}
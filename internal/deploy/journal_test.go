@@ -0,0 +1,101 @@
+package deploy
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestOutputManager_RecordsJournalEvents(t *testing.T) {
+	t.Parallel()
+
+	outputDir := t.TempDir()
+	mgr := NewOutputManager(outputDir)
+
+	steps := []func() error{
+		func() error {
+			return mgr.AddCreatedServers([]CreatedServerInfo{{Name: "ydyl-op-create-1", ServiceType: "op", IP: "1.1.1.1", InstanceID: "i-1"}})
+		},
+		// 重复记录不产生事件
+		func() error {
+			return mgr.AddCreatedServers([]CreatedServerInfo{{Name: "ydyl-op-create-1", ServiceType: "op", IP: "1.1.1.1", InstanceID: "i-1"}})
+		},
+		func() error { return mgr.UpdateSSHScriptStatus("1.1.1.1", "op", "", "success", 2, "", 0) },
+		func() error { return mgr.UpsertPlannedStatus("1.1.1.1", "op", "ydyl-op-1", "cmd", "", "", 0) },
+		func() error { return mgr.InitStatus("1.1.1.1", "op", "ydyl-op-1", "cmd", 4242, "", "", 0, 0, "") },
+		// 仅更新日志偏移不产生事件
+		func() error { return mgr.UpdateStatus("1.1.1.1", "op", func(st *ScriptStatus) { st.LogSize = 100 }) },
+		func() error {
+			return mgr.UpdateStatus("1.1.1.1", "op", func(st *ScriptStatus) {
+				st.Status = "failed"
+				st.Reason = "exit 1"
+			})
+		},
+		func() error {
+			return mgr.ReplaceIP("op", "1.1.1.1", "2.2.2.2", func(st *ScriptStatus) { st.Status = "pending" })
+		},
+	}
+	for i, step := range steps {
+		if err := step(); err != nil {
+			t.Fatalf("step %d: %v", i, err)
+		}
+	}
+
+	events, skipped, err := LoadEvents(outputDir)
+	if err != nil || skipped != 0 {
+		t.Fatalf("LoadEvents: skipped=%d err=%v", skipped, err)
+	}
+	var types []string
+	for _, ev := range events {
+		types = append(types, ev.Type)
+		if ev.Time.IsZero() {
+			t.Fatalf("event without timestamp: %+v", ev)
+		}
+	}
+	want := "instance_launched,ssh_ready,status_changed,command_started,status_changed,replaced,replaced"
+	if got := strings.Join(types, ","); got != want {
+		t.Fatalf("unexpected events:\n got=%s\nwant=%s", got, want)
+	}
+	if ev := events[3]; ev.PID != 4242 || ev.From != "pending" || ev.To != "running" {
+		t.Fatalf("unexpected command_started event: %+v", ev)
+	}
+	if ev := events[4]; ev.From != "running" || ev.To != "failed" || ev.Message != "exit 1" {
+		t.Fatalf("unexpected status_changed event: %+v", ev)
+	}
+}
+
+func TestBuildTimelines_GroupsByNode(t *testing.T) {
+	t.Parallel()
+
+	outputDir := t.TempDir()
+	recordEvents(outputDir,
+		Event{Type: EventInstanceLaunched, IP: "1.1.1.1", ServiceType: "op", Name: "ydyl-op-create-1"},
+		Event{Type: EventInstanceLaunched, IP: "1.1.1.2", ServiceType: "op", Name: "ydyl-op-create-2"},
+		Event{Type: EventInstanceTagged, IP: "1.1.1.1", ServiceType: "op", Name: "ydyl-op-1", InstanceID: "i-1"},
+		Event{Type: EventStatusChanged, IP: "1.1.1.1", ServiceType: "op", Name: "ydyl-op-1", From: "running", To: "failed"},
+		Event{Type: EventTerminated, IP: "1.1.1.1", ServiceType: "op", Name: "ydyl-op-1", To: "terminated"},
+	)
+	// 模拟进程中断留下的半行
+	f, err := os.OpenFile(filepath.Join(outputDir, eventJournalFileName), os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		t.Fatalf("open journal: %v", err)
+	}
+	_, _ = f.WriteString(`{"time":"2026-`)
+	_ = f.Close()
+
+	timelines, skipped, err := LoadTimelines(CommonConfig{OutputDir: outputDir}, "")
+	if err != nil {
+		t.Fatalf("LoadTimelines: %v", err)
+	}
+	if skipped != 1 || len(timelines) != 2 {
+		t.Fatalf("unexpected timelines: skipped=%d count=%d", skipped, len(timelines))
+	}
+	first := timelines[0]
+	if first.Name != "ydyl-op-1" || first.LastStatus != "failed" || len(first.Events) != 4 {
+		t.Fatalf("unexpected node timeline: %+v", first)
+	}
+	if timelines[1].Name != "ydyl-op-create-2" {
+		t.Fatalf("launch name should be kept until a formal name appears: %+v", timelines[1])
+	}
+}
@@ -22,6 +22,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/wangdayong228/ydyl-deploy-client/internal/chainstack"
 	"github.com/wangdayong228/ydyl-deploy-client/internal/utils/sshutil"
 )
 
@@ -91,7 +92,7 @@ func CollectLogs(ctx context.Context, commonCfg CommonConfig, opts CollectLogsOp
 		if st == nil {
 			continue
 		}
-		if strings.EqualFold(st.ServiceType, "xjst") && !shouldCollectXjstNodeByName(st.Name) {
+		if !shouldCollectNodeLogs(st) {
 			continue
 		}
 		collectNodeCount++
@@ -124,8 +125,8 @@ func CollectLogs(ctx context.Context, commonCfg CommonConfig, opts CollectLogsOp
 		if st == nil {
 			continue
 		}
-		if strings.EqualFold(st.ServiceType, "xjst") && !shouldCollectXjstNodeByName(st.Name) {
-			log.Printf("ℹ️ [collect-logs] 跳过非 node-1 XJST 节点: %s (%s)\n", st.Name, st.IP)
+		if !shouldCollectNodeLogs(st) {
+			log.Printf("ℹ️ [collect-logs] 跳过非组内主节点的 %s 节点: %s (%s)\n", st.ServiceType, st.Name, st.IP)
 			continue
 		}
 		targets := buildCollectTargets(st)
@@ -234,39 +235,14 @@ func buildCollectTargets(st *ScriptStatus) []remoteLogTarget {
 		},
 	}
 
-	switch strings.ToLower(strings.TrimSpace(st.ServiceType)) {
-	case "cdk":
-		targets = append(targets,
-			remoteLogTarget{
-				Category:    "deploy",
-				RemotePath:  "/home/ubuntu/workspace/ydyl-deployment-suite/cdk-work/scripts/deploy-gen.log",
-				LocalNameGz: "deploy-kurtosis-cdk.log.gz",
-			},
-			remoteLogTarget{
-				Category:    "runtime",
-				RemotePath:  buildRuntimeLogPath(st.Name),
-				LocalNameGz: "runtime.log.gz",
-			},
-		)
-	case "op":
-		targets = append(targets,
-			remoteLogTarget{
-				Category:    "deploy",
-				RemotePath:  "/home/ubuntu/workspace/ydyl-deployment-suite/op-work/scripts/deploy-gen.log",
-				LocalNameGz: "deploy-kurtosis-op.log.gz",
-			},
-			remoteLogTarget{
-				Category:    "runtime",
-				RemotePath:  buildRuntimeLogPath(st.Name),
-				LocalNameGz: "runtime.log.gz",
-			},
-		)
-	case "xjst":
-		targets = append(targets, remoteLogTarget{
-			Category:    "runtime",
-			RemotePath:  buildRuntimeLogPath(st.Name),
-			LocalNameGz: "runtime.log.gz",
-		})
+	if stack, ok := chainstack.LookupName(st.ServiceType); ok {
+		for _, t := range stack.LogTargets(buildRuntimeLogPath(st.Name)) {
+			targets = append(targets, remoteLogTarget{
+				Category:    t.Category,
+				RemotePath:  t.RemotePath,
+				LocalNameGz: t.LocalNameGz,
+			})
+		}
 	}
 	return targets
 }
@@ -527,7 +503,7 @@ func collectLogsWarmupIPs(statuses []*ScriptStatus, benchClientIP string) []stri
 		if st == nil {
 			continue
 		}
-		if strings.EqualFold(st.ServiceType, "xjst") && !shouldCollectXjstNodeByName(st.Name) {
+		if !shouldCollectNodeLogs(st) {
 			continue
 		}
 		add(st.IP)
@@ -537,17 +513,13 @@ func collectLogsWarmupIPs(statuses []*ScriptStatus, benchClientIP string) []stri
 	return ips
 }
 
-func shouldCollectXjstNodeByName(name string) bool {
-	re := regexp.MustCompile(`-(\d+)$`)
-	matches := re.FindStringSubmatch(strings.TrimSpace(name))
-	if len(matches) != 2 {
-		return false
-	}
-	idx, err := strconv.Atoi(matches[1])
-	if err != nil || idx <= 0 {
-		return false
+// shouldCollectNodeLogs 判断是否收集该节点的日志：分组组网的服务（xjst）仅收集组内主节点。
+func shouldCollectNodeLogs(st *ScriptStatus) bool {
+	stack, ok := chainstack.LookupName(st.ServiceType)
+	if !ok || stack.GroupSize() <= 1 {
+		return true
 	}
-	return (idx-1)%4 == 0
+	return chainstack.IsPrimaryName(stack, st.Name)
 }
 
 func probeRemoteFileLines(ctx context.Context, user, keyPath, ip, remotePath string) (int64, bool, error) {
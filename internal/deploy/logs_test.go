@@ -7,7 +7,7 @@ import (
 	"github.com/wangdayong228/ydyl-deploy-client/internal/constants/enums"
 )
 
-func TestShouldCollectXjstNodeByName(t *testing.T) {
+func TestShouldCollectNodeLogs_Xjst(t *testing.T) {
 	t.Parallel()
 
 	cases := []struct {
@@ -27,9 +27,9 @@ func TestShouldCollectXjstNodeByName(t *testing.T) {
 		tc := tc
 		t.Run(tc.name, func(t *testing.T) {
 			t.Parallel()
-			got := shouldCollectXjstNodeByName(tc.in)
+			got := shouldCollectNodeLogs(&ScriptStatus{ServiceType: "xjst", Name: tc.in})
 			if got != tc.want {
-				t.Fatalf("shouldCollectXjstNodeByName(%q)=%v, want=%v", tc.in, got, tc.want)
+				t.Fatalf("shouldCollectNodeLogs(xjst %q)=%v, want=%v", tc.in, got, tc.want)
 			}
 		})
 	}
@@ -240,7 +240,7 @@ func TestBuildRsyncSSHSpec(t *testing.T) {
 func TestBuildRuntimeMonitorCommand(t *testing.T) {
 	t.Parallel()
 
-	cmd, ok := buildRuntimeMonitorCommand(enums.ServiceTypeCDK, 0, "demo-cdk-1")
+	cmd, ok := buildRuntimeMonitorCommand(ServiceConfig{Type: enums.ServiceTypeCDK}, 0, "demo-cdk-1")
 	if !ok || cmd == "" {
 		t.Fatalf("cdk monitor command should be enabled")
 	}
@@ -257,7 +257,7 @@ func TestBuildRuntimeMonitorCommand(t *testing.T) {
 		t.Fatalf("cdk monitor command should pass --stack cdk, got: %s", cmd)
 	}
 
-	cmd, ok = buildRuntimeMonitorCommand(enums.ServiceTypeOP, 0, "demo-op-1")
+	cmd, ok = buildRuntimeMonitorCommand(ServiceConfig{Type: enums.ServiceTypeOP}, 0, "demo-op-1")
 	if !ok || cmd == "" {
 		t.Fatalf("op monitor command should be enabled")
 	}
@@ -265,16 +265,24 @@ func TestBuildRuntimeMonitorCommand(t *testing.T) {
 		t.Fatalf("op monitor command should pass --stack op, got: %s", cmd)
 	}
 
-	cmd, ok = buildRuntimeMonitorCommand(enums.ServiceTypeXJST, 1, "demo-xjst-2")
+	cmd, ok = buildRuntimeMonitorCommand(ServiceConfig{Type: enums.ServiceTypeXJST}, 1, "demo-xjst-2")
 	if ok || cmd != "" {
 		t.Fatalf("xjst non-node1 should not start runtime monitor")
 	}
 
-	cmd, ok = buildRuntimeMonitorCommand(enums.ServiceTypeXJST, 4, "demo-xjst-5")
+	cmd, ok = buildRuntimeMonitorCommand(ServiceConfig{Type: enums.ServiceTypeXJST}, 4, "demo-xjst-5")
 	if !ok || cmd == "" {
 		t.Fatalf("xjst node1 should start runtime monitor")
 	}
 	if strings.Contains(cmd, "--stack") {
 		t.Fatalf("xjst docker monitor command should not pass --stack, got: %s", cmd)
 	}
+
+	xjst7 := ServiceConfig{Type: enums.ServiceTypeXJST, GroupSize: 7}
+	if _, ok = buildRuntimeMonitorCommand(xjst7, 4, "demo-xjst-1-5"); ok {
+		t.Fatalf("xjst groupSize=7 index 4 is not a primary node")
+	}
+	if _, ok = buildRuntimeMonitorCommand(xjst7, 7, "demo-xjst-2-1"); !ok {
+		t.Fatalf("xjst groupSize=7 index 7 should start runtime monitor")
+	}
 }
@@ -1,12 +1,14 @@
 package deploy
 
 import (
-	"encoding/json"
 	"fmt"
+	"log"
 	"os"
-	"path/filepath"
 	"strings"
 	"sync"
+	"time"
+
+	"github.com/gofrs/flock"
 )
 
 // ServerInfo 描述一台服务器在本次部署中的基础信息。
@@ -34,6 +36,20 @@ type ScriptStatus struct {
 	UpdatedAt int64  `json:"updatedAt,omitempty"` // 状态最近更新时间（Unix 秒）
 	// LogSize 记录已经同步的远端日志字节数，用于增量拉取
 	LogSize int64 `json:"logSize,omitempty"`
+	// ShutdownAt 为远端计划自动关机时间（Unix 秒），启动脚本或 extend 时更新；0 表示未记录。
+	ShutdownAt int64 `json:"shutdownAt,omitempty"`
+	// ShutdownCanceled 为 true 表示已通过 extend --cancel 取消自动关机。
+	ShutdownCanceled bool `json:"shutdownCanceled,omitempty"`
+	// RepoCommit 为该节点部署命令 checkout 的部署仓库 commit（配置 repoRef 时记录），为空表示沿用 AMI 分支 git pull。
+	RepoCommit string `json:"repoCommit,omitempty"`
+}
+
+// ShutdownRemaining 返回距离计划关机的剩余时间；未记录或已取消时 ok=false。
+func (s *ScriptStatus) ShutdownRemaining(now time.Time) (time.Duration, bool) {
+	if s == nil || s.ShutdownCanceled || s.ShutdownAt <= 0 {
+		return 0, false
+	}
+	return time.Unix(s.ShutdownAt, 0).Sub(now), true
 }
 
 // SSHScriptStatus 描述 SSH 就绪探测结果。
@@ -52,109 +68,189 @@ type CreatedServerInfo struct {
 	Name        string `json:"name,omitempty"`
 	ServiceType string `json:"serviceType"`
 	IP          string `json:"ip"`
+
+	InstanceID   string `json:"instanceId,omitempty"`
+	InstanceType string `json:"instanceType,omitempty"`
+	LaunchTime   int64  `json:"launchTime,omitempty"` // EC2 启动时间（Unix 秒），用于成本估算
 }
 
-// OutputManager 负责维护 servers.json 和 script_status.json 两个输出文件。
+// OutputManager 负责维护部署状态文件 state.json（及其派生的 servers.json / script_status.json 等旧格式文件）。
+//
+// 多个 CLI 进程（deploy / sync / collect-logs / deploy-restore 等）可以同时操作同一 output 目录：
+// 每次修改都在 advisory 文件锁内重新读取 state.json、应用修改并原子写回，避免互相覆盖；
+// 快照读取时若发现 state.json 已被其它进程更新，会先重新加载。
 type OutputManager struct {
 	outputDir string
 
 	mu               sync.Mutex
+	lock             *flock.Flock
+	stamp            stateFileStamp
+	revision         uint64
 	servers          []ServerInfo
 	createdServers   []CreatedServerInfo
 	createdServerSet map[string]struct{}
 	statuses         map[string]*ScriptStatus
 	sshScripts       map[string]*SSHScriptStatus
+	fingerprints     map[string]*HostFingerprint
+	progress         *DeployProgress
 }
 
+// NewOutputManager 创建一个空状态的 OutputManager；outputDir 非空时首次写入会与目录中已有的 state.json 合并。
 func NewOutputManager(outputDir string) *OutputManager {
-	return &OutputManager{
+	m := &OutputManager{
 		outputDir:        outputDir,
 		createdServerSet: make(map[string]struct{}),
 		statuses:         make(map[string]*ScriptStatus),
 		sshScripts:       make(map[string]*SSHScriptStatus),
+		fingerprints:     make(map[string]*HostFingerprint),
+	}
+	if outputDir != "" {
+		m.lock = newStateLock(outputDir)
 	}
+	return m
 }
 
-// LoadOutputManager 从指定目录下已有的 JSON 文件（servers.json / script_status.json）恢复 OutputManager。
+// LoadOutputManager 从指定目录下已有的 state.json 恢复 OutputManager；
+// 旧版本输出（仅有 servers.json / script_status.json 等分散文件）会在加载时迁移，首次写入时落盘为 state.json。
 // 主要用于进程重启后，基于已有状态重新进行日志与脚本状态同步。
 func LoadOutputManager(outputDir string) (*OutputManager, error) {
-	m := &OutputManager{
-		outputDir:        outputDir,
-		createdServerSet: make(map[string]struct{}),
-		statuses:         make(map[string]*ScriptStatus),
-		sshScripts:       make(map[string]*SSHScriptStatus),
+	m := NewOutputManager(outputDir)
+	if outputDir == "" {
+		return m, nil
+	}
+	if _, err := os.Stat(outputDir); os.IsNotExist(err) {
+		return m, nil
 	}
 
-	// 尝试加载 servers.json（如果不存在则忽略）
-	if outputDir != "" {
-		if data, err := os.ReadFile(filepath.Join(outputDir, "servers.json")); err == nil {
-			var servers []ServerInfo
-			if uErr := json.Unmarshal(data, &servers); uErr != nil {
-				return nil, fmt.Errorf("解析 servers.json 失败: %w", uErr)
-			}
-			m.servers = servers
-		} else if !os.IsNotExist(err) {
-			return nil, fmt.Errorf("读取 servers.json 失败: %w", err)
-		}
+	if err := m.lock.RLock(); err != nil {
+		return nil, fmt.Errorf("获取状态文件锁失败: %w", err)
+	}
+	defer func() { _ = m.lock.Unlock() }()
 
-		// 尝试加载 script_status.json
-		if data, err := os.ReadFile(filepath.Join(outputDir, "script_status.json")); err == nil {
-			var list []*ScriptStatus
-			if uErr := json.Unmarshal(data, &list); uErr != nil {
-				return nil, fmt.Errorf("解析 script_status.json 失败: %w", uErr)
-			}
-			for _, st := range list {
-				if st == nil {
-					continue
-				}
-				key := compositeKey(st.IP, st.ServiceType)
-				m.statuses[key] = st
-			}
-		} else if !os.IsNotExist(err) {
-			return nil, fmt.Errorf("读取 script_status.json 失败: %w", err)
-		}
+	if err := m.reloadLocked(); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
 
-		// 尝试加载 ssh_scripts.json
-		if data, err := os.ReadFile(filepath.Join(outputDir, "ssh_scripts.json")); err == nil {
-			var list []*SSHScriptStatus
-			if uErr := json.Unmarshal(data, &list); uErr != nil {
-				return nil, fmt.Errorf("解析 ssh_scripts.json 失败: %w", uErr)
-			}
-			for _, st := range list {
-				if st == nil {
-					continue
-				}
-				key := compositeKey(st.IP, st.ServiceType)
-				m.sshScripts[key] = st
-			}
-		} else if !os.IsNotExist(err) {
-			return nil, fmt.Errorf("读取 ssh_scripts.json 失败: %w", err)
+// reloadLocked 从磁盘读取状态文档并替换内存状态；调用方需持有 m.mu 与文件锁。
+func (m *OutputManager) reloadLocked() error {
+	stamp, _ := statStateFile(m.outputDir)
+	doc, err := loadStateDocument(m.outputDir)
+	if err != nil {
+		return err
+	}
+
+	m.stamp = stamp
+	m.revision = doc.Revision
+	m.servers = doc.Servers
+	m.createdServers = nil
+	m.createdServerSet = make(map[string]struct{})
+	m.statuses = make(map[string]*ScriptStatus)
+	m.sshScripts = make(map[string]*SSHScriptStatus)
+	m.fingerprints = make(map[string]*HostFingerprint)
+	m.progress = doc.Progress
+
+	for _, created := range doc.CreatedServers {
+		_, _ = m.addCreatedServerLocked(created)
+	}
+	for _, st := range doc.Statuses {
+		if st == nil {
+			continue
+		}
+		m.statuses[compositeKey(st.IP, st.ServiceType)] = st
+	}
+	for _, st := range doc.SSHScripts {
+		if st == nil {
+			continue
+		}
+		m.sshScripts[compositeKey(st.IP, st.ServiceType)] = st
+	}
+	for _, fp := range doc.Fingerprints {
+		if fp == nil {
+			continue
 		}
+		m.fingerprints[compositeKey(fp.IP, fp.ServiceType)] = fp
+	}
+	return nil
+}
 
-		// 尝试加载 servers_create.json
-		if data, err := os.ReadFile(filepath.Join(outputDir, "servers_create.json")); err == nil {
-			var list []CreatedServerInfo
-			if uErr := json.Unmarshal(data, &list); uErr != nil {
-				return nil, fmt.Errorf("解析 servers_create.json 失败: %w", uErr)
-			}
-			for _, created := range list {
-				normalized := normalizeCreatedServerInfo(created)
-				if normalized.IP == "" || normalized.ServiceType == "" {
-					continue
-				}
-				key := compositeKey(normalized.IP, normalized.ServiceType)
-				if _, exists := m.createdServerSet[key]; exists {
-					continue
-				}
-				m.createdServerSet[key] = struct{}{}
-				m.createdServers = append(m.createdServers, normalized)
-			}
-		} else if !os.IsNotExist(err) {
-			return nil, fmt.Errorf("读取 servers_create.json 失败: %w", err)
+// mutate 在文件写锁内执行“重新加载 -> 修改 -> 原子写回”，调用方需持有 m.mu。
+// state.json 尚不存在时保留内存状态（NewOutputManager 的新部署或尚未写入的迁移结果）。
+func (m *OutputManager) mutate(fn func()) error {
+	if m.outputDir == "" {
+		fn()
+		return nil
+	}
+	if err := os.MkdirAll(m.outputDir, 0o755); err != nil {
+		return err
+	}
+	if err := m.lock.Lock(); err != nil {
+		return fmt.Errorf("获取状态文件锁失败: %w", err)
+	}
+	defer func() { _ = m.lock.Unlock() }()
+
+	if _, exists := statStateFile(m.outputDir); exists {
+		if err := m.reloadLocked(); err != nil {
+			return err
 		}
 	}
 
-	return m, nil
+	fn()
+
+	m.revision++
+	doc := &deploymentState{
+		SchemaVersion:  stateSchemaVersion,
+		Revision:       m.revision,
+		UpdatedAt:      time.Now().Unix(),
+		Servers:        m.servers,
+		CreatedServers: m.createdServers,
+		Statuses:       sortedStatuses(m.statuses),
+		SSHScripts:     sortedSSHScripts(m.sshScripts),
+		Fingerprints:   sortedFingerprints(m.fingerprints),
+		Progress:       m.progress,
+	}
+	if err := saveStateDocument(m.outputDir, doc); err != nil {
+		return err
+	}
+	m.stamp, _ = statStateFile(m.outputDir)
+	return nil
+}
+
+// refreshLocked 在 state.json 被其它进程更新后重新加载内存状态，调用方需持有 m.mu。
+// 快照接口没有错误返回，加载失败时打印告警并沿用内存状态。
+func (m *OutputManager) refreshLocked() {
+	if m.outputDir == "" {
+		return
+	}
+	stamp, exists := statStateFile(m.outputDir)
+	if !exists || stamp == m.stamp {
+		return
+	}
+	if err := m.lock.RLock(); err != nil {
+		log.Printf("⚠️ 获取状态文件锁失败，沿用内存状态: %v\n", err)
+		return
+	}
+	defer func() { _ = m.lock.Unlock() }()
+
+	if err := m.reloadLocked(); err != nil {
+		log.Printf("⚠️ 重新加载 %s 失败，沿用内存状态: %v\n", stateFileName, err)
+	}
+}
+
+// addCreatedServerLocked 按 IP + ServiceType 去重追加创建记录，返回规范化后的记录及是否为新增。
+func (m *OutputManager) addCreatedServerLocked(server CreatedServerInfo) (CreatedServerInfo, bool) {
+	normalized := normalizeCreatedServerInfo(server)
+	if normalized.IP == "" || normalized.ServiceType == "" {
+		return normalized, false
+	}
+	key := compositeKey(normalized.IP, normalized.ServiceType)
+	if _, exists := m.createdServerSet[key]; exists {
+		return normalized, false
+	}
+	m.createdServerSet[key] = struct{}{}
+	m.createdServers = append(m.createdServers, normalized)
+	return normalized, true
 }
 
 // AddCreatedServers 记录创建阶段拿到的实例信息并写入 servers_create.json。
@@ -167,20 +263,29 @@ func (m *OutputManager) AddCreatedServers(servers []CreatedServerInfo) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	for _, server := range servers {
-		normalized := normalizeCreatedServerInfo(server)
-		if normalized.IP == "" || normalized.ServiceType == "" {
-			continue
-		}
-		key := compositeKey(normalized.IP, normalized.ServiceType)
-		if _, exists := m.createdServerSet[key]; exists {
-			continue
+	var events []Event
+	err := m.mutate(func() {
+		events = events[:0]
+		for _, server := range servers {
+			created, added := m.addCreatedServerLocked(server)
+			if !added {
+				continue
+			}
+			events = append(events, Event{
+				Type:        EventInstanceLaunched,
+				IP:          created.IP,
+				ServiceType: created.ServiceType,
+				Name:        created.Name,
+				InstanceID:  created.InstanceID,
+				Message:     created.InstanceType,
+			})
 		}
-		m.createdServerSet[key] = struct{}{}
-		m.createdServers = append(m.createdServers, normalized)
+	})
+	if err != nil {
+		return err
 	}
-
-	return m.saveCreatedServersLocked()
+	recordEvents(m.outputDir, events...)
+	return nil
 }
 
 // UpdateSSHScriptStatus 更新某台服务器 SSH 就绪探测状态。
@@ -192,18 +297,61 @@ func (m *OutputManager) UpdateSSHScriptStatus(ip, serviceType, name, status stri
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	key := compositeKey(ip, serviceType)
-	m.sshScripts[key] = &SSHScriptStatus{
-		IP:          ip,
-		ServiceType: serviceType,
-		Name:        name,
-		Status:      status,
-		Attempts:    attempts,
-		Reason:      reason,
-		UpdatedAt:   updatedAt,
+	err := m.mutate(func() {
+		m.sshScripts[compositeKey(ip, serviceType)] = &SSHScriptStatus{
+			IP:          ip,
+			ServiceType: serviceType,
+			Name:        name,
+			Status:      status,
+			Attempts:    attempts,
+			Reason:      reason,
+			UpdatedAt:   updatedAt,
+		}
+	})
+	if err != nil {
+		return err
+	}
+	eventType := EventSSHReady
+	if status != "success" {
+		eventType = EventSSHFailed
+	}
+	recordEvents(m.outputDir, Event{Type: eventType, IP: ip, ServiceType: serviceType, Name: name, Attempts: attempts, Message: reason})
+	return nil
+}
+
+// UpsertFingerprints 按 IP + ServiceType 写入节点环境指纹，已有记录被覆盖。
+func (m *OutputManager) UpsertFingerprints(fps []HostFingerprint) error {
+	if m == nil || len(fps) == 0 {
+		return nil
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.mutate(func() {
+		for _, fp := range fps {
+			fp := fp
+			m.fingerprints[compositeKey(fp.IP, fp.ServiceType)] = &fp
+		}
+	})
+}
+
+// SnapshotFingerprints 返回已记录的节点环境指纹副本（按 IP + ServiceType 排序）。
+func (m *OutputManager) SnapshotFingerprints() []HostFingerprint {
+	if m == nil {
+		return nil
 	}
 
-	return m.saveSSHScriptsLocked()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.refreshLocked()
+
+	sorted := sortedFingerprints(m.fingerprints)
+	out := make([]HostFingerprint, 0, len(sorted))
+	for _, fp := range sorted {
+		out = append(out, *fp)
+	}
+	return out
 }
 
 func compositeKey(ip, serviceType string) string {
@@ -218,12 +366,28 @@ func (m *OutputManager) SnapshotServers() []ServerInfo {
 
 	m.mu.Lock()
 	defer m.mu.Unlock()
+	m.refreshLocked()
 
 	out := make([]ServerInfo, len(m.servers))
 	copy(out, m.servers)
 	return out
 }
 
+// SnapshotCreatedServers 返回创建阶段记录的实例列表副本（servers_create.json）。
+func (m *OutputManager) SnapshotCreatedServers() []CreatedServerInfo {
+	if m == nil {
+		return nil
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.refreshLocked()
+
+	out := make([]CreatedServerInfo, len(m.createdServers))
+	copy(out, m.createdServers)
+	return out
+}
+
 // AddServers 将一批服务器信息追加到列表并写入 servers.json。
 func (m *OutputManager) AddServers(servers []ServerInfo) error {
 	if m == nil {
@@ -233,15 +397,16 @@ func (m *OutputManager) AddServers(servers []ServerInfo) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	m.servers = append(m.servers, servers...)
-
-	return m.saveServersLocked()
+	return m.mutate(func() {
+		m.servers = append(m.servers, servers...)
+	})
 }
 
 // InitStatus 初始化某台服务器的脚本运行状态（通常在脚本后台启动成功后调用）。
 // name:  逻辑名称（例如 tagPrefix-type-index）
 // cmd:   实际执行的部署命令（不含 shutdown/nohup 等包装）
-func (m *OutputManager) InitStatus(ip, serviceType, name, cmd string, pid int, logPath, localLog string, updatedAt int64) error {
+// shutdownAt: 远端计划自动关机时间（Unix 秒）
+func (m *OutputManager) InitStatus(ip, serviceType, name, cmd string, pid int, logPath, localLog string, updatedAt, shutdownAt int64, repoCommit string) error {
 	if m == nil {
 		return nil
 	}
@@ -249,21 +414,31 @@ func (m *OutputManager) InitStatus(ip, serviceType, name, cmd string, pid int, l
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	key := compositeKey(ip, serviceType)
-	m.statuses[key] = &ScriptStatus{
-		IP:          ip,
-		ServiceType: serviceType,
-		Name:        name,
-		Command:     cmd,
-		PID:         pid,
-		Status:      "running",
-		LogPath:     logPath,
-		LocalLog:    localLog,
-		UpdatedAt:   updatedAt,
-		LogSize:     0,
-	}
-
-	return m.saveStatusesLocked()
+	var events []Event
+	err := m.mutate(func() {
+		key := compositeKey(ip, serviceType)
+		before := m.statusCopyLocked(key)
+		m.statuses[key] = &ScriptStatus{
+			IP:          ip,
+			ServiceType: serviceType,
+			Name:        name,
+			Command:     cmd,
+			PID:         pid,
+			Status:      "running",
+			LogPath:     logPath,
+			LocalLog:    localLog,
+			UpdatedAt:   updatedAt,
+			LogSize:     0,
+			ShutdownAt:  shutdownAt,
+			RepoCommit:  repoCommit,
+		}
+		events = statusEvents(before, m.statuses[key])
+	})
+	if err != nil {
+		return err
+	}
+	recordEvents(m.outputDir, events...)
+	return nil
 }
 
 // UpsertPlannedStatus 预写入一条“待执行”的脚本状态。
@@ -276,27 +451,35 @@ func (m *OutputManager) UpsertPlannedStatus(ip, serviceType, name, cmd, logPath,
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	key := compositeKey(ip, serviceType)
-	st, ok := m.statuses[key]
-	if !ok || st == nil {
-		st = &ScriptStatus{
-			IP:          ip,
-			ServiceType: serviceType,
+	var events []Event
+	err := m.mutate(func() {
+		key := compositeKey(ip, serviceType)
+		before := m.statusCopyLocked(key)
+		st, ok := m.statuses[key]
+		if !ok || st == nil {
+			st = &ScriptStatus{
+				IP:          ip,
+				ServiceType: serviceType,
+			}
+			m.statuses[key] = st
 		}
-		m.statuses[key] = st
-	}
 
-	st.Name = name
-	st.Command = cmd
-	st.PID = 0
-	st.Status = "pending"
-	st.Reason = ""
-	st.LogPath = logPath
-	st.LocalLog = localLog
-	st.UpdatedAt = updatedAt
-	st.LogSize = 0
-
-	return m.saveStatusesLocked()
+		st.Name = name
+		st.Command = cmd
+		st.PID = 0
+		st.Status = "pending"
+		st.Reason = ""
+		st.LogPath = logPath
+		st.LocalLog = localLog
+		st.UpdatedAt = updatedAt
+		st.LogSize = 0
+		events = statusEvents(before, st)
+	})
+	if err != nil {
+		return err
+	}
+	recordEvents(m.outputDir, events...)
+	return nil
 }
 
 // UpdateStatus 更新某台服务器脚本的状态信息。
@@ -308,124 +491,185 @@ func (m *OutputManager) UpdateStatus(ip, serviceType string, updateFn func(*Scri
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	key := compositeKey(ip, serviceType)
-	st, ok := m.statuses[key]
-	if !ok {
-		st = &ScriptStatus{
-			IP:          ip,
-			ServiceType: serviceType,
+	var events []Event
+	err := m.mutate(func() {
+		key := compositeKey(ip, serviceType)
+		before := m.statusCopyLocked(key)
+		st, ok := m.statuses[key]
+		if !ok {
+			st = &ScriptStatus{
+				IP:          ip,
+				ServiceType: serviceType,
+			}
+			m.statuses[key] = st
 		}
-		m.statuses[key] = st
-	}
-
-	updateFn(st)
 
-	return m.saveStatusesLocked()
+		updateFn(st)
+		events = statusEvents(before, st)
+	})
+	if err != nil {
+		return err
+	}
+	recordEvents(m.outputDir, events...)
+	return nil
 }
 
-// SnapshotStatuses 生成当前状态的浅拷贝，用于监控协程遍历。
-func (m *OutputManager) SnapshotStatuses() []*ScriptStatus {
+// UpdateExistingStatuses 对指定 IP 上已登记的所有脚本状态执行 updateFn，返回更新条数。
+// 与 UpdateStatus 不同，不会为未登记的 IP 新建状态。
+func (m *OutputManager) UpdateExistingStatuses(ip string, updateFn func(*ScriptStatus)) (int, error) {
 	if m == nil {
-		return nil
+		return 0, nil
 	}
 
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	result := make([]*ScriptStatus, 0, len(m.statuses))
+	m.refreshLocked()
+	found := false
 	for _, st := range m.statuses {
-		if st == nil {
-			continue
+		if st != nil && st.IP == ip {
+			found = true
+			break
 		}
-		copied := *st
-		result = append(result, &copied)
-	}
-	return result
-}
-
-func (m *OutputManager) saveServersLocked() error {
-	if m.outputDir == "" {
-		return nil
 	}
-	if err := os.MkdirAll(m.outputDir, 0o755); err != nil {
-		return err
+	if !found {
+		return 0, nil
 	}
 
-	data, err := json.MarshalIndent(m.servers, "", "  ")
+	updated := 0
+	var events []Event
+	err := m.mutate(func() {
+		updated, events = 0, events[:0]
+		for _, st := range m.statuses {
+			if st == nil || st.IP != ip {
+				continue
+			}
+			before := *st
+			updateFn(st)
+			updated++
+			events = append(events, statusEvents(before, st)...)
+		}
+	})
 	if err != nil {
-		return err
+		return updated, err
 	}
-
-	path := filepath.Join(m.outputDir, "servers.json")
-	return os.WriteFile(path, data, 0o644)
+	recordEvents(m.outputDir, events...)
+	return updated, nil
 }
 
-func (m *OutputManager) saveStatusesLocked() error {
-	if m.outputDir == "" {
+// RemoveEntries 按 compositeKey(ip, serviceType) 从 servers.json / script_status.json / ssh_scripts.json 中移除条目。
+// servers_create.json 作为创建阶段的原始记录保留，供 shutdown --terminate / inventory 兜底。
+func (m *OutputManager) RemoveEntries(keys []string) error {
+	if m == nil || len(keys) == 0 {
 		return nil
 	}
-	if err := os.MkdirAll(m.outputDir, 0o755); err != nil {
-		return err
-	}
 
-	// 将 map 转为 slice 输出，保证输出结构稳定。
-	list := make([]*ScriptStatus, 0, len(m.statuses))
-	for _, st := range m.statuses {
-		if st == nil {
-			continue
-		}
-		list = append(list, st)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	drop := make(map[string]struct{}, len(keys))
+	for _, key := range keys {
+		drop[key] = struct{}{}
 	}
 
-	data, err := json.MarshalIndent(list, "", "  ")
+	var events []Event
+	err := m.mutate(func() {
+		events = events[:0]
+		for key := range drop {
+			if st := m.statuses[key]; st != nil {
+				events = append(events, Event{Type: EventRemoved, IP: st.IP, ServiceType: st.ServiceType, Name: st.Name, PID: st.PID, From: st.Status})
+			}
+		}
+		kept := m.servers[:0]
+		for _, s := range m.servers {
+			if _, ok := drop[compositeKey(s.IP, s.ServiceType)]; ok {
+				continue
+			}
+			kept = append(kept, s)
+		}
+		m.servers = kept
+		for key := range drop {
+			delete(m.statuses, key)
+			delete(m.sshScripts, key)
+			delete(m.fingerprints, key)
+		}
+	})
 	if err != nil {
 		return err
 	}
-
-	path := filepath.Join(m.outputDir, "script_status.json")
-	return os.WriteFile(path, data, 0o644)
+	recordEvents(m.outputDir, events...)
+	return nil
 }
 
-func (m *OutputManager) saveSSHScriptsLocked() error {
-	if m.outputDir == "" {
+// ReplaceIP 将某个节点从 oldIP 迁移到 newIP：servers.json 条目改写 IP，脚本状态迁移到新键后执行 updateFn，
+// 旧 IP 的 SSH 探测记录删除（新 IP 的记录由 SSH 收敛阶段写入）。
+func (m *OutputManager) ReplaceIP(serviceType, oldIP, newIP string, updateFn func(*ScriptStatus)) error {
+	if m == nil {
 		return nil
 	}
-	if err := os.MkdirAll(m.outputDir, 0o755); err != nil {
-		return err
-	}
 
-	list := make([]*SSHScriptStatus, 0, len(m.sshScripts))
-	for _, st := range m.sshScripts {
-		if st == nil {
-			continue
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var ev Event
+	err := m.mutate(func() {
+		for i := range m.servers {
+			if m.servers[i].IP == oldIP && m.servers[i].ServiceType == serviceType {
+				m.servers[i].IP = newIP
+			}
 		}
-		list = append(list, st)
-	}
 
-	data, err := json.MarshalIndent(list, "", "  ")
+		oldKey := compositeKey(oldIP, serviceType)
+		st, ok := m.statuses[oldKey]
+		if !ok {
+			st = &ScriptStatus{ServiceType: serviceType}
+		}
+		delete(m.statuses, oldKey)
+		st.IP = newIP
+		if updateFn != nil {
+			updateFn(st)
+		}
+		m.statuses[compositeKey(newIP, serviceType)] = st
+		delete(m.sshScripts, oldKey)
+		delete(m.fingerprints, oldKey)
+		ev = Event{Type: EventReplaced, IP: newIP, ServiceType: serviceType, Name: st.Name, To: st.Status, Message: "替换旧节点 " + oldIP}
+	})
 	if err != nil {
 		return err
 	}
+	oldEv := ev
+	oldEv.IP, oldEv.To, oldEv.Message = oldIP, "", "已被新节点 "+newIP+" 替换"
+	recordEvents(m.outputDir, oldEv, ev)
+	return nil
+}
 
-	path := filepath.Join(m.outputDir, "ssh_scripts.json")
-	return os.WriteFile(path, data, 0o644)
+// statusCopyLocked 返回某条脚本状态的副本（不存在时为零值），用于对比修改前后的差异。
+func (m *OutputManager) statusCopyLocked(key string) ScriptStatus {
+	if st := m.statuses[key]; st != nil {
+		return *st
+	}
+	return ScriptStatus{}
 }
 
-func (m *OutputManager) saveCreatedServersLocked() error {
-	if m.outputDir == "" {
+// SnapshotStatuses 生成当前状态的浅拷贝，用于监控协程遍历。
+func (m *OutputManager) SnapshotStatuses() []*ScriptStatus {
+	if m == nil {
 		return nil
 	}
-	if err := os.MkdirAll(m.outputDir, 0o755); err != nil {
-		return err
-	}
 
-	data, err := json.MarshalIndent(m.createdServers, "", "  ")
-	if err != nil {
-		return err
-	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.refreshLocked()
 
-	path := filepath.Join(m.outputDir, "servers_create.json")
-	return os.WriteFile(path, data, 0o644)
+	result := make([]*ScriptStatus, 0, len(m.statuses))
+	for _, st := range m.statuses {
+		if st == nil {
+			continue
+		}
+		copied := *st
+		result = append(result, &copied)
+	}
+	return result
 }
 
 func normalizeCreatedServerInfo(server CreatedServerInfo) CreatedServerInfo {
@@ -433,5 +677,9 @@ func normalizeCreatedServerInfo(server CreatedServerInfo) CreatedServerInfo {
 		Name:        strings.TrimSpace(server.Name),
 		ServiceType: strings.TrimSpace(server.ServiceType),
 		IP:          strings.TrimSpace(server.IP),
+
+		InstanceID:   strings.TrimSpace(server.InstanceID),
+		InstanceType: strings.TrimSpace(server.InstanceType),
+		LaunchTime:   server.LaunchTime,
 	}
 }
@@ -0,0 +1,105 @@
+package deploy
+
+import (
+	"fmt"
+	"log"
+	"strings"
+)
+
+// DeployPhases 为 deploy 可通过 --only / --skip 选择的阶段（按执行顺序）：
+//   - fund：为各链 L1 vault 充值
+//   - launch：创建 EC2 实例（记录到 servers_create.json）
+//   - ssh：探测 SSH 就绪，登记 servers.json 并预登记 pending 脚本状态
+//   - tag：按最终节点名设置 EC2 Name 标签
+//   - exec：在远端后台启动部署命令
+//   - monitor：启动远端运行日志监控
+//   - sync：同步远端日志与脚本状态直到结束
+var DeployPhases = []string{
+	DeployPhaseFund,
+	DeployPhaseLaunch,
+	DeployPhaseSSH,
+	DeployPhaseTag,
+	DeployPhaseExec,
+	DeployPhaseMonitor,
+	DeployPhaseSync,
+}
+
+// resolveDeployPhases 根据 --only / --skip 计算本次执行的阶段集合；两者都为空时返回 nil（执行全部阶段）。
+func resolveDeployPhases(only, skip []string) (map[string]bool, error) {
+	only, err := normalizeDeployPhases(only)
+	if err != nil {
+		return nil, err
+	}
+	skip, err = normalizeDeployPhases(skip)
+	if err != nil {
+		return nil, err
+	}
+	if len(only) > 0 && len(skip) > 0 {
+		return nil, fmt.Errorf("--only 与 --skip 不能同时使用")
+	}
+	if len(only) == 0 && len(skip) == 0 {
+		return nil, nil
+	}
+
+	phases := make(map[string]bool, len(DeployPhases))
+	for _, phase := range DeployPhases {
+		phases[phase] = len(only) == 0
+	}
+	for _, phase := range only {
+		phases[phase] = true
+	}
+	for _, phase := range skip {
+		phases[phase] = false
+	}
+	return phases, nil
+}
+
+func normalizeDeployPhases(names []string) ([]string, error) {
+	var out []string
+	for _, raw := range names {
+		for _, name := range strings.Split(raw, ",") {
+			name = strings.ToLower(strings.TrimSpace(name))
+			if name == "" {
+				continue
+			}
+			if !isDeployPhase(name) {
+				return nil, fmt.Errorf("未知的 deploy 阶段 %q，可选: %s", name, strings.Join(DeployPhases, ", "))
+			}
+			out = append(out, name)
+		}
+	}
+	return out, nil
+}
+
+func isDeployPhase(name string) bool {
+	for _, phase := range DeployPhases {
+		if phase == name {
+			return true
+		}
+	}
+	return false
+}
+
+// phaseEnabled 判断本次是否执行某个阶段；未指定 --only / --skip 时全部执行。
+func (d *Deployer) phaseEnabled(phase string) bool {
+	if d.phases == nil {
+		return true
+	}
+	return d.phases[phase]
+}
+
+// logSelectedPhases 在选择了部分阶段时输出本次执行 / 跳过的阶段。
+func (d *Deployer) logSelectedPhases() {
+	if d.phases == nil {
+		return
+	}
+	var run, skipped []string
+	for _, phase := range DeployPhases {
+		if d.phases[phase] {
+			run = append(run, phase)
+		} else {
+			skipped = append(skipped, phase)
+		}
+	}
+	log.Printf("ℹ️ 本次执行阶段: [%s]，跳过: [%s]\n", strings.Join(run, ", "), strings.Join(skipped, ", "))
+}
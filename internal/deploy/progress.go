@@ -0,0 +1,495 @@
+package deploy
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"maps"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+)
+
+// deploy 流程的阶段名称（按执行顺序）。
+const (
+	DeployPhaseFund    = "fund"
+	DeployPhaseLaunch  = "launch"
+	DeployPhaseSSH     = "ssh"
+	DeployPhaseTag     = "tag"
+	DeployPhaseExec    = "exec"
+	DeployPhaseMonitor = "monitor"
+	DeployPhaseSync    = "sync"
+)
+
+// 阶段结果
+const (
+	PhaseStatusDone    = "done"
+	PhaseStatusSkipped = "skipped"
+	PhaseStatusFailed  = "failed"
+)
+
+// ErrDeployInterrupted 表示 deploy 因 SIGINT / SIGTERM 中断，进度已保存，可通过 deploy --resume 继续。
+var ErrDeployInterrupted = errors.New("deploy 被中断")
+
+// DeployProgress 记录最近一次 deploy 各阶段的完成情况，随 state.json 持久化，供 deploy --resume 从中断处继续。
+type DeployProgress struct {
+	// FundedVaults 为已成功发出充值交易的 L1 vault 地址；resume 时跳过，避免重复充值。
+	FundedVaults []string `json:"fundedVaults,omitempty"`
+	// StartIndexes 为本次 deploy 开始时各服务已有的节点数（append 续接起点）；分阶段续跑时充值范围从这里开始。
+	StartIndexes map[string]int `json:"startIndexes,omitempty"`
+	// Phases 按阶段名记录最近一次执行结果；未选择的阶段只在没有历史结果时记为 skipped，不覆盖之前的 done。
+	Phases map[string]*PhaseResult `json:"phases,omitempty"`
+	// Services 按服务类型记录最近完成的阶段（launch / ssh / exec）。
+	Services map[string]string `json:"services,omitempty"`
+	// Nodes 按 IP|ServiceType 记录逐节点阶段（tag / monitor）的结果；exec 的结果以 ScriptStatus.PID 为准。
+	Nodes map[string]*NodeProgress `json:"nodes,omitempty"`
+	// Interrupted 为最近一次收到 SIGINT / SIGTERM 时的现场记录；resume 完整结束后清空。
+	Interrupted *DeployInterrupt `json:"interrupted,omitempty"`
+}
+
+// PhaseResult 为某个阶段最近一次执行的结果。
+type PhaseResult struct {
+	Status    string `json:"status"` // done / skipped / failed
+	UpdatedAt int64  `json:"updatedAt,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+// NodeProgress 为单个节点逐节点阶段的结果，供后续只执行 exec / monitor 的 deploy 判断哪些节点还需处理。
+type NodeProgress struct {
+	InstanceID string `json:"instanceId,omitempty"`
+	Tagged     bool   `json:"tagged,omitempty"`
+	// MonitorDone 为 true 表示运行日志监控已启动，或该节点无需监控。
+	MonitorDone bool `json:"monitorDone,omitempty"`
+	MonitorPID  int  `json:"monitorPid,omitempty"`
+}
+
+// DeployInterrupt 描述一次中断：中断时正在执行的阶段以及每个节点最后完成的阶段。
+type DeployInterrupt struct {
+	At    int64  `json:"at"`
+	Phase string `json:"phase"`
+	// Services 为中断时各服务类型所处的阶段（服务部署期间记录），Phase 取其中最靠前的阶段。
+	Services map[string]string `json:"services,omitempty"`
+	Reason   string            `json:"reason,omitempty"`
+	Nodes    []NodeStopPoint   `json:"nodes,omitempty"`
+}
+
+// NodeStopPoint 记录中断时单个节点最后完成的阶段：
+//   - launch：实例已创建，SSH 尚未收敛（未写入 servers.json）
+//   - ssh：SSH 已就绪并登记，远程命令尚未确认启动（无 PID）
+//   - exec：远程命令已启动，同步尚未结束
+type NodeStopPoint struct {
+	IP          string `json:"ip"`
+	ServiceType string `json:"serviceType"`
+	Name        string `json:"name,omitempty"`
+	Phase       string `json:"phase"`
+}
+
+// phaseDone 判断某个阶段最近一次是否已完成。
+func (p *DeployProgress) phaseDone(phase string) bool {
+	r, ok := p.Phases[phase]
+	return ok && r != nil && r.Status == PhaseStatusDone
+}
+
+func (p *DeployProgress) isFunded(addr string) bool {
+	for _, funded := range p.FundedVaults {
+		if strings.EqualFold(funded, addr) {
+			return true
+		}
+	}
+	return false
+}
+
+func cloneDeployProgress(p *DeployProgress) DeployProgress {
+	if p == nil {
+		return DeployProgress{}
+	}
+	out := *p
+	out.FundedVaults = append([]string(nil), p.FundedVaults...)
+	if p.StartIndexes != nil {
+		out.StartIndexes = make(map[string]int, len(p.StartIndexes))
+		for k, v := range p.StartIndexes {
+			out.StartIndexes[k] = v
+		}
+	}
+	if p.Phases != nil {
+		out.Phases = make(map[string]*PhaseResult, len(p.Phases))
+		for k, v := range p.Phases {
+			if v != nil {
+				copied := *v
+				out.Phases[k] = &copied
+			}
+		}
+	}
+	if p.Services != nil {
+		out.Services = make(map[string]string, len(p.Services))
+		for k, v := range p.Services {
+			out.Services[k] = v
+		}
+	}
+	if p.Nodes != nil {
+		out.Nodes = make(map[string]*NodeProgress, len(p.Nodes))
+		for k, v := range p.Nodes {
+			if v != nil {
+				copied := *v
+				out.Nodes[k] = &copied
+			}
+		}
+	}
+	if p.Interrupted != nil {
+		interrupted := *p.Interrupted
+		interrupted.Nodes = append([]NodeStopPoint(nil), p.Interrupted.Nodes...)
+		interrupted.Services = maps.Clone(p.Interrupted.Services)
+		out.Interrupted = &interrupted
+	}
+	return out
+}
+
+// SnapshotProgress 返回 deploy 阶段进度的副本；ok=false 表示 state.json 中没有进度记录（旧版本输出或非 deploy 产生）。
+func (m *OutputManager) SnapshotProgress() (DeployProgress, bool) {
+	if m == nil {
+		return DeployProgress{}, false
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.refreshLocked()
+
+	return cloneDeployProgress(m.progress), m.progress != nil
+}
+
+// UpdateProgress 在状态文件锁内修改 deploy 阶段进度并落盘。
+func (m *OutputManager) UpdateProgress(updateFn func(*DeployProgress)) error {
+	if m == nil {
+		return nil
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.mutate(func() {
+		if m.progress == nil {
+			m.progress = &DeployProgress{}
+		}
+		updateFn(m.progress)
+	})
+}
+
+// buildNodeStopPoints 根据已落盘的节点状态推导每个未结束节点最后完成的阶段，结果按 ServiceType / Name / IP 排序。
+func buildNodeStopPoints(created []CreatedServerInfo, servers []ServerInfo, statuses []*ScriptStatus) []NodeStopPoint {
+	statusByKey := make(map[string]*ScriptStatus, len(statuses))
+	for _, st := range statuses {
+		if st != nil {
+			statusByKey[compositeKey(st.IP, st.ServiceType)] = st
+		}
+	}
+
+	seen := make(map[string]struct{})
+	var points []NodeStopPoint
+	for _, s := range servers {
+		key := compositeKey(s.IP, s.ServiceType)
+		seen[key] = struct{}{}
+		phase := DeployPhaseSSH
+		if st := statusByKey[key]; st != nil {
+			switch {
+			case st.PID > 0 && shouldMonitorSyncStatus(st.Status):
+				phase = DeployPhaseExec
+			case st.PID > 0 || st.Status != "pending":
+				continue
+			}
+		}
+		points = append(points, NodeStopPoint{IP: s.IP, ServiceType: s.ServiceType, Name: s.Name, Phase: phase})
+	}
+	for _, c := range created {
+		key := compositeKey(c.IP, c.ServiceType)
+		if _, ok := seen[key]; ok {
+			continue
+		}
+		if _, ok := statusByKey[key]; ok {
+			continue
+		}
+		seen[key] = struct{}{}
+		points = append(points, NodeStopPoint{IP: c.IP, ServiceType: c.ServiceType, Name: c.Name, Phase: DeployPhaseLaunch})
+	}
+
+	sort.Slice(points, func(i, j int) bool {
+		if points[i].ServiceType != points[j].ServiceType {
+			return points[i].ServiceType < points[j].ServiceType
+		}
+		if points[i].Name != points[j].Name {
+			return points[i].Name < points[j].Name
+		}
+		return points[i].IP < points[j].IP
+	})
+	return points
+}
+
+// setPhase 记录当前正在执行的阶段，中断时写入 DeployInterrupt.Phase。
+func (d *Deployer) setPhase(phase string) {
+	d.phaseMu.Lock()
+	defer d.phaseMu.Unlock()
+	d.phase = phase
+}
+
+func (d *Deployer) currentPhase() string {
+	d.phaseMu.Lock()
+	defer d.phaseMu.Unlock()
+	return d.phase
+}
+
+// setServicePhase 记录单个服务正在执行的阶段；并发部署的服务各自记录，互不覆盖。
+func (d *Deployer) setServicePhase(svc ServiceConfig, phase string) {
+	d.phaseMu.Lock()
+	defer d.phaseMu.Unlock()
+	if d.servicePhases == nil {
+		d.servicePhases = make(map[string]string)
+	}
+	d.servicePhases[svc.Type.String()] = phase
+}
+
+func (d *Deployer) servicePhase(svc ServiceConfig) string {
+	d.phaseMu.Lock()
+	defer d.phaseMu.Unlock()
+	return d.servicePhases[svc.Type.String()]
+}
+
+// interruptPhases 返回中断时 deploy 所处的阶段及各服务所处的阶段：服务部署期间取各服务中最靠前的阶段，
+// 即 resume 需要开始的位置。
+func (d *Deployer) interruptPhases() (string, map[string]string) {
+	d.phaseMu.Lock()
+	defer d.phaseMu.Unlock()
+	if d.phase == DeployPhaseFund || d.phase == DeployPhaseSync || len(d.servicePhases) == 0 {
+		return d.phase, nil
+	}
+	services := make(map[string]string, len(d.servicePhases))
+	earliest := len(DeployPhases)
+	for serviceType, phase := range d.servicePhases {
+		services[serviceType] = phase
+		if i := slices.Index(DeployPhases, phase); i >= 0 && i < earliest {
+			earliest = i
+		}
+	}
+	if earliest == len(DeployPhases) {
+		return d.phase, services
+	}
+	return DeployPhases[earliest], services
+}
+
+// phaseError 为带有失败阶段的服务部署错误，见 runService。
+type phaseError struct {
+	phase string
+	err   error
+}
+
+func (e *phaseError) Error() string { return e.err.Error() }
+
+func (e *phaseError) Unwrap() error { return e.err }
+
+// failedPhases 按阶段归并 err 中的失败：带 phaseError 的错误记到其阶段，其余记到 fallback。
+func failedPhases(err error, fallback string) map[string]error {
+	grouped := make(map[string][]error)
+	var walk func(error)
+	walk = func(err error) {
+		if multi, ok := err.(interface{ Unwrap() []error }); ok {
+			for _, inner := range multi.Unwrap() {
+				walk(inner)
+			}
+			return
+		}
+		phase := fallback
+		var pe *phaseError
+		if errors.As(err, &pe) && pe.phase != "" {
+			phase = pe.phase
+		}
+		grouped[phase] = append(grouped[phase], err)
+	}
+	walk(err)
+
+	out := make(map[string]error, len(grouped))
+	for phase, errs := range grouped {
+		if len(errs) == 1 {
+			out[phase] = errs[0]
+			continue
+		}
+		out[phase] = deployMultiError{errs: errs}
+	}
+	return out
+}
+
+// updateProgress 落盘阶段进度；写入失败只告警，不阻断部署（resume 时最多重复执行该阶段）。
+func (d *Deployer) updateProgress(updateFn func(*DeployProgress)) {
+	if d.skipProgress {
+		return
+	}
+	if err := d.outputMgr.UpdateProgress(updateFn); err != nil {
+		log.Printf("⚠️ 写入部署进度失败: %v\n", err)
+	}
+}
+
+// recordPhaseResult 记录阶段结果；err 非空记为 failed。
+func (d *Deployer) recordPhaseResult(phase string, err error) {
+	result := &PhaseResult{Status: PhaseStatusDone, UpdatedAt: time.Now().Unix()}
+	if err != nil {
+		result.Status = PhaseStatusFailed
+		result.Error = err.Error()
+	}
+	d.updateProgress(func(p *DeployProgress) {
+		if p.Phases == nil {
+			p.Phases = make(map[string]*PhaseResult)
+		}
+		p.Phases[phase] = result
+	})
+}
+
+// recordPhaseSkipped 将未选择的阶段记为 skipped；已有结果（例如之前的 done）保持不变，后续阶段据此判断。
+func (d *Deployer) recordPhaseSkipped(phase string) {
+	d.updateProgress(func(p *DeployProgress) {
+		if p.Phases == nil {
+			p.Phases = make(map[string]*PhaseResult)
+		}
+		if _, ok := p.Phases[phase]; !ok {
+			p.Phases[phase] = &PhaseResult{Status: PhaseStatusSkipped, UpdatedAt: time.Now().Unix()}
+		}
+	})
+}
+
+func (d *Deployer) recordNodeProgress(key string, updateFn func(*NodeProgress)) {
+	d.updateProgress(func(p *DeployProgress) {
+		if p.Nodes == nil {
+			p.Nodes = make(map[string]*NodeProgress)
+		}
+		np := p.Nodes[key]
+		if np == nil {
+			np = &NodeProgress{}
+			p.Nodes[key] = np
+		}
+		updateFn(np)
+	})
+}
+
+func (d *Deployer) markServicePhase(svc ServiceConfig, phase string) {
+	d.updateProgress(func(p *DeployProgress) {
+		if p.Services == nil {
+			p.Services = make(map[string]string)
+		}
+		p.Services[svc.Type.String()] = phase
+	})
+}
+
+// recordInterrupt 在 deploy 因信号取消后保存现场：每个节点最后完成的阶段写入 state.json 与事件日志。
+func (d *Deployer) recordInterrupt(cause error) error {
+	phase, services := d.interruptPhases()
+	points := buildNodeStopPoints(d.outputMgr.SnapshotCreatedServers(), d.outputMgr.SnapshotServers(), d.outputMgr.SnapshotStatuses())
+	interrupted := &DeployInterrupt{
+		At:       time.Now().Unix(),
+		Phase:    phase,
+		Services: services,
+		Reason:   cause.Error(),
+		Nodes:    points,
+	}
+	if err := d.outputMgr.UpdateProgress(func(p *DeployProgress) {
+		p.Interrupted = interrupted
+	}); err != nil {
+		log.Printf("⚠️ 保存中断现场失败: %v\n", err)
+	}
+
+	events := make([]Event, 0, len(points))
+	for _, pt := range points {
+		events = append(events, Event{Type: EventInterrupted, IP: pt.IP, ServiceType: pt.ServiceType, Name: pt.Name, Message: pt.Phase})
+	}
+	recordEvents(d.cfg.CommonConfig.OutputDir, events...)
+
+	log.Printf("⚠️ deploy 在 %s 阶段被中断%s，已保存 %d 个未完成节点的进度，可使用 deploy --resume 继续\n", phase, formatServicePhases(services), len(points))
+	return fmt.Errorf("%w（%s 阶段，可使用 deploy --resume 继续）: %v", ErrDeployInterrupted, phase, cause)
+}
+
+// formatServicePhases 将各服务所处的阶段格式化为（op=exec, xjst=ssh），为空时返回空串。
+func formatServicePhases(services map[string]string) string {
+	if len(services) == 0 {
+		return ""
+	}
+	parts := make([]string, 0, len(services))
+	for _, serviceType := range slices.Sorted(maps.Keys(services)) {
+		parts = append(parts, serviceType+"="+services[serviceType])
+	}
+	return "（" + strings.Join(parts, ", ") + "）"
+}
+
+// logResumePoint 输出上次中断的位置，便于确认 resume 的起点。
+func logResumePoint(p DeployProgress) {
+	if p.Interrupted == nil {
+		log.Printf("ℹ️ [resume] 上次 deploy 未记录中断现场，按已落盘进度继续（fund=%v, services=%v）\n", p.phaseDone(DeployPhaseFund), p.Services)
+		return
+	}
+	log.Printf("ℹ️ [resume] 上次 deploy 于 %s 在 %s 阶段中断%s，未完成节点 %d 个\n",
+		time.Unix(p.Interrupted.At, 0).Format(time.RFC3339), p.Interrupted.Phase, formatServicePhases(p.Interrupted.Services), len(p.Interrupted.Nodes))
+	for _, pt := range p.Interrupted.Nodes {
+		log.Printf("ℹ️ [resume]   [%s][%s][%s] 最后完成阶段=%s\n", pt.ServiceType, pt.IP, pt.Name, pt.Phase)
+	}
+}
+
+// resumeIPPool 返回该服务已创建但尚未登记到 servers.json 的实例 IP（中断于 SSH 收敛前），resume 时优先复用。
+func (d *Deployer) resumeIPPool(svc ServiceConfig) []string {
+	if !d.resume {
+		return nil
+	}
+	want := svc.Type.String()
+	registered := make(map[string]struct{})
+	for _, s := range d.outputMgr.SnapshotServers() {
+		registered[compositeKey(s.IP, s.ServiceType)] = struct{}{}
+	}
+	for _, st := range d.outputMgr.SnapshotStatuses() {
+		registered[compositeKey(st.IP, st.ServiceType)] = struct{}{}
+	}
+
+	var pool []string
+	for _, c := range d.outputMgr.SnapshotCreatedServers() {
+		if c.ServiceType != want {
+			continue
+		}
+		if _, ok := registered[compositeKey(c.IP, c.ServiceType)]; ok {
+			continue
+		}
+		registered[compositeKey(c.IP, c.ServiceType)] = struct{}{}
+		pool = append(pool, c.IP)
+	}
+	return pool
+}
+
+// nodePhaseNeeds 根据节点已有状态判断本次还需执行哪些逐节点阶段（仅限已选择的阶段）。
+func (d *Deployer) nodePhaseNeeds(st *ScriptStatus, np *NodeProgress) (tag, exec, monitor bool) {
+	if np == nil {
+		np = &NodeProgress{}
+	}
+	notStarted := st == nil || (st.Status == "pending" && st.PID <= 0)
+	running := st != nil && st.PID > 0 && shouldMonitorSyncStatus(st.Status)
+
+	tag = d.phaseEnabled(DeployPhaseTag) && !np.Tagged
+	exec = d.phaseEnabled(DeployPhaseExec) && notStarted
+	monitor = d.phaseEnabled(DeployPhaseMonitor) && !np.MonitorDone && (exec || running)
+	return tag, exec, monitor
+}
+
+// pendingNodeIndexes 返回 globalIps 中还需执行 tag / exec / monitor 的节点索引。
+// append 模式下已有节点不在本次处理范围；resume / 分阶段续跑时已登记节点按其进度继续。
+func (d *Deployer) pendingNodeIndexes(svc ServiceConfig, globalIps []string) []int {
+	offset := d.serviceIndexOffset(svc)
+	progress, _ := d.outputMgr.SnapshotProgress()
+	statusByKey := make(map[string]*ScriptStatus)
+	for _, st := range d.outputMgr.SnapshotStatuses() {
+		statusByKey[compositeKey(st.IP, st.ServiceType)] = st
+	}
+
+	var indexes []int
+	for i, ip := range globalIps {
+		if ip == "" || (i < offset && !d.resume) {
+			continue
+		}
+		key := compositeKey(ip, svc.Type.String())
+		tag, exec, monitor := d.nodePhaseNeeds(statusByKey[key], progress.Nodes[key])
+		if tag || exec || monitor {
+			indexes = append(indexes, i)
+		}
+	}
+	return indexes
+}
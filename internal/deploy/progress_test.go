@@ -0,0 +1,248 @@
+package deploy
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/wangdayong228/ydyl-deploy-client/internal/constants/enums"
+)
+
+// seedInterruptedOutput 构造一次在 op 服务中途中断的输出：
+// 1.1.1.1 已启动命令，1.1.1.2 已登记但命令未启动，1.1.1.3 已创建但 SSH 未收敛。
+func seedInterruptedOutput(t *testing.T, outputDir string) *OutputManager {
+	t.Helper()
+
+	mgr := NewOutputManager(outputDir)
+	if err := mgr.AddCreatedServers([]CreatedServerInfo{
+		{IP: "1.1.1.1", ServiceType: "op", Name: "ydyl-op-create-1"},
+		{IP: "1.1.1.2", ServiceType: "op", Name: "ydyl-op-create-2"},
+		{IP: "1.1.1.3", ServiceType: "op", Name: "ydyl-op-create-3"},
+	}); err != nil {
+		t.Fatalf("AddCreatedServers: %v", err)
+	}
+	if err := mgr.AddServers([]ServerInfo{
+		{IP: "1.1.1.1", ServiceType: "op", Name: "ydyl-op-1"},
+		{IP: "1.1.1.2", ServiceType: "op", Name: "ydyl-op-2"},
+	}); err != nil {
+		t.Fatalf("AddServers: %v", err)
+	}
+	if err := mgr.InitStatus("1.1.1.1", "op", "ydyl-op-1", "cmd", 42, "/remote/1.log", "/local/1.log", 1, 0, ""); err != nil {
+		t.Fatalf("InitStatus: %v", err)
+	}
+	if err := mgr.UpsertPlannedStatus("1.1.1.2", "op", "ydyl-op-2", "cmd", "/remote/2.log", "/local/2.log", 1); err != nil {
+		t.Fatalf("UpsertPlannedStatus: %v", err)
+	}
+	if err := mgr.UpdateProgress(func(p *DeployProgress) {
+		p.Phases = map[string]*PhaseResult{DeployPhaseFund: {Status: PhaseStatusDone}}
+		p.FundedVaults = []string{"0xAbC"}
+		p.Nodes = map[string]*NodeProgress{compositeKey("1.1.1.1", "op"): {Tagged: true, MonitorDone: true}}
+	}); err != nil {
+		t.Fatalf("UpdateProgress: %v", err)
+	}
+	return mgr
+}
+
+func TestRecordInterrupt_SavesNodeStopPoints(t *testing.T) {
+	t.Parallel()
+
+	outputDir := t.TempDir()
+	mgr := seedInterruptedOutput(t, outputDir)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	d := &Deployer{
+		ctx:       ctx,
+		cfg:       DeployConfig{CommonConfig: CommonConfig{OutputDir: outputDir}},
+		outputMgr: mgr,
+		phase:     DeployPhaseSSH,
+	}
+	err := d.recordInterrupt(ctx.Err())
+	if !errors.Is(err, ErrDeployInterrupted) {
+		t.Fatalf("recordInterrupt error = %v, want ErrDeployInterrupted", err)
+	}
+
+	reloaded, err := LoadOutputManager(outputDir)
+	if err != nil {
+		t.Fatalf("LoadOutputManager: %v", err)
+	}
+	progress, ok := reloaded.SnapshotProgress()
+	if !ok || !progress.phaseDone(DeployPhaseFund) || !progress.isFunded("0xabc") {
+		t.Fatalf("unexpected progress: ok=%v %+v", ok, progress)
+	}
+	if progress.Interrupted == nil || progress.Interrupted.Phase != DeployPhaseSSH {
+		t.Fatalf("unexpected interrupt record: %+v", progress.Interrupted)
+	}
+	got := make(map[string]string)
+	for _, pt := range progress.Interrupted.Nodes {
+		got[pt.IP] = pt.Phase
+	}
+	want := map[string]string{"1.1.1.1": DeployPhaseExec, "1.1.1.2": DeployPhaseSSH, "1.1.1.3": DeployPhaseLaunch}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("stop points = %v, want %v", got, want)
+	}
+
+	events, _, err := LoadEvents(outputDir)
+	if err != nil {
+		t.Fatalf("LoadEvents: %v", err)
+	}
+	interrupted := 0
+	for _, ev := range events {
+		if ev.Type == EventInterrupted {
+			interrupted++
+		}
+	}
+	if interrupted != 3 {
+		t.Fatalf("interrupted events = %d, want 3", interrupted)
+	}
+}
+
+func TestRecordInterrupt_UsesPerServicePhases(t *testing.T) {
+	t.Parallel()
+
+	outputDir := t.TempDir()
+	mgr := seedInterruptedOutput(t, outputDir)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	d := &Deployer{
+		ctx:       ctx,
+		cfg:       DeployConfig{CommonConfig: CommonConfig{OutputDir: outputDir}},
+		outputMgr: mgr,
+		phase:     DeployPhaseLaunch,
+	}
+	// 并发部署的服务各自记录阶段，后进入 exec 的 op 不得覆盖仍在 ssh 阶段的 xjst
+	d.setServicePhase(ServiceConfig{Type: enums.ServiceTypeXJST}, DeployPhaseSSH)
+	d.setServicePhase(ServiceConfig{Type: enums.ServiceTypeOP}, DeployPhaseExec)
+
+	if err := d.recordInterrupt(ctx.Err()); !errors.Is(err, ErrDeployInterrupted) {
+		t.Fatalf("recordInterrupt error = %v, want ErrDeployInterrupted", err)
+	}
+	progress, _ := mgr.SnapshotProgress()
+	want := map[string]string{"op": DeployPhaseExec, "xjst": DeployPhaseSSH}
+	if progress.Interrupted == nil || progress.Interrupted.Phase != DeployPhaseSSH || !reflect.DeepEqual(progress.Interrupted.Services, want) {
+		t.Fatalf("unexpected interrupt record: %+v", progress.Interrupted)
+	}
+}
+
+func TestFailedPhases_RecordsEachServiceFailurePhase(t *testing.T) {
+	t.Parallel()
+
+	sshErr := errors.New("xjst ssh 收敛失败")
+	execErr := errors.New("op 远程命令失败")
+	depErr := errors.New("依赖的服务部署失败，跳过")
+	got := failedPhases(deployMultiError{errs: []error{
+		&phaseError{phase: DeployPhaseSSH, err: sshErr},
+		&phaseError{phase: DeployPhaseExec, err: execErr},
+		depErr,
+	}}, DeployPhaseLaunch)
+
+	if len(got) != 3 {
+		t.Fatalf("unexpected phases: %v", got)
+	}
+	if !errors.Is(got[DeployPhaseSSH], sshErr) || !errors.Is(got[DeployPhaseExec], execErr) || !errors.Is(got[DeployPhaseLaunch], depErr) {
+		t.Fatalf("errors recorded under wrong phases: %v", got)
+	}
+
+	// 未带阶段的错误记到 fallback
+	if got := failedPhases(errors.New("boom"), DeployPhaseSync); got[DeployPhaseSync] == nil || len(got) != 1 {
+		t.Fatalf("unexpected fallback: %v", got)
+	}
+}
+
+func TestResume_SelectsUnstartedNodesAndUnregisteredInstances(t *testing.T) {
+	t.Parallel()
+
+	mgr := seedInterruptedOutput(t, t.TempDir())
+	svc := ServiceConfig{Type: enums.ServiceTypeOP, Count: 3}
+	d := &Deployer{
+		outputMgr:   mgr,
+		appendPlans: buildAppendPlans(mgr.SnapshotServers(), mgr.SnapshotStatuses(), nil),
+	}
+
+	globalIps := d.serviceGlobalIPs(svc, nil)
+	if got := d.pendingNodeIndexes(svc, globalIps); got != nil {
+		t.Fatalf("non-resume exec indexes = %v, want nil", got)
+	}
+	if got := d.resumeIPPool(svc); got != nil {
+		t.Fatalf("non-resume ip pool = %v, want nil", got)
+	}
+
+	d.resume = true
+	if got := d.pendingNodeIndexes(svc, globalIps); !reflect.DeepEqual(got, []int{1}) {
+		t.Fatalf("exec indexes = %v, want [1]", got)
+	}
+	if got := d.resumeIPPool(svc); !reflect.DeepEqual(got, []string{"1.1.1.3"}) {
+		t.Fatalf("ip pool = %v, want [1.1.1.3]", got)
+	}
+	if got := d.serviceNewCount(svc); got != 1 {
+		t.Fatalf("new count = %d, want 1", got)
+	}
+}
+
+func TestResolveDeployPhases(t *testing.T) {
+	t.Parallel()
+
+	if phases, err := resolveDeployPhases(nil, nil); err != nil || phases != nil {
+		t.Fatalf("no selection = %v, %v; want nil, nil", phases, err)
+	}
+
+	phases, err := resolveDeployPhases([]string{"launch, SSH"}, nil)
+	if err != nil {
+		t.Fatalf("resolve --only: %v", err)
+	}
+	for _, phase := range DeployPhases {
+		want := phase == DeployPhaseLaunch || phase == DeployPhaseSSH
+		if phases[phase] != want {
+			t.Fatalf("--only launch,ssh: phase %s = %v, want %v", phase, phases[phase], want)
+		}
+	}
+
+	phases, err = resolveDeployPhases(nil, []string{"fund"})
+	if err != nil {
+		t.Fatalf("resolve --skip: %v", err)
+	}
+	if phases[DeployPhaseFund] || !phases[DeployPhaseSync] {
+		t.Fatalf("--skip fund = %v", phases)
+	}
+
+	if _, err := resolveDeployPhases([]string{"fund"}, []string{"sync"}); err == nil {
+		t.Fatalf("expected error when combining --only and --skip")
+	}
+	if _, err := resolveDeployPhases([]string{"deploy"}, nil); err == nil {
+		t.Fatalf("expected error for unknown phase")
+	}
+}
+
+func TestPendingNodeIndexes_RespectsSelectedPhases(t *testing.T) {
+	t.Parallel()
+
+	mgr := seedInterruptedOutput(t, t.TempDir())
+	svc := ServiceConfig{Type: enums.ServiceTypeOP, Count: 3}
+	d := &Deployer{
+		outputMgr:   mgr,
+		appendPlans: buildAppendPlans(mgr.SnapshotServers(), mgr.SnapshotStatuses(), nil),
+		resume:      true,
+		phases:      map[string]bool{DeployPhaseMonitor: true},
+	}
+	globalIps := d.serviceGlobalIPs(svc, nil)
+
+	// 1.1.1.1 的监控已启动、1.1.1.2 的命令尚未启动：只选 monitor 时没有需要处理的节点
+	if got := d.pendingNodeIndexes(svc, globalIps); got != nil {
+		t.Fatalf("monitor-only indexes = %v, want nil", got)
+	}
+
+	if err := mgr.UpdateProgress(func(p *DeployProgress) {
+		p.Nodes[compositeKey("1.1.1.1", "op")].MonitorDone = false
+	}); err != nil {
+		t.Fatalf("UpdateProgress: %v", err)
+	}
+	if got := d.pendingNodeIndexes(svc, globalIps); !reflect.DeepEqual(got, []int{0}) {
+		t.Fatalf("monitor-only indexes = %v, want [0]", got)
+	}
+
+	d.phases = map[string]bool{DeployPhaseTag: true, DeployPhaseExec: true}
+	if got := d.pendingNodeIndexes(svc, globalIps); !reflect.DeepEqual(got, []int{1}) {
+		t.Fatalf("tag+exec indexes = %v, want [1]", got)
+	}
+}
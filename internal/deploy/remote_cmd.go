@@ -0,0 +1,112 @@
+package deploy
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"text/template"
+
+	"github.com/wangdayong228/ydyl-deploy-client/internal/utils/cryptoutil"
+)
+
+// RemoteCmdVars 为 services[].remoteCmd 模板（Go text/template，需设置 remoteCmdTemplate: true）可引用的变量，例如：
+//
+//	remoteCmd: "NODE_INDEX={{.Index}} CHAIN_ID={{.L2ChainID}} KEY={{.L1VaultPrivateKey}} ./run.sh {{join .GlobalIPs \",\"}}"
+//
+// L1VaultPrivateKey / XjstGroupIPs 为方法，仅在模板引用时才计算。
+type RemoteCmdVars struct {
+	// Index 为节点在服务内的 0-based 索引，Ordinal = Index + 1。
+	Index       int
+	Ordinal     int
+	Name        string
+	IP          string
+	GlobalIPs   []string
+	ServiceType string
+	// L2ChainID 仅 op / cdk / arb 有意义（按 services[].chainId 分配），其它类型为 0。
+	L2ChainID int
+	// XjstGroupID / XjstNodeID 按 services[].groupSize（默认 4）个节点一组计算（1-based），非 xjst 服务同样可用。
+	XjstGroupID int
+	XjstNodeID  int
+	L1RpcUrl    string
+	L1RpcUrlWs  string
+	// RepoCommit 为 repoRef 解析出的部署仓库 commit，未配置 repoRef 时为空。
+	RepoCommit string
+	Common     CommonConfig
+
+	d   *Deployer
+	svc ServiceConfig
+}
+
+// L1VaultPrivateKey 返回该节点的 L1 vault 私钥（0x 开头），派生索引见 resolveVaultIndex（op / cdk 为默认链 ID，xjst 为 XjstGroupID）。
+func (v RemoteCmdVars) L1VaultPrivateKey() (string, error) {
+	key, err := v.d.resolveL1VaultPrivateKey(v.Common.L1VaultMnemonic, v.svc.Type, v.d.resolveVaultIndex(v.svc, v.Index))
+	if err != nil {
+		return "", fmt.Errorf("生成 L1_VAULT_PRIVATE_KEY 失败: %w", err)
+	}
+	return cryptoutil.EcdsaPrivToWeb3Hex(key), nil
+}
+
+// XjstGroupIPs 返回节点所在分组的 IP 列表，格式与内置 xjst 命令的 CHAIN_NODE_IPS 一致（[ip1,ip2,...]）。
+func (v RemoteCmdVars) XjstGroupIPs() (string, error) {
+	return v.d.resolveXjstGroupIps(v.GlobalIPs, v.XjstGroupID, v.svc.groupSize())
+}
+
+var remoteCmdFuncs = template.FuncMap{
+	"join": strings.Join,
+}
+
+func parseRemoteCmdTemplate(remoteCmd string) (*template.Template, error) {
+	return template.New("remoteCmd").Funcs(remoteCmdFuncs).Option("missingkey=error").Parse(remoteCmd)
+}
+
+// isRemoteCmdTemplate 判断 svc.RemoteCmd 是否需要按模板渲染；未开启 remoteCmdTemplate 的命令原样执行。
+func isRemoteCmdTemplate(svc ServiceConfig) bool {
+	return svc.RemoteCmdTemplate && svc.RemoteCmd != ""
+}
+
+// buildRemoteCmdVars 构造 globalIps[i] 节点的模板变量。
+func (d *Deployer) buildRemoteCmdVars(globalIps []string, i int, svc ServiceConfig) RemoteCmdVars {
+	common := d.cfg.CommonConfig
+	ip := ""
+	if i >= 0 && i < len(globalIps) {
+		ip = globalIps[i]
+	}
+	return RemoteCmdVars{
+		Index:       i,
+		Ordinal:     i + 1,
+		Name:        d.buildServiceInstanceName(svc, i+1),
+		IP:          ip,
+		GlobalIPs:   globalIps,
+		ServiceType: svc.Type.String(),
+		L2ChainID:   svc.resolveL2ChainID(i),
+		XjstGroupID: d.resolveXjstGroupId(svc, i),
+		XjstNodeID:  i%svc.groupSize() + 1,
+		L1RpcUrl:    d.resolveL1RpcUrl(common.L1RpcUrl, svc.L1RpcUrl),
+		L1RpcUrlWs:  common.L1RpcUrlWs,
+		RepoCommit:  d.repoCommit(),
+		Common:      common,
+		d:           d,
+		svc:         svc,
+	}
+}
+
+// renderRemoteCmd 以 globalIps[i] 节点的变量渲染 svc.RemoteCmd。
+func (d *Deployer) renderRemoteCmd(globalIps []string, i int, svc ServiceConfig) (string, error) {
+	if !isRemoteCmdTemplate(svc) {
+		return svc.RemoteCmd, nil
+	}
+	return d.renderCommandTemplate(svc.RemoteCmd, globalIps, i, svc)
+}
+
+// renderCommandTemplate 以 globalIps[i] 节点的变量渲染命令模板（remoteCmd 或链部署栈的内置命令）。
+func (d *Deployer) renderCommandTemplate(text string, globalIps []string, i int, svc ServiceConfig) (string, error) {
+	tmpl, err := parseRemoteCmdTemplate(text)
+	if err != nil {
+		return "", fmt.Errorf("解析命令模板失败: %w", err)
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, d.buildRemoteCmdVars(globalIps, i, svc)); err != nil {
+		return "", fmt.Errorf("渲染命令模板失败（index=%d）: %w", i, err)
+	}
+	return buf.String(), nil
+}
@@ -0,0 +1,113 @@
+package deploy
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/wangdayong228/ydyl-deploy-client/internal/constants/enums"
+)
+
+func newRemoteCmdTestDeployer() *Deployer {
+	return &Deployer{
+		cfg: DeployConfig{
+			CommonConfig: CommonConfig{
+				L1ChainId:       "7655",
+				L1RpcUrl:        "https://l1.example/rpc",
+				L1RpcUrlWs:      "wss://l1.example/ws",
+				L1VaultMnemonic: "test test test test test test test test test test test junk",
+				DryRun:          true,
+			},
+		},
+		l1VaultDeriveRand: 1,
+	}
+}
+
+func TestBuildRemoteCommandForIndex_RendersTemplate(t *testing.T) {
+	t.Parallel()
+
+	d := newRemoteCmdTestDeployer()
+	globalIps := []string{"10.0.0.1", "10.0.0.2"}
+	svc := ServiceConfig{
+		Type:              enums.ServiceTypeOP,
+		TagPrefix:         "ydyl",
+		RemoteCmdTemplate: true,
+		RemoteCmd:         "IDX={{.Index}} NAME={{.Name}} IP={{.IP}} PEERS={{join .GlobalIPs \",\"}} L2={{.L2ChainID}} L1={{.Common.L1ChainId}} WS={{.L1RpcUrlWs}} DRYRUN={{.Common.DryRun}} KEY={{.L1VaultPrivateKey}} ./run.sh",
+	}
+
+	got, err := d.buildRemoteCommandForIndex(globalIps, 1, svc)
+	if err != nil {
+		t.Fatalf("buildRemoteCommandForIndex: %v", err)
+	}
+	want := "IDX=1 NAME=ydyl-op-2 IP=10.0.0.2 PEERS=10.0.0.1,10.0.0.2 L2=10001 L1=7655 WS=wss://l1.example/ws DRYRUN=true KEY="
+	if !strings.HasPrefix(got, want) {
+		t.Fatalf("rendered command = %q, want prefix %q", got, want)
+	}
+
+	// 模板中的 vault 私钥与内置命令派生的一致
+	builtin, err := d.buildRemoteCommandForIndex(globalIps, 1, ServiceConfig{Type: enums.ServiceTypeOP})
+	if err != nil {
+		t.Fatalf("builtin command: %v", err)
+	}
+	key := strings.TrimSuffix(strings.TrimPrefix(got, want), " ./run.sh")
+	if !strings.Contains(builtin, "L1_VAULT_PRIVATE_KEY="+key+" ") {
+		t.Fatalf("template key %q does not match builtin command %q", key, builtin)
+	}
+}
+
+func TestBuildRemoteCommandForIndex_XjstTemplateAndPlainCommand(t *testing.T) {
+	t.Parallel()
+
+	d := newRemoteCmdTestDeployer()
+	globalIps := []string{"10.0.0.1", "10.0.0.2", "10.0.0.3", "10.0.0.4", "10.0.0.5", "10.0.0.6", "10.0.0.7", "10.0.0.8"}
+	svc := ServiceConfig{
+		Type:              enums.ServiceTypeXJST,
+		RemoteCmdTemplate: true,
+		RemoteCmd:         "GROUP_ID={{.XjstGroupID}} NODE_ID=node-{{.XjstNodeID}} CHAIN_NODE_IPS='{{.XjstGroupIPs}}' ./xjst_pipe.sh",
+	}
+	got, err := d.buildRemoteCommandForIndex(globalIps, 5, svc)
+	if err != nil {
+		t.Fatalf("buildRemoteCommandForIndex: %v", err)
+	}
+	if want := "GROUP_ID=2 NODE_ID=node-2 CHAIN_NODE_IPS='[10.0.0.5,10.0.0.6,10.0.0.7,10.0.0.8]' ./xjst_pipe.sh"; got != want {
+		t.Fatalf("rendered command = %q, want %q", got, want)
+	}
+
+	// 未开启 remoteCmdTemplate 的命令按原样执行，即使其中包含 {{ }}
+	plain := ServiceConfig{Type: enums.ServiceTypeGeneric, RemoteCmd: "docker ps --format '{{.Names}}' && ./start.sh"}
+	if got, err := d.buildRemoteCommandForIndex(nil, 0, plain); err != nil || got != plain.RemoteCmd {
+		t.Fatalf("plain command = %q, %v", got, err)
+	}
+
+	// 模板中需要原样输出的 {{ 通过 {{"{{"}} 转义
+	escaped := ServiceConfig{Type: enums.ServiceTypeGeneric, RemoteCmdTemplate: true, RemoteCmd: `docker ps --format '{{"{{"}}.Names}}' --filter name=node-{{.Ordinal}}`}
+	if got, err := d.buildRemoteCommandForIndex(nil, 0, escaped); err != nil || got != "docker ps --format '{{.Names}}' --filter name=node-1" {
+		t.Fatalf("escaped command = %q, %v", got, err)
+	}
+
+	// 未知字段在渲染时报错
+	bad := ServiceConfig{Type: enums.ServiceTypeGeneric, RemoteCmdTemplate: true, RemoteCmd: "echo {{.Unknown}}"}
+	if _, err := d.buildRemoteCommandForIndex(nil, 0, bad); err == nil {
+		t.Fatalf("expected error for unknown template field")
+	}
+}
+
+func TestServiceConfigCheckValid_RemoteCmdTemplate(t *testing.T) {
+	t.Parallel()
+
+	svc := ServiceConfig{Type: enums.ServiceTypeGeneric, InstanceType: []string{"c6a.xlarge"}, RemoteCmd: "echo {{.Index}"}
+	if err := svc.CheckValid(); err != nil {
+		t.Fatalf("remoteCmd without remoteCmdTemplate must not be parsed: %v", err)
+	}
+	svc.RemoteCmdTemplate = true
+	if err := svc.CheckValid(); err == nil {
+		t.Fatalf("expected CheckValid to reject malformed remoteCmd template")
+	}
+	svc.RemoteCmd = "echo {{.Index}}"
+	if err := svc.CheckValid(); err != nil {
+		t.Fatalf("CheckValid: %v", err)
+	}
+	svc.RemoteCmd = ""
+	if err := svc.CheckValid(); err == nil {
+		t.Fatalf("expected CheckValid to reject remoteCmdTemplate without remoteCmd")
+	}
+}
@@ -0,0 +1,439 @@
+package deploy
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/wangdayong228/ydyl-deploy-client/internal/chainstack"
+)
+
+var runRemoveSSHCommandFunc = runSSH
+
+// RemoveOptions 描述一次缩容：按名称 glob 选中节点，停止远端脚本并从输出文件中移除。
+type RemoveOptions struct {
+	ServersPath string
+	// Names 为节点名称的 glob 模式（path.Match 语法），例如 ydyl-op-3、ydyl-xjst-2-*。
+	Names []string
+	// Terminate 为 true 时同时通过 EC2 API 终止实例。
+	Terminate bool
+}
+
+// RemoveTarget 为单个待移除节点及其处理结果。
+type RemoveTarget struct {
+	Name        string
+	ServiceType string
+	IP          string
+	PID         int
+	Status      string
+	InstanceID  string
+	// Group 为 xjst 节点所在分组（tagPrefix-xjst-groupId），同组节点整体移除。
+	Group string
+	// Expanded 为 true 表示该节点未被模式直接匹配，而是因同组节点被选中而一并移除。
+	Expanded   bool
+	Stopped    bool
+	Terminated bool
+	Removed    bool
+	// Partial 为 true 表示所在分组只完成了部分移除：本节点已停止或已终止，但同组其它节点失败。
+	Partial bool
+	Err     error
+}
+
+func (t RemoveTarget) key() string {
+	return compositeKey(t.IP, t.ServiceType)
+}
+
+// ResolveRemoveTargets 在 servers.json / script_status.json 中按名称模式选出待移除节点；
+// 任一 xjst 节点被选中时，同组全部节点一并纳入。
+func ResolveRemoveTargets(commonCfg CommonConfig, opts RemoveOptions) ([]RemoveTarget, error) {
+	outputMgr, err := LoadOutputManager(filepath.Dir(opts.resolveServersPath(commonCfg)))
+	if err != nil {
+		return nil, err
+	}
+	return resolveRemoveTargets(outputMgr, opts.Names)
+}
+
+func (o RemoveOptions) resolveServersPath(commonCfg CommonConfig) string {
+	if p := strings.TrimSpace(o.ServersPath); p != "" {
+		return p
+	}
+	return filepath.Join(resolveOutputDir(commonCfg, ""), "servers.json")
+}
+
+func resolveRemoveTargets(outputMgr *OutputManager, patterns []string) ([]RemoveTarget, error) {
+	cleaned := make([]string, 0, len(patterns))
+	for _, p := range patterns {
+		p = strings.TrimSpace(p)
+		if p == "" {
+			continue
+		}
+		if _, err := path.Match(p, ""); err != nil {
+			return nil, fmt.Errorf("--name 模式不合法 %q: %w", p, err)
+		}
+		cleaned = append(cleaned, p)
+	}
+	if len(cleaned) == 0 {
+		return nil, fmt.Errorf("至少需要指定一个 --name")
+	}
+
+	candidates := collectRemoveCandidates(outputMgr.SnapshotServers(), outputMgr.SnapshotStatuses())
+
+	selected := make(map[string]bool, len(candidates))
+	groups := make(map[string]struct{})
+	for _, p := range cleaned {
+		matched := false
+		for _, c := range candidates {
+			if ok, _ := path.Match(p, c.Name); !ok {
+				continue
+			}
+			matched = true
+			selected[c.key()] = true
+			if c.Group != "" {
+				groups[c.Group] = struct{}{}
+			}
+		}
+		if !matched {
+			return nil, fmt.Errorf("--name %q 未匹配任何节点", p)
+		}
+	}
+
+	targets := make([]RemoveTarget, 0, len(selected))
+	for _, c := range candidates {
+		_, inGroup := groups[c.Group]
+		direct := selected[c.key()]
+		if !direct && !(c.Group != "" && inGroup) {
+			continue
+		}
+		c.Expanded = !direct
+		targets = append(targets, c)
+	}
+	sort.Slice(targets, func(i, j int) bool {
+		if targets[i].ServiceType != targets[j].ServiceType {
+			return targets[i].ServiceType < targets[j].ServiceType
+		}
+		return targets[i].Name < targets[j].Name
+	})
+	return targets, nil
+}
+
+// collectRemoveCandidates 合并 servers.json 与 script_status.json 条目（按 IP+类型去重），补齐 PID / 状态。
+func collectRemoveCandidates(servers []ServerInfo, statuses []*ScriptStatus) []RemoveTarget {
+	byKey := make(map[string]*RemoveTarget)
+	order := make([]string, 0, len(servers)+len(statuses))
+	upsert := func(ip, serviceType, name string) *RemoveTarget {
+		ip, serviceType = strings.TrimSpace(ip), strings.TrimSpace(serviceType)
+		key := compositeKey(ip, serviceType)
+		t, ok := byKey[key]
+		if !ok {
+			t = &RemoveTarget{IP: ip, ServiceType: serviceType}
+			byKey[key] = t
+			order = append(order, key)
+		}
+		if t.Name == "" {
+			t.Name = strings.TrimSpace(name)
+		}
+		return t
+	}
+	for _, s := range servers {
+		if strings.TrimSpace(s.IP) != "" {
+			upsert(s.IP, s.ServiceType, s.Name)
+		}
+	}
+	for _, st := range statuses {
+		if st == nil || strings.TrimSpace(st.IP) == "" {
+			continue
+		}
+		t := upsert(st.IP, st.ServiceType, st.Name)
+		t.PID = st.PID
+		t.Status = st.Status
+	}
+
+	out := make([]RemoveTarget, 0, len(order))
+	for _, key := range order {
+		t := byKey[key]
+		if t.Name == "" {
+			continue
+		}
+		t.Group = nodeGroupOf(t.Name, t.ServiceType)
+		out = append(out, *t)
+	}
+	return out
+}
+
+// nodeGroupOf 返回分组组网节点（如 xjst）名称中的分组前缀（tagPrefix-serviceType-groupId）；独立成链的节点返回空串。
+func nodeGroupOf(name, serviceType string) string {
+	stack, ok := chainstack.LookupName(serviceType)
+	if !ok || stack.GroupSize() <= 1 {
+		return ""
+	}
+	if _, _, err := chainstack.ParseGroupMember(stack, name); err != nil {
+		return ""
+	}
+	return name[:strings.LastIndex(name, "-")]
+}
+
+// Remove 从部署中移除选中的节点：
+//  1. 非终止模式下先通过 SSH 预检 xjst 分组内待停止的节点，任一不可达则整组跳过，不停止任何成员；
+//  2. 对仍在运行的节点通过 SSH 停止远端 pipe 脚本（ScriptStatus.PID）；
+//  3. 可选通过 EC2 API 终止实例；
+//  4. 从 servers.json / script_status.json / ssh_scripts.json 中删除条目。
+//
+// 任一步骤失败的节点保留在输出文件中。xjst 分组只有在全部成员停止后才会终止实例、删除条目；
+// 若组内已有成员被停止或终止而其它成员失败，该组标记为部分移除（Partial），不会报告为整组保留。
+func Remove(ctx context.Context, commonCfg CommonConfig, opts RemoveOptions) ([]RemoveTarget, error) {
+	outputMgr, err := LoadOutputManager(filepath.Dir(opts.resolveServersPath(commonCfg)))
+	if err != nil {
+		return nil, err
+	}
+	targets, err := resolveRemoveTargets(outputMgr, opts.Names)
+	if err != nil {
+		return nil, err
+	}
+
+	sshUser := strings.TrimSpace(commonCfg.SSHUser)
+	if sshUser == "" {
+		return nil, fmt.Errorf("sshUser 不能为空")
+	}
+	sshKeyPath := buildSSHKeyPath(commonCfg)
+	deploymentID := ""
+	if opts.Terminate {
+		if deploymentID, err = resolveDeploymentID(commonCfg, filepath.Dir(opts.resolveServersPath(commonCfg))); err != nil {
+			return nil, err
+		}
+	}
+
+	// 1) 分组预检：停止脚本不可回滚，先确认组内待停止节点均可达；终止模式下 SSH 失败不阻断，无需预检
+	if !opts.Terminate {
+		runWithBatchLimit("remove-precheck-remote", len(targets), resolveSSHMaxConcurrency(commonCfg), func(i int) {
+			t := &targets[i]
+			if t.Group == "" || !t.needsStop() {
+				return
+			}
+			if _, runErr := runRemoveSSHCommandFunc(ctx, sshUser, sshKeyPath, t.IP, "true"); runErr != nil {
+				t.Err = fmt.Errorf("SSH 预检失败: %w", runErr)
+			}
+		})
+		for group, failedName := range failedRemoveGroups(targets) {
+			for i := range targets {
+				if t := &targets[i]; t.Group == group && t.Err == nil {
+					t.Err = fmt.Errorf("同组节点 %s 预检失败，整组保留", failedName)
+				}
+			}
+		}
+	}
+
+	// 2) 停止远端脚本；终止实例时 SSH 失败不阻断（实例随后会被销毁）
+	log.Printf("👉 [remove] 开始停止远端脚本，共 %d 个节点\n", len(targets))
+	runWithBatchLimit("remove-stop-remote", len(targets), resolveSSHMaxConcurrency(commonCfg), func(i int) {
+		t := &targets[i]
+		if t.Err != nil || !t.needsStop() {
+			return
+		}
+		output, runErr := runRemoveSSHCommandFunc(ctx, sshUser, sshKeyPath, t.IP, buildStopRemoteCommand(t.PID))
+		if runErr != nil {
+			if msg := strings.TrimSpace(output); msg != "" {
+				runErr = fmt.Errorf("%w，输出: %s", runErr, msg)
+			}
+			if opts.Terminate {
+				log.Printf("⚠️ [remove][%s][%s] 停止远端脚本失败，将直接终止实例: %v\n", t.IP, t.Name, runErr)
+				return
+			}
+			t.Err = fmt.Errorf("停止远端脚本失败: %w", runErr)
+			return
+		}
+		t.Stopped = true
+		log.Printf("[remove][%s][%s] 已停止远端脚本 pid=%d\n", t.IP, t.Name, t.PID)
+	})
+
+	// 3) 同组有成员未能停止时，其余成员既不终止也不删除条目；已停止的成员标记为部分移除
+	for group, failedName := range failedRemoveGroups(targets) {
+		for i := range targets {
+			t := &targets[i]
+			if t.Group != group || t.Err != nil {
+				continue
+			}
+			if t.Stopped {
+				t.Partial = true
+				t.Err = fmt.Errorf("同组节点 %s 停止失败，本节点脚本已停止但条目保留（分组部分移除）", failedName)
+			} else {
+				t.Err = fmt.Errorf("同组节点 %s 停止失败，本节点未处理", failedName)
+			}
+		}
+	}
+
+	// 4) 终止实例：只处理尚未失败的节点
+	if opts.Terminate {
+		pending := make([]RemoveTarget, 0, len(targets))
+		pendingIdx := make([]int, 0, len(targets))
+		for i, t := range targets {
+			if t.Err == nil {
+				pending = append(pending, t)
+				pendingIdx = append(pendingIdx, i)
+			}
+		}
+		if len(pending) > 0 {
+			terminateRemoveTargets(ctx, commonCfg, deploymentID, outputMgr.SnapshotCreatedServers(), pending)
+			for j, i := range pendingIdx {
+				targets[i] = pending[j]
+			}
+		}
+		// 实例已终止的同组成员无法恢复：照常删除其条目，并标记该组部分移除
+		for group := range failedRemoveGroups(targets) {
+			for i := range targets {
+				if t := &targets[i]; t.Group == group && t.Err == nil && t.Terminated {
+					t.Partial = true
+				}
+			}
+		}
+	}
+
+	keys := make([]string, 0, len(targets))
+	for _, t := range targets {
+		if t.Err == nil {
+			keys = append(keys, t.key())
+		}
+	}
+	if err := outputMgr.RemoveEntries(keys); err != nil {
+		return targets, err
+	}
+
+	var errs []error
+	partialGroups := make(map[string]struct{})
+	for i := range targets {
+		t := &targets[i]
+		if t.Partial {
+			partialGroups[t.Group] = struct{}{}
+		}
+		if t.Err != nil {
+			errs = append(errs, fmt.Errorf("[%s][%s] %w", t.IP, t.Name, t.Err))
+			continue
+		}
+		t.Removed = true
+	}
+	for group := range partialGroups {
+		errs = append(errs, fmt.Errorf("分组 %s 仅部分移除，请处理失败节点后重新执行 remove", group))
+	}
+	if len(errs) > 0 {
+		return targets, deployMultiError{errs: errs}
+	}
+	log.Printf("✅ [remove] 已移除 %d 个节点，可重新执行 gen-cross-tx-config 生成 jobs\n", len(targets))
+	return targets, nil
+}
+
+// needsStop 表示节点记录了 PID 且脚本仍处于运行相关状态，需要通过 SSH 停止。
+func (t RemoveTarget) needsStop() bool {
+	return t.PID > 0 && shouldMonitorSyncStatus(t.Status)
+}
+
+// failedRemoveGroups 返回存在失败成员的 xjst 分组及其中一个失败节点名称。
+func failedRemoveGroups(targets []RemoveTarget) map[string]string {
+	failed := make(map[string]string)
+	for _, t := range targets {
+		if t.Err != nil && t.Group != "" && !t.Partial {
+			if _, ok := failed[t.Group]; !ok {
+				failed[t.Group] = t.Name
+			}
+		}
+	}
+	return failed
+}
+
+// buildStopRemoteCommand 终止远端后台脚本及其直接子进程；进程已退出时视为成功。
+func buildStopRemoteCommand(pid int) string {
+	return fmt.Sprintf("pkill -TERM -P %d 2>/dev/null; kill -TERM %d 2>/dev/null; true", pid, pid)
+}
+
+// terminateRemoveTargets 解析实例 ID（优先 servers_create.json 记录，否则按公网 IP）并终止实例，结果写回 targets；
+// 无法解析实例 ID 的节点记为失败，不视为已终止。
+func terminateRemoveTargets(ctx context.Context, commonCfg CommonConfig, deploymentID string, created []CreatedServerInfo, targets []RemoveTarget) {
+	setErr := func(err error) {
+		for i := range targets {
+			if targets[i].Err == nil {
+				targets[i].Err = err
+			}
+		}
+	}
+
+	createdByKey := make(map[string]string, len(created))
+	for _, c := range created {
+		if c.InstanceID != "" {
+			createdByKey[compositeKey(c.IP, c.ServiceType)] = c.InstanceID
+		}
+	}
+	client, err := newEC2APIFunc(commonCfg.Region)
+	if err != nil {
+		setErr(err)
+		return
+	}
+
+	var unresolved []string
+	for i := range targets {
+		if id, ok := createdByKey[targets[i].key()]; ok {
+			targets[i].InstanceID = id
+		} else {
+			unresolved = append(unresolved, targets[i].IP)
+		}
+	}
+	if len(unresolved) > 0 {
+		byIP, err := describeInstancesByIPs(ctx, client, unresolved, deploymentID)
+		if err != nil {
+			setErr(err)
+			return
+		}
+		for i := range targets {
+			if targets[i].InstanceID == "" {
+				targets[i].InstanceID = byIP[targets[i].IP].InstanceID
+			}
+		}
+	}
+
+	ids := make([]string, 0, len(targets))
+	seen := make(map[string]struct{}, len(targets))
+	for _, t := range targets {
+		if t.InstanceID == "" {
+			continue
+		}
+		if _, ok := seen[t.InstanceID]; ok {
+			continue
+		}
+		seen[t.InstanceID] = struct{}{}
+		ids = append(ids, t.InstanceID)
+	}
+	log.Printf("👉 [remove] 开始终止 %d 台实例\n", len(ids))
+	failed := terminateInstances(ctx, client, ids)
+	var waitErr error
+	submitted := make([]string, 0, len(ids))
+	for _, id := range ids {
+		if _, ok := failed[id]; !ok {
+			submitted = append(submitted, id)
+		}
+	}
+	if errs := waitInstancesTerminated(ctx, client, submitted); len(errs) > 0 {
+		waitErr = deployMultiError{errs: errs}
+	}
+
+	for i := range targets {
+		t := &targets[i]
+		switch {
+		case t.InstanceID == "":
+			// 与 teardown 的 unresolved 相同：可能已终止，也可能仍在运行但缺少本部署标签，保留条目以免丢失跟踪
+			if t.Err == nil {
+				t.Err = fmt.Errorf("实例 ID 未解析（servers_create.json 无记录，且未找到带本部署标签的存活实例），未终止")
+			}
+		case failed[t.InstanceID] != nil:
+			if t.Err == nil {
+				t.Err = fmt.Errorf("终止实例 %s 失败: %w", t.InstanceID, failed[t.InstanceID])
+			}
+		case waitErr != nil:
+			if t.Err == nil {
+				t.Err = fmt.Errorf("等待实例 %s 终止失败: %w", t.InstanceID, waitErr)
+			}
+		default:
+			t.Terminated = true
+		}
+	}
+}
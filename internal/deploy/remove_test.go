@@ -0,0 +1,277 @@
+package deploy
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/service/ec2/ec2iface"
+)
+
+func seedRemoveOutput(t *testing.T) string {
+	t.Helper()
+
+	outputDir := t.TempDir()
+	mgr := NewOutputManager(outputDir)
+	servers := []ServerInfo{
+		{IP: "1.1.1.1", ServiceType: "op", Name: "ydyl-op-1"},
+		{IP: "1.1.1.2", ServiceType: "op", Name: "ydyl-op-2"},
+		{IP: "1.1.1.3", ServiceType: "op", Name: "ydyl-op-3"},
+	}
+	for i, ip := range []string{"2.2.2.1", "2.2.2.2", "2.2.2.3", "2.2.2.4", "2.2.3.1", "2.2.3.2", "2.2.3.3", "2.2.3.4"} {
+		servers = append(servers, ServerInfo{IP: ip, ServiceType: "xjst", Name: (&Deployer{}).buildInstanceName("ydyl", "xjst", i+1)})
+	}
+	if err := mgr.AddServers(servers); err != nil {
+		t.Fatalf("AddServers: %v", err)
+	}
+	for i, s := range servers {
+		if err := mgr.InitStatus(s.IP, s.ServiceType, s.Name, "cmd", 100+i, "", "", 0, 0, ""); err != nil {
+			t.Fatalf("InitStatus: %v", err)
+		}
+		if err := mgr.UpdateSSHScriptStatus(s.IP, s.ServiceType, s.Name, "success", 1, "", 0); err != nil {
+			t.Fatalf("UpdateSSHScriptStatus: %v", err)
+		}
+	}
+	return outputDir
+}
+
+func TestResolveRemoveTargets_ExpandsXJSTGroup(t *testing.T) {
+	t.Parallel()
+
+	outputDir := seedRemoveOutput(t)
+	targets, err := ResolveRemoveTargets(CommonConfig{}, RemoveOptions{
+		ServersPath: filepath.Join(outputDir, "servers.json"),
+		Names:       []string{"ydyl-op-3", "ydyl-xjst-2-3"},
+	})
+	if err != nil {
+		t.Fatalf("ResolveRemoveTargets: %v", err)
+	}
+	var names []string
+	expanded := 0
+	for _, tgt := range targets {
+		names = append(names, tgt.Name)
+		if tgt.Expanded {
+			expanded++
+		}
+	}
+	want := "ydyl-op-3,ydyl-xjst-2-1,ydyl-xjst-2-2,ydyl-xjst-2-3,ydyl-xjst-2-4"
+	if strings.Join(names, ",") != want || expanded != 3 {
+		t.Fatalf("unexpected targets: %v (expanded=%d)", names, expanded)
+	}
+
+	if _, err := ResolveRemoveTargets(CommonConfig{}, RemoveOptions{
+		ServersPath: filepath.Join(outputDir, "servers.json"),
+		Names:       []string{"ydyl-cdk-*"},
+	}); err == nil {
+		t.Fatalf("expected error for pattern without matches")
+	}
+}
+
+func TestRemove_KeepsWholeGroupWhenPrecheckFails(t *testing.T) {
+	outputDir := seedRemoveOutput(t)
+
+	orig := runRemoveSSHCommandFunc
+	t.Cleanup(func() { runRemoveSSHCommandFunc = orig })
+	var (
+		mu      sync.Mutex
+		stopped []string
+	)
+	runRemoveSSHCommandFunc = func(_ context.Context, _, _, ip, remoteCmd string) (string, error) {
+		if ip == "2.2.3.2" {
+			return "", errors.New("ssh timeout")
+		}
+		if remoteCmd == "true" {
+			return "", nil
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		stopped = append(stopped, ip+" "+remoteCmd)
+		return "", nil
+	}
+
+	targets, err := Remove(context.Background(), CommonConfig{SSHUser: "ubuntu", KeyName: "k"}, RemoveOptions{
+		ServersPath: filepath.Join(outputDir, "servers.json"),
+		Names:       []string{"ydyl-op-[23]", "ydyl-xjst-2-1"},
+	})
+	if err == nil || !strings.Contains(err.Error(), "2.2.3.2") {
+		t.Fatalf("expected aggregated error mentioning 2.2.3.2, got=%v", err)
+	}
+	if len(targets) != 6 {
+		t.Fatalf("unexpected targets count: %d", len(targets))
+	}
+	// 预检失败的分组不得停止任何成员
+	sort.Strings(stopped)
+	if len(stopped) != 2 || stopped[0] != "1.1.1.2 "+buildStopRemoteCommand(101) {
+		t.Fatalf("unexpected stop commands: %v", stopped)
+	}
+	for _, tgt := range targets {
+		if tgt.Group != "" && (tgt.Stopped || tgt.Partial || tgt.Removed) {
+			t.Fatalf("group member must be left untouched: %+v", tgt)
+		}
+	}
+
+	reloaded, err := LoadOutputManager(outputDir)
+	if err != nil {
+		t.Fatalf("LoadOutputManager: %v", err)
+	}
+	var names []string
+	for _, s := range reloaded.SnapshotServers() {
+		names = append(names, s.Name)
+	}
+	if strings.Contains(strings.Join(names, ","), "ydyl-op-2") || strings.Contains(strings.Join(names, ","), "ydyl-op-3") {
+		t.Fatalf("op-2/op-3 should be removed, got=%v", names)
+	}
+	if len(names) != 9 {
+		t.Fatalf("xjst group 2 must be kept as a whole, got=%v", names)
+	}
+	if got := len(reloaded.SnapshotStatuses()); got != 9 {
+		t.Fatalf("unexpected statuses count after remove: %d", got)
+	}
+}
+
+func TestRemove_ReportsPartialGroupWhenStopFails(t *testing.T) {
+	outputDir := seedRemoveOutput(t)
+
+	orig := runRemoveSSHCommandFunc
+	t.Cleanup(func() { runRemoveSSHCommandFunc = orig })
+	// 预检通过，但 2.2.3.2 停止脚本失败：其余成员已被停止，条目须保留并标记部分移除
+	runRemoveSSHCommandFunc = func(_ context.Context, _, _, ip, remoteCmd string) (string, error) {
+		if ip == "2.2.3.2" && remoteCmd != "true" {
+			return "", errors.New("kill failed")
+		}
+		return "", nil
+	}
+
+	targets, err := Remove(context.Background(), CommonConfig{SSHUser: "ubuntu", KeyName: "k"}, RemoveOptions{
+		ServersPath: filepath.Join(outputDir, "servers.json"),
+		Names:       []string{"ydyl-xjst-2-1"},
+	})
+	if err == nil || !strings.Contains(err.Error(), "仅部分移除") {
+		t.Fatalf("expected partial removal error, got=%v", err)
+	}
+	partial := 0
+	for _, tgt := range targets {
+		if tgt.Removed {
+			t.Fatalf("no member may be removed before the whole group stopped: %+v", tgt)
+		}
+		if tgt.Partial {
+			if !tgt.Stopped || tgt.Err == nil {
+				t.Fatalf("partial member should be stopped and carry an error: %+v", tgt)
+			}
+			partial++
+		}
+	}
+	if partial != 3 {
+		t.Fatalf("expected 3 partially removed members, got=%d", partial)
+	}
+
+	reloaded, err := LoadOutputManager(outputDir)
+	if err != nil {
+		t.Fatalf("LoadOutputManager: %v", err)
+	}
+	if got := len(reloaded.SnapshotServers()); got != 11 {
+		t.Fatalf("group entries must be kept, got %d servers", got)
+	}
+}
+
+func TestRemove_TerminatesInstances(t *testing.T) {
+	outputDir := seedRemoveOutput(t)
+	mgr, err := LoadOutputManager(outputDir)
+	if err != nil {
+		t.Fatalf("LoadOutputManager: %v", err)
+	}
+	if err := mgr.AddCreatedServers([]CreatedServerInfo{{Name: "ydyl-op-create-1", ServiceType: "op", IP: "1.1.1.1", InstanceID: "i-op-1"}}); err != nil {
+		t.Fatalf("AddCreatedServers: %v", err)
+	}
+
+	if err := SaveDeploymentInfo(outputDir, DeploymentInfo{DeploymentID: "dep-1"}); err != nil {
+		t.Fatalf("SaveDeploymentInfo: %v", err)
+	}
+
+	// op-2 未记录实例 ID，只能按 IP 解析且须带本部署标签；op-3 的 IP 已被无关实例复用，不得终止。
+	fake := newFakeEC2(fakeInstance("i-op-1", "1.1.1.1", ""), fakeInstance("i-op-2", "1.1.1.2", "dep-1"), fakeInstance("i-reused", "1.1.1.3", ""))
+	origEC2, origSSH := newEC2APIFunc, runRemoveSSHCommandFunc
+	t.Cleanup(func() {
+		newEC2APIFunc = origEC2
+		runRemoveSSHCommandFunc = origSSH
+	})
+	newEC2APIFunc = func(string) (ec2iface.EC2API, error) { return fake, nil }
+	// 终止模式下 SSH 失败不阻断
+	runRemoveSSHCommandFunc = func(context.Context, string, string, string, string) (string, error) {
+		return "", errors.New("unreachable")
+	}
+
+	targets, err := Remove(context.Background(), CommonConfig{SSHUser: "ubuntu", KeyName: "k"}, RemoveOptions{
+		ServersPath: filepath.Join(outputDir, "servers.json"),
+		Names:       []string{"ydyl-op-1", "ydyl-op-2"},
+		Terminate:   true,
+	})
+	if err != nil {
+		t.Fatalf("Remove: %v", err)
+	}
+	for _, tgt := range targets {
+		if !tgt.Removed || !tgt.Terminated || tgt.Stopped {
+			t.Fatalf("unexpected result: %+v", tgt)
+		}
+	}
+	if len(fake.terminated) != 1 || strings.Join(fake.terminated[0], ",") != "i-op-1,i-op-2" {
+		t.Fatalf("unexpected terminated ids: %v", fake.terminated)
+	}
+}
+
+func TestRemove_KeepsUnresolvedInstance(t *testing.T) {
+	outputDir := seedRemoveOutput(t)
+	if err := SaveDeploymentInfo(outputDir, DeploymentInfo{DeploymentID: "dep-1"}); err != nil {
+		t.Fatalf("SaveDeploymentInfo: %v", err)
+	}
+
+	// op-3 没有 servers_create.json 记录，其 IP 上的实例缺少本部署标签：无法确认已终止，条目须保留
+	fake := newFakeEC2(fakeInstance("i-op-2", "1.1.1.2", "dep-1"), fakeInstance("i-untagged", "1.1.1.3", ""))
+	origEC2, origSSH := newEC2APIFunc, runRemoveSSHCommandFunc
+	t.Cleanup(func() {
+		newEC2APIFunc = origEC2
+		runRemoveSSHCommandFunc = origSSH
+	})
+	newEC2APIFunc = func(string) (ec2iface.EC2API, error) { return fake, nil }
+	runRemoveSSHCommandFunc = func(context.Context, string, string, string, string) (string, error) { return "", nil }
+
+	targets, err := Remove(context.Background(), CommonConfig{SSHUser: "ubuntu", KeyName: "k"}, RemoveOptions{
+		ServersPath: filepath.Join(outputDir, "servers.json"),
+		Names:       []string{"ydyl-op-2", "ydyl-op-3"},
+		Terminate:   true,
+	})
+	if err == nil || !strings.Contains(err.Error(), "1.1.1.3") {
+		t.Fatalf("expected error for unresolved instance, got=%v", err)
+	}
+	for _, tgt := range targets {
+		switch tgt.Name {
+		case "ydyl-op-2":
+			if !tgt.Removed || !tgt.Terminated {
+				t.Fatalf("resolved instance should be terminated and removed: %+v", tgt)
+			}
+		case "ydyl-op-3":
+			if tgt.Removed || tgt.Terminated || tgt.Err == nil {
+				t.Fatalf("unresolved instance must not be treated as terminated: %+v", tgt)
+			}
+		}
+	}
+	if len(fake.terminated) != 1 || strings.Join(fake.terminated[0], ",") != "i-op-2" {
+		t.Fatalf("unexpected terminated ids: %v", fake.terminated)
+	}
+
+	reloaded, err := LoadOutputManager(outputDir)
+	if err != nil {
+		t.Fatalf("LoadOutputManager: %v", err)
+	}
+	var names []string
+	for _, s := range reloaded.SnapshotServers() {
+		names = append(names, s.Name)
+	}
+	if joined := strings.Join(names, ","); strings.Contains(joined, "ydyl-op-2") || !strings.Contains(joined, "ydyl-op-3") {
+		t.Fatalf("unexpected servers after remove: %v", names)
+	}
+}
@@ -0,0 +1,370 @@
+package deploy
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ReplaceOptions 描述一次原位替换：为已失效的节点新建实例并沿用其逻辑身份。
+type ReplaceOptions struct {
+	ServersPath string
+	// Name 为待替换节点的逻辑名称（servers.json 中的 name）。
+	Name string
+	// RestartGroup 为 true 时，xjst 同组其它节点也按新的 CHAIN_NODE_IPS 重新执行部署命令。
+	RestartGroup bool
+	// TerminateOld 为 true 时通过 EC2 API 终止旧实例（若仍存活）。
+	TerminateOld bool
+}
+
+// ReplaceResult 为一次替换的结果摘要。
+type ReplaceResult struct {
+	Name        string
+	ServiceType string
+	OldIP       string
+	NewIP       string
+	InstanceID  string
+	// GroupIPs 为重新渲染了 CHAIN_NODE_IPS 的 xjst 同组其它节点。
+	GroupIPs []string
+}
+
+// Replace 为指定节点新建一台同配置实例，并让其接管原节点身份：
+//  1. 按 services 中对应配置创建实例、等待 SSH 就绪，并将 Name 标签改为原逻辑名称；
+//  2. 以相同索引重建远端命令（L2 chainId / xjst groupId / L1 vault 私钥保持不变）；
+//     deployment.json 未记录派生随机段时，沿用 script_status.json 中已记录的命令；
+//  3. 将 servers.json / script_status.json 中的旧 IP 改写为新 IP；xjst 同时重新渲染同组其它节点的 CHAIN_NODE_IPS；
+//  4. 在新实例上启动部署命令并同步日志与状态。
+func Replace(ctx context.Context, cfg DeployConfig, opts ReplaceOptions) (*ReplaceResult, error) {
+	serversPath := strings.TrimSpace(opts.ServersPath)
+	if serversPath == "" {
+		serversPath = filepath.Join(resolveOutputDir(cfg.CommonConfig, ""), "servers.json")
+	}
+	outputDir := filepath.Dir(serversPath)
+	cfg.CommonConfig.OutputDir = outputDir
+	commonCfg := cfg.CommonConfig
+	if err := os.MkdirAll(commonCfg.LogDir, 0o755); err != nil {
+		return nil, fmt.Errorf("创建日志目录失败: %w", err)
+	}
+
+	outputMgr, err := LoadOutputManager(outputDir)
+	if err != nil {
+		return nil, fmt.Errorf("加载输出状态失败: %w", err)
+	}
+	servers, statuses := outputMgr.SnapshotServers(), outputMgr.SnapshotStatuses()
+	target, err := findReplaceTarget(servers, statuses, opts.Name)
+	if err != nil {
+		return nil, err
+	}
+	svc, err := findServiceConfigForNode(cfg.Services, target.Name, target.ServiceType)
+	if err != nil {
+		return nil, err
+	}
+	plans := buildAppendPlans(servers, statuses, serviceGroupSizes(cfg.Services))
+	plan := plans[target.ServiceType]
+	ordinal, ok := parseInstanceOrdinal(target.Name, target.ServiceType, svc.GroupSize)
+	if !ok {
+		return nil, fmt.Errorf("无法按 groupSize=%d 从名称 %q 解析节点序号", svc.groupSize(), target.Name)
+	}
+
+	deployment, err := LoadDeploymentInfo(outputDir)
+	if err != nil {
+		return nil, err
+	}
+	if deployment == nil {
+		info := newDeploymentInfo(commonCfg, time.Now())
+		deployment = &info
+	}
+	d := newReplaceDeployer(ctx, cfg, outputMgr, *deployment, plans)
+	// builder 为空表示无法按索引重建（缺少派生随机段），改为沿用已记录的命令
+	builder := d
+	if deployment.L1VaultDeriveRand != nil {
+		d.l1VaultDeriveRand = *deployment.L1VaultDeriveRand
+	} else if strings.TrimSpace(svc.RemoteCmd) == "" || isRemoteCmdTemplate(svc) {
+		builder = nil
+		log.Printf("⚠️ [replace] %s 未记录 L1 vault 派生随机段，沿用 script_status.json 中已记录的命令\n", deploymentInfoFileName)
+	}
+	recorded := make(map[string]string, len(statuses))
+	for _, st := range statuses {
+		if st.ServiceType == target.ServiceType {
+			recorded[st.IP] = st.Command
+		}
+	}
+	// 创建实例前先用旧 IP 试渲染一次，避免新建实例后才发现命令无法生成
+	if _, err := renderReplacementCommands(builder, plan, ordinal-1, target.IP, target.IP, svc, recorded); err != nil {
+		return nil, err
+	}
+
+	ec2Client, err := newEC2Client(commonCfg.Region)
+	if err != nil {
+		return nil, err
+	}
+	d.ec2Client = ec2Client
+
+	// 1) 新建实例并等待 SSH 就绪
+	log.Printf("👉 [replace][%s] 为节点 %s（旧 IP %s）创建替换实例...\n", target.ServiceType, target.Name, target.IP)
+	readyIPs, err := d.acquireSSHReadyIPs(svc, 1)
+	if err != nil {
+		return nil, err
+	}
+	newIP := readyIPs[0]
+	instID, err := d.findInstanceByIP(newIP)
+	if err != nil {
+		return nil, err
+	}
+	if err := d.tagInstanceName(instID, target.Name); err != nil {
+		return nil, err
+	}
+	outputMgr.RecordEvent(Event{Type: EventInstanceTagged, IP: newIP, ServiceType: target.ServiceType, Name: target.Name, InstanceID: instID})
+	log.Printf("✅ [replace] 新实例 %s（%s）已就绪并沿用名称 %s\n", instID, newIP, target.Name)
+
+	if err := d.provisionFiles(newIP, svc, "[replace]"); err != nil {
+		return nil, err
+	}
+	if err := d.ensureRepoCommit(newIP); err != nil {
+		return nil, err
+	}
+
+	// 2) 以相同索引重建命令
+	commands, err := renderReplacementCommands(builder, plan, ordinal-1, target.IP, newIP, svc, recorded)
+	if err != nil {
+		return nil, err
+	}
+
+	// 3) 改写输出文件
+	now := time.Now().Unix()
+	remoteLogFile, _ := buildRemoteLogPath("", target.Name)
+	if err := outputMgr.ReplaceIP(target.ServiceType, target.IP, newIP, func(st *ScriptStatus) {
+		st.Name = target.Name
+		st.Command = commands[newIP]
+		st.PID = 0
+		st.Status = "pending"
+		st.Reason = ""
+		st.LogPath = remoteLogFile
+		st.LocalLog = buildLocalLogPath(commonCfg.LogDir, newIP, target.Name)
+		st.LogSize = 0
+		st.UpdatedAt = now
+		if builder != nil {
+			st.RepoCommit = d.repoCommit()
+		}
+	}); err != nil {
+		return nil, err
+	}
+	d.probeFingerprints(svc, []ServerInfo{{IP: newIP, ServiceType: target.ServiceType, Name: target.Name}})
+	result := &ReplaceResult{Name: target.Name, ServiceType: target.ServiceType, OldIP: target.IP, NewIP: newIP, InstanceID: instID}
+	// 同组节点只有随 --restart-group 重新启动时才会 checkout 新的 commit，未重启的节点保留原 RepoCommit
+	repoCommit := ""
+	if builder != nil && opts.RestartGroup {
+		repoCommit = d.repoCommit()
+	}
+	groupIPs, err := updateGroupCommands(outputMgr, target.ServiceType, newIP, commands, repoCommit, now)
+	result.GroupIPs = groupIPs
+	if err != nil {
+		return result, err
+	}
+	if len(result.GroupIPs) > 0 {
+		log.Printf("📝 [replace] 已重新渲染同组节点的 CHAIN_NODE_IPS: %s\n", strings.Join(result.GroupIPs, ", "))
+	}
+
+	if opts.TerminateOld {
+		terminateReplacedInstance(ctx, commonCfg, outputDir, target.IP)
+	}
+
+	// 4) 启动新节点（以及可选的同组节点）并同步
+	restartIPs := []string{newIP}
+	if opts.RestartGroup {
+		restartIPs = append(restartIPs, result.GroupIPs...)
+	} else if len(result.GroupIPs) > 0 {
+		log.Printf("ℹ️ [replace] 同组节点未重启；如需使其连接新节点，可执行 deploy-restore --ips %s\n", strings.Join(result.GroupIPs, ","))
+	}
+	candidates, err := filterStatusesByIPs(outputMgr.SnapshotStatuses(), restartIPs)
+	if err != nil {
+		return result, err
+	}
+	candidates = filterRestoreCommandCandidates(candidates, true)
+	return result, NewRestorer(commonCfg, outputMgr).Run(ctx, candidates)
+}
+
+// newReplaceDeployer 构造 replace 复用 deploy 创建 / SSH 收敛流程所需的 Deployer；不记录 deploy 进度，
+// 避免改写 state.json 中该服务的阶段与续跑信息。
+func newReplaceDeployer(ctx context.Context, cfg DeployConfig, outputMgr *OutputManager, deployment DeploymentInfo, plans map[string]*appendPlan) *Deployer {
+	return &Deployer{
+		ctx:          ctx,
+		cfg:          cfg,
+		outputMgr:    outputMgr,
+		sshKeyPath:   buildSSHKeyPath(cfg.CommonConfig),
+		deployment:   deployment,
+		appendPlans:  plans,
+		skipProgress: true,
+	}
+}
+
+// updateGroupCommands 将重新渲染的命令写回 newIP 以外的同组节点，返回已更新的 IP（按字典序）；
+// repoCommit 非空时同时更新这些节点的 RepoCommit。
+func updateGroupCommands(outputMgr *OutputManager, serviceType, newIP string, commands map[string]string, repoCommit string, now int64) ([]string, error) {
+	ips := make([]string, 0, len(commands))
+	for ip := range commands {
+		if ip != newIP {
+			ips = append(ips, ip)
+		}
+	}
+	sort.Strings(ips)
+
+	updated := make([]string, 0, len(ips))
+	for _, ip := range ips {
+		cmd := commands[ip]
+		if err := outputMgr.UpdateStatus(ip, serviceType, func(st *ScriptStatus) {
+			st.Command = cmd
+			st.UpdatedAt = now
+			if repoCommit != "" {
+				st.RepoCommit = repoCommit
+			}
+		}); err != nil {
+			return updated, fmt.Errorf("[%s] 更新同组节点命令失败: %w", ip, err)
+		}
+		updated = append(updated, ip)
+	}
+	return updated, nil
+}
+
+// findReplaceTarget 按名称精确查找节点。
+func findReplaceTarget(servers []ServerInfo, statuses []*ScriptStatus, name string) (RemoveTarget, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return RemoveTarget{}, fmt.Errorf("--name 不能为空")
+	}
+	var found []RemoveTarget
+	for _, c := range collectRemoveCandidates(servers, statuses) {
+		if c.Name == name {
+			found = append(found, c)
+		}
+	}
+	switch len(found) {
+	case 0:
+		return RemoveTarget{}, fmt.Errorf("servers.json / script_status.json 中未找到节点 %q", name)
+	case 1:
+	default:
+		return RemoveTarget{}, fmt.Errorf("节点名称 %q 对应多条记录，无法确定替换目标", name)
+	}
+	if !isInstanceName(found[0].Name, found[0].ServiceType) {
+		return RemoveTarget{}, fmt.Errorf("无法从名称 %q 解析节点序号（期望 deploy 生成的 tagPrefix-%s-序号 格式）", name, found[0].ServiceType)
+	}
+	return found[0], nil
+}
+
+// findServiceConfigForNode 在 services 中查找生成该节点的配置（类型一致且名称以 tagPrefix-type- 开头）。
+func findServiceConfigForNode(services []ServiceConfig, name, serviceType string) (ServiceConfig, error) {
+	for _, svc := range services {
+		if svc.Type.String() != serviceType {
+			continue
+		}
+		if strings.HasPrefix(name, fmt.Sprintf("%s-%s-", svc.TagPrefix, serviceType)) {
+			return svc, nil
+		}
+	}
+	return ServiceConfig{}, fmt.Errorf("配置文件 services 中未找到生成节点 %q 的 %s 服务（tagPrefix 不匹配）", name, serviceType)
+}
+
+// renderReplacementCommands 生成替换后需要更新的命令，返回 IP -> 命令：
+//   - 非 xjst 只包含被替换节点；
+//   - xjst 包含同组全部节点（CHAIN_NODE_IPS 中的旧 IP 被替换为新 IP）。
+//
+// d 非空时按索引重建命令；为空时沿用 recorded 中已记录的命令，仅改写 CHAIN_NODE_IPS。
+func renderReplacementCommands(d *Deployer, plan *appendPlan, index int, oldIP, newIP string, svc ServiceConfig, recorded map[string]string) (map[string]string, error) {
+	if plan == nil || index < 0 || index >= len(plan.existingIPs) {
+		return nil, fmt.Errorf("未找到节点索引 %d 的记录", index)
+	}
+	globalIps := make([]string, len(plan.existingIPs))
+	copy(globalIps, plan.existingIPs)
+	globalIps[index] = newIP
+
+	// 分组组网的服务（xjst）替换任一节点都需要重新渲染整组命令（CHAIN_NODE_IPS）
+	indexes := []int{index}
+	if size := svc.groupSize(); size > 1 {
+		start := index / size * size
+		indexes = indexes[:0]
+		for i := start; i < start+size; i++ {
+			if i >= len(globalIps) || globalIps[i] == "" {
+				return nil, fmt.Errorf("%s 分组 %d 的第 %d 个节点 IP 缺失，无法渲染 CHAIN_NODE_IPS", svc.Type.String(), index/size+1, i%size+1)
+			}
+			indexes = append(indexes, i)
+		}
+	}
+
+	commands := make(map[string]string, len(indexes))
+	for _, i := range indexes {
+		ip := globalIps[i]
+		if d != nil {
+			cmd, err := d.buildRemoteCommandForIndex(globalIps, i, svc)
+			if err != nil {
+				return nil, err
+			}
+			commands[ip] = cmd
+			continue
+		}
+
+		recordedIP := ip
+		if i == index {
+			recordedIP = oldIP
+		}
+		cmd := recorded[recordedIP]
+		if strings.TrimSpace(cmd) == "" {
+			return nil, fmt.Errorf("[%s] script_status.json 中未记录部署命令，无法沿用", recordedIP)
+		}
+		commands[ip] = replaceChainNodeIP(cmd, oldIP, newIP)
+	}
+	return commands, nil
+}
+
+// replaceChainNodeIP 仅改写命令中 CHAIN_NODE_IPS='[...]' 列表里的 IP，避免误伤其它参数。
+func replaceChainNodeIP(cmd, oldIP, newIP string) string {
+	const key = "CHAIN_NODE_IPS='["
+	start := strings.Index(cmd, key)
+	if start < 0 {
+		return cmd
+	}
+	start += len(key)
+	end := strings.Index(cmd[start:], "]")
+	if end < 0 {
+		return cmd
+	}
+	ips := strings.Split(cmd[start:start+end], ",")
+	for i, ip := range ips {
+		if strings.TrimSpace(ip) == oldIP {
+			ips[i] = newIP
+		}
+	}
+	return cmd[:start] + strings.Join(ips, ",") + cmd[start+end:]
+}
+
+// terminateReplacedInstance 尽力终止旧实例；失败只记录告警，不影响替换流程。
+// 旧 IP 只在对应实例带有本部署标签时才终止，避免 IP 被复用后误删无关实例。
+func terminateReplacedInstance(ctx context.Context, commonCfg CommonConfig, outputDir, oldIP string) {
+	deploymentID, err := resolveDeploymentID(commonCfg, outputDir)
+	if err != nil || deploymentID == "" {
+		log.Printf("⚠️ [replace] 无法确定 deploymentId，跳过终止旧实例 %s: %v\n", oldIP, err)
+		return
+	}
+	client, err := newEC2APIFunc(commonCfg.Region)
+	if err != nil {
+		log.Printf("⚠️ [replace] 创建 EC2 client 失败，跳过终止旧实例: %v\n", err)
+		return
+	}
+	byIP, err := describeInstancesByIPs(ctx, client, []string{oldIP}, deploymentID)
+	if err != nil {
+		log.Printf("⚠️ [replace] 查询旧实例失败，跳过终止: %v\n", err)
+		return
+	}
+	inst, ok := byIP[oldIP]
+	if !ok {
+		log.Printf("ℹ️ [replace] 旧 IP %s 上未找到带部署 %s 标签的存活实例，跳过终止\n", oldIP, deploymentID)
+		return
+	}
+	if failed := terminateInstances(ctx, client, []string{inst.InstanceID}); len(failed) > 0 {
+		log.Printf("⚠️ [replace] 终止旧实例 %s 失败: %v\n", inst.InstanceID, failed[inst.InstanceID])
+	}
+}
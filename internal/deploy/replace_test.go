@@ -0,0 +1,235 @@
+package deploy
+
+import (
+	"context"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/wangdayong228/ydyl-deploy-client/internal/constants/enums"
+)
+
+func TestFindReplaceTargetAndServiceConfig(t *testing.T) {
+	t.Parallel()
+
+	outputDir := seedRemoveOutput(t)
+	mgr, err := LoadOutputManager(outputDir)
+	if err != nil {
+		t.Fatalf("LoadOutputManager: %v", err)
+	}
+
+	target, err := findReplaceTarget(mgr.SnapshotServers(), mgr.SnapshotStatuses(), "ydyl-xjst-2-3")
+	if err != nil {
+		t.Fatalf("findReplaceTarget: %v", err)
+	}
+	if target.IP != "2.2.3.3" || target.ServiceType != "xjst" {
+		t.Fatalf("unexpected target: %+v", target)
+	}
+	if _, err := findReplaceTarget(mgr.SnapshotServers(), mgr.SnapshotStatuses(), "ydyl-op-*"); err == nil {
+		t.Fatalf("replace should not accept glob patterns")
+	}
+
+	services := []ServiceConfig{
+		{Type: enums.ServiceTypeOP, TagPrefix: "other"},
+		{Type: enums.ServiceTypeXJST, TagPrefix: "ydyl"},
+	}
+	svc, err := findServiceConfigForNode(services, target.Name, target.ServiceType)
+	if err != nil || svc.TagPrefix != "ydyl" {
+		t.Fatalf("unexpected service config: %+v err=%v", svc, err)
+	}
+	if _, err := findServiceConfigForNode(services, "ydyl-op-1", "op"); err == nil {
+		t.Fatalf("expected error when tagPrefix does not match")
+	}
+}
+
+func TestRenderReplacementCommands_XJSTRebuildsWholeGroup(t *testing.T) {
+	t.Parallel()
+
+	servers := []ServerInfo{
+		{IP: "1.0.0.1", ServiceType: "xjst", Name: "ydyl-xjst-1-1"},
+		{IP: "1.0.0.2", ServiceType: "xjst", Name: "ydyl-xjst-1-2"},
+		{IP: "1.0.0.3", ServiceType: "xjst", Name: "ydyl-xjst-1-3"},
+		{IP: "1.0.0.4", ServiceType: "xjst", Name: "ydyl-xjst-1-4"},
+	}
+	d := &Deployer{
+		cfg: DeployConfig{
+			CommonConfig: CommonConfig{
+				L1ChainId:       "7655",
+				L1RpcUrl:        "https://l1.example/rpc",
+				L1VaultMnemonic: "test test test test test test test test test test test junk",
+			},
+		},
+		l1VaultDeriveRand: 1,
+	}
+	svc := ServiceConfig{Type: enums.ServiceTypeXJST, Count: 4, TagPrefix: "ydyl"}
+	plan := buildAppendPlans(servers, nil, nil)["xjst"]
+
+	before, err := d.buildRemoteCommandForIndex(plan.existingIPs, 2, svc)
+	if err != nil {
+		t.Fatalf("buildRemoteCommandForIndex: %v", err)
+	}
+
+	commands, err := renderReplacementCommands(d, plan, 2, "1.0.0.3", "9.9.9.9", svc, nil)
+	if err != nil {
+		t.Fatalf("renderReplacementCommands: %v", err)
+	}
+	if len(commands) != 4 {
+		t.Fatalf("xjst replace should re-render all 4 group members, got=%v", commands)
+	}
+	for _, ip := range []string{"1.0.0.1", "1.0.0.2", "9.9.9.9", "1.0.0.4"} {
+		if !strings.Contains(commands[ip], "CHAIN_NODE_IPS='[1.0.0.1,1.0.0.2,9.9.9.9,1.0.0.4]'") {
+			t.Fatalf("[%s] CHAIN_NODE_IPS not updated: %s", ip, commands[ip])
+		}
+	}
+	// 同一索引：除 CHAIN_NODE_IPS 外命令完全一致（NODE_ID / GROUP_ID / vault 私钥不变）
+	if want := replaceChainNodeIP(before, "1.0.0.3", "9.9.9.9"); commands["9.9.9.9"] != want {
+		t.Fatalf("replacement command mismatch:\nwant=%s\ngot=%s", want, commands["9.9.9.9"])
+	}
+	if _, ok := commands["1.0.0.3"]; ok {
+		t.Fatalf("old ip should not receive a command")
+	}
+}
+
+func TestRenderReplacementCommands_FallbackToRecorded(t *testing.T) {
+	t.Parallel()
+
+	plan := &appendPlan{offset: 2, existingIPs: []string{"1.0.0.1", "1.0.0.2"}}
+	recorded := map[string]string{"1.0.0.2": "cd op && L2_CHAIN_ID=10001 ./op_pipe.sh"}
+	svc := ServiceConfig{Type: enums.ServiceTypeOP, TagPrefix: "ydyl"}
+
+	commands, err := renderReplacementCommands(nil, plan, 1, "1.0.0.2", "9.9.9.9", svc, recorded)
+	if err != nil {
+		t.Fatalf("renderReplacementCommands: %v", err)
+	}
+	if len(commands) != 1 || commands["9.9.9.9"] != recorded["1.0.0.2"] {
+		t.Fatalf("unexpected commands: %v", commands)
+	}
+
+	if _, err := renderReplacementCommands(nil, plan, 0, "1.0.0.1", "9.9.9.9", svc, recorded); err == nil {
+		t.Fatalf("expected error when no command is recorded")
+	}
+}
+
+func TestOutputManagerReplaceIP(t *testing.T) {
+	t.Parallel()
+
+	outputDir := seedRemoveOutput(t)
+	mgr, err := LoadOutputManager(outputDir)
+	if err != nil {
+		t.Fatalf("LoadOutputManager: %v", err)
+	}
+	if err := mgr.ReplaceIP("op", "1.1.1.2", "9.9.9.9", func(st *ScriptStatus) {
+		st.Status = "pending"
+		st.PID = 0
+	}); err != nil {
+		t.Fatalf("ReplaceIP: %v", err)
+	}
+
+	reloaded, err := LoadOutputManager(filepath.Clean(outputDir))
+	if err != nil {
+		t.Fatalf("LoadOutputManager: %v", err)
+	}
+	var found bool
+	for _, s := range reloaded.SnapshotServers() {
+		if s.IP == "1.1.1.2" {
+			t.Fatalf("old ip still present in servers.json: %+v", s)
+		}
+		if s.IP == "9.9.9.9" && s.Name == "ydyl-op-2" {
+			found = true
+		}
+	}
+	if !found {
+		t.Fatalf("new ip not written to servers.json")
+	}
+	for _, st := range reloaded.SnapshotStatuses() {
+		if st.IP == "1.1.1.2" {
+			t.Fatalf("old ip still present in script_status.json: %+v", st)
+		}
+		if st.IP == "9.9.9.9" && (st.Name != "ydyl-op-2" || st.Status != "pending" || st.PID != 0) {
+			t.Fatalf("unexpected replaced status: %+v", st)
+		}
+	}
+}
+
+func TestUpdateGroupCommands_RepoCommitOnlyForRestartedMembers(t *testing.T) {
+	t.Parallel()
+
+	mgr, err := LoadOutputManager(seedRemoveOutput(t))
+	if err != nil {
+		t.Fatalf("LoadOutputManager: %v", err)
+	}
+	group := []string{"2.2.3.1", "2.2.3.3", "2.2.3.4"}
+	for _, ip := range group {
+		if err := mgr.UpdateStatus(ip, "xjst", func(st *ScriptStatus) { st.RepoCommit = "old" }); err != nil {
+			t.Fatalf("UpdateStatus: %v", err)
+		}
+	}
+	commands := map[string]string{"9.9.9.9": "new-node", "2.2.3.1": "cmd-1", "2.2.3.3": "cmd-3", "2.2.3.4": "cmd-4"}
+	statusOf := func(ip string) *ScriptStatus {
+		for _, st := range mgr.SnapshotStatuses() {
+			if st.IP == ip && st.ServiceType == "xjst" {
+				return st
+			}
+		}
+		t.Fatalf("status for %s not found", ip)
+		return nil
+	}
+
+	// 未重启同组节点：命令更新，RepoCommit 保留
+	updated, err := updateGroupCommands(mgr, "xjst", "9.9.9.9", commands, "", 1)
+	if err != nil {
+		t.Fatalf("updateGroupCommands: %v", err)
+	}
+	if strings.Join(updated, ",") != strings.Join(group, ",") {
+		t.Fatalf("unexpected updated ips: %v", updated)
+	}
+	for _, ip := range group {
+		if st := statusOf(ip); st.Command != commands[ip] || st.RepoCommit != "old" {
+			t.Fatalf("member not restarted must keep its RepoCommit: %+v", st)
+		}
+	}
+
+	// --restart-group：同组节点随重启 checkout 新 commit
+	if _, err := updateGroupCommands(mgr, "xjst", "9.9.9.9", commands, "new", 2); err != nil {
+		t.Fatalf("updateGroupCommands: %v", err)
+	}
+	for _, ip := range group {
+		if st := statusOf(ip); st.RepoCommit != "new" {
+			t.Fatalf("restarted member should record the new RepoCommit: %+v", st)
+		}
+	}
+}
+
+func TestNewReplaceDeployer_LeavesDeployProgressUntouched(t *testing.T) {
+	t.Parallel()
+
+	outputDir := seedRemoveOutput(t)
+	mgr, err := LoadOutputManager(outputDir)
+	if err != nil {
+		t.Fatalf("LoadOutputManager: %v", err)
+	}
+	if err := mgr.UpdateProgress(func(p *DeployProgress) {
+		p.Services = map[string]string{"xjst": DeployPhaseExec}
+		p.Phases = map[string]*PhaseResult{DeployPhaseExec: {Status: PhaseStatusDone}}
+	}); err != nil {
+		t.Fatalf("UpdateProgress: %v", err)
+	}
+	before, _ := mgr.SnapshotProgress()
+
+	// replace 复用 deploy 的创建 / SSH 收敛流程，其中的进度写入不得落盘
+	d := newReplaceDeployer(context.Background(), DeployConfig{}, mgr, DeploymentInfo{}, nil)
+	svc := ServiceConfig{Type: enums.ServiceTypeXJST}
+	d.markServicePhase(svc, DeployPhaseLaunch)
+	d.markServicePhase(svc, DeployPhaseSSH)
+	d.recordNodeProgress(compositeKey("9.9.9.9", "xjst"), func(np *NodeProgress) { np.Tagged = true })
+
+	reloaded, err := LoadOutputManager(outputDir)
+	if err != nil {
+		t.Fatalf("LoadOutputManager: %v", err)
+	}
+	after, _ := reloaded.SnapshotProgress()
+	if !reflect.DeepEqual(before, after) {
+		t.Fatalf("replace must not change deploy progress:\nbefore=%+v\nafter=%+v", before, after)
+	}
+}
@@ -0,0 +1,104 @@
+package deploy
+
+import (
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+)
+
+var (
+	repoRefPattern    = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._/-]*$`)
+	repoCommitPattern = regexp.MustCompile(`^[0-9a-f]{40}$`)
+)
+
+func validateRepoRef(ref string) error {
+	ref = strings.TrimSpace(ref)
+	if ref == "" {
+		return nil
+	}
+	if !repoRefPattern.MatchString(ref) || strings.Contains(ref, "..") {
+		return fmt.Errorf("repoRef %q is not a valid git commit / tag / branch name", ref)
+	}
+	return nil
+}
+
+// buildRepoRefResolveCommand 生成在远端部署仓库中把 ref 解析为 commit hash 的命令：
+// 先 fetch 远端分支与 tag，分支优先取 origin/<ref>，否则按 tag / commit 解析。
+func buildRepoRefResolveCommand(ref string) string {
+	return fmt.Sprintf(
+		"cd %s && GIT_SSH_COMMAND='ssh -o StrictHostKeyChecking=no' git fetch --force --tags --quiet origin && { git rev-parse --verify --quiet %s || git rev-parse --verify %s; }",
+		remoteRepoDirDefault,
+		shellQuote("origin/"+ref+"^{commit}"),
+		shellQuote(ref+"^{commit}"),
+	)
+}
+
+// parseRepoCommit 取远端输出最后一行作为 commit hash。
+func parseRepoCommit(out string) (string, error) {
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	commit := strings.TrimSpace(lines[len(lines)-1])
+	if !repoCommitPattern.MatchString(commit) {
+		return "", fmt.Errorf("无法从输出中解析 commit: %q", strings.TrimSpace(out))
+	}
+	return commit, nil
+}
+
+var resolveRepoRefFunc = func(d *Deployer, ip, ref string) (string, error) {
+	out, err := runSSH(d.ctx, d.cfg.CommonConfig.SSHUser, d.sshKeyPath, ip, buildRepoRefResolveCommand(ref))
+	if err != nil {
+		return "", err
+	}
+	return parseRepoCommit(out)
+}
+
+// ensureRepoCommit 在配置 repoRef 时将其解析为 commit hash：同一部署只在首个节点上解析一次并写入 deployment.json，
+// 之后所有节点（含 append / resume / replace）均 checkout 该 commit，避免部署过程中分支被推送导致节点代码不一致。
+func (d *Deployer) ensureRepoCommit(ip string) error {
+	ref := strings.TrimSpace(d.cfg.CommonConfig.RepoRef)
+	if ref == "" {
+		return nil
+	}
+
+	d.repoMu.Lock()
+	defer d.repoMu.Unlock()
+	if d.deployment.RepoCommit != "" && d.deployment.RepoRef == ref {
+		return nil
+	}
+	if d.deployment.RepoCommit != "" {
+		log.Printf("⚠️ repoRef 由 %s 变更为 %s，重新解析 commit；已启动的节点仍运行 %s\n", d.deployment.RepoRef, ref, ShortCommit(d.deployment.RepoCommit))
+	}
+
+	commit := strings.ToLower(ref)
+	if !repoCommitPattern.MatchString(commit) {
+		var err error
+		if commit, err = resolveRepoRefFunc(d, ip, ref); err != nil {
+			return fmt.Errorf("解析 repoRef %s 失败: %w", ref, err)
+		}
+	}
+	d.deployment.RepoRef = ref
+	d.deployment.RepoCommit = commit
+	if err := SaveDeploymentInfo(d.cfg.CommonConfig.OutputDir, d.deployment); err != nil {
+		return err
+	}
+	log.Printf("📌 repoRef %s 已解析为 commit %s，所有节点将 checkout 该版本\n", ref, commit)
+	return nil
+}
+
+// repoCommit 返回已解析的部署仓库 commit；未配置 repoRef 时为空。
+func (d *Deployer) repoCommit() string {
+	d.repoMu.Lock()
+	defer d.repoMu.Unlock()
+	if d.deployment.RepoRef != strings.TrimSpace(d.cfg.CommonConfig.RepoRef) {
+		return ""
+	}
+	return d.deployment.RepoCommit
+}
+
+// ShortCommit 截取 commit hash 前 8 位用于展示。
+func ShortCommit(commit string) string {
+	if len(commit) > 8 {
+		return commit[:8]
+	}
+	return commit
+}
@@ -0,0 +1,120 @@
+package deploy
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/wangdayong228/ydyl-deploy-client/internal/constants/enums"
+)
+
+func TestValidateRepoRef(t *testing.T) {
+	t.Parallel()
+
+	for _, ref := range []string{"", "main", "release/v1.2", "v1.0.0-rc1", "0123456789abcdef0123456789abcdef01234567"} {
+		if err := validateRepoRef(ref); err != nil {
+			t.Fatalf("ref %q should be valid, err=%v", ref, err)
+		}
+	}
+	for _, ref := range []string{"-main", "main;rm -rf /", "a b", "a..b", "$(id)"} {
+		if err := validateRepoRef(ref); err == nil {
+			t.Fatalf("ref %q should be rejected", ref)
+		}
+	}
+}
+
+func TestParseRepoCommit(t *testing.T) {
+	t.Parallel()
+
+	commit, err := parseRepoCommit("warning: something\n0123456789abcdef0123456789abcdef01234567\n")
+	if err != nil || commit != "0123456789abcdef0123456789abcdef01234567" {
+		t.Fatalf("unexpected commit=%q err=%v", commit, err)
+	}
+	if _, err := parseRepoCommit("fatal: Needed a single revision\n"); err == nil {
+		t.Fatalf("expected parse error")
+	}
+}
+
+func TestEnsureRepoCommit_ResolvesOnceAndPersists(t *testing.T) {
+	outputDir := t.TempDir()
+	const (
+		commit   = "0123456789abcdef0123456789abcdef01234567"
+		mnemonic = "test test test test test test test test test test test junk"
+	)
+
+	calls := 0
+	origResolve := resolveRepoRefFunc
+	resolveRepoRefFunc = func(_ *Deployer, ip, ref string) (string, error) {
+		calls++
+		if ref != "main" {
+			t.Errorf("unexpected ref %q", ref)
+		}
+		return commit, nil
+	}
+	defer func() { resolveRepoRefFunc = origResolve }()
+
+	d := &Deployer{
+		ctx: context.Background(),
+		cfg: DeployConfig{CommonConfig: CommonConfig{OutputDir: outputDir, RepoRef: "main", L1VaultMnemonic: mnemonic}},
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := d.ensureRepoCommit("1.1.1.1"); err != nil {
+				t.Errorf("ensureRepoCommit: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+	if calls != 1 || d.repoCommit() != commit {
+		t.Fatalf("expected single resolution, calls=%d commit=%q", calls, d.repoCommit())
+	}
+
+	info, err := LoadDeploymentInfo(outputDir)
+	if err != nil || info == nil || info.RepoRef != "main" || info.RepoCommit != commit {
+		t.Fatalf("repo commit should be persisted, info=%+v err=%v", info, err)
+	}
+
+	// 内置命令 checkout 解析出的 commit，而不是 git pull
+	ips := []string{"1.1.1.1", "1.1.1.2", "1.1.1.3", "1.1.1.4"}
+	got, err := d.buildRemoteCommandForIndex(ips, 0, ServiceConfig{Type: enums.ServiceTypeXJST})
+	if err != nil {
+		t.Fatalf("buildRemoteCommandForIndex: %v", err)
+	}
+	if !strings.Contains(got, "git checkout --force --detach "+commit+" && ") || strings.Contains(got, "git pull") {
+		t.Fatalf("command should pin commit, got=%s", got)
+	}
+
+	// repoRef 为完整 commit 时无需远端解析
+	d2 := &Deployer{cfg: DeployConfig{CommonConfig: CommonConfig{OutputDir: t.TempDir(), RepoRef: strings.ToUpper(commit)}}}
+	if err := d2.ensureRepoCommit("1.1.1.1"); err != nil || calls != 1 || d2.repoCommit() != commit {
+		t.Fatalf("full commit ref should not be resolved remotely, calls=%d commit=%q err=%v", calls, d2.repoCommit(), err)
+	}
+
+	// 未配置 repoRef 时保持 git pull
+	d3 := &Deployer{cfg: DeployConfig{CommonConfig: CommonConfig{L1VaultMnemonic: mnemonic}}}
+	got, err = d3.buildRemoteCommandForIndex(ips, 0, ServiceConfig{Type: enums.ServiceTypeXJST})
+	if err != nil || !strings.HasPrefix(got, " git pull && ") {
+		t.Fatalf("command without repoRef should git pull, got=%s err=%v", got, err)
+	}
+}
+
+func TestCollectRepoCommits(t *testing.T) {
+	t.Parallel()
+
+	got := collectRepoCommits([]*ScriptStatus{
+		{ServiceType: "op", RepoCommit: "bbb"},
+		{ServiceType: "op", RepoCommit: "aaa"},
+		{ServiceType: "op", RepoCommit: "bbb"},
+		{ServiceType: "xjst"},
+	})
+	if len(got) != 1 || strings.Join(got["op"], ",") != "aaa,bbb" {
+		t.Fatalf("unexpected repo commits: %v", got)
+	}
+	if collectRepoCommits([]*ScriptStatus{{ServiceType: "op"}}) != nil {
+		t.Fatalf("expected nil when no commit recorded")
+	}
+}
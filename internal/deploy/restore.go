@@ -204,6 +204,7 @@ func (r *Restorer) Run(ctx context.Context, statuses []*ScriptStatus) error {
 		if name == "" {
 			name = fmt.Sprintf("%s-%s", st.ServiceType, st.IP)
 		}
+		r.outputMgr.RecordEvent(Event{Type: EventRestoreAttempt, IP: st.IP, ServiceType: st.ServiceType, Name: st.Name, PID: st.PID, From: st.Status})
 
 		attempts, readyErr := waitSSHReadyWithRetry(
 			ctx,
@@ -263,6 +264,7 @@ func (r *Restorer) runForStatus(ctx context.Context, st ScriptStatus, keyPath st
 		localLogPath = buildLocalLogPath(r.cfg.LogDir, st.IP, name)
 	}
 
+	launchedAt := time.Now()
 	sshCmd := exec.CommandContext(ctx, "ssh",
 		"-o", "StrictHostKeyChecking=no",
 		"-o", "IdentitiesOnly=yes",
@@ -302,6 +304,9 @@ func (r *Restorer) runForStatus(ctx context.Context, st ScriptStatus, keyPath st
 			s.LocalLog = localLogPath
 			s.UpdatedAt = now
 			s.LogSize = 0
+			// 重新执行会重新下发 shutdown -h +N，覆盖之前的关机计划。
+			s.ShutdownAt = resolveShutdownAt(launchedAt, r.cfg.RunDuration)
+			s.ShutdownCanceled = false
 		},
 	)
 	return nil
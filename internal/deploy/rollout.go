@@ -0,0 +1,236 @@
+package deploy
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+
+	"github.com/wangdayong228/ydyl-deploy-client/internal/constants/enums"
+)
+
+// runServiceFunc 便于测试替换单个服务的部署流程。
+var runServiceFunc = (*Deployer).runService
+
+// runServices 并发部署所有服务：未配置 dependsOn 的服务同时开始，配置了 dependsOn 的服务等待所依赖类型的服务全部完成 rollout。
+// 所有服务的 SSH 操作共享 d.sshBudget() 的并发预算，总并发不超过 sshMaxConcurrency。
+func (d *Deployer) runServices() error {
+	services := d.cfg.Services
+	done := make([]chan struct{}, len(services))
+	// failed[i] 在 close(done[i]) 之前写入，等待方读取时无需额外加锁。
+	failed := make([]bool, len(services))
+	for i := range done {
+		done[i] = make(chan struct{})
+	}
+
+	var (
+		mu   sync.Mutex
+		errs []error
+		wg   sync.WaitGroup
+	)
+	addErr := func(err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		errs = append(errs, err)
+	}
+
+	for i, svc := range services {
+		wg.Add(1)
+		go func(i int, svc ServiceConfig) {
+			defer wg.Done()
+			defer close(done[i])
+
+			deps := serviceDependencyIndexes(services, i)
+			for _, dep := range deps {
+				select {
+				case <-done[dep]:
+				case <-d.ctx.Done():
+					failed[i] = true
+					return
+				}
+				if failed[dep] {
+					failed[i] = true
+					if d.ctx.Err() == nil {
+						addErr(fmt.Errorf("[%s] 依赖的服务 %s 部署失败，跳过", svc.Type.String(), services[dep].Type.String()))
+					}
+					return
+				}
+			}
+
+			if d.serviceNewCount(svc) <= 0 && !d.resume {
+				return
+			}
+			if len(deps) > 0 {
+				log.Printf("👉 [%s] 依赖的服务 %s 已完成 rollout，开始部署\n", svc.Type.String(), strings.Join(svc.DependsOn, ","))
+			}
+			if err := runServiceFunc(d, svc); err != nil {
+				failed[i] = true
+				addErr(err)
+			}
+		}(i, svc)
+	}
+	wg.Wait()
+
+	switch len(errs) {
+	case 0:
+		return d.ctx.Err()
+	case 1:
+		return errs[0]
+	default:
+		return deployMultiError{errs: errs}
+	}
+}
+
+// serviceDependencyIndexes 返回 services[i] 依赖的服务下标（dependsOn 按服务类型匹配，同类型的多个服务都需完成）。
+func serviceDependencyIndexes(services []ServiceConfig, i int) []int {
+	var deps []int
+	for _, name := range services[i].DependsOn {
+		depType, err := enums.ParseServiceType(strings.TrimSpace(name))
+		if err != nil {
+			continue
+		}
+		for j, other := range services {
+			if j != i && other.Type == depType {
+				deps = append(deps, j)
+			}
+		}
+	}
+	return deps
+}
+
+// sshBudget 返回本次 deploy 共享的 SSH 并发令牌，并发部署的多个服务从同一预算中获取。
+func (d *Deployer) sshBudget() chan struct{} {
+	d.sshSemOnce.Do(func() {
+		d.sshSem = make(chan struct{}, d.sshMaxConcurrency())
+	})
+	return d.sshSem
+}
+
+// rolloutNodes 为服务的 indexes 节点执行 tag / exec / monitor 阶段。
+// 配置了 rollout 且本次执行 exec 阶段时按批次启动：canary 批次需全部 success，之后每批结束后检查失败比例，
+// 超过 maxFailureRatio 时停止启动剩余节点（剩余节点保持 pending，可修复后通过 deploy --resume 继续）。
+func (d *Deployer) rolloutNodes(svc ServiceConfig, globalIps []string, indexes []int) error {
+	if svc.Rollout == nil || !d.phaseEnabled(DeployPhaseExec) {
+		return d.runCommandsOnInstances(globalIps, indexes, svc)
+	}
+
+	r := svc.Rollout
+	waves := planRolloutWaves(indexes, r)
+	var startedKeys []string
+	for w, wave := range waves {
+		canary := w == 0 && r.Canary > 0
+		label := fmt.Sprintf("第 %d/%d 批", w+1, len(waves))
+		if canary {
+			label = "canary 批次"
+		}
+		log.Printf("👉 [%s] 灰度发布：启动%s，节点数=%d\n", svc.Type.String(), label, len(wave))
+		if err := d.runCommandsOnInstances(globalIps, wave, svc); err != nil {
+			return err
+		}
+		for _, i := range wave {
+			startedKeys = append(startedKeys, compositeKey(globalIps[i], svc.Type.String()))
+		}
+		// 最后一批无需在此等待，交给 sync 阶段统一同步
+		if w == len(waves)-1 {
+			break
+		}
+
+		log.Printf("👉 [%s] 灰度发布：等待%s执行结束...\n", svc.Type.String(), label)
+		if err := waitRolloutWaveFunc(d, startedKeys[len(startedKeys)-len(wave):]); err != nil {
+			return err
+		}
+		remaining := 0
+		for _, rest := range waves[w+1:] {
+			remaining += len(rest)
+		}
+		if err := checkRolloutFailures(d.outputMgr.SnapshotStatuses(), startedKeys, canary, r.MaxFailureRatio); err != nil {
+			return fmt.Errorf("[%s] 灰度发布中止，剩余 %d 个节点未启动（修复后可使用 deploy --resume 继续）: %w", svc.Type.String(), remaining, err)
+		}
+		log.Printf("✅ [%s] 灰度发布：%s检查通过\n", svc.Type.String(), label)
+	}
+	return nil
+}
+
+// waitRolloutWaveFunc 便于测试替换批次等待逻辑。
+var waitRolloutWaveFunc = (*Deployer).waitRolloutWave
+
+// waitRolloutWave 通过 Sync 同步 keys 对应节点的日志与状态直到全部结束，SSH 操作共享本次 deploy 的并发预算。
+// 单个节点失败不在此返回错误，由 checkRolloutFailures 统一按比例判定。
+func (d *Deployer) waitRolloutWave(keys []string) error {
+	want := make(map[string]struct{}, len(keys))
+	for _, key := range keys {
+		want[key] = struct{}{}
+	}
+	var statuses []*ScriptStatus
+	for _, st := range d.outputMgr.SnapshotStatuses() {
+		if _, ok := want[compositeKey(st.IP, st.ServiceType)]; ok {
+			statuses = append(statuses, st)
+		}
+	}
+
+	s := NewSync(d.cfg.CommonConfig, d.outputMgr)
+	if err := s.runStatuses(d.ctx, statuses, d.sshBudget()); err != nil {
+		log.Printf("⚠️ 灰度批次存在失败节点: %v\n", err)
+	}
+	return d.ctx.Err()
+}
+
+// planRolloutWaves 将待启动节点按 canary 与 waveSize 切分为有序批次。
+func planRolloutWaves(indexes []int, r *RolloutConfig) [][]int {
+	var waves [][]int
+	rest := indexes
+	if r.Canary > 0 && len(rest) > 0 {
+		n := min(r.Canary, len(rest))
+		waves = append(waves, rest[:n])
+		rest = rest[n:]
+	}
+	for len(rest) > 0 {
+		n := len(rest)
+		if r.WaveSize > 0 {
+			n = min(r.WaveSize, n)
+		}
+		waves = append(waves, rest[:n])
+		rest = rest[n:]
+	}
+	return waves
+}
+
+// checkRolloutFailures 统计 keys 对应节点的脚本状态：canary 批次要求全部 success，
+// 其它批次按已结束节点中 failed 的比例与 maxFailureRatio 比较。
+func checkRolloutFailures(statuses []*ScriptStatus, keys []string, canary bool, maxFailureRatio float64) error {
+	byKey := make(map[string]*ScriptStatus, len(statuses))
+	for _, st := range statuses {
+		byKey[compositeKey(st.IP, st.ServiceType)] = st
+	}
+
+	var finished int
+	var failed []string
+	for _, key := range keys {
+		st := byKey[key]
+		if st == nil {
+			continue
+		}
+		switch strings.ToLower(strings.TrimSpace(st.Status)) {
+		case "success":
+			finished++
+		case "failed":
+			finished++
+			failed = append(failed, st.IP)
+		}
+	}
+
+	if canary {
+		if len(failed) > 0 || finished < len(keys) {
+			return fmt.Errorf("canary 节点未全部成功（成功 %d/%d，失败节点: %v）", finished-len(failed), len(keys), failed)
+		}
+		return nil
+	}
+	if finished == 0 {
+		return nil
+	}
+	ratio := float64(len(failed)) / float64(finished)
+	if ratio > maxFailureRatio {
+		return fmt.Errorf("失败比例 %.2f 超过阈值 %.2f（失败 %d/%d，失败节点: %v）", ratio, maxFailureRatio, len(failed), finished, failed)
+	}
+	return nil
+}
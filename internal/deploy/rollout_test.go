@@ -0,0 +1,209 @@
+package deploy
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/wangdayong228/ydyl-deploy-client/internal/constants/enums"
+)
+
+func TestRunServices_ConcurrentWithDependsOn(t *testing.T) {
+	oldRun := runServiceFunc
+	defer func() { runServiceFunc = oldRun }()
+
+	var (
+		mu     sync.Mutex
+		events []string
+	)
+	record := func(ev string) {
+		mu.Lock()
+		defer mu.Unlock()
+		events = append(events, ev)
+	}
+	xjstStarted := make(chan struct{})
+	runServiceFunc = func(d *Deployer, svc ServiceConfig) error {
+		name := svc.Type.String()
+		record("start:" + name)
+		if svc.Type == enums.ServiceTypeOP {
+			// op 在 xjst 开始之前不会结束，证明无依赖的服务是并发启动的
+			select {
+			case <-xjstStarted:
+			case <-time.After(2 * time.Second):
+				return errors.New("xjst did not start concurrently")
+			}
+		}
+		if svc.Type == enums.ServiceTypeXJST {
+			close(xjstStarted)
+		}
+		record("end:" + name)
+		return nil
+	}
+
+	d := &Deployer{
+		ctx: context.Background(),
+		cfg: DeployConfig{Services: []ServiceConfig{
+			{Type: enums.ServiceTypeOP, Count: 1},
+			{Type: enums.ServiceTypeCDK, Count: 1, DependsOn: []string{"op"}},
+			{Type: enums.ServiceTypeXJST, Count: 4},
+		}},
+	}
+	if err := d.runServices(); err != nil {
+		t.Fatalf("runServices: %v", err)
+	}
+
+	pos := make(map[string]int)
+	for i, ev := range events {
+		pos[ev] = i
+	}
+	if len(pos) != 6 {
+		t.Fatalf("unexpected events: %v", events)
+	}
+	if pos["start:cdk"] < pos["end:op"] {
+		t.Fatalf("cdk must start after op finished: %v", events)
+	}
+}
+
+func TestRunServices_SkipsDependentsOfFailedService(t *testing.T) {
+	oldRun := runServiceFunc
+	defer func() { runServiceFunc = oldRun }()
+
+	var ran []string
+	var mu sync.Mutex
+	runServiceFunc = func(d *Deployer, svc ServiceConfig) error {
+		mu.Lock()
+		ran = append(ran, svc.Type.String())
+		mu.Unlock()
+		if svc.Type == enums.ServiceTypeOP {
+			return errors.New("op boom")
+		}
+		return nil
+	}
+
+	d := &Deployer{
+		ctx: context.Background(),
+		cfg: DeployConfig{Services: []ServiceConfig{
+			{Type: enums.ServiceTypeOP, Count: 1},
+			{Type: enums.ServiceTypeCDK, Count: 1, DependsOn: []string{"op"}},
+		}},
+	}
+	err := d.runServices()
+	if err == nil || !strings.Contains(err.Error(), "op boom") || !strings.Contains(err.Error(), "依赖的服务 op 部署失败") {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(ran) != 1 || ran[0] != "op" {
+		t.Fatalf("cdk must not run after op failed, ran=%v", ran)
+	}
+}
+
+func TestCheckServiceDependencies(t *testing.T) {
+	t.Parallel()
+
+	svc := func(typ enums.ServiceType, deps ...string) ServiceConfig {
+		return ServiceConfig{Type: typ, DependsOn: deps}
+	}
+	for _, tc := range []struct {
+		name     string
+		services []ServiceConfig
+		wantErr  string
+	}{
+		{"ok", []ServiceConfig{svc(enums.ServiceTypeOP), svc(enums.ServiceTypeCDK, "op")}, ""},
+		{"unknown", []ServiceConfig{svc(enums.ServiceTypeOP, "zksync")}, "unknown service type"},
+		{"self", []ServiceConfig{svc(enums.ServiceTypeOP, "op")}, "must not depend on itself"},
+		{"missing", []ServiceConfig{svc(enums.ServiceTypeOP, "cdk")}, "is not configured"},
+		{"cycle", []ServiceConfig{svc(enums.ServiceTypeOP, "cdk"), svc(enums.ServiceTypeCDK, "xjst"), svc(enums.ServiceTypeXJST, "op")}, "cycle"},
+	} {
+		err := checkServiceDependencies(tc.services)
+		if tc.wantErr == "" {
+			if err != nil {
+				t.Fatalf("%s: unexpected error: %v", tc.name, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+			t.Fatalf("%s: error = %v, want contains %q", tc.name, err, tc.wantErr)
+		}
+	}
+}
+
+func TestPlanRolloutWaves(t *testing.T) {
+	t.Parallel()
+
+	indexes := []int{0, 1, 2, 3, 4, 5, 6}
+	got := planRolloutWaves(indexes, &RolloutConfig{Canary: 1, WaveSize: 3})
+	want := [][]int{{0}, {1, 2, 3}, {4, 5, 6}}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("canary+waves = %v, want %v", got, want)
+	}
+
+	got = planRolloutWaves(indexes, &RolloutConfig{Canary: 2})
+	want = [][]int{{0, 1}, {2, 3, 4, 5, 6}}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("canary only = %v, want %v", got, want)
+	}
+
+	got = planRolloutWaves(indexes[:2], &RolloutConfig{Canary: 4, WaveSize: 2})
+	want = [][]int{{0, 1}}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("canary larger than nodes = %v, want %v", got, want)
+	}
+}
+
+func TestCheckRolloutFailures(t *testing.T) {
+	t.Parallel()
+
+	statuses := []*ScriptStatus{
+		{IP: "1.1.1.1", ServiceType: "op", Status: "success"},
+		{IP: "1.1.1.2", ServiceType: "op", Status: "failed"},
+		{IP: "1.1.1.3", ServiceType: "op", Status: "success"},
+		{IP: "1.1.1.4", ServiceType: "op", Status: "success"},
+		{IP: "1.1.1.5", ServiceType: "op", Status: "running"},
+	}
+	key := func(ip string) string { return compositeKey(ip, "op") }
+
+	if err := checkRolloutFailures(statuses, []string{key("1.1.1.1")}, true, 0); err != nil {
+		t.Fatalf("successful canary: %v", err)
+	}
+	if err := checkRolloutFailures(statuses, []string{key("1.1.1.1"), key("1.1.1.2")}, true, 0.9); err == nil {
+		t.Fatalf("expected failed canary to abort regardless of ratio")
+	}
+	if err := checkRolloutFailures(statuses, []string{key("1.1.1.5")}, true, 0); err == nil {
+		t.Fatalf("expected unfinished canary to abort")
+	}
+
+	all := []string{key("1.1.1.1"), key("1.1.1.2"), key("1.1.1.3"), key("1.1.1.4"), key("1.1.1.5")}
+	if err := checkRolloutFailures(statuses, all, false, 0.25); err != nil {
+		t.Fatalf("ratio 1/4 within threshold 0.25: %v", err)
+	}
+	if err := checkRolloutFailures(statuses, all, false, 0.2); err == nil {
+		t.Fatalf("expected ratio 1/4 to exceed threshold 0.2")
+	}
+}
+
+func TestServiceConfigCheckValid_Rollout(t *testing.T) {
+	t.Parallel()
+
+	base := ServiceConfig{Type: enums.ServiceTypeXJST, Count: 8, InstanceType: []string{"c6a.xlarge"}}
+	cases := []struct {
+		rollout *RolloutConfig
+		wantErr bool
+	}{
+		{rollout: nil},
+		{rollout: &RolloutConfig{Canary: 4, WaveSize: 4, MaxFailureRatio: 0.5}},
+		{rollout: &RolloutConfig{}, wantErr: true},
+		{rollout: &RolloutConfig{Canary: 2}, wantErr: true},
+		{rollout: &RolloutConfig{WaveSize: 4, MaxFailureRatio: 1.5}, wantErr: true},
+		{rollout: &RolloutConfig{Canary: -4}, wantErr: true},
+	}
+	for i, tc := range cases {
+		svc := base
+		svc.Rollout = tc.rollout
+		if err := svc.CheckValid(); (err != nil) != tc.wantErr {
+			t.Fatalf("case %d: CheckValid() = %v, wantErr=%v", i, err, tc.wantErr)
+		}
+	}
+}
@@ -0,0 +1,496 @@
+package deploy
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	runRecordFileName = "run.json"
+
+	redactedValue = "<redacted>"
+)
+
+// RunRecord 描述一次 deploy 运行的历史记录，落盘到 output/run.json，随 output 目录一起归档为 output-<ts>。
+// StatusCounts / NodeCounts / JobConfig 在结束时写入，读取时若 output 目录中仍有状态文件则按最新状态重新计算。
+type RunRecord struct {
+	ID       string `json:"id"`
+	Operator string `json:"operator,omitempty"`
+	Region   string `json:"region,omitempty"`
+
+	// ConfigHash 为脱敏后配置的 sha256，相同哈希表示部署参数完全一致。
+	ConfigHash string `json:"configHash,omitempty"`
+	// Config 为脱敏后的完整部署配置（助记词 / 私钥替换为 <redacted>，remoteCmd / userData 只保留哈希），供 runs diff 对比。
+	Config   *DeployConfig       `json:"config,omitempty"`
+	Services []RunServiceSummary `json:"services,omitempty"`
+
+	StartedAt int64 `json:"startedAt"`
+	// AppendedAt 记录每次 deploy --append 的启动时间（Unix 秒）。
+	AppendedAt []int64 `json:"appendedAt,omitempty"`
+	EndedAt    int64   `json:"endedAt,omitempty"`
+	// Result 为 running / success / failed / interrupted。
+	Result string `json:"result,omitempty"`
+	Error  string `json:"error,omitempty"`
+
+	StatusCounts map[string]int `json:"statusCounts,omitempty"`
+	NodeCounts   map[string]int `json:"nodeCounts,omitempty"`
+	JobConfig    *RunJobConfig  `json:"jobConfig,omitempty"`
+	// RepoCommits 为各服务类型节点 checkout 的部署仓库 commit（去重排序），未配置 repoRef 的节点不计入。
+	RepoCommits map[string][]string `json:"repoCommits,omitempty"`
+
+	// OutputDir 为记录所在目录（读取时填充）。
+	OutputDir string `json:"-"`
+}
+
+// RunServiceSummary 描述一次运行中单个 service 的配置摘要。
+type RunServiceSummary struct {
+	Type         string   `json:"type"`
+	TagPrefix    string   `json:"tagPrefix,omitempty"`
+	Count        uint     `json:"count"`
+	InstanceType []string `json:"instanceType,omitempty"`
+}
+
+// RunJobConfig 描述 gen-cross-tx-config 生成的 jobs/all.json。
+type RunJobConfig struct {
+	Path string `json:"path"`
+	Hash string `json:"hash"`
+	Jobs int    `json:"jobs"`
+}
+
+// lastStartedAt 返回最近一次 deploy（含 append）的启动时间。
+func (r *RunRecord) lastStartedAt() int64 {
+	last := r.StartedAt
+	for _, ts := range r.AppendedAt {
+		if ts > last {
+			last = ts
+		}
+	}
+	return last
+}
+
+// Duration 返回运行时长；尚未结束时返回 0。
+func (r *RunRecord) Duration() time.Duration {
+	if r == nil || r.EndedAt <= 0 || r.StartedAt <= 0 {
+		return 0
+	}
+	return time.Duration(r.EndedAt-r.StartedAt) * time.Second
+}
+
+// redactDeployConfig 返回去除助记词与私钥后的配置副本。
+// services[].remoteCmd / userData 可能内联密钥，只保留内容哈希，runs diff 仍能识别其是否变化。
+func redactDeployConfig(cfg DeployConfig) DeployConfig {
+	if cfg.L1VaultMnemonic != "" {
+		cfg.L1VaultMnemonic = redactedValue
+	}
+	if cfg.L1RegisterBridgePrivateKey != "" {
+		cfg.L1RegisterBridgePrivateKey = redactedValue
+	}
+	cfg.Services = append([]ServiceConfig(nil), cfg.Services...)
+	for i := range cfg.Services {
+		cfg.Services[i].RemoteCmd = redactWithHash(cfg.Services[i].RemoteCmd)
+		cfg.Services[i].UserData = redactWithHash(cfg.Services[i].UserData)
+	}
+	return cfg
+}
+
+// redactWithHash 将非空内容替换为 <redacted sha256:前 16 位>。
+func redactWithHash(value string) string {
+	if value == "" {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(value))
+	return fmt.Sprintf("<redacted sha256:%s>", hex.EncodeToString(sum[:])[:16])
+}
+
+func hashDeployConfig(cfg DeployConfig) (string, error) {
+	data, err := json.Marshal(cfg)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+func summarizeServices(services []ServiceConfig) []RunServiceSummary {
+	out := make([]RunServiceSummary, 0, len(services))
+	for _, svc := range services {
+		out = append(out, RunServiceSummary{
+			Type:         svc.Type.String(),
+			TagPrefix:    svc.TagPrefix,
+			Count:        svc.Count,
+			InstanceType: append([]string(nil), svc.InstanceType...),
+		})
+	}
+	return out
+}
+
+// beginRunRecord 在 deploy 初始化完成后写入 run.json；append 模式沿用已有记录并追加本次启动时间。
+func beginRunRecord(cfg DeployConfig, deployment DeploymentInfo, appendMode bool, now time.Time) error {
+	outputDir := cfg.CommonConfig.OutputDir
+	redacted := redactDeployConfig(cfg)
+	hash, err := hashDeployConfig(redacted)
+	if err != nil {
+		return fmt.Errorf("计算配置哈希失败: %w", err)
+	}
+
+	var record *RunRecord
+	if appendMode {
+		if record, err = readRunRecord(outputDir); err != nil {
+			return err
+		}
+	}
+	if record == nil || record.ID != deployment.DeploymentID {
+		record = &RunRecord{StartedAt: now.Unix()}
+	} else {
+		record.AppendedAt = append(record.AppendedAt, now.Unix())
+	}
+
+	record.ID = deployment.DeploymentID
+	record.Operator = deployment.Operator
+	record.Region = deployment.Region
+	record.ConfigHash = hash
+	record.Config = &redacted
+	record.Services = summarizeServices(cfg.Services)
+	record.EndedAt = 0
+	record.Result = "running"
+	record.Error = ""
+	return writeJSONFileAtomic(filepath.Join(outputDir, runRecordFileName), record)
+}
+
+// finishRunRecord 在 deploy（含失败链重试）结束后补充结束时间、结果与最终状态统计。
+// 仅当 run.json 由本次运行（启动时间不早于 startedAt）写入时才更新，避免初始化失败时改写归档前的旧记录。
+func finishRunRecord(outputDir string, startedAt time.Time, runErr error, now time.Time) error {
+	record, err := readRunRecord(outputDir)
+	if err != nil || record == nil {
+		return err
+	}
+	if record.lastStartedAt() < startedAt.Unix() {
+		return nil
+	}
+
+	record.EndedAt = now.Unix()
+	record.Result = "success"
+	record.Error = ""
+	if runErr != nil {
+		record.Result = "failed"
+		record.Error = runErr.Error()
+		if errors.Is(runErr, ErrDeployInterrupted) {
+			record.Result = "interrupted"
+		}
+	}
+	if err := refreshRunRecord(outputDir, record); err != nil {
+		return err
+	}
+	return writeJSONFileAtomic(filepath.Join(outputDir, runRecordFileName), record)
+}
+
+func readRunRecord(outputDir string) (*RunRecord, error) {
+	data, err := os.ReadFile(filepath.Join(outputDir, runRecordFileName))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("读取 %s 失败: %w", runRecordFileName, err)
+	}
+	var record RunRecord
+	if err := json.Unmarshal(data, &record); err != nil {
+		return nil, fmt.Errorf("解析 %s 失败: %w", filepath.Join(outputDir, runRecordFileName), err)
+	}
+	return &record, nil
+}
+
+// refreshRunRecord 按 output 目录中的最新状态重新计算节点数、状态统计与 jobs 配置。
+func refreshRunRecord(outputDir string, record *RunRecord) error {
+	outputMgr, err := LoadOutputManager(outputDir)
+	if err != nil {
+		return err
+	}
+	if servers := outputMgr.SnapshotServers(); len(servers) > 0 {
+		record.NodeCounts = make(map[string]int)
+		for _, s := range servers {
+			record.NodeCounts[s.ServiceType]++
+		}
+	}
+	if statuses := outputMgr.SnapshotStatuses(); len(statuses) > 0 {
+		record.StatusCounts = make(map[string]int)
+		for _, st := range statuses {
+			record.StatusCounts[st.Status]++
+		}
+		record.RepoCommits = collectRepoCommits(statuses)
+	}
+
+	jobs, err := loadRunJobConfig(outputDir)
+	if err != nil {
+		return err
+	}
+	if jobs != nil {
+		record.JobConfig = jobs
+	}
+	return nil
+}
+
+// collectRepoCommits 按服务类型汇总节点记录的部署仓库 commit；没有任何记录时返回 nil。
+func collectRepoCommits(statuses []*ScriptStatus) map[string][]string {
+	seen := make(map[string]map[string]struct{})
+	for _, st := range statuses {
+		if st.RepoCommit == "" {
+			continue
+		}
+		if seen[st.ServiceType] == nil {
+			seen[st.ServiceType] = make(map[string]struct{})
+		}
+		seen[st.ServiceType][st.RepoCommit] = struct{}{}
+	}
+	if len(seen) == 0 {
+		return nil
+	}
+	out := make(map[string][]string, len(seen))
+	for serviceType, commits := range seen {
+		for c := range commits {
+			out[serviceType] = append(out[serviceType], c)
+		}
+		sort.Strings(out[serviceType])
+	}
+	return out
+}
+
+// loadRunJobConfig 读取 gen-cross-tx-config 默认输出的 <outputDir>/jobs/all.json；不存在时返回 nil。
+func loadRunJobConfig(outputDir string) (*RunJobConfig, error) {
+	path := filepath.Join(outputDir, "jobs", "all.json")
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("读取 jobs 配置失败: %w", err)
+	}
+	var jobs []json.RawMessage
+	if err := json.Unmarshal(data, &jobs); err != nil {
+		return nil, fmt.Errorf("解析 jobs 配置 %s 失败: %w", path, err)
+	}
+	sum := sha256.Sum256(data)
+	return &RunJobConfig{Path: path, Hash: hex.EncodeToString(sum[:]), Jobs: len(jobs)}, nil
+}
+
+// LoadRunRecord 读取某个 output 目录（当前或已归档）的运行记录。
+// 早于 runs 功能的旧输出没有 run.json，按 deployment.json 与状态文件尽量还原；两者都不存在时返回 nil。
+func LoadRunRecord(outputDir string) (*RunRecord, error) {
+	record, err := readRunRecord(outputDir)
+	if err != nil {
+		return nil, err
+	}
+	if record == nil {
+		info, err := LoadDeploymentInfo(outputDir)
+		if err != nil {
+			return nil, err
+		}
+		_, hasState := statStateFile(outputDir)
+		_, legacyErr := os.Stat(filepath.Join(outputDir, "script_status.json"))
+		if info == nil && !hasState && legacyErr != nil {
+			return nil, nil
+		}
+		record = &RunRecord{ID: filepath.Base(outputDir)}
+		if info != nil {
+			record.ID = info.DeploymentID
+			record.Operator = info.Operator
+			record.Region = info.Region
+			if ts, parseErr := time.Parse(time.RFC3339, info.RunTimestamp); parseErr == nil {
+				record.StartedAt = ts.Unix()
+			}
+		}
+	}
+
+	record.OutputDir = outputDir
+	if err := refreshRunRecord(outputDir, record); err != nil {
+		return nil, err
+	}
+	return record, nil
+}
+
+// ListRuns 扫描当前 output 目录（outputDirOverride 优先）及其归档目录，按启动时间倒序返回运行记录。
+func ListRuns(commonCfg CommonConfig, outputDirOverride string) ([]*RunRecord, error) {
+	return listRuns(resolveOutputDir(commonCfg, outputDirOverride), commonCfg.LogDir)
+}
+
+// listRuns 的归档目录包括 <outputDir>-<ts>；outputDir 位于 logDir 内时还包括随 logs 一起归档的 <logDir>-<ts>/<output>-<ts>。
+func listRuns(outputDir, logDir string) ([]*RunRecord, error) {
+	outputDir = filepath.Clean(outputDir)
+	patterns := []string{outputDir + "-*"}
+	if strings.TrimSpace(logDir) != "" {
+		logDir = filepath.Clean(logDir)
+		if rel, err := filepath.Rel(logDir, outputDir); err == nil && rel != "." && !strings.HasPrefix(rel, "..") {
+			patterns = append(patterns, filepath.Join(logDir+"-*", rel), filepath.Join(logDir+"-*", rel+"-*"))
+		}
+	}
+
+	dirs := []string{outputDir}
+	for _, pattern := range patterns {
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("扫描归档目录失败: %w", err)
+		}
+		dirs = append(dirs, matches...)
+	}
+
+	var records []*RunRecord
+	seen := make(map[string]struct{}, len(dirs))
+	for _, dir := range dirs {
+		if _, ok := seen[dir]; ok {
+			continue
+		}
+		seen[dir] = struct{}{}
+		info, statErr := os.Stat(dir)
+		if statErr != nil || !info.IsDir() {
+			continue
+		}
+		record, err := LoadRunRecord(dir)
+		if err != nil {
+			return nil, fmt.Errorf("[%s] %w", dir, err)
+		}
+		if record != nil {
+			records = append(records, record)
+		}
+	}
+
+	sort.SliceStable(records, func(i, j int) bool {
+		if records[i].StartedAt != records[j].StartedAt {
+			return records[i].StartedAt > records[j].StartedAt
+		}
+		return records[i].OutputDir > records[j].OutputDir
+	})
+	return records, nil
+}
+
+// FindRun 按 deploymentId、目录名或其唯一前缀查找运行记录。
+func FindRun(records []*RunRecord, id string) (*RunRecord, error) {
+	id = strings.TrimSpace(id)
+	if id == "" {
+		return nil, fmt.Errorf("run id 不能为空")
+	}
+
+	match := func(pred func(r *RunRecord) bool) []*RunRecord {
+		var out []*RunRecord
+		for _, r := range records {
+			if pred(r) {
+				out = append(out, r)
+			}
+		}
+		return out
+	}
+	candidates := match(func(r *RunRecord) bool { return r.ID == id || filepath.Base(r.OutputDir) == id })
+	if len(candidates) == 0 {
+		candidates = match(func(r *RunRecord) bool {
+			return strings.HasPrefix(r.ID, id) || strings.HasPrefix(filepath.Base(r.OutputDir), id)
+		})
+	}
+
+	switch len(candidates) {
+	case 0:
+		return nil, fmt.Errorf("未找到运行记录 %q", id)
+	case 1:
+		return candidates[0], nil
+	default:
+		dirs := make([]string, 0, len(candidates))
+		for _, r := range candidates {
+			dirs = append(dirs, filepath.Base(r.OutputDir))
+		}
+		return nil, fmt.Errorf("运行记录 %q 不唯一，请改用目录名指定: %s", id, strings.Join(dirs, ", "))
+	}
+}
+
+// RunDiffEntry 描述两次运行之间的一项差异。
+type RunDiffEntry struct {
+	Field string
+	A     string
+	B     string
+}
+
+// DiffRuns 对比两次运行的配置、节点数、最终状态统计与 jobs 配置，按字段名排序返回差异项。
+func DiffRuns(a, b *RunRecord) []RunDiffEntry {
+	fa, fb := flattenRunRecord(a), flattenRunRecord(b)
+
+	fields := make(map[string]struct{}, len(fa)+len(fb))
+	for k := range fa {
+		fields[k] = struct{}{}
+	}
+	for k := range fb {
+		fields[k] = struct{}{}
+	}
+
+	var diffs []RunDiffEntry
+	for field := range fields {
+		if fa[field] != fb[field] {
+			diffs = append(diffs, RunDiffEntry{Field: field, A: fa[field], B: fb[field]})
+		}
+	}
+	sort.Slice(diffs, func(i, j int) bool { return diffs[i].Field < diffs[j].Field })
+	return diffs
+}
+
+func flattenRunRecord(r *RunRecord) map[string]string {
+	out := make(map[string]string)
+	if r == nil {
+		return out
+	}
+	if r.Config != nil {
+		if data, err := json.Marshal(r.Config); err == nil {
+			var v any
+			if json.Unmarshal(data, &v) == nil {
+				flattenJSONValue("config", v, out)
+			}
+		}
+		// time.Duration 序列化为纳秒整数，按可读形式展示
+		out["config.RunDuration"] = r.Config.RunDuration.String()
+		out["config.SSHReadyRetryInterval"] = r.Config.SSHReadyRetryInterval.String()
+	}
+	for k, v := range r.NodeCounts {
+		out["nodes."+k] = fmt.Sprint(v)
+	}
+	for k, v := range r.StatusCounts {
+		out["status."+k] = fmt.Sprint(v)
+	}
+	for k, v := range r.RepoCommits {
+		out["repoCommit."+k] = strings.Join(v, ",")
+	}
+	if r.JobConfig != nil {
+		out["jobs.count"] = fmt.Sprint(r.JobConfig.Jobs)
+		out["jobs.hash"] = ShortHash(r.JobConfig.Hash)
+	}
+	if d := r.Duration(); d > 0 {
+		out["duration"] = d.String()
+	}
+	out["result"] = r.Result
+	return out
+}
+
+func flattenJSONValue(prefix string, v any, out map[string]string) {
+	switch val := v.(type) {
+	case map[string]any:
+		for k, child := range val {
+			flattenJSONValue(prefix+"."+k, child, out)
+		}
+	case []any:
+		for i, child := range val {
+			flattenJSONValue(fmt.Sprintf("%s[%d]", prefix, i), child, out)
+		}
+	case nil:
+	default:
+		out[prefix] = fmt.Sprint(val)
+	}
+}
+
+// ShortHash 截取哈希前 12 位用于展示。
+func ShortHash(hash string) string {
+	if len(hash) > 12 {
+		return hash[:12]
+	}
+	return hash
+}
@@ -0,0 +1,198 @@
+package deploy
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/wangdayong228/ydyl-deploy-client/internal/constants/enums"
+)
+
+func seedRun(t *testing.T, outputDir, deploymentID string, count uint, startedAt time.Time, statuses map[string]string) DeployConfig {
+	t.Helper()
+
+	cfg := DeployConfig{
+		CommonConfig: CommonConfig{
+			OutputDir:       outputDir,
+			Region:          "us-west-2",
+			RunDuration:     2 * time.Hour,
+			L1VaultMnemonic: "test test test test test test test test test test test junk",
+		},
+		Services: []ServiceConfig{{Type: enums.ServiceTypeOP, TagPrefix: "ydyl", Count: count, InstanceType: []string{"c6a.xlarge"}}},
+	}
+	if err := beginRunRecord(cfg, DeploymentInfo{DeploymentID: deploymentID, Operator: "alice", Region: "us-west-2"}, false, startedAt); err != nil {
+		t.Fatalf("beginRunRecord: %v", err)
+	}
+
+	mgr := NewOutputManager(outputDir)
+	for ip, status := range statuses {
+		if err := mgr.AddServers([]ServerInfo{{IP: ip, ServiceType: "op"}}); err != nil {
+			t.Fatalf("AddServers: %v", err)
+		}
+		if err := mgr.UpdateStatus(ip, "op", func(st *ScriptStatus) { st.Status = status }); err != nil {
+			t.Fatalf("UpdateStatus: %v", err)
+		}
+	}
+	return cfg
+}
+
+func TestRunRecord_BeginAndFinish(t *testing.T) {
+	t.Parallel()
+
+	outputDir := t.TempDir()
+	started := time.Unix(1_700_000_000, 0)
+	seedRun(t, outputDir, "dep-1", 2, started, map[string]string{"1.1.1.1": "success", "1.1.1.2": "failed"})
+
+	// 启动时间早于 run.json 的调用（例如初始化失败、未写入新记录）不得改写记录
+	if err := finishRunRecord(outputDir, started.Add(time.Hour), nil, started.Add(2*time.Hour)); err != nil {
+		t.Fatalf("finishRunRecord: %v", err)
+	}
+	if r, _ := readRunRecord(outputDir); r.Result != "running" {
+		t.Fatalf("stale finish should be ignored, got=%+v", r)
+	}
+
+	if err := finishRunRecord(outputDir, started, errors.New("1 条链失败"), started.Add(90*time.Minute)); err != nil {
+		t.Fatalf("finishRunRecord: %v", err)
+	}
+	r, err := LoadRunRecord(outputDir)
+	if err != nil {
+		t.Fatalf("LoadRunRecord: %v", err)
+	}
+	if r.ID != "dep-1" || r.Result != "failed" || r.Duration() != 90*time.Minute {
+		t.Fatalf("unexpected record: %+v", r)
+	}
+	if r.StatusCounts["success"] != 1 || r.StatusCounts["failed"] != 1 || r.NodeCounts["op"] != 2 {
+		t.Fatalf("unexpected counts: status=%v nodes=%v", r.StatusCounts, r.NodeCounts)
+	}
+	if r.Config == nil || r.Config.L1VaultMnemonic != redactedValue || len(r.ConfigHash) != 64 {
+		t.Fatalf("config must be stored redacted with hash, got=%+v", r.Config)
+	}
+}
+
+func TestListRunsAndDiff(t *testing.T) {
+	t.Parallel()
+
+	logDir := filepath.Join(t.TempDir(), "logs")
+	outputDir := filepath.Join(logDir, "output")
+	// 上一次运行的 output 随 logs 一起归档为 logs-<ts>/output-<ts>
+	archived := filepath.Join(logDir+"-20260101-000000", "output-20260101-000000")
+	if err := os.MkdirAll(archived, 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	seedRun(t, archived, "dep-old", 4, time.Unix(1_700_000_000, 0), map[string]string{"1.1.1.1": "success"})
+	seedRun(t, outputDir, "dep-new", 8, time.Unix(1_700_100_000, 0), map[string]string{"2.2.2.1": "success", "2.2.2.2": "running"})
+	if err := os.MkdirAll(filepath.Join(outputDir, "jobs"), 0o755); err != nil {
+		t.Fatalf("mkdir jobs: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(outputDir, "jobs", "all.json"), []byte(`[{"a":1},{"a":2}]`), 0o644); err != nil {
+		t.Fatalf("write jobs: %v", err)
+	}
+
+	records, err := ListRuns(CommonConfig{LogDir: logDir, OutputDir: outputDir}, "")
+	if err != nil {
+		t.Fatalf("ListRuns: %v", err)
+	}
+	if len(records) != 2 || records[0].ID != "dep-new" || records[1].ID != "dep-old" {
+		t.Fatalf("unexpected runs: %+v", records)
+	}
+	if records[0].JobConfig == nil || records[0].JobConfig.Jobs != 2 {
+		t.Fatalf("job config not detected: %+v", records[0].JobConfig)
+	}
+
+	a, err := FindRun(records, "output-2026")
+	if err != nil || a.ID != "dep-old" {
+		t.Fatalf("FindRun by dir prefix: %+v err=%v", a, err)
+	}
+	if _, err := FindRun(records, "dep-"); err == nil {
+		t.Fatalf("ambiguous prefix should fail")
+	}
+
+	diffs := DiffRuns(a, records[0])
+	got := make(map[string]RunDiffEntry, len(diffs))
+	for _, d := range diffs {
+		got[d.Field] = d
+	}
+	if d := got["config.Services[0].Count"]; d.A != "4" || d.B != "8" {
+		t.Fatalf("service count diff missing: %+v", diffs)
+	}
+	if d := got["status.running"]; d.A != "" || d.B != "1" {
+		t.Fatalf("status diff missing: %+v", diffs)
+	}
+	if _, ok := got["jobs.count"]; !ok {
+		t.Fatalf("jobs diff missing: %+v", diffs)
+	}
+	if _, ok := got["config.Region"]; ok {
+		t.Fatalf("identical fields must not be reported: %+v", diffs)
+	}
+	for field := range got {
+		if strings.Contains(field, "Mnemonic") {
+			t.Fatalf("redacted secrets must not differ: %s", field)
+		}
+	}
+}
+
+func TestRunRecord_RedactsServiceSecrets(t *testing.T) {
+	t.Parallel()
+
+	const (
+		mnemonic   = "test test test test test test test test test test test junk"
+		privateKey = "0xac0974bec39a17e36ba4a6b4d238ff944bacb478cbed5efcae784d7bf4f2ff80"
+		apiToken   = "ghp_secretTokenValue"
+	)
+	newCfg := func(outputDir, token string) DeployConfig {
+		return DeployConfig{
+			CommonConfig: CommonConfig{
+				OutputDir:                  outputDir,
+				L1VaultMnemonic:            mnemonic,
+				L1RegisterBridgePrivateKey: privateKey,
+			},
+			Services: []ServiceConfig{{
+				Type:      enums.ServiceTypeOP,
+				Count:     1,
+				RemoteCmd: "PRIVATE_KEY=" + privateKey + " GITHUB_TOKEN=" + token + " ./run.sh",
+				UserData:  "#!/bin/bash\necho " + token + " > /etc/token",
+			}},
+		}
+	}
+
+	dirA, dirB := t.TempDir(), t.TempDir()
+	cfgA := newCfg(dirA, apiToken)
+	if err := beginRunRecord(cfgA, DeploymentInfo{DeploymentID: "dep-a"}, false, time.Unix(1_700_000_000, 0)); err != nil {
+		t.Fatalf("beginRunRecord: %v", err)
+	}
+	if err := beginRunRecord(newCfg(dirB, "ghp_rotated"), DeploymentInfo{DeploymentID: "dep-b"}, false, time.Unix(1_700_000_100, 0)); err != nil {
+		t.Fatalf("beginRunRecord: %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dirA, runRecordFileName))
+	if err != nil {
+		t.Fatalf("read run.json: %v", err)
+	}
+	for _, secret := range []string{mnemonic, privateKey, apiToken} {
+		if strings.Contains(string(data), secret) {
+			t.Fatalf("run.json leaks %q:\n%s", secret, data)
+		}
+	}
+	if cfgA.Services[0].RemoteCmd == "" || !strings.Contains(cfgA.Services[0].RemoteCmd, apiToken) {
+		t.Fatalf("redaction must not mutate the caller's config")
+	}
+
+	a, err := LoadRunRecord(dirA)
+	if err != nil {
+		t.Fatalf("LoadRunRecord: %v", err)
+	}
+	b, err := LoadRunRecord(dirB)
+	if err != nil {
+		t.Fatalf("LoadRunRecord: %v", err)
+	}
+	fields := make(map[string]bool)
+	for _, d := range DiffRuns(a, b) {
+		fields[d.Field] = true
+	}
+	if !fields["config.Services[0].RemoteCmd"] || !fields["config.Services[0].UserData"] {
+		t.Fatalf("changed remoteCmd / userData should still be reported by diff: %v", fields)
+	}
+}
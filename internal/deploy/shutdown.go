@@ -8,6 +8,7 @@ import (
 	"log"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"strings"
 	"sync"
 )
@@ -54,6 +55,15 @@ func Shutdown(ctx context.Context, commonCfg CommonConfig, serversPath string) e
 		errs = append(errs, err)
 	}
 
+	names := make(map[string]ServerInfo, len(servers))
+	for _, s := range servers {
+		names[strings.TrimSpace(s.IP)] = s
+	}
+	recordShutdown := func(ip, message string) {
+		s := names[ip]
+		recordEvents(filepath.Dir(serversPath), Event{Type: EventShutdown, IP: ip, ServiceType: s.ServiceType, Name: s.Name, Message: message})
+	}
+
 	log.Printf("👉 [shutdown] 开始关机，共 %d 台服务器\n", len(ips))
 	runWithBatchLimit("shutdown-servers", len(ips), resolveSSHMaxConcurrency(commonCfg), func(i int) {
 		ip := ips[i]
@@ -62,16 +72,18 @@ func Shutdown(ctx context.Context, commonCfg CommonConfig, serversPath string) e
 			msg := strings.TrimSpace(output)
 			if isExpectedShutdownDisconnect(runErr, msg) {
 				log.Printf("[shutdown][%s] 连接被远端主动断开，视为关机命令已生效\n", ip)
+				recordShutdown(ip, "")
 				return
 			}
 			if msg != "" {
-				addErr(fmt.Errorf("[%s] 关机命令执行失败: %w，输出: %s", ip, runErr, msg))
-				return
+				runErr = fmt.Errorf("%w，输出: %s", runErr, msg)
 			}
+			recordShutdown(ip, "关机命令执行失败: "+runErr.Error())
 			addErr(fmt.Errorf("[%s] 关机命令执行失败: %w", ip, runErr))
 			return
 		}
 		log.Printf("[shutdown][%s] 关机命令已下发\n", ip)
+		recordShutdown(ip, "")
 	})
 
 	if len(errs) > 0 {
@@ -12,6 +12,16 @@ import (
 type RunOptions struct {
 	// ServersCreateJSONPath 为 servers_create.json 格式快照的绝对路径（通常由 CLI 先复制到临时文件后再传入）。
 	ServersCreateJSONPath string
+	// Append 为 true 时在已有 output 上扩容：不归档旧目录，新节点的序号 / L2 chainId / xjst groupId 从已有最大值之后续接，
+	// 且 services[].count 视为该服务的目标总数（只创建差额）。
+	Append bool
+	// Resume 为 true 时从 state.json 记录的阶段进度继续上次被中断的 deploy：已完成的充值、已创建的实例、
+	// 已登记的节点与已启动的命令都不会重复执行。
+	Resume bool
+	// Only / Skip 选择本次执行的阶段（见 DeployPhases），二者互斥；都为空时执行全部阶段。
+	// 未选择 launch 时在已有 output 上继续，已登记节点按 state.json 中的进度执行剩余的逐节点阶段。
+	Only []string
+	Skip []string
 }
 
 // CopyServersCreateSnapshotToTemp 将任意路径下的 servers_create 快照复制到系统临时目录，返回临时文件绝对路径与 cleanup。
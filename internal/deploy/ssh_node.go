@@ -0,0 +1,120 @@
+package deploy
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// sshCandidatesShownLimit 为查询匹配到多个节点时错误信息中最多列出的候选数。
+const sshCandidatesShownLimit = 10
+
+// SSHTarget 为 ssh 命令解析出的单个节点。
+type SSHTarget struct {
+	Name        string
+	ServiceType string
+	IP          string
+	Status      string
+	// LogPath 为远端 pipe 日志路径：优先取 script_status.json 中记录的 logPath，未记录时按默认规则推导。
+	LogPath string
+}
+
+// ResolveSSHTarget 在 servers.json / script_status.json 中按 query 查找唯一节点，依次尝试：
+// IP 或名称精确匹配 -> 名称 glob（path.Match 语法）-> 名称子串（不区分大小写）。
+// 某一步匹配到多个节点时报错并列出候选，不再继续下一步。
+func ResolveSSHTarget(commonCfg CommonConfig, serversPath, query string) (SSHTarget, error) {
+	if strings.TrimSpace(serversPath) == "" {
+		serversPath = filepath.Join(resolveOutputDir(commonCfg, ""), "servers.json")
+	}
+	outputMgr, err := LoadOutputManager(filepath.Dir(serversPath))
+	if err != nil {
+		return SSHTarget{}, err
+	}
+	return matchSSHTarget(collectSSHTargets(outputMgr.SnapshotServers(), outputMgr.SnapshotStatuses()), query)
+}
+
+func collectSSHTargets(servers []ServerInfo, statuses []*ScriptStatus) []SSHTarget {
+	logPaths := make(map[string]string, len(statuses))
+	for _, st := range statuses {
+		if st != nil && st.LogPath != "" {
+			logPaths[compositeKey(st.IP, st.ServiceType)] = st.LogPath
+		}
+	}
+
+	candidates := collectRemoveCandidates(servers, statuses)
+	targets := make([]SSHTarget, 0, len(candidates))
+	for _, c := range candidates {
+		logPath, _ := buildRemoteLogPath(logPaths[c.key()], c.Name)
+		targets = append(targets, SSHTarget{Name: c.Name, ServiceType: c.ServiceType, IP: c.IP, Status: c.Status, LogPath: logPath})
+	}
+	sort.Slice(targets, func(i, j int) bool {
+		if targets[i].ServiceType != targets[j].ServiceType {
+			return targets[i].ServiceType < targets[j].ServiceType
+		}
+		return targets[i].Name < targets[j].Name
+	})
+	return targets
+}
+
+func matchSSHTarget(targets []SSHTarget, query string) (SSHTarget, error) {
+	query = strings.TrimSpace(query)
+	if query == "" {
+		return SSHTarget{}, fmt.Errorf("节点名称或 IP 不能为空")
+	}
+	if len(targets) == 0 {
+		return SSHTarget{}, fmt.Errorf("servers.json / script_status.json 中没有任何节点")
+	}
+	if _, err := path.Match(query, ""); err != nil {
+		return SSHTarget{}, fmt.Errorf("节点名称模式不合法 %q: %w", query, err)
+	}
+
+	lower := strings.ToLower(query)
+	matchers := []func(t SSHTarget) bool{
+		func(t SSHTarget) bool { return t.IP == query || t.Name == query },
+		func(t SSHTarget) bool { ok, _ := path.Match(query, t.Name); return ok },
+		func(t SSHTarget) bool { return strings.Contains(strings.ToLower(t.Name), lower) },
+	}
+	for _, match := range matchers {
+		var matched []SSHTarget
+		for _, t := range targets {
+			if match(t) {
+				matched = append(matched, t)
+			}
+		}
+		switch {
+		case len(matched) == 1:
+			return matched[0], nil
+		case len(matched) > 1:
+			return SSHTarget{}, fmt.Errorf("%q 匹配到 %d 个节点，请指定更精确的名称: %s", query, len(matched), formatSSHCandidates(matched))
+		}
+	}
+	return SSHTarget{}, fmt.Errorf("%q 未匹配任何节点", query)
+}
+
+func formatSSHCandidates(targets []SSHTarget) string {
+	shown := make([]string, 0, sshCandidatesShownLimit+1)
+	for i, t := range targets {
+		if i == sshCandidatesShownLimit {
+			shown = append(shown, fmt.Sprintf("...等 %d 个", len(targets)))
+			break
+		}
+		shown = append(shown, fmt.Sprintf("%s(%s)", t.Name, t.IP))
+	}
+	return strings.Join(shown, ", ")
+}
+
+// BuildInteractiveSSHArgs 返回登录 target 的 ssh 参数（不含 "ssh" 本身），复用部署时的 SSH 用户与私钥；
+// tailLines > 0 时改为 tail -F 跟随远端 pipe 日志（先输出最后 tailLines 行）。
+func BuildInteractiveSSHArgs(commonCfg CommonConfig, target SSHTarget, tailLines int) ([]string, error) {
+	sshUser := strings.TrimSpace(commonCfg.SSHUser)
+	if sshUser == "" {
+		return nil, fmt.Errorf("sshUser 不能为空")
+	}
+	args := append(collectLogsSSHArgs(buildSSHKeyPath(commonCfg)), "-t", fmt.Sprintf("%s@%s", sshUser, target.IP))
+	if tailLines > 0 {
+		args = append(args, fmt.Sprintf("tail -n %d -F %s", tailLines, shellQuote(target.LogPath)))
+	}
+	return args, nil
+}
@@ -0,0 +1,90 @@
+package deploy
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMatchSSHTarget(t *testing.T) {
+	t.Parallel()
+
+	targets := collectSSHTargets(
+		[]ServerInfo{
+			{IP: "1.1.1.1", ServiceType: "xjst", Name: "ydyl-xjst-3-1"},
+			{IP: "1.1.1.2", ServiceType: "xjst", Name: "ydyl-xjst-3-2"},
+			{IP: "2.2.2.1", ServiceType: "op", Name: "ydyl-op-1"},
+			{IP: "2.2.2.12", ServiceType: "op", Name: "ydyl-op-12"},
+		},
+		[]*ScriptStatus{
+			{IP: "2.2.2.1", ServiceType: "op", Name: "ydyl-op-1", Status: "failed", LogPath: "/data/logs/op-1.log"},
+		},
+	)
+
+	tests := []struct {
+		query   string
+		want    string
+		wantErr string
+	}{
+		{query: "ydyl-xjst-3-1", want: "ydyl-xjst-3-1"},
+		{query: "2.2.2.12", want: "ydyl-op-12"},
+		// 精确匹配优先于子串匹配（ydyl-op-1 同时是 ydyl-op-12 的子串）
+		{query: "ydyl-op-1", want: "ydyl-op-1"},
+		{query: "ydyl-xjst-*-2", want: "ydyl-xjst-3-2"},
+		{query: "XJST-3-1", want: "ydyl-xjst-3-1"},
+		{query: "xjst-3", wantErr: "匹配到 2 个节点"},
+		{query: "cdk", wantErr: "未匹配任何节点"},
+		{query: "[", wantErr: "不合法"},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.query, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := matchSSHTarget(targets, tt.query)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error containing %q, got=%v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got.Name != tt.want {
+				t.Fatalf("got=%s, want=%s", got.Name, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuildInteractiveSSHArgs_TailUsesRecordedLogPath(t *testing.T) {
+	t.Parallel()
+
+	targets := collectSSHTargets(
+		[]ServerInfo{{IP: "1.1.1.1", ServiceType: "op", Name: "ydyl-op-1"}, {IP: "1.1.1.2", ServiceType: "op", Name: "ydyl-op-2"}},
+		[]*ScriptStatus{{IP: "1.1.1.1", ServiceType: "op", Name: "ydyl-op-1", LogPath: "/data/logs/op-1.log"}},
+	)
+	if targets[0].LogPath != "/data/logs/op-1.log" || targets[1].LogPath != remoteLogDirDefault+"/ydyl-op-2.log" {
+		t.Fatalf("unexpected log paths: %+v", targets)
+	}
+
+	cfg := CommonConfig{SSHUser: "ubuntu", SSHKeyDir: "/keys", KeyName: "k"}
+	args, err := BuildInteractiveSSHArgs(cfg, targets[0], 100)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	joined := strings.Join(args, " ")
+	for _, want := range []string{"-i /keys/k.pem", "-t ubuntu@1.1.1.1", "tail -n 100 -F '/data/logs/op-1.log'"} {
+		if !strings.Contains(joined, want) {
+			t.Fatalf("args %q missing %q", joined, want)
+		}
+	}
+
+	args, err = BuildInteractiveSSHArgs(cfg, targets[0], 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if args[len(args)-1] != "ubuntu@1.1.1.1" {
+		t.Fatalf("interactive shell must not pass a remote command: %v", args)
+	}
+}
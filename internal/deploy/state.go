@@ -0,0 +1,237 @@
+package deploy
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/gofrs/flock"
+)
+
+const (
+	// stateFileName 为部署状态的权威文件；servers.json / script_status.json / ssh_scripts.json / servers_create.json
+	// 由它派生，仅供旧命令与外部脚本按原格式读取。
+	stateFileName = "state.json"
+	// stateLockFileName 为多个 CLI 进程共享同一 output 目录时使用的 advisory 文件锁。
+	stateLockFileName = "state.json.lock"
+
+	// stateSchemaVersion 为当前客户端写入的状态文件版本；结构变化时递增并在 stateMigrations 中补充迁移。
+	stateSchemaVersion = 3
+)
+
+// deploymentState 为 state.json 的文档结构。
+type deploymentState struct {
+	SchemaVersion int `json:"schemaVersion"`
+	// Revision 每次写入递增，便于排查并发写入顺序。
+	Revision  uint64 `json:"revision"`
+	UpdatedAt int64  `json:"updatedAt,omitempty"`
+
+	Servers        []ServerInfo        `json:"servers"`
+	CreatedServers []CreatedServerInfo `json:"createdServers"`
+	Statuses       []*ScriptStatus     `json:"statuses"`
+	SSHScripts     []*SSHScriptStatus  `json:"sshScripts"`
+	// Fingerprints 为 SSH 就绪后采集的节点环境指纹，见 HostFingerprint。
+	Fingerprints []*HostFingerprint `json:"fingerprints,omitempty"`
+
+	// Progress 为最近一次 deploy 的阶段进度，供 deploy --resume 使用；非 deploy 产生的输出为空。
+	Progress *DeployProgress `json:"progress,omitempty"`
+}
+
+// stateMigrations 以源版本为键，将状态文档升级到下一个版本。
+//   - 0 -> 1: 旧版本没有 state.json，从分散的 servers.json / script_status.json / ssh_scripts.json / servers_create.json 导入。
+//   - 1 -> 2: 新增 progress；旧文档没有阶段进度，保持为空（deploy --resume 会拒绝继续）。
+//   - 2 -> 3: 新增 fingerprints；旧文档没有环境指纹，保持为空。
+var stateMigrations = map[int]func(outputDir string, doc *deploymentState) error{
+	0: migrateStateFromLegacyFiles,
+	1: func(string, *deploymentState) error { return nil },
+	2: func(string, *deploymentState) error { return nil },
+}
+
+// loadStateDocument 读取 outputDir 下的状态文档并迁移到当前版本；state.json 不存在时按版本 0 从旧文件导入。
+// 调用方需持有状态文件锁（读锁即可）。
+func loadStateDocument(outputDir string) (*deploymentState, error) {
+	doc := &deploymentState{}
+	data, err := os.ReadFile(filepath.Join(outputDir, stateFileName))
+	switch {
+	case err == nil:
+		if uErr := json.Unmarshal(data, doc); uErr != nil {
+			return nil, fmt.Errorf("解析 %s 失败: %w", stateFileName, uErr)
+		}
+		if doc.SchemaVersion < 1 {
+			return nil, fmt.Errorf("%s 缺少有效的 schemaVersion", stateFileName)
+		}
+	case os.IsNotExist(err):
+	default:
+		return nil, fmt.Errorf("读取 %s 失败: %w", stateFileName, err)
+	}
+
+	if doc.SchemaVersion > stateSchemaVersion {
+		return nil, fmt.Errorf("%s 版本为 %d，高于当前客户端支持的 %d，请升级 ydyl-deploy-client", stateFileName, doc.SchemaVersion, stateSchemaVersion)
+	}
+	for doc.SchemaVersion < stateSchemaVersion {
+		migrate, ok := stateMigrations[doc.SchemaVersion]
+		if !ok {
+			return nil, fmt.Errorf("缺少 %s 从版本 %d 升级的迁移", stateFileName, doc.SchemaVersion)
+		}
+		if err := migrate(outputDir, doc); err != nil {
+			return nil, fmt.Errorf("迁移 %s（版本 %d）失败: %w", stateFileName, doc.SchemaVersion, err)
+		}
+		doc.SchemaVersion++
+	}
+	return doc, nil
+}
+
+func migrateStateFromLegacyFiles(outputDir string, doc *deploymentState) error {
+	if err := readLegacyJSON(outputDir, "servers.json", &doc.Servers); err != nil {
+		return err
+	}
+	if err := readLegacyJSON(outputDir, "script_status.json", &doc.Statuses); err != nil {
+		return err
+	}
+	if err := readLegacyJSON(outputDir, "ssh_scripts.json", &doc.SSHScripts); err != nil {
+		return err
+	}
+	return readLegacyJSON(outputDir, "servers_create.json", &doc.CreatedServers)
+}
+
+// readLegacyJSON 读取旧版输出文件，文件不存在时忽略。
+func readLegacyJSON(outputDir, name string, v any) error {
+	data, err := os.ReadFile(filepath.Join(outputDir, name))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("读取 %s 失败: %w", name, err)
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("解析 %s 失败: %w", name, err)
+	}
+	return nil
+}
+
+// saveStateDocument 原子写入 state.json，并同步刷新派生的旧格式文件。调用方需持有状态文件写锁。
+func saveStateDocument(outputDir string, doc *deploymentState) error {
+	if err := writeJSONFileAtomic(filepath.Join(outputDir, stateFileName), doc); err != nil {
+		return fmt.Errorf("写入 %s 失败: %w", stateFileName, err)
+	}
+
+	views := []struct {
+		name string
+		v    any
+	}{
+		{"servers.json", doc.Servers},
+		{"script_status.json", doc.Statuses},
+		{"ssh_scripts.json", doc.SSHScripts},
+		{"servers_create.json", doc.CreatedServers},
+	}
+	for _, view := range views {
+		if err := writeJSONFileAtomic(filepath.Join(outputDir, view.name), view.v); err != nil {
+			return fmt.Errorf("写入 %s 失败: %w", view.name, err)
+		}
+	}
+	return nil
+}
+
+// writeJSONFileAtomic 先写同目录临时文件再 rename，读者不会看到写了一半的 JSON。
+func writeJSONFileAtomic(path string, v any) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(path)+"-*.tmp")
+	if err != nil {
+		return err
+	}
+	tmpPath := tmp.Name()
+	defer func() {
+		_ = os.Remove(tmpPath)
+	}()
+
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmpPath, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, path)
+}
+
+// stateFileStamp 记录 state.json 的修改时间与大小，用于判断其它进程是否已写入新版本。
+type stateFileStamp struct {
+	modTime time.Time
+	size    int64
+}
+
+func statStateFile(outputDir string) (stateFileStamp, bool) {
+	info, err := os.Stat(filepath.Join(outputDir, stateFileName))
+	if err != nil {
+		return stateFileStamp{}, false
+	}
+	return stateFileStamp{modTime: info.ModTime(), size: info.Size()}, true
+}
+
+func newStateLock(outputDir string) *flock.Flock {
+	return flock.New(filepath.Join(outputDir, stateLockFileName))
+}
+
+// sortedStatuses 按 compositeKey 排序输出，保证 state.json 与派生文件的结构稳定、便于 diff。
+func sortedStatuses(statuses map[string]*ScriptStatus) []*ScriptStatus {
+	keys := make([]string, 0, len(statuses))
+	for key, st := range statuses {
+		if st != nil {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	list := make([]*ScriptStatus, 0, len(keys))
+	for _, key := range keys {
+		list = append(list, statuses[key])
+	}
+	return list
+}
+
+func sortedSSHScripts(sshScripts map[string]*SSHScriptStatus) []*SSHScriptStatus {
+	keys := make([]string, 0, len(sshScripts))
+	for key, st := range sshScripts {
+		if st != nil {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	list := make([]*SSHScriptStatus, 0, len(keys))
+	for _, key := range keys {
+		list = append(list, sshScripts[key])
+	}
+	return list
+}
+
+func sortedFingerprints(fingerprints map[string]*HostFingerprint) []*HostFingerprint {
+	keys := make([]string, 0, len(fingerprints))
+	for key, fp := range fingerprints {
+		if fp != nil {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	list := make([]*HostFingerprint, 0, len(keys))
+	for _, key := range keys {
+		list = append(list, fingerprints[key])
+	}
+	return list
+}
@@ -0,0 +1,133 @@
+package deploy
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func readStateFile(t *testing.T, outputDir string) deploymentState {
+	t.Helper()
+
+	data, err := os.ReadFile(filepath.Join(outputDir, stateFileName))
+	if err != nil {
+		t.Fatalf("read %s: %v", stateFileName, err)
+	}
+	var doc deploymentState
+	if err := json.Unmarshal(data, &doc); err != nil {
+		t.Fatalf("unmarshal %s: %v", stateFileName, err)
+	}
+	return doc
+}
+
+func TestLoadOutputManager_MigratesLegacyFiles(t *testing.T) {
+	t.Parallel()
+
+	outputDir := t.TempDir()
+	legacy := map[string]string{
+		"servers.json":        `[{"ip":"1.1.1.1","serviceType":"op","name":"ydyl-op-1"}]`,
+		"script_status.json":  `[{"ip":"1.1.1.1","serviceType":"op","name":"ydyl-op-1","pid":42,"status":"running"}]`,
+		"ssh_scripts.json":    `[{"ip":"1.1.1.1","serviceType":"op","status":"success"}]`,
+		"servers_create.json": `[{"ip":"1.1.1.1","serviceType":"op","instanceId":"i-1"},{"ip":"1.1.1.1","serviceType":"op"}]`,
+	}
+	for name, content := range legacy {
+		if err := os.WriteFile(filepath.Join(outputDir, name), []byte(content), 0o644); err != nil {
+			t.Fatalf("write %s: %v", name, err)
+		}
+	}
+
+	mgr, err := LoadOutputManager(outputDir)
+	if err != nil {
+		t.Fatalf("LoadOutputManager: %v", err)
+	}
+	if got := mgr.SnapshotStatuses(); len(got) != 1 || got[0].PID != 42 {
+		t.Fatalf("unexpected migrated statuses: %+v", got)
+	}
+	if got := mgr.SnapshotCreatedServers(); len(got) != 1 || got[0].InstanceID != "i-1" {
+		t.Fatalf("unexpected migrated created servers: %+v", got)
+	}
+	if _, err := os.Stat(filepath.Join(outputDir, stateFileName)); !os.IsNotExist(err) {
+		t.Fatalf("loading must not write %s, err=%v", stateFileName, err)
+	}
+
+	if err := mgr.UpdateStatus("1.1.1.1", "op", func(st *ScriptStatus) { st.Status = "success" }); err != nil {
+		t.Fatalf("UpdateStatus: %v", err)
+	}
+	doc := readStateFile(t, outputDir)
+	if doc.SchemaVersion != stateSchemaVersion || doc.Revision != 1 {
+		t.Fatalf("unexpected state header: version=%d revision=%d", doc.SchemaVersion, doc.Revision)
+	}
+	if len(doc.Servers) != 1 || len(doc.SSHScripts) != 1 || len(doc.Statuses) != 1 || doc.Statuses[0].Status != "success" {
+		t.Fatalf("unexpected state content: %+v", doc)
+	}
+
+	// 派生的旧格式文件同步刷新
+	data, err := os.ReadFile(filepath.Join(outputDir, "script_status.json"))
+	if err != nil || !strings.Contains(string(data), `"status": "success"`) {
+		t.Fatalf("script_status.json not refreshed: %s err=%v", data, err)
+	}
+}
+
+func TestLoadOutputManager_RejectsNewerSchema(t *testing.T) {
+	t.Parallel()
+
+	outputDir := t.TempDir()
+	content := fmt.Sprintf(`{"schemaVersion":%d,"revision":3}`, stateSchemaVersion+1)
+	if err := os.WriteFile(filepath.Join(outputDir, stateFileName), []byte(content), 0o644); err != nil {
+		t.Fatalf("write state: %v", err)
+	}
+	if _, err := LoadOutputManager(outputDir); err == nil || !strings.Contains(err.Error(), "升级") {
+		t.Fatalf("expected newer schema to be rejected, got=%v", err)
+	}
+}
+
+func TestOutputManager_ConcurrentManagersDoNotClobber(t *testing.T) {
+	t.Parallel()
+
+	outputDir := t.TempDir()
+	// 模拟 deploy 与 sync / deploy-restore 两个进程各自持有一份 OutputManager
+	deployMgr := NewOutputManager(outputDir)
+	syncMgr, err := LoadOutputManager(outputDir)
+	if err != nil {
+		t.Fatalf("LoadOutputManager: %v", err)
+	}
+
+	const perManager = 20
+	var wg sync.WaitGroup
+	errCh := make(chan error, 2*perManager)
+	for i := 0; i < perManager; i++ {
+		wg.Add(2)
+		go func(i int) {
+			defer wg.Done()
+			errCh <- deployMgr.InitStatus(fmt.Sprintf("10.0.0.%d", i), "op", "", "cmd", i, "", "", 0, 0, "")
+		}(i)
+		go func(i int) {
+			defer wg.Done()
+			errCh <- syncMgr.UpdateSSHScriptStatus(fmt.Sprintf("10.0.1.%d", i), "cdk", "", "success", 1, "", 0)
+		}(i)
+	}
+	wg.Wait()
+	close(errCh)
+	for err := range errCh {
+		if err != nil {
+			t.Fatalf("concurrent write failed: %v", err)
+		}
+	}
+
+	doc := readStateFile(t, outputDir)
+	if len(doc.Statuses) != perManager || len(doc.SSHScripts) != perManager {
+		t.Fatalf("writes were lost: statuses=%d sshScripts=%d", len(doc.Statuses), len(doc.SSHScripts))
+	}
+	if doc.Revision != 2*perManager {
+		t.Fatalf("unexpected revision: %d", doc.Revision)
+	}
+
+	// 快照会感知其它进程的写入
+	if got := len(syncMgr.SnapshotStatuses()); got != perManager {
+		t.Fatalf("snapshot should reload statuses written by another manager, got=%d", got)
+	}
+}
@@ -70,9 +70,13 @@ func (m *Sync) Run(ctx context.Context) error {
 		return nil
 	}
 
+	return m.runStatuses(ctx, m.outputMgr.SnapshotStatuses(), make(chan struct{}, resolveSSHMaxConcurrency(m.cfg)))
+}
+
+// runStatuses 同步 statuses 中仍在运行的脚本直到全部结束，SSH 操作从 sshSem 获取并发令牌。
+// 返回第一个失败节点的错误（其余节点仍会同步到终态）。
+func (m *Sync) runStatuses(ctx context.Context, statuses []*ScriptStatus, sshSem chan struct{}) error {
 	keyPath := buildSSHKeyPath(m.cfg)
-	statuses := m.outputMgr.SnapshotStatuses()
-	sshSem := make(chan struct{}, resolveSSHMaxConcurrency(m.cfg))
 
 	var (
 		wg    sync.WaitGroup
@@ -0,0 +1,349 @@
+package deploy
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/service/ec2"
+)
+
+const teardownReportFileName = "teardown_report.json"
+
+// 实例 ID 的解析来源，写入 teardown 报告便于核对。
+const (
+	teardownSourceServers       = "servers"
+	teardownSourceServersCreate = "servers_create"
+	teardownSourceTag           = "tag"
+
+	teardownResolvedByRecord = "record"
+	teardownResolvedByIP     = "ip"
+	teardownResolvedByTag    = "tag"
+)
+
+// TeardownTarget 描述一台待终止实例及其处理结果。
+type TeardownTarget struct {
+	Name        string `json:"name,omitempty"`
+	ServiceType string `json:"serviceType,omitempty"`
+	IP          string `json:"ip,omitempty"`
+	InstanceID  string `json:"instanceId,omitempty"`
+	// Source 为该条目来自哪份记录：servers / servers_create / tag。
+	Source string `json:"source"`
+	// ResolvedBy 为实例 ID 的解析方式：record（servers_create 已记录）/ ip / tag；为空表示未找到存活实例。
+	ResolvedBy string `json:"resolvedBy,omitempty"`
+	State      string `json:"state,omitempty"`
+	Error      string `json:"error,omitempty"`
+}
+
+// TeardownReport 为 shutdown --terminate 的执行报告，写入 output/teardown_report.json。
+type TeardownReport struct {
+	GeneratedAt  string           `json:"generatedAt"`
+	DeploymentID string           `json:"deploymentId,omitempty"`
+	Region       string           `json:"region,omitempty"`
+	Targets      []TeardownTarget `json:"targets"`
+	Terminated   int              `json:"terminated"`
+	Unresolved   int              `json:"unresolved"`
+	Failed       int              `json:"failed"`
+}
+
+// Terminate 通过 EC2 API 终止一次部署的全部实例：
+//  1. 汇总 servers.json 与同目录 servers_create.json（包含从未进入 servers.json 的机器）；
+//  2. 优先使用已记录的实例 ID，否则按公网 IP 解析（仅接受带本部署标签的实例）；再按 deploymentId 标签补充遗漏实例；
+//  3. 分批 TerminateInstances 并等待进入 terminated 状态；
+//  4. 将结果写入同目录 teardown_report.json。
+func Terminate(ctx context.Context, commonCfg CommonConfig, serversPath string) (*TeardownReport, error) {
+	serversPath = strings.TrimSpace(serversPath)
+	if serversPath == "" {
+		return nil, fmt.Errorf("servers 路径不能为空")
+	}
+	outputDir := filepath.Dir(serversPath)
+
+	var servers []ServerInfo
+	if _, statErr := os.Stat(serversPath); statErr == nil {
+		loaded, err := loadServersFromFile(serversPath)
+		if err != nil {
+			return nil, err
+		}
+		servers = loaded
+	} else if !os.IsNotExist(statErr) {
+		return nil, fmt.Errorf("读取 servers.json 失败: %w", statErr)
+	}
+
+	outputMgr, err := LoadOutputManager(outputDir)
+	if err != nil {
+		return nil, err
+	}
+	deploymentID, err := resolveDeploymentID(commonCfg, outputDir)
+	if err != nil {
+		return nil, err
+	}
+
+	targets := collectTeardownTargets(servers, outputMgr.SnapshotCreatedServers())
+	if len(targets) == 0 && deploymentID == "" {
+		return nil, fmt.Errorf("servers.json / servers_create.json 中均未找到服务器，且无 deploymentId 可用于按标签查询: %s", outputDir)
+	}
+
+	client, err := newEC2APIFunc(commonCfg.Region)
+	if err != nil {
+		return nil, err
+	}
+
+	// 1) 未记录实例 ID 的条目按公网 IP 解析；只接受带本部署标签的实例，避免误删复用了旧 IP 的无关实例
+	var unresolvedIPs []string
+	for _, t := range targets {
+		if t.InstanceID == "" && t.IP != "" {
+			unresolvedIPs = append(unresolvedIPs, t.IP)
+		}
+	}
+	if len(unresolvedIPs) > 0 && deploymentID == "" {
+		log.Printf("⚠️ [shutdown] 无 deploymentId，无法确认 %d 个未记录实例 ID 的 IP 归属，将记为 unresolved\n", len(unresolvedIPs))
+	}
+	if len(unresolvedIPs) > 0 {
+		byIP, err := describeInstancesByIPs(ctx, client, unresolvedIPs, deploymentID)
+		if err != nil {
+			return nil, err
+		}
+		for i := range targets {
+			if targets[i].InstanceID != "" {
+				continue
+			}
+			if inst, ok := byIP[targets[i].IP]; ok {
+				targets[i].InstanceID = inst.InstanceID
+				targets[i].ResolvedBy = teardownResolvedByIP
+			}
+		}
+	}
+
+	// 2) 按部署标签补充遗漏实例（例如创建后未拿到公网 IP、或记录丢失）
+	if deploymentID != "" {
+		tagged, err := describeTaggedInstances(ctx, client, deploymentID)
+		if err != nil {
+			return nil, err
+		}
+		targets = mergeTaggedTeardownTargets(targets, tagged)
+	}
+
+	ids := uniqueTeardownInstanceIDs(targets)
+	log.Printf("👉 [shutdown] 开始通过 EC2 API 终止实例，共 %d 台（记录条目 %d）\n", len(ids), len(targets))
+
+	// 3) 分批终止并等待 terminated
+	failed := terminateInstances(ctx, client, ids)
+	submitted := make([]string, 0, len(ids))
+	for _, id := range ids {
+		if _, ok := failed[id]; !ok {
+			submitted = append(submitted, id)
+		}
+	}
+	var errs []error
+	if len(submitted) > 0 {
+		log.Printf("👉 [shutdown] 等待 %d 台实例进入 terminated 状态...\n", len(submitted))
+		errs = append(errs, waitInstancesTerminated(ctx, client, submitted)...)
+	}
+	states := map[string]string{}
+	if len(ids) > 0 {
+		if states, err = describeInstanceStates(ctx, client, ids); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	report := buildTeardownReport(targets, failed, states)
+	report.DeploymentID = deploymentID
+	report.Region = commonCfg.Region
+	recordEvents(outputDir, teardownEvents(report.Targets)...)
+
+	reportPath := filepath.Join(outputDir, teardownReportFileName)
+	if err := writeJSONFile(reportPath, report); err != nil {
+		errs = append(errs, fmt.Errorf("写入 %s 失败: %w", teardownReportFileName, err))
+	} else {
+		log.Printf("📝 [shutdown] teardown 报告已写入 %s\n", reportPath)
+	}
+
+	for id, err := range failed {
+		errs = append(errs, fmt.Errorf("[%s] %w", id, err))
+	}
+	if len(errs) > 0 {
+		return report, deployMultiError{errs: errs}
+	}
+	if report.Unresolved > 0 {
+		// 未解析的实例可能仍在运行并占用链 ID，释放后下次部署可能领用同一链 ID
+		log.Printf("⚠️ [shutdown] 有 %d 个节点未解析到实例，保留本部署的 L2 链 ID 登记（%s），确认实例均已终止后需手动删除对应条目\n", report.Unresolved, chainIDRegistryDisplayPath(commonCfg))
+	} else {
+		releaseDeploymentChainIDs(commonCfg, outputDir)
+	}
+	log.Printf("✅ [shutdown] 实例终止完成：terminated=%d, unresolved=%d\n", report.Terminated, report.Unresolved)
+	return report, nil
+}
+
+// teardownEvents 为已解析到实例的终止目标生成事件，State 为终止后的实例状态，Error 为失败原因。
+func teardownEvents(targets []TeardownTarget) []Event {
+	events := make([]Event, 0, len(targets))
+	for _, t := range targets {
+		if t.InstanceID == "" {
+			continue
+		}
+		events = append(events, Event{
+			Type:        EventTerminated,
+			IP:          t.IP,
+			ServiceType: t.ServiceType,
+			Name:        t.Name,
+			InstanceID:  t.InstanceID,
+			To:          t.State,
+			Message:     t.Error,
+		})
+	}
+	return events
+}
+
+// collectTeardownTargets 合并 servers.json 与 servers_create.json：
+// servers.json 条目可借用 servers_create.json 中同 IP/类型记录的实例 ID；未进入 servers.json 的创建记录单独列出。
+func collectTeardownTargets(servers []ServerInfo, created []CreatedServerInfo) []TeardownTarget {
+	createdByKey := make(map[string]CreatedServerInfo, len(created))
+	for _, c := range created {
+		createdByKey[compositeKey(c.IP, c.ServiceType)] = c
+	}
+
+	seen := make(map[string]struct{}, len(servers)+len(created))
+	targets := make([]TeardownTarget, 0, len(servers)+len(created))
+	for _, s := range servers {
+		ip, serviceType := strings.TrimSpace(s.IP), strings.TrimSpace(s.ServiceType)
+		if ip == "" {
+			continue
+		}
+		key := compositeKey(ip, serviceType)
+		if _, ok := seen[key]; ok {
+			continue
+		}
+		seen[key] = struct{}{}
+		t := TeardownTarget{Name: s.Name, ServiceType: serviceType, IP: ip, Source: teardownSourceServers}
+		if c, ok := createdByKey[key]; ok && c.InstanceID != "" {
+			t.InstanceID = c.InstanceID
+			t.ResolvedBy = teardownResolvedByRecord
+		}
+		targets = append(targets, t)
+	}
+	for _, c := range created {
+		key := compositeKey(c.IP, c.ServiceType)
+		if _, ok := seen[key]; ok {
+			continue
+		}
+		seen[key] = struct{}{}
+		t := TeardownTarget{Name: c.Name, ServiceType: c.ServiceType, IP: c.IP, InstanceID: c.InstanceID, Source: teardownSourceServersCreate}
+		if c.InstanceID != "" {
+			t.ResolvedBy = teardownResolvedByRecord
+		}
+		targets = append(targets, t)
+	}
+	return targets
+}
+
+func mergeTaggedTeardownTargets(targets []TeardownTarget, tagged []TaggedInstance) []TeardownTarget {
+	known := make(map[string]struct{}, len(targets))
+	for _, t := range targets {
+		if t.InstanceID != "" {
+			known[t.InstanceID] = struct{}{}
+		}
+	}
+	byIP := make(map[string]int, len(targets))
+	for i, t := range targets {
+		if t.InstanceID == "" && t.IP != "" {
+			byIP[t.IP] = i
+		}
+	}
+	for _, inst := range tagged {
+		if _, ok := known[inst.InstanceID]; ok {
+			continue
+		}
+		known[inst.InstanceID] = struct{}{}
+		if i, ok := byIP[inst.PublicIP]; ok && inst.PublicIP != "" {
+			targets[i].InstanceID = inst.InstanceID
+			targets[i].ResolvedBy = teardownResolvedByTag
+			continue
+		}
+		targets = append(targets, TeardownTarget{
+			Name:        inst.Name,
+			ServiceType: inst.ServiceType,
+			IP:          inst.PublicIP,
+			InstanceID:  inst.InstanceID,
+			Source:      teardownSourceTag,
+			ResolvedBy:  teardownResolvedByTag,
+		})
+	}
+	return targets
+}
+
+func uniqueTeardownInstanceIDs(targets []TeardownTarget) []string {
+	seen := make(map[string]struct{}, len(targets))
+	ids := make([]string, 0, len(targets))
+	for _, t := range targets {
+		if t.InstanceID == "" {
+			continue
+		}
+		if _, ok := seen[t.InstanceID]; ok {
+			continue
+		}
+		seen[t.InstanceID] = struct{}{}
+		ids = append(ids, t.InstanceID)
+	}
+	return ids
+}
+
+func buildTeardownReport(targets []TeardownTarget, failed map[string]error, states map[string]string) *TeardownReport {
+	report := &TeardownReport{
+		GeneratedAt: time.Now().UTC().Format(time.RFC3339),
+		Targets:     make([]TeardownTarget, 0, len(targets)),
+	}
+	for _, t := range targets {
+		switch {
+		case t.InstanceID == "":
+			t.Error = "未找到带本部署标签的存活实例（可能已终止，或 IP 已被其它实例复用）"
+			report.Unresolved++
+		case failed[t.InstanceID] != nil:
+			t.Error = failed[t.InstanceID].Error()
+			t.State = states[t.InstanceID]
+			report.Failed++
+		default:
+			t.State = states[t.InstanceID]
+			if t.State == ec2.InstanceStateNameTerminated {
+				report.Terminated++
+			} else {
+				if t.State == "" {
+					t.State = "unknown"
+				}
+				t.Error = "等待超时，实例尚未进入 terminated 状态"
+				report.Failed++
+			}
+		}
+		report.Targets = append(report.Targets, t)
+	}
+	return report
+}
+
+// chainIDRegistryDisplayPath 返回用于日志提示的链 ID 登记文件路径。
+func chainIDRegistryDisplayPath(commonCfg CommonConfig) string {
+	registryPath, err := resolveChainIDRegistryPath(commonCfg)
+	if err != nil {
+		return chainIDRegistryFileName
+	}
+	return registryPath
+}
+
+// releaseDeploymentChainIDs 在实例全部终止后释放该 output 目录登记的 L2 链 ID；失败仅告警。
+func releaseDeploymentChainIDs(commonCfg CommonConfig, outputDir string) {
+	registryPath, err := resolveChainIDRegistryPath(commonCfg)
+	if err != nil {
+		log.Printf("⚠️ [shutdown] 释放 L2 链 ID 登记失败: %v\n", err)
+		return
+	}
+	released, err := releaseChainIDs(registryPath, absOutputDir(outputDir))
+	if err != nil {
+		log.Printf("⚠️ [shutdown] 释放 L2 链 ID 登记失败: %v\n", err)
+		return
+	}
+	if released > 0 {
+		log.Printf("ℹ️ [shutdown] 已释放 %d 个 L2 链 ID 登记: %s\n", released, registryPath)
+	}
+}
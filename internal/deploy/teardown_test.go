@@ -0,0 +1,261 @@
+package deploy
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/aws/aws-sdk-go/service/ec2/ec2iface"
+	"github.com/wangdayong228/ydyl-deploy-client/internal/constants/enums"
+)
+
+// fakeEC2 仅实现 teardown / inventory 用到的 DescribeInstances / TerminateInstances / 等待接口。
+type fakeEC2 struct {
+	ec2iface.EC2API
+
+	mu         sync.Mutex
+	instances  map[string]*ec2.Instance
+	terminated [][]string
+}
+
+func newFakeEC2(instances ...*ec2.Instance) *fakeEC2 {
+	f := &fakeEC2{instances: make(map[string]*ec2.Instance)}
+	for _, inst := range instances {
+		f.instances[aws.StringValue(inst.InstanceId)] = inst
+	}
+	return f
+}
+
+func fakeInstance(id, ip, deploymentID string) *ec2.Instance {
+	inst := &ec2.Instance{
+		InstanceId:      aws.String(id),
+		PublicIpAddress: aws.String(ip),
+		State:           &ec2.InstanceState{Name: aws.String(ec2.InstanceStateNameRunning)},
+	}
+	if deploymentID != "" {
+		inst.Tags = []*ec2.Tag{{Key: aws.String(TagKeyDeploymentID), Value: aws.String(deploymentID)}}
+	}
+	return inst
+}
+
+func (f *fakeEC2) DescribeInstancesPagesWithContext(_ aws.Context, input *ec2.DescribeInstancesInput, fn func(*ec2.DescribeInstancesOutput, bool) bool, _ ...request.Option) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	ids := make([]string, 0, len(f.instances))
+	for id := range f.instances {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	var matched []*ec2.Instance
+	for _, id := range ids {
+		inst := f.instances[id]
+		if len(input.InstanceIds) > 0 && !containsString(aws.StringValueSlice(input.InstanceIds), id) {
+			continue
+		}
+		if fakeMatchesFilters(inst, input.Filters) {
+			matched = append(matched, inst)
+		}
+	}
+	fn(&ec2.DescribeInstancesOutput{Reservations: []*ec2.Reservation{{Instances: matched}}}, true)
+	return nil
+}
+
+func (f *fakeEC2) TerminateInstancesWithContext(_ aws.Context, input *ec2.TerminateInstancesInput, _ ...request.Option) (*ec2.TerminateInstancesOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	ids := aws.StringValueSlice(input.InstanceIds)
+	f.terminated = append(f.terminated, ids)
+	for _, id := range ids {
+		if inst, ok := f.instances[id]; ok {
+			inst.State = &ec2.InstanceState{Name: aws.String(ec2.InstanceStateNameTerminated)}
+		}
+	}
+	return &ec2.TerminateInstancesOutput{}, nil
+}
+
+func (f *fakeEC2) WaitUntilInstanceTerminatedWithContext(aws.Context, *ec2.DescribeInstancesInput, ...request.WaiterOption) error {
+	return nil
+}
+
+func fakeMatchesFilters(inst *ec2.Instance, filters []*ec2.Filter) bool {
+	for _, filter := range filters {
+		name := aws.StringValue(filter.Name)
+		values := aws.StringValueSlice(filter.Values)
+		switch {
+		case name == "instance-state-name":
+			if !containsString(values, aws.StringValue(inst.State.Name)) {
+				return false
+			}
+		case name == "ip-address":
+			if !containsString(values, aws.StringValue(inst.PublicIpAddress)) {
+				return false
+			}
+		case name == "tag-key":
+			if !fakeHasTag(inst, values[0], "") {
+				return false
+			}
+		case strings.HasPrefix(name, "tag:"):
+			if !fakeHasTag(inst, strings.TrimPrefix(name, "tag:"), values[0]) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func fakeHasTag(inst *ec2.Instance, key, value string) bool {
+	for _, tag := range inst.Tags {
+		if aws.StringValue(tag.Key) == key && (value == "" || aws.StringValue(tag.Value) == value) {
+			return true
+		}
+	}
+	return false
+}
+
+func containsString(list []string, v string) bool {
+	for _, item := range list {
+		if item == v {
+			return true
+		}
+	}
+	return false
+}
+
+func TestTerminate_CoversServersCreateAndTaggedInstances(t *testing.T) {
+	fake := newFakeEC2(
+		fakeInstance("i-op-1", "1.1.1.1", "dep-1"),
+		fakeInstance("i-op-ssh-failed", "2.2.2.2", "dep-1"),
+		fakeInstance("i-by-ip", "3.3.3.3", "dep-1"),
+		fakeInstance("i-no-record", "4.4.4.4", "dep-1"),
+		fakeInstance("i-other-deployment", "5.5.5.5", "dep-2"),
+		fakeInstance("i-reused-ip", "9.9.9.9", ""),
+	)
+	orig := newEC2APIFunc
+	t.Cleanup(func() { newEC2APIFunc = orig })
+	newEC2APIFunc = func(string) (ec2iface.EC2API, error) { return fake, nil }
+
+	outputDir := t.TempDir()
+	mgr := NewOutputManager(outputDir)
+	if err := mgr.AddCreatedServers([]CreatedServerInfo{
+		{Name: "ydyl-op-create-1", ServiceType: "op", IP: "1.1.1.1", InstanceID: "i-op-1"},
+		{Name: "ydyl-op-create-2", ServiceType: "op", IP: "2.2.2.2", InstanceID: "i-op-ssh-failed"},
+	}); err != nil {
+		t.Fatalf("AddCreatedServers: %v", err)
+	}
+	if err := mgr.AddServers([]ServerInfo{
+		{IP: "1.1.1.1", ServiceType: "op", Name: "ydyl-op-1"},
+		{IP: "3.3.3.3", ServiceType: "cdk", Name: "ydyl-cdk-1"},
+		{IP: "9.9.9.9", ServiceType: "cdk", Name: "ydyl-cdk-gone"},
+		{IP: "5.5.5.5", ServiceType: "cdk", Name: "ydyl-cdk-stale"},
+	}); err != nil {
+		t.Fatalf("AddServers: %v", err)
+	}
+	if err := SaveDeploymentInfo(outputDir, DeploymentInfo{DeploymentID: "dep-1"}); err != nil {
+		t.Fatalf("SaveDeploymentInfo: %v", err)
+	}
+
+	report, err := Terminate(context.Background(), CommonConfig{}, filepath.Join(outputDir, "servers.json"))
+	if err != nil {
+		t.Fatalf("Terminate error: %v", err)
+	}
+
+	if len(fake.terminated) != 1 {
+		t.Fatalf("expected one terminate batch, got=%v", fake.terminated)
+	}
+	gotIDs := append([]string(nil), fake.terminated[0]...)
+	sort.Strings(gotIDs)
+	wantIDs := []string{"i-by-ip", "i-no-record", "i-op-1", "i-op-ssh-failed"}
+	if strings.Join(gotIDs, ",") != strings.Join(wantIDs, ",") {
+		t.Fatalf("unexpected terminated ids: got=%v want=%v", gotIDs, wantIDs)
+	}
+	// 9.9.9.9 / 5.5.5.5 已被无标签或其它部署的实例复用，不得按 IP 终止。
+	if report.Terminated != 4 || report.Unresolved != 2 || report.Failed != 0 {
+		t.Fatalf("unexpected report counts: %+v", report)
+	}
+
+	sources := make(map[string]string)
+	for _, target := range report.Targets {
+		sources[target.InstanceID] = target.Source + "/" + target.ResolvedBy
+	}
+	want := map[string]string{
+		"i-op-1":          "servers/record",
+		"i-by-ip":         "servers/ip",
+		"i-op-ssh-failed": "servers_create/record",
+		"i-no-record":     "tag/tag",
+	}
+	for id, w := range want {
+		if sources[id] != w {
+			t.Fatalf("unexpected source for %s: got=%s want=%s", id, sources[id], w)
+		}
+	}
+
+	data, err := os.ReadFile(filepath.Join(outputDir, teardownReportFileName))
+	if err != nil {
+		t.Fatalf("read teardown report: %v", err)
+	}
+	var saved TeardownReport
+	if err := json.Unmarshal(data, &saved); err != nil {
+		t.Fatalf("unmarshal teardown report: %v", err)
+	}
+	if saved.DeploymentID != "dep-1" || len(saved.Targets) != 6 {
+		t.Fatalf("unexpected saved report: %+v", saved)
+	}
+}
+
+func TestTerminate_KeepsChainIDsWhenUnresolved(t *testing.T) {
+	orig := newEC2APIFunc
+	t.Cleanup(func() { newEC2APIFunc = orig })
+
+	registry := filepath.Join(t.TempDir(), "chain_ids.json")
+	commonCfg := CommonConfig{L1ChainId: "7655", ChainIDRegistry: registry}
+	cfg := DeployConfig{CommonConfig: commonCfg, Services: []ServiceConfig{{Type: enums.ServiceTypeOP, Count: 2}}}
+	seed := func(servers ...ServerInfo) string {
+		outputDir := t.TempDir()
+		if err := NewOutputManager(outputDir).AddServers(servers); err != nil {
+			t.Fatalf("AddServers: %v", err)
+		}
+		if err := SaveDeploymentInfo(outputDir, DeploymentInfo{DeploymentID: "dep-1"}); err != nil {
+			t.Fatalf("SaveDeploymentInfo: %v", err)
+		}
+		if err := claimChainIDs(registry, absOutputDir(outputDir), buildChainIDClaims(cfg, "dep-1", absOutputDir(outputDir), time.Now())); err != nil {
+			t.Fatalf("claimChainIDs: %v", err)
+		}
+		return outputDir
+	}
+
+	// 1.1.1.2 上的实例缺少本部署标签，记为 unresolved：链 ID 登记须保留
+	newEC2APIFunc = func(string) (ec2iface.EC2API, error) {
+		return newFakeEC2(fakeInstance("i-op-1", "1.1.1.1", "dep-1"), fakeInstance("i-untagged", "1.1.1.2", "")), nil
+	}
+	outputDir := seed(ServerInfo{IP: "1.1.1.1", ServiceType: "op", Name: "ydyl-op-1"}, ServerInfo{IP: "1.1.1.2", ServiceType: "op", Name: "ydyl-op-2"})
+	report, err := Terminate(context.Background(), commonCfg, filepath.Join(outputDir, "servers.json"))
+	if err != nil || report.Unresolved != 1 {
+		t.Fatalf("Terminate: report=%+v err=%v", report, err)
+	}
+	if err := claimChainIDs(registry, "/out/other", buildChainIDClaims(cfg, "dep-2", "/out/other", time.Now())); err == nil {
+		t.Fatalf("chain ids must stay claimed while instances are unresolved")
+	}
+
+	// 全部解析并终止后释放
+	newEC2APIFunc = func(string) (ec2iface.EC2API, error) {
+		return newFakeEC2(fakeInstance("i-op-1", "1.1.1.1", "dep-1"), fakeInstance("i-op-2", "1.1.1.2", "dep-1")), nil
+	}
+	if report, err := Terminate(context.Background(), commonCfg, filepath.Join(outputDir, "servers.json")); err != nil || report.Unresolved != 0 {
+		t.Fatalf("Terminate: report=%+v err=%v", report, err)
+	}
+	if err := claimChainIDs(registry, "/out/other", buildChainIDClaims(cfg, "dep-2", "/out/other", time.Now())); err != nil {
+		t.Fatalf("chain ids should be released once every instance is terminated: %v", err)
+	}
+}
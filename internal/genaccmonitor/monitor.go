@@ -10,6 +10,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/wangdayong228/ydyl-deploy-client/internal/chainstack"
 	"github.com/wangdayong228/ydyl-deploy-client/internal/deploy"
 	ydylconsolesdk "github.com/wangdayong228/ydyl-deploy-client/pkg/ydyl-console-service-sdk"
 )
@@ -52,7 +53,7 @@ func Run(ctx context.Context, p Params) error {
 	}
 	targets := pickMonitorTargets(servers)
 	if len(targets) == 0 {
-		return fmt.Errorf("servers 中没有可监控的链节点（serviceType 仅支持 op/cdk/xjst）")
+		return fmt.Errorf("servers 中没有可监控的链节点（serviceType 仅支持 %s）", strings.Join(chainstack.L2Names(), "/"))
 	}
 
 	if err := runOneRound(ctx, targets, p.OutPath); err != nil {
@@ -139,7 +140,7 @@ func pickMonitorTargets(servers []deploy.ServerInfo) []deploy.ServerInfo {
 	out := make([]deploy.ServerInfo, 0, len(servers))
 	for _, s := range servers {
 		t := strings.ToLower(strings.TrimSpace(s.ServiceType))
-		if t != "op" && t != "cdk" && t != "xjst" {
+		if stack, ok := chainstack.LookupName(t); !ok || !stack.IsL2() {
 			continue
 		}
 		ip := strings.TrimSpace(s.IP)
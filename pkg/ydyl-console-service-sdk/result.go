@@ -62,3 +62,11 @@ func (r Result) GetXjstNodeDeploymentContracts(ctx context.Context) (*XjstNodeDe
 	}
 	return &out, nil
 }
+
+func (r Result) GetArbNodeDeploymentContracts(ctx context.Context) (*ArbNodeDeploymentContracts, error) {
+	var out ArbNodeDeploymentContracts
+	if err := r.http.get(ctx, "/v1/result/node-deployment-contracts/arb", &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
@@ -87,3 +87,18 @@ type XjstNodeDeploymentContracts struct {
 	L2StateSender      common.Address
 	L2UnifiedBridge    common.Address
 }
+
+type ArbNodeDeploymentContracts struct {
+	Rollup           common.Address
+	Bridge           common.Address
+	Inbox            common.Address
+	Outbox           common.Address
+	SequencerInbox   common.Address
+	RollupEventInbox common.Address
+	ChallengeManager common.Address
+	UpgradeExecutor  common.Address
+	L1GatewayRouter  common.Address
+	L1ERC20Gateway   common.Address
+	L2GatewayRouter  common.Address
+	L2ERC20Gateway   common.Address
+}